@@ -10,11 +10,18 @@ import (
 	clientsUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/client/usecase"
 	dealDeliveryPkg "github.com/KeynihAV/exchange/pkg/broker/deal/delivery"
 	dealUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/deal/usecase"
+	depthDeliveryPkg "github.com/KeynihAV/exchange/pkg/broker/depth/delivery"
+	depthUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/depth/usecase"
+	instrumentDeliveryPkg "github.com/KeynihAV/exchange/pkg/broker/instrument/delivery"
+	marketDeliveryPkg "github.com/KeynihAV/exchange/pkg/broker/market/delivery"
 	metricsPkg "github.com/KeynihAV/exchange/pkg/broker/metrics"
 	sessDeliveryPkg "github.com/KeynihAV/exchange/pkg/broker/session/delivery"
 	sessUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/session/usecase"
 	statsDeliveryPkg "github.com/KeynihAV/exchange/pkg/broker/stats/delivery"
-	statsRepoPkg "github.com/KeynihAV/exchange/pkg/broker/stats/repo"
+	statsUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/stats/usecase"
+	streamPkg "github.com/KeynihAV/exchange/pkg/broker/stream"
+	streamDeliveryPkg "github.com/KeynihAV/exchange/pkg/broker/stream/delivery"
+	"github.com/KeynihAV/exchange/pkg/common"
 	configPkg "github.com/KeynihAV/exchange/pkg/config"
 	"github.com/KeynihAV/exchange/pkg/logging"
 	"github.com/gorilla/mux"
@@ -58,12 +65,14 @@ func startBroker(db *sql.DB, config *configPkg.Config, logger *logging.Logger) e
 	if err != nil {
 		return err
 	}
-	statsRepo, err := statsRepoPkg.NewStatsRepo(db)
+	statsManager, err := statsUsecasePkg.NewStatsManager(db)
 	if err != nil {
 		return err
 	}
 
-	dealsManager, err := dealUsecasePkg.NewDealsManager(db, config)
+	depthManager := depthUsecasePkg.NewDepthManager()
+
+	dealsManager, err := dealUsecasePkg.NewDealsManager(db, clientsManager.CR, depthManager, config)
 	if err != nil {
 		return err
 	}
@@ -79,27 +88,66 @@ func startBroker(db *sql.DB, config *configPkg.Config, logger *logging.Logger) e
 		Config:         config,
 	}
 
-	go statsDeliveryPkg.ConsumeStats(statsRepo, config, logger)
+	supervisor := streamPkg.NewSupervisor(logger)
+
+	go statsDeliveryPkg.ConsumeStats(statsManager, config, supervisor, logger)
+
+	go statsManager.RollupCandles(logger)
+
+	go statsDeliveryPkg.ConsumeTrades(statsManager, config, supervisor, logger)
+
+	go depthDeliveryPkg.ConsumeDepth(depthManager, config, supervisor, logger)
 
-	go dealDeliveryPkg.ConsumeDeals(dealsManager, config, logger)
+	go dealDeliveryPkg.ConsumeDeals(dealsManager, config, supervisor, logger)
+
+	go dealsManager.DispatchOutbox(logger)
+
+	go marketDeliveryPkg.ConsumeSession(dealsManager.Market, config, supervisor, logger)
+
+	go instrumentDeliveryPkg.SyncInstruments(dealsManager.Instruments, config, logger)
+	go instrumentDeliveryPkg.ConsumeHalts(dealsManager.Instruments, config, supervisor, logger)
 
 	logger.Zap.Info("starting broker",
 		zap.String("logger", "ZAP"),
 		zap.Int("port", config.HTTP.Port),
 	)
+	if config.Broker.AdminToken == "" {
+		logger.Zap.Warn("broker.adminToken is empty, admin requests are forbidden",
+			zap.String("logger", "ZAP"),
+		)
+	}
 
 	clientsHandler := clientDeliveryPkg.ClientsHandler{ClientsManager: clientsManager}
-	statsHandler := statsDeliveryPkg.StatsHandler{StatsRepo: statsRepo}
+	statsHandler := statsDeliveryPkg.StatsHandler{StatsManager: statsManager}
 	dealsHandler := dealDeliveryPkg.DealsHandler{DealsManager: dealsManager, Config: config}
+	depthHandler := depthDeliveryPkg.DepthHandler{DepthManager: depthManager}
+	marketHandler := marketDeliveryPkg.MarketHandler{MarketManager: dealsManager.Market}
+	instrumentsHandler := instrumentDeliveryPkg.InstrumentsHandler{InstrumentsManager: dealsManager.Instruments}
+	healthHandler := streamDeliveryPkg.HealthHandler{Supervisor: supervisor}
 
 	r := mux.NewRouter()
 	r.HandleFunc("/api/v1/stats/{ticker}", statsHandler.GeStatsByTicker).Methods("GET")
+	r.HandleFunc("/api/v1/trades/{ticker}", statsHandler.TradesByTicker).Methods("GET")
+	r.HandleFunc("/api/v1/indicators/{ticker}", statsHandler.Indicators).Methods("GET")
+	r.HandleFunc("/api/v1/depth/{ticker}", depthHandler.GetDepth).Methods("GET")
+	r.HandleFunc("/api/v1/market", marketHandler.GetStatus).Methods("GET")
+	r.HandleFunc("/api/v1/instruments", instrumentsHandler.List).Methods("GET")
 	r.HandleFunc("/api/v1/deal", dealsHandler.CreateOrder).Methods("POST")
 	r.HandleFunc("/api/v1/cancel/{order}", dealsHandler.CancelOrder).Methods("DELETE")
+	r.HandleFunc("/api/v1/order/{order}", dealsHandler.ReplaceOrder).Methods("PATCH")
 	r.HandleFunc("/api/v1/orders/byClient/{client}", dealsHandler.OrdersByClient).Methods("GET")
+	r.HandleFunc("/api/v1/orders/{order}/history", dealsHandler.OrderHistory).Methods("GET")
+	r.HandleFunc("/api/v1/deals/byClient/{client}", dealsHandler.TradesByClient).Methods("GET")
+	r.HandleFunc("/api/v1/statement/{client}", dealsHandler.Statement).Methods("GET")
 	r.HandleFunc("/api/v1/status/{client}", clientsHandler.GetBalance).Methods("GET")
+	r.HandleFunc("/api/v1/cash/{client}", clientsHandler.GetCash).Methods("GET")
+	r.HandleFunc("/api/v1/cash/{client}/deposit", common.AdminOnly(config.Broker.AdminToken, clientsHandler.Deposit)).Methods("POST")
+	r.HandleFunc("/api/v1/cash/{client}/withdraw", common.AdminOnly(config.Broker.AdminToken, clientsHandler.Withdraw)).Methods("POST")
+	r.HandleFunc("/api/v1/risk/{client}", clientsHandler.GetRiskProfile).Methods("GET")
+	r.HandleFunc("/api/v1/risk/{client}", common.AdminOnly(config.Broker.AdminToken, clientsHandler.SetRiskProfile)).Methods("PUT")
 	r.HandleFunc("/api/v1/checkAuth", sessHandler.CheckAuth).Methods("POST")
 	r.HandleFunc("/api/v1/user/login_oauth", sessHandler.AuthCallback).Methods("GET")
+	r.HandleFunc("/api/v1/health", healthHandler.Health).Methods("GET")
 	r.HandleFunc("/metrics", promhttp.Handler().ServeHTTP)
 
 	mux := logger.WriteAccessLog(r)
@@ -5,14 +5,19 @@ import (
 	"database/sql"
 	"fmt"
 	"net"
-	"os"
+	"net/http"
 	"path/filepath"
 	"strconv"
 
 	configPkg "github.com/KeynihAV/exchange/pkg/config"
 	dealDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/delivery"
 	dealsFlowDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow/delivery"
+	dealsFlowUsecasePkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow/usecase"
+	instrumentDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument/delivery"
+	instrumentUsecasePkg "github.com/KeynihAV/exchange/pkg/exchange/instrument/usecase"
 	"github.com/KeynihAV/exchange/pkg/logging"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
@@ -28,13 +33,15 @@ func main() {
 
 	exConfig := &configPkg.Config{}
 	configPkg.Read(appName, exConfig)
-	filePath, err := filepath.Abs(exConfig.Exchange.DealsFlowFile)
-	if err != nil {
-		logger.Zap.Fatal("not find deals flow file",
-			zap.String("logger", "ZAP"),
-			zap.String("err: ", err.Error()))
+	if exConfig.Exchange.DealsFlowFile != "" {
+		filePath, err := filepath.Abs(exConfig.Exchange.DealsFlowFile)
+		if err != nil {
+			logger.Zap.Fatal("not find deals flow file",
+				zap.String("logger", "ZAP"),
+				zap.String("err: ", err.Error()))
+		}
+		exConfig.Exchange.DealsFlowFile = filePath
 	}
-	exConfig.Exchange.DealsFlowFile = filePath
 
 	db, err := initDB(exConfig)
 	if err != nil {
@@ -64,20 +71,43 @@ func StartExchange(ctx context.Context, db *sql.DB, config *configPkg.Config, lo
 		return err
 	}
 	dealDeliveryPkg.RegisterExchangeServer(grpcServer, exchangeServer)
+	if exchangeServer.DealsManager.Instruments.Empty() && config.Exchange.AllowUnlisted {
+		logger.Zap.Warn("instruments registry is empty, orders are not checked against it",
+			zap.String("logger", "ZAP"),
+		)
+	} else if exchangeServer.DealsManager.Instruments.Empty() {
+		logger.Zap.Error("instruments registry is empty, all orders are rejected until instruments are added",
+			zap.String("logger", "ZAP"),
+		)
+	}
 
 	go func() {
 		<-ctx.Done()
 		grpcServer.Stop()
 	}()
 
-	file, err := os.Open(config.Exchange.DealsFlowFile)
+	replay, err := dealsFlowUsecasePkg.NewReplay(config, exchangeServer.DealsManager.DealsFlowCh)
 	if err != nil {
 		return err
 	}
-	go dealsFlowDeliveryPkg.StartFlow(file, exchangeServer.DealsManager.DealsFlowCh, logger)
+	go replay.Run(logger)
 
 	go exchangeServer.DealsManager.ProcessingTradingOperations(config.Exchange.TradingInterval, logger)
 
+	go exchangeServer.DealsManager.SweepExpiredOrders(logger)
+
+	go exchangeServer.DealsManager.RunSession(logger)
+
+	go exchangeServer.DealsManager.RunBreaker(logger)
+
+	if config.Exchange.MetricsPort > 0 {
+		go listenMetrics(":"+strconv.Itoa(config.Exchange.MetricsPort), logger)
+	}
+
+	if config.Exchange.AdminPort > 0 {
+		go listenAdmin(":"+strconv.Itoa(config.Exchange.AdminPort), replay, exchangeServer.DealsManager.Instruments, logger)
+	}
+
 	logger.Zap.Info("starting exchange server",
 		zap.String("logger", "ZAP"),
 		zap.Int("port", config.HTTP.Port),
@@ -87,6 +117,42 @@ func StartExchange(ctx context.Context, db *sql.DB, config *configPkg.Config, lo
 	return err
 }
 
+func listenMetrics(addr string, logger *logging.Logger) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		logger.Zap.Error("metrics server",
+			zap.String("logger", "ZAP"),
+			zap.String("err", err.Error()))
+	}
+}
+
+func listenAdmin(addr string, replay *dealsFlowUsecasePkg.Replay, instruments *instrumentUsecasePkg.InstrumentsManager,
+	logger *logging.Logger) {
+	replayHandler := dealsFlowDeliveryPkg.ReplayHandler{Replay: replay}
+	instrumentsHandler := instrumentDeliveryPkg.InstrumentsHandler{InstrumentsManager: instruments}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/v1/replay", replayHandler.Status).Methods("GET")
+	r.HandleFunc("/api/v1/replay/pause", replayHandler.Pause).Methods("POST")
+	r.HandleFunc("/api/v1/replay/resume", replayHandler.Resume).Methods("POST")
+	r.HandleFunc("/api/v1/replay/seek", replayHandler.Seek).Methods("POST")
+	r.HandleFunc("/api/v1/instruments", instrumentsHandler.List).Methods("GET")
+	r.HandleFunc("/api/v1/instruments/{ticker}", instrumentsHandler.Save).Methods("PUT")
+
+	handler := logger.WriteAccessLog(r)
+	handler = logger.SetupLogger(handler)
+	handler = logger.AddReqID(handler)
+
+	err := http.ListenAndServe(addr, handler)
+	if err != nil {
+		logger.Zap.Error("admin server",
+			zap.String("logger", "ZAP"),
+			zap.String("err", err.Error()))
+	}
+}
+
 func initDB(config *configPkg.Config) (*sql.DB, error) {
 	dbName := "exchange"
 
@@ -1,6 +1,28 @@
 package client
 
-import dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+import (
+	"errors"
+
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+)
+
+const (
+	CashDeposit  = "deposit"
+	CashWithdraw = "withdraw"
+	CashBuy      = "buy"
+	CashSell     = "sell"
+)
+
+// коды отказа в заявке, отдаются клиенту вместе с текстом ошибки
+const (
+	CodeInsufficientFunds = "insufficient_funds"
+	CodeShortSelling      = "short_selling"
+	CodePositionLimit     = "position_limit"
+	CodeExposureLimit     = "exposure_limit"
+)
+
+// ErrInsufficientFunds - не хватает свободных денег клиента на покупку или вывод
+var ErrInsufficientFunds = errors.New("insufficient funds")
 
 type Client struct {
 	ID      int
@@ -10,17 +32,86 @@ type Client struct {
 	Balance float32
 }
 
+// Position - позиция клиента: Price - средняя цена открытых лотов, Total - их себестоимость,
+// Realized - реализованный результат закрытых лотов, Unrealized - результат открытых лотов по LastPrice
 type Position struct {
-	ID       int
+	ID         int
+	ClientID   int32
+	Ticker     string
+	Volume     int32
+	Price      float32
+	Total      float32
+	Realized   float32
+	LastPrice  float32
+	Unrealized float32
+}
+
+// CashFlow - движение денег клиента, баланс меняется только вместе с записью в ledger
+type CashFlow struct {
+	ID       int64
 	ClientID int32
-	Ticker   string
-	Volume   int32
-	Price    float32
-	Total    float32
+	Amount   float32
+	Reason   string
+	DealID   int64
+	Time     int32
+}
+
+// Cash - деньги клиента, Reserved - зарезервировано под неисполненные заявки на покупку
+type Cash struct {
+	Balance   float32
+	Reserved  float32
+	Available float32
+}
+
+// RiskProfile - ограничения клиента, 0 в лимите - без ограничений
+type RiskProfile struct {
+	ClientID    int32
+	AllowShort  bool
+	MaxPosition int32   // максимальная позиция по модулю в одном инструменте, шт.
+	MaxExposure float32 // максимальная суммарная позиция по всем инструментам в деньгах
+}
+
+// RiskError - заявка нарушает риск-профиль клиента
+type RiskError struct {
+	Code    string
+	Message string
+}
+
+func (e *RiskError) Error() string {
+	return e.Message
+}
+
+// RiskPosition - позиция клиента в инструменте вместе с неисполненными заявками
+type RiskPosition struct {
+	Ticker      string
+	Volume      int32
+	PendingBuy  int32
+	PendingSell int32
+	Price       float32 // последняя цена инструмента
+}
+
+// MaxVolume - позиция по модулю, если исполнятся все покупки или все продажи
+func (p *RiskPosition) MaxVolume() int32 {
+	long, short := p.Volume+p.PendingBuy, p.Volume-p.PendingSell
+	if long < 0 {
+		long = -long
+	}
+	if short < 0 {
+		short = -short
+	}
+	if long > short {
+		return long
+	}
+	return short
+}
+
+func (p *RiskPosition) Exposure() float32 {
+	return float32(p.MaxVolume()) * p.Price
 }
 
 type Dialog struct {
 	CurrentCommand string
 	LastMsg        string
 	CurrentOrder   *dealPkg.Order
+	Ticker         string
 }
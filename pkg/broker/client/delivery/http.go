@@ -1,9 +1,12 @@
 package delivery
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
 	clientUsecasekg "github.com/KeynihAV/exchange/pkg/broker/client/usecase"
 	"github.com/KeynihAV/exchange/pkg/common"
 	"github.com/gorilla/mux"
@@ -24,3 +27,110 @@ func (h *ClientsHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
 	}
 	common.WriteStructToResponse(positions, r.Context(), w)
 }
+
+func (h *ClientsHandler) GetCash(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clientID, err := strconv.Atoi(vars["client"])
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	cash, err := h.ClientsManager.GetCash(clientID)
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+	common.WriteStructToResponse(cash, r.Context(), w)
+}
+
+// Deposit и Withdraw принимают сумму в теле: {"Amount": 100}
+func (h *ClientsHandler) Deposit(w http.ResponseWriter, r *http.Request) {
+	h.changeCash(w, r, h.ClientsManager.Deposit)
+}
+
+func (h *ClientsHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	h.changeCash(w, r, h.ClientsManager.Withdraw)
+}
+
+func (h *ClientsHandler) changeCash(w http.ResponseWriter, r *http.Request, change func(clientID int, amount float32) error) {
+	vars := mux.Vars(r)
+	clientID, err := strconv.Atoi(vars["client"])
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	req := &struct{ Amount float32 }{}
+	ok := common.GetStructFromRequest(req, r, w)
+	if !ok {
+		return
+	}
+	if req.Amount <= 0 {
+		err = fmt.Errorf("amount must be positive")
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	err = change(clientID, req.Amount)
+	if errors.Is(err, clientPkg.ErrInsufficientFunds) {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+
+	cash, err := h.ClientsManager.GetCash(clientID)
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+	common.WriteStructToResponse(cash, r.Context(), w)
+}
+
+func (h *ClientsHandler) GetRiskProfile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clientID, err := strconv.Atoi(vars["client"])
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	profile, err := h.ClientsManager.GetRiskProfile(clientID)
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+	common.WriteStructToResponse(profile, r.Context(), w)
+}
+
+// SetRiskProfile принимает профиль целиком: {"AllowShort": false, "MaxPosition": 100, "MaxExposure": 10000}
+func (h *ClientsHandler) SetRiskProfile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clientID, err := strconv.Atoi(vars["client"])
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	profile := &clientPkg.RiskProfile{}
+	ok := common.GetStructFromRequest(profile, r, w)
+	if !ok {
+		return
+	}
+	profile.ClientID = int32(clientID)
+	if profile.MaxPosition < 0 || profile.MaxExposure < 0 {
+		err = fmt.Errorf("limits must not be negative")
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	err = h.ClientsManager.SetRiskProfile(profile)
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+	common.WriteStructToResponse(profile, r.Context(), w)
+}
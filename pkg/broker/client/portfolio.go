@@ -0,0 +1,79 @@
+package client
+
+// способы учета себестоимости позиции
+const (
+	CostFIFO    = "fifo"    // закрываются самые старые лоты
+	CostAverage = "average" // все лоты сливаются в один по средней цене
+)
+
+// Lot - открытая часть позиции по цене сделки, Volume < 0 - короткая позиция
+type Lot struct {
+	ID       int64
+	ClientID int32
+	Ticker   string
+	Volume   int32
+	Price    float32
+	DealID   int64
+	Time     int32
+}
+
+// ApplyFill закрывает лоты противоположного знака сделкой fill (Volume со знаком: покупка > 0, продажа < 0),
+// остаток сделки открывает новый лот. Возвращает оставшиеся лоты и реализованный результат
+func ApplyFill(lots []*Lot, fill *Lot, method string) ([]*Lot, float32) {
+	var realized float32
+	rest := fill.Volume
+	for len(lots) > 0 && rest != 0 && (lots[0].Volume > 0) != (rest > 0) {
+		lot := lots[0]
+		closed := abs(lot.Volume)
+		if abs(rest) < closed {
+			closed = abs(rest)
+		}
+		if lot.Volume > 0 {
+			realized += (fill.Price - lot.Price) * float32(closed)
+			lot.Volume -= closed
+			rest += closed
+		} else {
+			realized += (lot.Price - fill.Price) * float32(closed)
+			lot.Volume += closed
+			rest -= closed
+		}
+		if lot.Volume == 0 {
+			lots = lots[1:]
+		}
+	}
+
+	if rest != 0 {
+		opened := *fill
+		opened.Volume = rest
+		lots = append(lots, &opened)
+	}
+
+	if method == CostAverage && len(lots) > 1 {
+		merged := *lots[0]
+		merged.Volume, merged.Price = PositionCost(lots)
+		lots = []*Lot{&merged}
+	}
+
+	return lots, realized
+}
+
+// PositionCost - объем позиции и ее средняя цена по открытым лотам
+func PositionCost(lots []*Lot) (int32, float32) {
+	var volume int32
+	var total float32
+	for _, lot := range lots {
+		volume += lot.Volume
+		total += lot.Price * float32(lot.Volume)
+	}
+	if volume == 0 {
+		return 0, 0
+	}
+	return volume, total / float32(volume)
+}
+
+func abs(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"math"
+	"testing"
+)
+
+func TestApplyFill(t *testing.T) {
+	tests := []struct {
+		name         string
+		lots         []*Lot
+		fill         *Lot
+		method       string
+		wantLots     []Lot
+		wantRealized float32
+	}{
+		{name: "Покупка без позиции открывает лот",
+			fill:     &Lot{Volume: 5, Price: 100, DealID: 1},
+			method:   CostFIFO,
+			wantLots: []Lot{{Volume: 5, Price: 100, DealID: 1}},
+		},
+		{name: "FIFO закрывает сначала старые лоты",
+			lots:         []*Lot{{Volume: 2, Price: 100, DealID: 1}, {Volume: 3, Price: 110, DealID: 2}},
+			fill:         &Lot{Volume: -3, Price: 120, DealID: 3},
+			method:       CostFIFO,
+			wantLots:     []Lot{{Volume: 2, Price: 110, DealID: 2}},
+			wantRealized: 2*20 + 1*10,
+		},
+		{name: "Средняя цена сливает лоты в один",
+			lots:     []*Lot{{Volume: 2, Price: 100, DealID: 1}},
+			fill:     &Lot{Volume: 2, Price: 110, DealID: 2},
+			method:   CostAverage,
+			wantLots: []Lot{{Volume: 4, Price: 105, DealID: 1}},
+		},
+		{name: "Продажа больше позиции переворачивает ее в шорт",
+			lots:         []*Lot{{Volume: 2, Price: 100, DealID: 1}},
+			fill:         &Lot{Volume: -5, Price: 90, DealID: 2},
+			method:       CostFIFO,
+			wantLots:     []Lot{{Volume: -3, Price: 90, DealID: 2}},
+			wantRealized: -20,
+		},
+		{name: "Покупка закрывает шорт",
+			lots:         []*Lot{{Volume: -3, Price: 90, DealID: 1}},
+			fill:         &Lot{Volume: 3, Price: 80, DealID: 2},
+			method:       CostFIFO,
+			wantLots:     []Lot{},
+			wantRealized: 30,
+		},
+		{name: "Открывающий лот позиции до учета лотами закрывается по средней цене",
+			lots:         []*Lot{{Volume: 10, Price: 95.5, DealID: 0}},
+			fill:         &Lot{Volume: -4, Price: 100, DealID: 7},
+			method:       CostFIFO,
+			wantLots:     []Lot{{Volume: 6, Price: 95.5, DealID: 0}},
+			wantRealized: 4 * 4.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lots, realized := ApplyFill(tt.lots, tt.fill, tt.method)
+			if math.Abs(float64(realized-tt.wantRealized)) > 1e-3 {
+				t.Errorf("ApplyFill() realized = %v, want %v", realized, tt.wantRealized)
+			}
+			if len(lots) != len(tt.wantLots) {
+				t.Fatalf("ApplyFill() lots = %v, want %v", len(lots), len(tt.wantLots))
+			}
+			for i, lot := range lots {
+				want := tt.wantLots[i]
+				if lot.Volume != want.Volume || math.Abs(float64(lot.Price-want.Price)) > 1e-3 || lot.DealID != want.DealID {
+					t.Errorf("ApplyFill() lot %v = %+v, want %+v", i, *lot, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPositionCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		lots       []*Lot
+		wantVolume int32
+		wantPrice  float32
+	}{
+		{name: "Без лотов", wantVolume: 0, wantPrice: 0},
+		{name: "Средневзвешенная цена длинной позиции",
+			lots:       []*Lot{{Volume: 1, Price: 100}, {Volume: 3, Price: 120}},
+			wantVolume: 4, wantPrice: 115},
+		{name: "Короткая позиция",
+			lots:       []*Lot{{Volume: -2, Price: 90}, {Volume: -2, Price: 110}},
+			wantVolume: -4, wantPrice: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volume, price := PositionCost(tt.lots)
+			if volume != tt.wantVolume || math.Abs(float64(price-tt.wantPrice)) > 1e-3 {
+				t.Errorf("PositionCost() = %v, %v, want %v, %v", volume, price, tt.wantVolume, tt.wantPrice)
+			}
+		})
+	}
+}
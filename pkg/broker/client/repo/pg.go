@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+
 	_ "github.com/jackc/pgx/v5/stdlib"
 
 	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
@@ -35,13 +37,69 @@ func NewClientsRepo(db *sql.DB) (*ClientsRepo, error) {
 			ticker varchar(200) NOT NULL,
 			volume int NOT NULL,			
 			price float8 NOT NULL,
-			total float8 NOT NULL);
-		CREATE UNIQUE INDEX IF NOT EXISTS client_idx ON positions (clientID, ticker);`)
+			total float8 NOT NULL,
+			realized float8 NOT NULL DEFAULT 0);
+		CREATE UNIQUE INDEX IF NOT EXISTS client_idx ON positions (clientID, ticker);
+		ALTER TABLE positions ADD COLUMN IF NOT EXISTS realized float8 NOT NULL DEFAULT 0;`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS lots(
+			id SERIAL PRIMARY KEY,
+			clientID int NOT NULL,
+			ticker varchar(200) NOT NULL,
+			volume int NOT NULL,
+			price float8 NOT NULL,
+			dealID int NOT NULL,
+			time int NOT NULL);
+		CREATE INDEX IF NOT EXISTS lots_client_idx ON lots (clientID, ticker);`)
+	if err != nil {
+		return nil, err
+	}
+	cr := &ClientsRepo{DB: db}
+	err = cr.seedLots()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS ledger(
+			id SERIAL PRIMARY KEY,
+			clientID int NOT NULL,
+			amount float8 NOT NULL,
+			reason varchar(10) NOT NULL,
+			dealID int NOT NULL DEFAULT 0,
+			time int NOT NULL);
+		CREATE INDEX IF NOT EXISTS ledger_client_idx ON ledger (clientID);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS riskProfiles(
+			clientID int PRIMARY KEY,
+			allowShort boolean NOT NULL DEFAULT false,
+			maxPosition int NOT NULL DEFAULT 0,
+			maxExposure float8 NOT NULL DEFAULT 0);`)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ClientsRepo{DB: db}, nil
+	return cr, nil
+}
+
+// seedLots открывает по одному лоту на позиции, накопленные до учета лотами, иначе первая сделка по ним
+// пересчитает позицию только по новым лотам; средняя цена - total / volume, price в таких позициях -
+// простое среднее цен сделок. Позиции с лотами не меняются, поэтому повторный запуск ничего не делает
+func (cr *ClientsRepo) seedLots() error {
+	_, err := cr.DB.Exec(`INSERT INTO lots(clientID, ticker, volume, price, dealID, time)
+		SELECT p.clientID, p.ticker, p.volume, p.total / p.volume, 0, 0
+		FROM positions p
+		WHERE p.volume <> 0
+			AND NOT EXISTS (SELECT 1 FROM lots l WHERE l.clientID = p.clientID AND l.ticker = p.ticker)`)
+	return err
 }
 
 func (cr *ClientsRepo) GetByIDs(ids ...int64) (map[int64]*clientPkg.Client, error) {
@@ -92,8 +150,9 @@ func (cr *ClientsRepo) Add(client *clientPkg.Client) error {
 }
 
 func (cr *ClientsRepo) GetBalance(clientID int) ([]*clientPkg.Position, error) {
-	result, err := cr.DB.Query(`SELECT id, clientID, ticker, volume, price, total
-		 FROM positions WHERE clientID = $1`, clientID)
+	result, err := cr.DB.Query(`SELECT id, clientID, ticker, volume, price, total, realized,
+			COALESCE((SELECT close FROM stats WHERE stats.ticker = positions.ticker ORDER BY time DESC, id DESC LIMIT 1), 0)
+		FROM positions WHERE clientID = $1`, clientID)
 	if err != nil {
 		return nil, err
 	}
@@ -102,7 +161,8 @@ func (cr *ClientsRepo) GetBalance(clientID int) ([]*clientPkg.Position, error) {
 	positions := make([]*clientPkg.Position, 0)
 	for result.Next() {
 		position := &clientPkg.Position{}
-		err = result.Scan(&position.ID, &position.ClientID, &position.Ticker, &position.Volume, &position.Price, &position.Total)
+		err = result.Scan(&position.ID, &position.ClientID, &position.Ticker, &position.Volume, &position.Price, &position.Total,
+			&position.Realized, &position.LastPrice)
 		if err != nil {
 			return nil, err
 		}
@@ -111,3 +171,186 @@ func (cr *ClientsRepo) GetBalance(clientID int) ([]*clientPkg.Position, error) {
 
 	return positions, nil
 }
+
+// cashQuery - баланс клиента и резерв под остатки заявок на покупку по цене резервирования
+const cashQuery = `SELECT clients.balance,
+		COALESCE((SELECT SUM(orders.reservePrice * (orders.volume - orders.completedVolume))
+			FROM orders WHERE orders.clientID = clients.id AND orders.type = $2 AND orders.status <> $3), 0)
+	FROM clients WHERE clients.id = $1`
+
+func (cr *ClientsRepo) GetCash(clientID int) (*clientPkg.Cash, error) {
+	qr := cr.DB.QueryRow(cashQuery, clientID, dealPkg.TypeBuy, dealPkg.OrderStatusRejected)
+	return scanCash(qr)
+}
+
+// LockCash - деньги клиента с блокировкой строки клиента до конца транзакции,
+// чтобы параллельные заявки и вывод не потратили одни и те же деньги
+func (cr *ClientsRepo) LockCash(clientID int32, tx *sql.Tx) (*clientPkg.Cash, error) {
+	var id int32
+	err := tx.QueryRow(`SELECT id FROM clients WHERE id = $1 FOR UPDATE`, clientID).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	qr := tx.QueryRow(cashQuery, clientID, dealPkg.TypeBuy, dealPkg.OrderStatusRejected)
+	return scanCash(qr)
+}
+
+func scanCash(qr *sql.Row) (*clientPkg.Cash, error) {
+	cash := &clientPkg.Cash{}
+	err := qr.Scan(&cash.Balance, &cash.Reserved)
+	if err != nil {
+		return nil, err
+	}
+	cash.Available = cash.Balance - cash.Reserved
+	return cash, nil
+}
+
+// AddCashFlow записывает движение денег в ledger и меняет баланс клиента
+func (cr *ClientsRepo) AddCashFlow(flow *clientPkg.CashFlow, tx *sql.Tx) error {
+	_, err := tx.Exec(`INSERT INTO ledger(clientID, amount, reason, dealID, time) values($1, $2, $3, $4, $5)`,
+		flow.ClientID, flow.Amount, flow.Reason, flow.DealID, flow.Time)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`UPDATE clients SET balance = balance + $1 WHERE id = $2`, flow.Amount, flow.ClientID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return fmt.Errorf("client %v not found", flow.ClientID)
+	}
+	return nil
+}
+
+// GetRiskProfile - риск-профиль клиента, без записи - шорт запрещен, лимитов нет
+func (cr *ClientsRepo) GetRiskProfile(clientID int32) (*clientPkg.RiskProfile, error) {
+	qr := cr.DB.QueryRow(`SELECT clientID, allowShort, maxPosition, maxExposure FROM riskProfiles WHERE clientID = $1`, clientID)
+
+	profile := &clientPkg.RiskProfile{}
+	err := qr.Scan(&profile.ClientID, &profile.AllowShort, &profile.MaxPosition, &profile.MaxExposure)
+	if err == sql.ErrNoRows {
+		return &clientPkg.RiskProfile{ClientID: clientID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+func (cr *ClientsRepo) SetRiskProfile(profile *clientPkg.RiskProfile) error {
+	result, err := cr.DB.Exec(`INSERT INTO riskProfiles(clientID, allowShort, maxPosition, maxExposure) values($1, $2, $3, $4)
+		ON CONFLICT (clientID) DO UPDATE
+			SET allowShort = EXCLUDED.allowShort, maxPosition = EXCLUDED.maxPosition, maxExposure = EXCLUDED.maxExposure`,
+		profile.ClientID, profile.AllowShort, profile.MaxPosition, profile.MaxExposure)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		return fmt.Errorf("not save risk profile of client %v", profile.ClientID)
+	}
+	return nil
+}
+
+// GetLots - открытые лоты клиента по инструменту в порядке открытия
+func (cr *ClientsRepo) GetLots(clientID int32, ticker string, tx *sql.Tx) ([]*clientPkg.Lot, error) {
+	result, err := tx.Query(`SELECT id, clientID, ticker, volume, price, dealID, time
+		FROM lots WHERE clientID = $1 AND ticker = $2 ORDER BY id`, clientID, ticker)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	lots := make([]*clientPkg.Lot, 0)
+	for result.Next() {
+		lot := &clientPkg.Lot{}
+		err = result.Scan(&lot.ID, &lot.ClientID, &lot.Ticker, &lot.Volume, &lot.Price, &lot.DealID, &lot.Time)
+		if err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+
+	return lots, nil
+}
+
+// SaveLots заменяет открытые лоты клиента по инструменту, порядок лотов сохраняется
+func (cr *ClientsRepo) SaveLots(clientID int32, ticker string, lots []*clientPkg.Lot, tx *sql.Tx) error {
+	_, err := tx.Exec(`DELETE FROM lots WHERE clientID = $1 AND ticker = $2`, clientID, ticker)
+	if err != nil {
+		return err
+	}
+
+	for _, lot := range lots {
+		_, err = tx.Exec(`INSERT INTO lots(clientID, ticker, volume, price, dealID, time) values($1, $2, $3, $4, $5, $6)`,
+			clientID, ticker, lot.Volume, lot.Price, lot.DealID, lot.Time)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdatePosition пересчитывает позицию по открытым лотам и добавляет реализованный результат сделки
+func (cr *ClientsRepo) UpdatePosition(clientID int32, ticker string, lots []*clientPkg.Lot, realized float32, tx *sql.Tx) error {
+	volume, price := clientPkg.PositionCost(lots)
+	result, err := tx.Exec(`INSERT INTO positions (clientID, ticker, volume, price, total, realized)
+		values($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (clientID, ticker) DO UPDATE
+			SET volume = EXCLUDED.volume, price = EXCLUDED.price, total = EXCLUDED.total,
+				realized = positions.realized + EXCLUDED.realized`,
+		clientID, ticker, volume, price, price*float32(volume), realized)
+	if err != nil {
+		return err
+	}
+	_, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// CashFlows - движения денег клиента за [from, to) в порядке записи
+func (cr *ClientsRepo) CashFlows(clientID int32, from, to int32) ([]*clientPkg.CashFlow, error) {
+	result, err := cr.DB.Query(`SELECT id, clientID, amount, reason, dealID, time
+		FROM ledger WHERE clientID = $1 AND time >= $2 AND time < $3 ORDER BY id`, clientID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	flows := make([]*clientPkg.CashFlow, 0)
+	for result.Next() {
+		flow := &clientPkg.CashFlow{}
+		err = result.Scan(&flow.ID, &flow.ClientID, &flow.Amount, &flow.Reason, &flow.DealID, &flow.Time)
+		if err != nil {
+			return nil, err
+		}
+		flows = append(flows, flow)
+	}
+
+	return flows, nil
+}
+
+// CashAt - деньги клиента по ledger на момент time
+func (cr *ClientsRepo) CashAt(clientID int32, time int32) (float32, error) {
+	qr := cr.DB.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM ledger WHERE clientID = $1 AND time < $2`, clientID, time)
+
+	var cash float32
+	err := qr.Scan(&cash)
+	if err != nil {
+		return 0, err
+	}
+	return cash, nil
+}
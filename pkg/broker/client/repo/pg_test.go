@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -76,6 +77,45 @@ func TestClientsRepo_GetByIDs(t *testing.T) {
 	}
 }
 
+func TestClientsRepo_seedLots(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO lots`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Лоты открыты только для позиций без лотов",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO lots(.+)SELECT(.+)p.total / p.volume(.+)FROM positions p(.+)NOT EXISTS`).
+					WillReturnResult(sqlmock.NewResult(0, 3))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			if err := tt.cr.seedLots(); (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.seedLots() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestClientsRepo_Add(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -169,10 +209,11 @@ func TestClientsRepo_GetBalance(t *testing.T) {
 			cr:      &ClientsRepo{DB: db},
 			args:    args{&clientPkg.Client{}},
 			wantErr: false,
-			want:    []*clientPkg.Position{{ID: 1, ClientID: 1, Ticker: "ticker1", Volume: 10, Price: 100, Total: 1000}},
+			want: []*clientPkg.Position{{ID: 1, ClientID: 1, Ticker: "ticker1", Volume: 10, Price: 100, Total: 1000,
+				Realized: 50, LastPrice: 110}},
 			mockF: func(s sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "clientid", "ticker", "volume", "price", "total"}).
-					AddRow(1, 1, "ticker1", 10, 100, 1000)
+				rows := sqlmock.NewRows([]string{"id", "clientid", "ticker", "volume", "price", "total", "realized", "lastPrice"}).
+					AddRow(1, 1, "ticker1", 10, 100, 1000, 50, 110)
 				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
 			},
 		},
@@ -191,3 +232,542 @@ func TestClientsRepo_GetBalance(t *testing.T) {
 		})
 	}
 }
+
+func TestClientsRepo_GetCash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		want    *clientPkg.Cash
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT clients.balance`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Клиент не найден",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT clients.balance`).WillReturnRows(sqlmock.NewRows([]string{"balance", "reserved"}))
+			},
+		},
+		{name: "Успешный select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			want:    &clientPkg.Cash{Balance: 1000, Reserved: 300, Available: 700},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT clients.balance`).WithArgs(1, "buy", "rejected").
+					WillReturnRows(sqlmock.NewRows([]string{"balance", "reserved"}).AddRow(1000, 300))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.cr.GetCash(1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.GetCash() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClientsRepo.GetCash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientsRepo_LockCash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		want    *clientPkg.Cash
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Клиент не найден",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id FROM clients .* FOR UPDATE`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+			},
+		},
+		{name: "Ошибка select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id FROM clients .* FOR UPDATE`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectQuery(`SELECT clients.balance`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Успешный select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			want:    &clientPkg.Cash{Balance: 500, Reserved: 0, Available: 500},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id FROM clients .* FOR UPDATE`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectQuery(`SELECT clients.balance`).
+					WillReturnRows(sqlmock.NewRows([]string{"balance", "reserved"}).AddRow(500, 0))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.cr.LockCash(1, tx1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.LockCash() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClientsRepo.LockCash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientsRepo_AddCashFlow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	flow := &clientPkg.CashFlow{ClientID: 1, Amount: -250, Reason: clientPkg.CashBuy, DealID: 7, Time: 1}
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO ledger`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Клиент не найден",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO ledger`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`UPDATE clients SET balance`).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{name: "Успешная запись",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO ledger`).WithArgs(1, float64(-250), "buy", 7, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`UPDATE clients SET balance`).WithArgs(float64(-250), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			if err := tt.cr.AddCashFlow(flow, tx1); (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.AddCashFlow() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientsRepo_GetRiskProfile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		want    *clientPkg.RiskProfile
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT clientID, allowShort`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Профиль по умолчанию",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			want:    &clientPkg.RiskProfile{ClientID: 1},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT clientID, allowShort`).WillReturnRows(sqlmock.NewRows([]string{"clientID", "allowShort", "maxPosition", "maxExposure"}))
+			},
+		},
+		{name: "Успешный select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			want:    &clientPkg.RiskProfile{ClientID: 1, AllowShort: true, MaxPosition: 100, MaxExposure: 5000},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT clientID, allowShort`).WithArgs(1).
+					WillReturnRows(sqlmock.NewRows([]string{"clientID", "allowShort", "maxPosition", "maxExposure"}).AddRow(1, true, 100, 5000))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.cr.GetRiskProfile(1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.GetRiskProfile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClientsRepo.GetRiskProfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientsRepo_SetRiskProfile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	profile := &clientPkg.RiskProfile{ClientID: 1, AllowShort: false, MaxPosition: 100, MaxExposure: 5000}
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO riskProfiles`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Ошибка rows affected",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO riskProfiles`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+			},
+		},
+		{name: "Успешный insert",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO riskProfiles`).WithArgs(1, false, 100, float64(5000)).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			if err := tt.cr.SetRiskProfile(profile); (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.SetRiskProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientsRepo_GetLots(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		want    []*clientPkg.Lot
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id, clientID, ticker, volume, price, dealID, time`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Ошибка scan",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id, clientID, ticker, volume, price, dealID, time`).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "clientID"}).AddRow("one", 1))
+			},
+		},
+		{name: "Успешный select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			want: []*clientPkg.Lot{
+				{ID: 1, ClientID: 1, Ticker: "ticker1", Volume: 10, Price: 100, DealID: 5, Time: 1},
+				{ID: 2, ClientID: 1, Ticker: "ticker1", Volume: 5, Price: 110, DealID: 8, Time: 2},
+			},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id, clientID, ticker, volume, price, dealID, time`).WithArgs(1, "ticker1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "clientID", "ticker", "volume", "price", "dealID", "time"}).
+						AddRow(1, 1, "ticker1", 10, 100, 5, 1).AddRow(2, 1, "ticker1", 5, 110, 8, 2))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.cr.GetLots(1, "ticker1", tx1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.GetLots() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClientsRepo.GetLots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientsRepo_SaveLots(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	lots := []*clientPkg.Lot{{ClientID: 1, Ticker: "ticker1", Volume: 5, Price: 110, DealID: 8, Time: 2}}
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка delete",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`DELETE FROM lots`).WillReturnError(fmt.Errorf("delete error"))
+			},
+		},
+		{name: "Ошибка insert",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`DELETE FROM lots`).WillReturnResult(sqlmock.NewResult(0, 2))
+				s.ExpectExec(`INSERT INTO lots`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Успешная запись",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`DELETE FROM lots`).WithArgs(1, "ticker1").WillReturnResult(sqlmock.NewResult(0, 2))
+				s.ExpectExec(`INSERT INTO lots`).WithArgs(1, "ticker1", 5, float64(110), 8, 2).WillReturnResult(sqlmock.NewResult(3, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			if err := tt.cr.SaveLots(1, "ticker1", lots, tx1); (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.SaveLots() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientsRepo_UpdatePosition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	lots := []*clientPkg.Lot{{Volume: 10, Price: 100}, {Volume: 10, Price: 120}}
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO positions`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Ошибка rows affected",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO positions`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+			},
+		},
+		{name: "Средняя цена по лотам",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO positions`).WithArgs(1, "ticker1", 20, float64(110), float64(2200), float64(30)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			if err := tt.cr.UpdatePosition(1, "ticker1", lots, 30, tx1); (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.UpdatePosition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientsRepo_CashFlows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		want    []*clientPkg.CashFlow
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`FROM ledger`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Успешный select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			want: []*clientPkg.CashFlow{
+				{ID: 1, ClientID: 1, Amount: 1000, Reason: "deposit", Time: 100},
+				{ID: 2, ClientID: 1, Amount: -500, Reason: "buy", DealID: 3, Time: 200},
+			},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`FROM ledger`).WithArgs(1, 0, 1000).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "clientID", "amount", "reason", "dealID", "time"}).
+						AddRow(1, 1, 1000, "deposit", 0, 100).AddRow(2, 1, -500, "buy", 3, 200))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.cr.CashFlows(1, 0, 1000)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.CashFlows() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ClientsRepo.CashFlows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientsRepo_CashAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		cr      *ClientsRepo
+		want    float32
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM ledger`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Успешный select",
+			cr:      &ClientsRepo{DB: db},
+			wantErr: false,
+			want:    500,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT COALESCE\(SUM\(amount\), 0\) FROM ledger`).WithArgs(1, 1000).
+					WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(500))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.cr.CashAt(1, 1000)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ClientsRepo.CashAt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ClientsRepo.CashAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
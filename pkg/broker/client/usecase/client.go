@@ -1,7 +1,10 @@
 package usecase
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
 	clientRepoPkg "github.com/KeynihAV/exchange/pkg/broker/client/repo"
@@ -41,6 +44,77 @@ func (cm *ClientsManager) CheckAndCreateClient(login string, ID int64) (*clientP
 	return client, nil
 }
 
+// GetBalance - позиции клиента с нереализованным результатом по последней цене инструмента
 func (cm *ClientsManager) GetBalance(clientID int) ([]*clientPkg.Position, error) {
-	return cm.CR.GetBalance(clientID)
+	positions, err := cm.CR.GetBalance(clientID)
+	if err != nil {
+		return nil, err
+	}
+	for _, position := range positions {
+		if position.LastPrice > 0 {
+			position.Unrealized = (position.LastPrice - position.Price) * float32(position.Volume)
+		}
+	}
+	return positions, nil
+}
+
+func (cm *ClientsManager) GetCash(clientID int) (*clientPkg.Cash, error) {
+	return cm.CR.GetCash(clientID)
+}
+
+func (cm *ClientsManager) Deposit(clientID int, amount float32) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	return cm.changeCash(int32(clientID), amount, clientPkg.CashDeposit)
+}
+
+// Withdraw выводит только свободные деньги, зарезервированные под заявки остаются на счете
+func (cm *ClientsManager) Withdraw(clientID int, amount float32) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	return cm.changeCash(int32(clientID), -amount, clientPkg.CashWithdraw)
+}
+
+func (cm *ClientsManager) changeCash(clientID int32, amount float32, reason string) error {
+	tx, err := cm.CR.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	cash, err := cm.CR.LockCash(clientID, tx)
+	if err != nil {
+		return err
+	}
+	if cash.Available+amount < 0 {
+		err = fmt.Errorf("%w: available %.2f, required %.2f", clientPkg.ErrInsufficientFunds, cash.Available, -amount)
+		return err
+	}
+
+	err = cm.CR.AddCashFlow(&clientPkg.CashFlow{
+		ClientID: clientID,
+		Amount:   amount,
+		Reason:   reason,
+		Time:     int32(time.Now().Unix()),
+	}, tx)
+	return err
+}
+
+func (cm *ClientsManager) GetRiskProfile(clientID int) (*clientPkg.RiskProfile, error) {
+	return cm.CR.GetRiskProfile(int32(clientID))
+}
+
+func (cm *ClientsManager) SetRiskProfile(profile *clientPkg.RiskProfile) error {
+	if profile.MaxPosition < 0 || profile.MaxExposure < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return cm.CR.SetRiskProfile(profile)
 }
@@ -0,0 +1,64 @@
+package deal
+
+const (
+	CommandCreate  = "create"
+	CommandCancel  = "cancel"
+	CommandReplace = "replace"
+)
+
+// OutboxCommand - команда бирже, записанная в одной транзакции с заявкой, доставляется диспетчером с повторами,
+// Price и Volume - новые цена и полный объем заявки вместе с исполненным для CommandReplace, 0 - без изменения
+type OutboxCommand struct {
+	ID          int64
+	OrderID     int64
+	Command     string
+	Attempts    int32
+	NextAttempt int32
+	Price       float32
+	Volume      int32
+}
+
+const (
+	ReplaceApplied = "applied"
+	ReplacePending = "pending"
+)
+
+// ReplaceResult - итог изменения заявки: ReplaceApplied - биржа приняла изменение, Price и Volume - заявка
+// после него по данным биржи; ReplacePending - биржа недоступна, изменение сохранено и будет доставлено позже
+type ReplaceResult struct {
+	OrderID int64
+	Status  string
+	Price   float32
+	Volume  int32
+}
+
+// Trade - сделка клиента, записанная брокером, ID - номер у брокера, по нему идет пагинация
+type Trade struct {
+	ID         int64
+	ExchangeID int64
+	ClientID   int32
+	Ticker     string
+	Volume     int32
+	Price      float32
+	Type       string
+	Partial    bool
+	OrderID    int64 // номер заявки на бирже
+	Time       int32
+}
+
+// TradeFilter - выборка сделок клиента за [From, To), 0 в границе - без ограничения,
+// Cursor - ID последней полученной сделки, Limit 0 - все сделки
+type TradeFilter struct {
+	ClientID int32
+	From     int32
+	To       int32
+	Ticker   string
+	Cursor   int64
+	Limit    int
+}
+
+// TradesPage - страница сделок, NextCursor 0 - сделок больше нет
+type TradesPage struct {
+	Trades     []*Trade
+	NextCursor int64
+}
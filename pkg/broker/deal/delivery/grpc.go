@@ -3,8 +3,9 @@ package delivery
 import (
 	"context"
 	"fmt"
-	"io"
 
+	metricsPkg "github.com/KeynihAV/exchange/pkg/broker/metrics"
+	streamPkg "github.com/KeynihAV/exchange/pkg/broker/stream"
 	"github.com/KeynihAV/exchange/pkg/config"
 	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
 	dealDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/delivery"
@@ -16,17 +17,25 @@ import (
 
 type DealsManagerInterface interface {
 	DealProcessing(deal *dealPkg.Deal) error
+	DeadLetter(deal *dealPkg.Deal, reason error) error
+	LastDealID() (int64, error)
 }
 
-func CreateOrder(order *dealPkg.Order, exchClient dealDeliveryPkg.ExchangeClient) (int64, error) {
+func CreateOrder(order *dealPkg.Order, idempotencyKey string, exchClient dealDeliveryPkg.ExchangeClient) (int64, error) {
 
 	deal := &dealDeliveryPkg.Deal{
-		BrokerID: order.BrokerID,
-		ClientID: order.ClientID,
-		Ticker:   order.Ticker,
-		Volume:   order.Volume,
-		Price:    order.Price,
-		Type:     order.Type,
+		BrokerID:       order.BrokerID,
+		ClientID:       order.ClientID,
+		Ticker:         order.Ticker,
+		Volume:         order.Volume,
+		Price:          order.Price,
+		Type:           order.Type,
+		Side:           dealDeliveryPkg.SideToProto(order.Type),
+		Kind:           dealDeliveryPkg.KindToProto(order.Kind),
+		StopPrice:      order.StopPrice,
+		TimeInForce:    dealDeliveryPkg.TimeInForceToProto(order.TimeInForce),
+		ExpiresAt:      order.ExpiresAt,
+		IdempotencyKey: idempotencyKey,
 	}
 
 	ctx := context.Background()
@@ -54,44 +63,69 @@ func CancelOrder(exchangeID int64, exchClient dealDeliveryPkg.ExchangeClient) er
 	return nil
 }
 
-func ConsumeDeals(dmInterface DealsManagerInterface, config *config.Config, logger *logging.Logger) error {
+// ReplaceOrder отправляет бирже новую цену и полный объем заявки вместе с исполненным,
+// возвращает заявку биржи после изменения
+func ReplaceOrder(exchangeID int64, price float32, volume int32, exchClient dealDeliveryPkg.ExchangeClient) (*dealDeliveryPkg.ReplaceResult, error) {
+	ctx := context.Background()
+
+	req := &dealDeliveryPkg.ReplaceRequest{ID: exchangeID, Price: price, Volume: volume}
+	replaceResult, err := exchClient.Replace(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !replaceResult.Success {
+		return nil, fmt.Errorf("not replace order %v", exchangeID)
+	}
+
+	return replaceResult, nil
+}
+
+// ConsumeDeals получает сделки биржи, при обрыве supervisor переподписывается с последней обработанной сделки
+func ConsumeDeals(dmInterface DealsManagerInterface, config *config.Config, supervisor *streamPkg.Supervisor, logger *logging.Logger) error {
 	grcpConn, err := grpc.Dial(
 		config.Broker.ExchangeEndpoint,
 		grpc.WithInsecure(),
 	)
 	if err != nil {
-		logger.Zap.Error("consume stats dial exchange",
+		logger.Zap.Error("consume deals dial exchange",
 			zap.String("logger", "grpcClient"),
 			zap.String("err", err.Error()),
 		)
 		return err
 	}
 
-	ctx := context.Background()
+	exchClient := dealDeliveryPkg.NewExchangeClient(grcpConn)
+	supervisor.Run("results", func(connected func()) error {
+		return consumeDeals(dmInterface, exchClient, config, connected, logger)
+	})
+	return nil
+}
+
+func consumeDeals(dmInterface DealsManagerInterface, exchClient dealDeliveryPkg.ExchangeClient, config *config.Config,
+	connected func(), logger *logging.Logger) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	md := metadata.Pairs()
 
-	exchClient := dealDeliveryPkg.NewExchangeClient(grcpConn)
-	resultsStream, err := exchClient.Results(metadata.NewOutgoingContext(ctx, md), &dealDeliveryPkg.BrokerID{ID: int64(config.Broker.ID)})
+	lastDealID, err := dmInterface.LastDealID()
 	if err != nil {
-		logger.Zap.Error("get deals stream",
-			zap.String("logger", "grpcClient"),
-			zap.String("err", err.Error()),
-		)
-		return err
+		return fmt.Errorf("last deal id: %v", err)
+	}
+
+	resultsStream, err := exchClient.Results(metadata.NewOutgoingContext(ctx, md),
+		&dealDeliveryPkg.BrokerID{ID: int64(config.Broker.ID), LastDealID: lastDealID})
+	if err != nil {
+		return fmt.Errorf("get deals stream: %v", err)
 	}
+	connected()
 
 	for {
 		deal, err := resultsStream.Recv()
-		if err != nil && err != io.EOF {
-			logger.Zap.Warn("unexpected error",
-				zap.String("logger", "grpcClient"),
-				zap.String("err", err.Error()),
-			)
-			continue
-		} else if err == io.EOF {
-			break
+		if err != nil {
+			return err
 		}
-		err = dmInterface.DealProcessing(&dealPkg.Deal{
+		result := &dealPkg.Deal{
 			ClientID: deal.ClientID,
 			Ticker:   deal.Ticker,
 			Volume:   deal.Volume,
@@ -100,15 +134,34 @@ func ConsumeDeals(dmInterface DealsManagerInterface, config *config.Config, logg
 			Price:    deal.Price,
 			ID:       deal.ID,
 			OrderID:  deal.OrderID,
-			Type:     deal.Type,
-		})
+			Type:     dealDeliveryPkg.SideFromProto(deal.Side, deal.Type),
+			Event:    dealDeliveryPkg.EventFromProto(deal.Event),
+		}
+		err = dmInterface.DealProcessing(result)
+		if err != nil {
+			//событие, которое не удается обработать, откладывается, иначе оно будет приходить снова и остановит поток
+			logger.Zap.Error("process deal, moved to dead letters",
+				zap.String("logger", "grpcClient"),
+				zap.Int64("dealID", deal.ID),
+				zap.Int64("orderID", deal.OrderID),
+				zap.String("event", result.Event),
+				zap.String("err", err.Error()),
+			)
+			err = dmInterface.DeadLetter(result, err)
+			if err != nil {
+				//подтверждение накопительное, поэтому дальше читать нельзя: сделка придет снова при переподключении
+				return fmt.Errorf("dead letter deal %v: %v", deal.ID, err)
+			}
+			metricsPkg.DealDeadLettered()
+		}
+
+		_, err = exchClient.Ack(ctx, &dealDeliveryPkg.DealAck{BrokerID: int64(config.Broker.ID), DealID: deal.ID})
 		if err != nil {
-			logger.Zap.Warn("write deal stream",
+			//следующее подтверждение покроет и эту сделку
+			logger.Zap.Warn("ack deal",
 				zap.String("logger", "grpcClient"),
 				zap.String("err", err.Error()),
 			)
-			continue
 		}
 	}
-	return nil
 }
@@ -0,0 +1,125 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	dealDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/delivery"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// fakeResults - поток событий биржи, после последнего события возвращает io.EOF
+type fakeResults struct {
+	grpc.ClientStream
+	Deals []*dealDeliveryPkg.Deal
+}
+
+func (fr *fakeResults) Recv() (*dealDeliveryPkg.Deal, error) {
+	if len(fr.Deals) == 0 {
+		return nil, io.EOF
+	}
+	deal := fr.Deals[0]
+	fr.Deals = fr.Deals[1:]
+	return deal, nil
+}
+
+type fakeExchange struct {
+	dealDeliveryPkg.ExchangeClient
+	Stream *fakeResults
+	Acked  []int64
+}
+
+func (fe *fakeExchange) Results(ctx context.Context, in *dealDeliveryPkg.BrokerID, opts ...grpc.CallOption) (dealDeliveryPkg.Exchange_ResultsClient, error) {
+	return fe.Stream, nil
+}
+
+func (fe *fakeExchange) Ack(ctx context.Context, in *dealDeliveryPkg.DealAck, opts ...grpc.CallOption) (*dealDeliveryPkg.AckResult, error) {
+	fe.Acked = append(fe.Acked, in.DealID)
+	return &dealDeliveryPkg.AckResult{}, nil
+}
+
+// fakeDealsManager не может обработать события из Bad, отложить - события из BadDeadLetter
+type fakeDealsManager struct {
+	Bad           map[int64]bool
+	BadDeadLetter map[int64]bool
+	Processed     []int64
+	DeadLetters   []int64
+}
+
+func (fm *fakeDealsManager) DealProcessing(deal *dealPkg.Deal) error {
+	if fm.Bad[deal.ID] {
+		return fmt.Errorf("order for deal %v not found", deal.ID)
+	}
+	fm.Processed = append(fm.Processed, deal.ID)
+	return nil
+}
+
+func (fm *fakeDealsManager) DeadLetter(deal *dealPkg.Deal, reason error) error {
+	if fm.BadDeadLetter[deal.ID] {
+		return fmt.Errorf("db is down")
+	}
+	fm.DeadLetters = append(fm.DeadLetters, deal.ID)
+	return nil
+}
+
+func (fm *fakeDealsManager) LastDealID() (int64, error) {
+	return 0, nil
+}
+
+func TestConsumeDeals(t *testing.T) {
+	tests := []struct {
+		name            string
+		bad             map[int64]bool
+		badDeadLetter   map[int64]bool
+		wantProcessed   []int64
+		wantDeadLetters []int64
+		wantAcked       []int64
+		wantErr         error
+	}{
+		{name: "Все события обработаны и подтверждены",
+			wantProcessed: []int64{1, 2, 3},
+			wantAcked:     []int64{1, 2, 3},
+			wantErr:       io.EOF,
+		},
+		{name: "Необрабатываемое событие откладывается, подтверждается и не останавливает поток",
+			bad:             map[int64]bool{2: true},
+			wantProcessed:   []int64{1, 3},
+			wantDeadLetters: []int64{2},
+			wantAcked:       []int64{1, 2, 3},
+			wantErr:         io.EOF,
+		},
+		{name: "Событие не удалось и отложить - поток переподключится без подтверждения",
+			bad:           map[int64]bool{2: true},
+			badDeadLetter: map[int64]bool{2: true},
+			wantProcessed: []int64{1},
+			wantAcked:     []int64{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exchange := &fakeExchange{Stream: &fakeResults{Deals: []*dealDeliveryPkg.Deal{{ID: 1}, {ID: 2}, {ID: 3}}}}
+			dm := &fakeDealsManager{Bad: tt.bad, BadDeadLetter: tt.badDeadLetter}
+
+			err := consumeDeals(dm, exchange, &config.Config{}, func() {}, &logging.Logger{Zap: zap.NewNop()})
+			if err == nil || (tt.wantErr != nil && err != tt.wantErr) {
+				t.Errorf("consumeDeals() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(dm.Processed, tt.wantProcessed) {
+				t.Errorf("processed %v, want %v", dm.Processed, tt.wantProcessed)
+			}
+			if !reflect.DeepEqual(dm.DeadLetters, tt.wantDeadLetters) {
+				t.Errorf("dead letters %v, want %v", dm.DeadLetters, tt.wantDeadLetters)
+			}
+			if !reflect.DeepEqual(exchange.Acked, tt.wantAcked) {
+				t.Errorf("acked %v, want %v", exchange.Acked, tt.wantAcked)
+			}
+		})
+	}
+}
@@ -1,12 +1,20 @@
 package delivery
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
+	brokerDealPkg "github.com/KeynihAV/exchange/pkg/broker/deal"
+	marketPkg "github.com/KeynihAV/exchange/pkg/broker/market"
 	"github.com/KeynihAV/exchange/pkg/common"
 	"github.com/KeynihAV/exchange/pkg/config"
 	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+	"github.com/KeynihAV/exchange/pkg/logging"
 	"github.com/gorilla/mux"
 )
 
@@ -19,6 +27,10 @@ type DMInterface interface {
 	CancelOrder(orderID int64, config *config.Config) error
 	OrdersByClient(clientID int) ([]*dealPkg.Order, error)
 	CreateOrder(order *dealPkg.Order, config *config.Config) (int64, error)
+	ReplaceOrder(orderID int64, price float32, volume int32, config *config.Config) (*brokerDealPkg.ReplaceResult, error)
+	TradesByClient(filter *brokerDealPkg.TradeFilter) (*brokerDealPkg.TradesPage, error)
+	Statement(clientID int32, from, to int32) (*brokerDealPkg.Statement, error)
+	OrderHistory(orderID int64) ([]*dealPkg.OrderHistoryEvent, error)
 }
 
 func (h *DealsHandler) OrdersByClient(w http.ResponseWriter, r *http.Request) {
@@ -43,9 +55,23 @@ func (h *DealsHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	if !ok {
 		return
 	}
+	if order.Kind == "" {
+		order.Kind = dealPkg.KindLimit
+	}
+	if order.TimeInForce == "" {
+		order.TimeInForce = dealPkg.TimeInForceGTC
+	}
+	err := order.Validate()
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
 
 	orderID, err := h.DealsManager.CreateOrder(order, h.Config)
 	order.ID = orderID
+	if ok := respRiskError(w, r, err); ok {
+		return
+	}
 	if err != nil {
 		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
 		return
@@ -67,3 +93,156 @@ func (h *DealsHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// ReplaceOrder меняет цену и/или неисполненный остаток заявки, в теле Price и Volume, 0 - без изменений;
+// в ответе Status pending, если биржа недоступна и изменение будет применено позже
+func (h *DealsHandler) ReplaceOrder(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderID, err := strconv.ParseInt(vars["order"], 10, 64)
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	changes := &dealPkg.Order{}
+	ok := common.GetStructFromRequest(changes, r, w)
+	if !ok {
+		return
+	}
+	if changes.Price < 0 || changes.Volume < 0 || (changes.Price == 0 && changes.Volume == 0) {
+		err = fmt.Errorf("price or volume must be positive")
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	result, err := h.DealsManager.ReplaceOrder(orderID, changes.Price, changes.Volume, h.Config)
+	if ok := respRiskError(w, r, err); ok {
+		return
+	}
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+	common.WriteStructToResponse(result, r.Context(), w)
+}
+
+// OrderHistory - журнал заявки: создание, принятие биржей, исполнения, изменения, снятие
+func (h *DealsHandler) OrderHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderID, err := strconv.ParseInt(vars["order"], 10, 64)
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	events, err := h.DealsManager.OrderHistory(orderID)
+	if errors.Is(err, dealPkg.ErrOrderNotFound) {
+		common.RespJSONError(w, http.StatusNotFound, err, err.Error(), r.Context())
+		return
+	}
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+	common.WriteStructToResponse(events, r.Context(), w)
+}
+
+// TradesByClient - сделки клиента, параметры запроса: from, to (unix time), ticker, cursor, limit
+func (h *DealsHandler) TradesByClient(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clientID, err := strconv.Atoi(vars["client"])
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	filter := &brokerDealPkg.TradeFilter{ClientID: int32(clientID), Ticker: r.URL.Query().Get("ticker")}
+	from, to, err := common.PeriodFromQuery(r)
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+	filter.From, filter.To = from, to
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		filter.Cursor, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			common.RespJSONError(w, http.StatusBadRequest, err, "bad cursor: "+err.Error(), r.Context())
+			return
+		}
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			common.RespJSONError(w, http.StatusBadRequest, err, "bad limit: "+err.Error(), r.Context())
+			return
+		}
+	}
+
+	page, err := h.DealsManager.TradesByClient(filter)
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+	common.WriteStructToResponse(page, r.Context(), w)
+}
+
+// Statement - отчет клиента за период from-to, format=csv отдает файл, иначе json
+func (h *DealsHandler) Statement(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clientID, err := strconv.Atoi(vars["client"])
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+	from, to, err := common.PeriodFromQuery(r)
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	statement, err := h.DealsManager.Statement(int32(clientID), from, to)
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		common.WriteStructToResponse(statement, r.Context(), w)
+		return
+	}
+	//отчет собирается целиком до заголовков, чтобы ошибку еще можно было вернуть ответом
+	csv := &bytes.Buffer{}
+	err = statement.WriteCSV(csv)
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), r.Context())
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=statement_%v.csv", clientID))
+	_, err = csv.WriteTo(w)
+	if err != nil {
+		//клиент отключился посреди ответа, заголовки уже отправлены
+		logging.Sl(r.Context()).Warnw("write statement", "err", err.Error())
+	}
+}
+
+// respRiskError отвечает 400 с кодом причины, если заявка отклонена по деньгам или риск-профилю клиента
+func respRiskError(w http.ResponseWriter, r *http.Request, err error) bool {
+	riskErr := &clientPkg.RiskError{}
+	phaseErr := &marketPkg.PhaseError{}
+	switch {
+	case errors.As(err, &riskErr):
+		common.RespJSONErrorCode(w, http.StatusBadRequest, riskErr.Code, err, err.Error(), r.Context())
+	case errors.Is(err, clientPkg.ErrInsufficientFunds):
+		common.RespJSONErrorCode(w, http.StatusBadRequest, clientPkg.CodeInsufficientFunds, err, err.Error(), r.Context())
+	case errors.As(err, &phaseErr):
+		common.RespJSONErrorCode(w, http.StatusConflict, phaseErr.Code, err, err.Error(), r.Context())
+	case errors.Is(err, instrumentPkg.ErrNotTrading):
+		common.RespJSONErrorCode(w, http.StatusConflict, instrumentPkg.CodeNotTrading, err, err.Error(), r.Context())
+	case errors.Is(err, dealPkg.ErrInvalidOrder):
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+	default:
+		return false
+	}
+	return true
+}
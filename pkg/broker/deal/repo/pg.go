@@ -2,7 +2,12 @@ package repo
 
 import (
 	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
 
+	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
+	brokerDealPkg "github.com/KeynihAV/exchange/pkg/broker/deal"
 	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
@@ -12,6 +17,8 @@ type DealRepo struct {
 }
 
 func NewDealRepo(db *sql.DB) (*DealRepo, error) {
+	//столбцы, появившиеся позже таблицы, добавляются и в уже созданную; открытые покупки без резерва
+	//резервируются по цене заявки, у стоп-заявки без цены - по стоп-цене, иначе резерв клиента занижен
 	_, err := db.Exec(
 		`CREATE TABLE IF NOT EXISTS orders(
 			id SERIAL PRIMARY KEY,
@@ -23,9 +30,23 @@ func NewDealRepo(db *sql.DB) (*DealRepo, error) {
 			completedVolume int NOT NULL,
 			time int NOT NULL,
 			price float8 NOT NULL,
-			type varchar(10) NOT NULL);
+			type varchar(10) NOT NULL,
+			kind varchar(10) NOT NULL DEFAULT 'limit',
+			stopPrice float8 NOT NULL DEFAULT 0,
+			timeInForce varchar(3) NOT NULL DEFAULT 'gtc',
+			expiresAt int NOT NULL DEFAULT 0,
+			status varchar(10) NOT NULL DEFAULT 'accepted',
+			reservePrice float8 NOT NULL DEFAULT 0);
 		CREATE UNIQUE INDEX IF NOT EXISTS exchangeID_idx ON orders (exchangeID);
-		CREATE INDEX IF NOT EXISTS clientID_idx ON orders (clientID);`)
+		CREATE INDEX IF NOT EXISTS clientID_idx ON orders (clientID);
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS kind varchar(10) NOT NULL DEFAULT 'limit';
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS stopPrice float8 NOT NULL DEFAULT 0;
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS timeInForce varchar(3) NOT NULL DEFAULT 'gtc';
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS expiresAt int NOT NULL DEFAULT 0;
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS status varchar(10) NOT NULL DEFAULT 'accepted';
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS reservePrice float8 NOT NULL DEFAULT 0;
+		UPDATE orders SET reservePrice = CASE WHEN price > 0 THEN price ELSE stopPrice END
+			WHERE type = 'buy' AND reservePrice = 0 AND volume > completedVolume AND status <> 'rejected';`)
 	if err != nil {
 		return nil, err
 	}
@@ -42,8 +63,59 @@ func NewDealRepo(db *sql.DB) (*DealRepo, error) {
 			price float8 NOT NULL,
 			type varchar(10) NOT NULL,
 			exchangeOrderID int NOT NULL);
-		CREATE INDEX IF NOT EXISTS exchangeID_idx ON deals (exchangeID);
-		CREATE INDEX IF NOT EXISTS aggregate_idx ON deals (clientID, ticker);`)
+		CREATE UNIQUE INDEX IF NOT EXISTS deals_exchangeID_idx ON deals (exchangeID);
+		CREATE INDEX IF NOT EXISTS aggregate_idx ON deals (clientID, ticker);
+		CREATE INDEX IF NOT EXISTS deals_client_time_idx ON deals (clientID, time);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS outbox(
+			id SERIAL PRIMARY KEY,
+			orderID int NOT NULL,
+			command varchar(10) NOT NULL,
+			attempts int NOT NULL DEFAULT 0,
+			nextAttempt int NOT NULL);
+		CREATE INDEX IF NOT EXISTS nextAttempt_idx ON outbox (nextAttempt);
+		ALTER TABLE outbox ADD COLUMN IF NOT EXISTS price float8 NOT NULL DEFAULT 0;
+		ALTER TABLE outbox ADD COLUMN IF NOT EXISTS volume int NOT NULL DEFAULT 0;`)
+	if err != nil {
+		return nil, err
+	}
+
+	//resultsCursor - последнее обработанное событие биржи любого типа, deadDeals - события, которые не удалось обработать
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS resultsCursor(
+			id int PRIMARY KEY,
+			lastDealID int NOT NULL);
+		CREATE TABLE IF NOT EXISTS deadDeals(
+			id SERIAL PRIMARY KEY,
+			exchangeID int NOT NULL,
+			exchangeOrderID int NOT NULL,
+			clientID int NOT NULL,
+			ticker varchar(200) NOT NULL,
+			volume int NOT NULL,
+			partial boolean NOT NULL,
+			time int NOT NULL,
+			price float8 NOT NULL,
+			type varchar(10) NOT NULL,
+			event varchar(20) NOT NULL,
+			reason text NOT NULL,
+			createdAt int NOT NULL);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS orderHistory(
+			id SERIAL PRIMARY KEY,
+			orderID int NOT NULL,
+			event varchar(20) NOT NULL,
+			volume int NOT NULL,
+			price float8 NOT NULL,
+			time int NOT NULL);
+		CREATE INDEX IF NOT EXISTS orderHistory_order_idx ON orderHistory (orderID);`)
 	if err != nil {
 		return nil, err
 	}
@@ -53,18 +125,21 @@ func NewDealRepo(db *sql.DB) (*DealRepo, error) {
 	}, nil
 }
 
-func (dr *DealRepo) AddOrder(order *dealPkg.Order) (int64, error) {
-	query := `INSERT INTO orders(brokerID, clientID, ticker, volume, completedVolume, time, price, type)
-	values($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id;`
+// AddOrder сохраняет заявку, под остаток покупки резервируются деньги по цене reservePrice
+func (dr *DealRepo) AddOrder(order *dealPkg.Order, reservePrice float32, tx *sql.Tx) (int64, error) {
+	query := `INSERT INTO orders(brokerID, clientID, ticker, volume, completedVolume, time, price, type, kind, stopPrice,
+		timeInForce, expiresAt, status, reservePrice)
+	values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id;`
 
-	statement, err := dr.DB.Prepare(query)
+	statement, err := tx.Prepare(query)
 	if err != nil {
 		return 0, err
 	}
 	defer statement.Close()
 
 	var lastID int64
-	err = statement.QueryRow(order.BrokerID, order.ClientID, order.Ticker, order.Volume, 0, order.Time, order.Price, order.Type).Scan(&lastID)
+	err = statement.QueryRow(order.BrokerID, order.ClientID, order.Ticker, order.Volume, 0, order.Time, order.Price, order.Type, order.Kind, order.StopPrice,
+		order.TimeInForce, order.ExpiresAt, order.Status, reservePrice).Scan(&lastID)
 	if err != nil {
 		return 0, err
 	}
@@ -85,7 +160,8 @@ func (dr *DealRepo) DeleteOrder(id int64, tx *sql.Tx) error {
 }
 
 func (dr *DealRepo) OrdersByClient(clientID int) ([]*dealPkg.Order, error) {
-	result, err := dr.DB.Query(`SELECT id, brokerID, clientID, ticker, volume, completedVolume, time, price, type
+	result, err := dr.DB.Query(`SELECT id, brokerID, clientID, ticker, volume, completedVolume, time, price, type, kind, stopPrice,
+		timeInForce, expiresAt, status
 		 FROM orders WHERE clientID = $1`, clientID)
 	if err != nil {
 		return nil, err
@@ -96,7 +172,7 @@ func (dr *DealRepo) OrdersByClient(clientID int) ([]*dealPkg.Order, error) {
 	for result.Next() {
 		order := &dealPkg.Order{}
 		err = result.Scan(&order.ID, &order.BrokerID, &order.ClientID, &order.Ticker, &order.Volume, &order.CompletedVolume,
-			&order.Time, &order.Price, &order.Type)
+			&order.Time, &order.Price, &order.Type, &order.Kind, &order.StopPrice, &order.TimeInForce, &order.ExpiresAt, &order.Status)
 		if err != nil {
 			return nil, err
 		}
@@ -106,6 +182,21 @@ func (dr *DealRepo) OrdersByClient(clientID int) ([]*dealPkg.Order, error) {
 	return orders, nil
 }
 
+func (dr *DealRepo) GetOrder(id int64) (*dealPkg.Order, error) {
+	qr := dr.DB.QueryRow(`SELECT id, brokerID, clientID, ticker, volume, completedVolume, time, price, type, kind, stopPrice,
+		timeInForce, expiresAt, status
+		 FROM orders WHERE id = $1`, id)
+
+	order := &dealPkg.Order{}
+	err := qr.Scan(&order.ID, &order.BrokerID, &order.ClientID, &order.Ticker, &order.Volume, &order.CompletedVolume,
+		&order.Time, &order.Price, &order.Type, &order.Kind, &order.StopPrice, &order.TimeInForce, &order.ExpiresAt, &order.Status)
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
 func (dr *DealRepo) GetExchangeID(orderID int64) (int64, error) {
 	qr := dr.DB.QueryRow(`SELECT exchangeID
 		FROM orders WHERE id = $1`, orderID)
@@ -119,6 +210,20 @@ func (dr *DealRepo) GetExchangeID(orderID int64) (int64, error) {
 	return exchangeID, nil
 }
 
+// GetReservePrice - цена, по которой зарезервированы деньги под остаток заявки
+func (dr *DealRepo) GetReservePrice(orderID int64) (float32, error) {
+	qr := dr.DB.QueryRow(`SELECT reservePrice
+		FROM orders WHERE id = $1`, orderID)
+
+	var reservePrice float32
+	err := qr.Scan(&reservePrice)
+	if err != nil {
+		return 0, err
+	}
+
+	return reservePrice, nil
+}
+
 func (dr *DealRepo) GetOrderID(exchangeID int64) (int64, error) {
 	qr := dr.DB.QueryRow(`SELECT id
 		FROM orders WHERE exchangeID = $1`, exchangeID)
@@ -132,8 +237,127 @@ func (dr *DealRepo) GetOrderID(exchangeID int64) (int64, error) {
 	return orderID, nil
 }
 
-func (dr *DealRepo) MarkOrderShipped(id, exchangeID int64) error {
-	result, err := dr.DB.Exec(`UPDATE orders SET exchangeID = $1 WHERE id = $2`, exchangeID, id)
+// MarkOrderShipped сохраняет ID заявки на бирже, заявка считается принятой
+func (dr *DealRepo) MarkOrderShipped(id, exchangeID int64, tx *sql.Tx) error {
+	result, err := tx.Exec(`UPDATE orders SET exchangeID = $1, status = $2 WHERE id = $3`,
+		exchangeID, dealPkg.OrderStatusAccepted, id)
+	if err != nil {
+		return err
+	}
+	_, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DealRepo) SetOrderStatus(id int64, status string, tx *sql.Tx) error {
+	result, err := tx.Exec(`UPDATE orders SET status = $1 WHERE id = $2`, status, id)
+	if err != nil {
+		return err
+	}
+	_, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddOutboxCommand сохраняет команду и заполняет ее ID
+func (dr *DealRepo) AddOutboxCommand(command *brokerDealPkg.OutboxCommand, tx *sql.Tx) error {
+	err := tx.QueryRow(`INSERT INTO outbox(orderID, command, attempts, nextAttempt, price, volume)
+	values($1, $2, $3, $4, $5, $6) RETURNING id`,
+		command.OrderID, command.Command, command.Attempts, command.NextAttempt, command.Price, command.Volume).Scan(&command.ID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetOutboxCommands - команды, время отправки которых наступило, в порядке записи
+func (dr *DealRepo) GetOutboxCommands(now int32) ([]*brokerDealPkg.OutboxCommand, error) {
+	result, err := dr.DB.Query(`SELECT id, orderID, command, attempts, nextAttempt, price, volume
+		FROM outbox WHERE nextAttempt <= $1 ORDER BY id`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	commands := make([]*brokerDealPkg.OutboxCommand, 0)
+	for result.Next() {
+		command := &brokerDealPkg.OutboxCommand{}
+		err = result.Scan(&command.ID, &command.OrderID, &command.Command, &command.Attempts, &command.NextAttempt,
+			&command.Price, &command.Volume)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, command)
+	}
+
+	return commands, nil
+}
+
+// RetryOutboxCommand откладывает команду до следующей попытки
+func (dr *DealRepo) RetryOutboxCommand(id int64, attempts, nextAttempt int32) error {
+	result, err := dr.DB.Exec(`UPDATE outbox SET attempts = $1, nextAttempt = $2 WHERE id = $3`, attempts, nextAttempt, id)
+	if err != nil {
+		return err
+	}
+	_, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (dr *DealRepo) DeleteOutboxCommand(id int64, tx *sql.Tx) error {
+	result, err := tx.Exec(`DELETE FROM outbox WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeleteUnsentCreate удаляет команду создания заявки, если ее еще ни разу не отправляли на биржу
+func (dr *DealRepo) DeleteUnsentCreate(orderID int64, tx *sql.Tx) (bool, error) {
+	result, err := tx.Exec(`DELETE FROM outbox WHERE orderID = $1 AND command = $2 AND attempts = 0`,
+		orderID, brokerDealPkg.CommandCreate)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ReplaceOrder записывает цену и полный объем заявки из ответа биржи, объем не считается от своего исполненного,
+// который может отставать от биржи на сделки в пути; нулевая цена резерва оставляет резерв как есть
+func (dr *DealRepo) ReplaceOrder(id int64, price float32, volume int32, reservePrice float32, tx *sql.Tx) error {
+	result, err := tx.Exec(`UPDATE orders SET
+		price = $1,
+		reservePrice = CASE WHEN $4 > 0 THEN $4 ELSE reservePrice END,
+		volume = $2
+		WHERE id = $3`, price, volume, id, reservePrice)
+	if err != nil {
+		return err
+	}
+	_, err = result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// TriggerOrder переводит сработавшую на бирже стоп-заявку в рыночную или лимитную
+func (dr *DealRepo) TriggerOrder(id int64, tx *sql.Tx) error {
+	result, err := tx.Exec(`UPDATE orders SET kind = CASE kind WHEN $1 THEN $2 WHEN $3 THEN $4 ELSE kind END WHERE id = $5`,
+		dealPkg.KindStop, dealPkg.KindMarket, dealPkg.KindStopLimit, dealPkg.KindLimit, id)
 	if err != nil {
 		return err
 	}
@@ -159,7 +383,7 @@ func (dr *DealRepo) WriteDeal(deal *dealPkg.Deal, tx *sql.Tx) error {
 }
 
 func (dr *DealRepo) UpdateOrderClosedVolume(orderID int64, completedVolume int32, tx *sql.Tx) error {
-	result, err := tx.Exec(`UPDATE orders SET completedVolume = completedVolume + $1 WHERE id = $2`,
+	result, err := tx.Exec(`UPDATE orders SET completedVolume = $1 WHERE id = $2`,
 		completedVolume, orderID)
 	if err != nil {
 		return err
@@ -184,31 +408,193 @@ func (dr *DealRepo) OrderClosedVolume(exchangeOrderID int64, tx *sql.Tx) (int32,
 	return closedVolume, nil
 }
 
-func (dr *DealRepo) UpdatePositionsByClientAndTicker(clientID int32, ticker string, tx *sql.Tx) error {
-	result, err := tx.Exec(`
-		INSERT INTO positions (clientID, ticker, volume, total, price)
-		SELECT 
-			deals.clientID,
-			deals.ticker,
-			SUM(CASE WHEN deals.type = $1 THEN deals.volume
-					ELSE -deals.volume 
-				END) as volume,
-			SUM(CASE WHEN deals.type = $1 THEN deals.volume * deals.price
-				ELSE -deals.volume * deals.price
-			END) as total,
-			AVG(deals.price) as price
-		FROM deals as deals
-		WHERE deals.clientID = $2 AND deals.ticker = $3
-		GROUP BY deals.clientID, deals.ticker
-		ON CONFLICT (clientID, ticker) DO UPDATE
-			SET volume = EXCLUDED.volume, total = EXCLUDED.total, price = EXCLUDED.price`,
-		"buy", clientID, ticker)
+// LastPrice - цена закрытия последней свечи инструмента, 0 если цен еще не было
+func (dr *DealRepo) LastPrice(ticker string) (float32, error) {
+	qr := dr.DB.QueryRow(`SELECT close FROM stats WHERE ticker = $1 ORDER BY time DESC, id DESC LIMIT 1`, ticker)
+
+	var price float32
+	err := qr.Scan(&price)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
 	if err != nil {
-		return err
+		return 0, err
 	}
-	_, err = result.RowsAffected()
+
+	return price, nil
+}
+
+// DealExists - сделка биржи уже записана, повторно пришедшую сделку нужно пропустить
+func (dr *DealRepo) DealExists(exchangeID int64, tx *sql.Tx) (bool, error) {
+	qr := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM deals WHERE exchangeID = $1)`, exchangeID)
+
+	var exists bool
+	err := qr.Scan(&exists)
 	if err != nil {
-		return err
+		return false, err
 	}
-	return nil
+
+	return exists, nil
+}
+
+// LastExchangeDealID - ID последнего обработанного события биржи, включая отмены, срабатывания стопов и
+// отложенные в deadDeals, сделки учитываются для баз, где курсора еще нет, 0 если событий еще не было
+func (dr *DealRepo) LastExchangeDealID() (int64, error) {
+	qr := dr.DB.QueryRow(`SELECT GREATEST(
+		COALESCE((SELECT MAX(exchangeID) FROM deals), 0),
+		COALESCE((SELECT MAX(lastDealID) FROM resultsCursor), 0))`)
+
+	var lastID int64
+	err := qr.Scan(&lastID)
+	if err != nil {
+		return 0, err
+	}
+
+	return lastID, nil
+}
+
+// SaveResultsCursor сдвигает курсор обработанных событий биржи, назад курсор не двигается
+func (dr *DealRepo) SaveResultsCursor(dealID int64, tx *sql.Tx) error {
+	_, err := tx.Exec(`INSERT INTO resultsCursor(id, lastDealID) values(1, $1)
+	ON CONFLICT (id) DO UPDATE SET lastDealID = GREATEST(resultsCursor.lastDealID, EXCLUDED.lastDealID)`, dealID)
+	return err
+}
+
+// AddDeadDeal откладывает событие биржи, которое не удалось обработать, для ручного разбора
+func (dr *DealRepo) AddDeadDeal(deal *dealPkg.Deal, reason string, createdAt int32, tx *sql.Tx) error {
+	_, err := tx.Exec(`INSERT INTO deadDeals(exchangeID, exchangeOrderID, clientID, ticker, volume, partial, time, price, type,
+		event, reason, createdAt)
+	values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		deal.ID, deal.OrderID, deal.ClientID, deal.Ticker, deal.Volume, deal.Partial, deal.Time, deal.Price, deal.Type,
+		deal.Event, reason, createdAt)
+	return err
+}
+
+// RiskPositions - позиции клиента и остатки его заявок по всем инструментам, где есть позиция или заявки
+func (dr *DealRepo) RiskPositions(clientID int32, tx *sql.Tx) ([]*clientPkg.RiskPosition, error) {
+	result, err := tx.Query(`SELECT t.ticker,
+			COALESCE((SELECT volume FROM positions WHERE clientID = $1 AND ticker = t.ticker), 0),
+			COALESCE((SELECT SUM(volume - completedVolume) FROM orders
+				WHERE clientID = $1 AND ticker = t.ticker AND type = $2 AND status <> $4), 0),
+			COALESCE((SELECT SUM(volume - completedVolume) FROM orders
+				WHERE clientID = $1 AND ticker = t.ticker AND type = $3 AND status <> $4), 0),
+			COALESCE((SELECT close FROM stats WHERE ticker = t.ticker ORDER BY time DESC, id DESC LIMIT 1), 0)
+		FROM (SELECT ticker FROM positions WHERE clientID = $1
+			UNION SELECT ticker FROM orders WHERE clientID = $1 AND status <> $4) AS t`,
+		clientID, dealPkg.TypeBuy, dealPkg.TypeSell, dealPkg.OrderStatusRejected)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	positions := make([]*clientPkg.RiskPosition, 0)
+	for result.Next() {
+		position := &clientPkg.RiskPosition{}
+		err = result.Scan(&position.Ticker, &position.Volume, &position.PendingBuy, &position.PendingSell, &position.Price)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+
+	return positions, nil
+}
+
+// TradesByClient - сделки клиента по фильтру в порядке записи, начиная после filter.Cursor
+func (dr *DealRepo) TradesByClient(filter *brokerDealPkg.TradeFilter) ([]*brokerDealPkg.Trade, error) {
+	conditions := []string{"clientID = $1", "id > $2"}
+	values := []interface{}{filter.ClientID, filter.Cursor}
+	addCondition := func(condition string, value interface{}) {
+		values = append(values, value)
+		conditions = append(conditions, condition+" $"+strconv.Itoa(len(values)))
+	}
+	if filter.From > 0 {
+		addCondition("time >=", filter.From)
+	}
+	if filter.To > 0 {
+		addCondition("time <", filter.To)
+	}
+	if filter.Ticker != "" {
+		addCondition("ticker =", filter.Ticker)
+	}
+
+	queryString := fmt.Sprintf(`SELECT id, COALESCE(exchangeID, 0), clientID, ticker, volume, price, type, partial, exchangeOrderID, time
+		FROM deals WHERE %v ORDER BY id`, strings.Join(conditions, " AND "))
+	if filter.Limit > 0 {
+		queryString += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	result, err := dr.DB.Query(queryString, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	trades := make([]*brokerDealPkg.Trade, 0)
+	for result.Next() {
+		trade := &brokerDealPkg.Trade{}
+		err = result.Scan(&trade.ID, &trade.ExchangeID, &trade.ClientID, &trade.Ticker, &trade.Volume, &trade.Price,
+			&trade.Type, &trade.Partial, &trade.OrderID, &trade.Time)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+
+	return trades, nil
+}
+
+// PositionsAt - позиции клиента по сделкам до момента to и последняя цена инструмента на тот момент
+func (dr *DealRepo) PositionsAt(clientID int32, to int32) ([]*clientPkg.Position, error) {
+	result, err := dr.DB.Query(`SELECT deals.ticker,
+			SUM(CASE WHEN deals.type = $1 THEN deals.volume ELSE -deals.volume END),
+			COALESCE((SELECT close FROM stats WHERE stats.ticker = deals.ticker AND stats.time < $3
+				ORDER BY time DESC, id DESC LIMIT 1), 0)
+		FROM deals WHERE deals.clientID = $2 AND deals.time < $3
+		GROUP BY deals.ticker ORDER BY deals.ticker`, dealPkg.TypeBuy, clientID, to)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	positions := make([]*clientPkg.Position, 0)
+	for result.Next() {
+		position := &clientPkg.Position{ClientID: clientID}
+		err = result.Scan(&position.Ticker, &position.Volume, &position.LastPrice)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+
+	return positions, nil
+}
+
+// AddOrderHistory дописывает событие в журнал заявки, пишется в транзакции изменения заявки
+func (dr *DealRepo) AddOrderHistory(event *dealPkg.OrderHistoryEvent, tx *sql.Tx) error {
+	_, err := tx.Exec(`INSERT INTO orderHistory(orderID, event, volume, price, time) values($1, $2, $3, $4, $5)`,
+		event.OrderID, event.Event, event.Volume, event.Price, event.Time)
+	return err
+}
+
+// OrderHistory - журнал заявки в порядке событий, заявка к этому времени может быть уже удалена
+func (dr *DealRepo) OrderHistory(orderID int64) ([]*dealPkg.OrderHistoryEvent, error) {
+	result, err := dr.DB.Query(`SELECT id, orderID, event, volume, price, time
+		FROM orderHistory WHERE orderID = $1 ORDER BY id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	events := make([]*dealPkg.OrderHistoryEvent, 0)
+	for result.Next() {
+		event := &dealPkg.OrderHistoryEvent{}
+		err = result.Scan(&event.ID, &event.OrderID, &event.Event, &event.Volume, &event.Price, &event.Time)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
 }
@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
+	brokerDealPkg "github.com/KeynihAV/exchange/pkg/broker/deal"
 	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"gopkg.in/DATA-DOG/go-sqlmock.v2"
@@ -20,8 +22,15 @@ func TestDealRepo_AddOrder(t *testing.T) {
 	}
 	defer db.Close()
 
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
 	type args struct {
-		order *dealPkg.Order
+		order        *dealPkg.Order
+		reservePrice float32
 	}
 
 	tests := []struct {
@@ -34,7 +43,7 @@ func TestDealRepo_AddOrder(t *testing.T) {
 	}{
 		{name: "Ошибка insert",
 			dr:      &DealRepo{DB: db},
-			args:    args{&dealPkg.Order{}},
+			args:    args{order: &dealPkg.Order{}},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
 				s.ExpectPrepare(`INSERT INTO orders`).WillReturnError(fmt.Errorf("insert error"))
@@ -42,7 +51,7 @@ func TestDealRepo_AddOrder(t *testing.T) {
 		},
 		{name: "Ошибка result",
 			dr:      &DealRepo{DB: db},
-			args:    args{&dealPkg.Order{}},
+			args:    args{order: &dealPkg.Order{}},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
 				s.ExpectPrepare(`INSERT INTO orders`).WillReturnError(nil)
@@ -50,8 +59,9 @@ func TestDealRepo_AddOrder(t *testing.T) {
 			},
 		},
 		{name: "Успешный insert",
-			dr:      &DealRepo{DB: db},
-			args:    args{&dealPkg.Order{BrokerID: 1, ClientID: 1, Ticker: "ticker1", Volume: 10, Time: int32(time.Now().Unix()), Price: 100, Type: "sell"}},
+			dr: &DealRepo{DB: db},
+			args: args{order: &dealPkg.Order{BrokerID: 1, ClientID: 1, Ticker: "ticker1", Volume: 10, Time: int32(time.Now().Unix()), Price: 100, Type: "buy",
+				Status: "pending"}, reservePrice: 100},
 			wantErr: false,
 			want:    1,
 			mockF: func(s sqlmock.Sqlmock) {
@@ -63,7 +73,7 @@ func TestDealRepo_AddOrder(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockF(mock)
-			got, err := tt.dr.AddOrder(tt.args.order)
+			got, err := tt.dr.AddOrder(tt.args.order, tt.args.reservePrice, tx1)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("DealRepo.AddOrder() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -173,10 +183,12 @@ func TestDealRepo_OrdersByClient(t *testing.T) {
 			args:    args{clientID: 1},
 			wantErr: false,
 			want: []*dealPkg.Order{{ID: 1, BrokerID: 1, ClientID: 1, Ticker: "ticker1",
-				Volume: 10, Time: 12345678, Type: "buy", Price: 100, CompletedVolume: 4}},
+				Volume: 10, Time: 12345678, Type: "buy", Price: 100, CompletedVolume: 4, Kind: "stop", StopPrice: 110,
+				TimeInForce: "day", ExpiresAt: 12349999, Status: "accepted"}},
 			mockF: func(s sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "brokerid", "clientid", "ticker", "volume", "completedVolume", "time", "price", "type"}).
-					AddRow(1, 1, 1, "ticker1", 10, 4, 12345678, 100, "buy")
+				rows := sqlmock.NewRows([]string{"id", "brokerid", "clientid", "ticker", "volume", "completedVolume", "time", "price", "type", "kind", "stopPrice",
+					"timeInForce", "expiresAt", "status"}).
+					AddRow(1, 1, 1, "ticker1", 10, 4, 12345678, 100, "buy", "stop", 110, "day", 12349999, "accepted")
 				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
 			},
 		},
@@ -196,6 +208,56 @@ func TestDealRepo_OrdersByClient(t *testing.T) {
 	}
 }
 
+func TestDealRepo_GetOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		want    *dealPkg.Order
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Заявка не найдена",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			want:    nil,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT`).WillReturnError(sql.ErrNoRows)
+			},
+		},
+		{name: "Успешный select",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			want: &dealPkg.Order{ID: 1, BrokerID: 1, ClientID: 1, Ticker: "ticker1",
+				Volume: 10, Time: 12345678, Type: "buy", Price: 100, Kind: "limit", TimeInForce: "gtc", Status: "pending"},
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "brokerid", "clientid", "ticker", "volume", "completedVolume", "time", "price", "type", "kind", "stopPrice",
+					"timeInForce", "expiresAt", "status"}).
+					AddRow(1, 1, 1, "ticker1", 10, 0, 12345678, 100, "buy", "limit", 0, "gtc", 0, "pending")
+				s.ExpectQuery(`SELECT`).WithArgs(1).WillReturnRows(rows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.dr.GetOrder(1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.GetOrder() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DealRepo.GetOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDealRepo_GetExchangeID(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -250,6 +312,54 @@ func TestDealRepo_GetExchangeID(t *testing.T) {
 	}
 }
 
+func TestDealRepo_GetReservePrice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		orderID int64
+		want    float32
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			dr:      &DealRepo{DB: db},
+			orderID: 1,
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT reservePrice`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Успешный select",
+			dr:      &DealRepo{DB: db},
+			orderID: 1,
+			want:    105,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"reservePrice"}).AddRow(105)
+				s.ExpectQuery(`SELECT reservePrice`).WithArgs(1).WillReturnRows(rows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.dr.GetReservePrice(tt.orderID)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.GetReservePrice() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("DealRepo.GetReservePrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDealRepo_GetOrderID(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -311,9 +421,16 @@ func TestDealRepo_MarkOrderShipped(t *testing.T) {
 	}
 	defer db.Close()
 
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
 	type args struct {
 		id         int64
 		exchangeID int64
+		tx         *sql.Tx
 	}
 
 	tests := []struct {
@@ -325,7 +442,7 @@ func TestDealRepo_MarkOrderShipped(t *testing.T) {
 	}{
 		{name: "Ошибка update",
 			dr:      &DealRepo{DB: db},
-			args:    args{id: 1, exchangeID: 1},
+			args:    args{id: 1, exchangeID: 1, tx: tx1},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
 				s.ExpectExec(`UPDATE orders SET exchangeID`).WillReturnError(fmt.Errorf("update error"))
@@ -333,7 +450,7 @@ func TestDealRepo_MarkOrderShipped(t *testing.T) {
 		},
 		{name: "Ошибка rows affected",
 			dr:      &DealRepo{DB: db},
-			args:    args{id: 1, exchangeID: 1},
+			args:    args{id: 1, exchangeID: 1, tx: tx1},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
 				s.ExpectExec(`UPDATE orders SET exchangeID`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
@@ -341,35 +458,30 @@ func TestDealRepo_MarkOrderShipped(t *testing.T) {
 		},
 		{name: "Успешный update",
 			dr:      &DealRepo{DB: db},
-			args:    args{id: 1, exchangeID: 1},
+			args:    args{id: 1, exchangeID: 5, tx: tx1},
 			wantErr: false,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`UPDATE orders SET exchangeID`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`UPDATE orders SET exchangeID`).WithArgs(5, "accepted", 1).WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 		},
 	}
 	for _, tt := range tests {
 		tt.mockF(mock)
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.dr.MarkOrderShipped(tt.args.id, tt.args.exchangeID); (err != nil) != tt.wantErr {
+			if err := tt.dr.MarkOrderShipped(tt.args.id, tt.args.exchangeID, tt.args.tx); (err != nil) != tt.wantErr {
 				t.Errorf("DealRepo.MarkOrderShipped() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestDealRepo_WriteDeal(t *testing.T) {
+func TestDealRepo_SetOrderStatus(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("cant create mock: %s", err)
 	}
 	defer db.Close()
 
-	type args struct {
-		deal *dealPkg.Deal
-		tx   *sql.Tx
-	}
-
 	mock.ExpectBegin()
 	tx1, err := db.BeginTx(context.TODO(), nil)
 	if err != nil {
@@ -379,46 +491,35 @@ func TestDealRepo_WriteDeal(t *testing.T) {
 	tests := []struct {
 		name    string
 		dr      *DealRepo
-		args    args
 		wantErr bool
 		mockF   func(sqlmock.Sqlmock)
 	}{
-		{name: "Ошибка insert",
-			dr:      &DealRepo{DB: db},
-			args:    args{deal: &dealPkg.Deal{}, tx: tx1},
-			wantErr: true,
-			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`INSERT INTO deals`).WillReturnError(fmt.Errorf("insert error"))
-			},
-		},
-		{name: "Ошибка rows affected",
+		{name: "Ошибка update",
 			dr:      &DealRepo{DB: db},
-			args:    args{deal: &dealPkg.Deal{}, tx: tx1},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`INSERT INTO deals`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+				s.ExpectExec(`UPDATE orders SET status`).WillReturnError(fmt.Errorf("update error"))
 			},
 		},
-		{name: "Успешный insert",
+		{name: "Успешный update",
 			dr:      &DealRepo{DB: db},
-			args:    args{deal: &dealPkg.Deal{ID: 1, ClientID: 1, Ticker: "ticker1", Volume: 100, Partial: false, Type: "sell", OrderID: 1}, tx: tx1},
 			wantErr: false,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`INSERT INTO deals`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`UPDATE orders SET status`).WithArgs("rejected", 1).WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 		},
 	}
 	for _, tt := range tests {
 		tt.mockF(mock)
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.dr.WriteDeal(tt.args.deal, tt.args.tx); (err != nil) != tt.wantErr {
-				t.Errorf("DealRepo.WriteDeal() error = %v, wantErr %v", err, tt.wantErr)
+			if err := tt.dr.SetOrderStatus(1, "rejected", tx1); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.SetOrderStatus() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestDealRepo_UpdateOrderClosedVolume(t *testing.T) {
+func TestDealRepo_AddOutboxCommand(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("cant create mock: %s", err)
@@ -431,54 +532,155 @@ func TestDealRepo_UpdateOrderClosedVolume(t *testing.T) {
 		t.Errorf("tx error %v", err)
 	}
 
-	type args struct {
-		orderID         int64
-		completedVolume int32
-		tx              *sql.Tx
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		command *brokerDealPkg.OutboxCommand
+		wantErr bool
+		wantID  int64
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			dr:      &DealRepo{DB: db},
+			command: &brokerDealPkg.OutboxCommand{OrderID: 1, Command: "create"},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`INSERT INTO outbox`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Успешный insert",
+			dr:      &DealRepo{DB: db},
+			command: &brokerDealPkg.OutboxCommand{OrderID: 1, Command: "cancel", NextAttempt: 12345678},
+			wantErr: false,
+			wantID:  7,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`INSERT INTO outbox`).WithArgs(1, "cancel", 0, 12345678, float64(0), 0).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			},
+		},
+		{name: "Успешный insert изменения заявки",
+			dr:      &DealRepo{DB: db},
+			command: &brokerDealPkg.OutboxCommand{OrderID: 1, Command: "replace", NextAttempt: 12345678, Price: 101, Volume: 5},
+			wantErr: false,
+			wantID:  8,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`INSERT INTO outbox`).WithArgs(1, "replace", 0, 12345678, float64(101), 5).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dr.AddOutboxCommand(tt.command, tx1); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.AddOutboxCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.command.ID != tt.wantID {
+				t.Errorf("DealRepo.AddOutboxCommand() ID = %v, want %v", tt.command.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestDealRepo_GetOutboxCommands(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
 	}
+	defer db.Close()
+
 	tests := []struct {
 		name    string
 		dr      *DealRepo
-		args    args
+		want    []*brokerDealPkg.OutboxCommand
 		wantErr bool
 		mockF   func(sqlmock.Sqlmock)
 	}{
-		{name: "Ошибка update",
+		{name: "Ошибка select",
 			dr:      &DealRepo{DB: db},
-			args:    args{orderID: 1, tx: tx1},
 			wantErr: true,
+			want:    nil,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`UPDATE orders SET completedVolume`).WillReturnError(fmt.Errorf("update error"))
+				s.ExpectQuery(`SELECT`).WillReturnError(fmt.Errorf("select error"))
 			},
 		},
-		{name: "Ошибка rows affected",
+		{name: "Ошибка scan",
 			dr:      &DealRepo{DB: db},
-			args:    args{orderID: 1, tx: tx1},
 			wantErr: true,
+			want:    nil,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`UPDATE orders SET completedVolume`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+				rows := sqlmock.NewRows([]string{"id", "orderID"}).AddRow(1, "one")
+				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
+			},
+		},
+		{name: "Успешный select",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			want: []*brokerDealPkg.OutboxCommand{
+				{ID: 1, OrderID: 10, Command: "create", Attempts: 0, NextAttempt: 12345678},
+				{ID: 2, OrderID: 10, Command: "replace", Attempts: 3, NextAttempt: 12345670, Price: 101, Volume: 5}},
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "orderID", "command", "attempts", "nextAttempt", "price", "volume"}).
+					AddRow(1, 10, "create", 0, 12345678, 0, 0).
+					AddRow(2, 10, "replace", 3, 12345670, 101, 5)
+				s.ExpectQuery(`SELECT`).WithArgs(12345680).WillReturnRows(rows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.dr.GetOutboxCommands(12345680)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.GetOutboxCommands() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DealRepo.GetOutboxCommands() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealRepo_RetryOutboxCommand(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка update",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE outbox SET attempts`).WillReturnError(fmt.Errorf("update error"))
 			},
 		},
 		{name: "Успешный update",
 			dr:      &DealRepo{DB: db},
-			args:    args{orderID: 1, tx: tx1},
 			wantErr: false,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`UPDATE orders SET completedVolume`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`UPDATE outbox SET attempts`).WithArgs(2, 12345682, 1).WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 		},
 	}
 	for _, tt := range tests {
-		tt.mockF(mock)
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.dr.UpdateOrderClosedVolume(tt.args.orderID, tt.args.completedVolume, tt.args.tx); (err != nil) != tt.wantErr {
-				t.Errorf("DealRepo.UpdateOrderClosedVolume() error = %v, wantErr %v", err, tt.wantErr)
+			tt.mockF(mock)
+			if err := tt.dr.RetryOutboxCommand(1, 2, 12345682); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.RetryOutboxCommand() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestDealRepo_OrderClosedVolume(t *testing.T) {
+func TestDealRepo_DeleteOutboxCommand(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("cant create mock: %s", err)
@@ -491,55 +693,38 @@ func TestDealRepo_OrderClosedVolume(t *testing.T) {
 		t.Errorf("tx error %v", err)
 	}
 
-	type args struct {
-		exchangeOrderID int64
-		tx              *sql.Tx
-	}
 	tests := []struct {
 		name    string
 		dr      *DealRepo
-		args    args
-		want    int32
 		wantErr bool
 		mockF   func(sqlmock.Sqlmock)
 	}{
-		{name: "Ошибка scan",
+		{name: "Ошибка delete",
 			dr:      &DealRepo{DB: db},
-			args:    args{exchangeOrderID: 1, tx: tx1},
 			wantErr: true,
-			want:    0,
 			mockF: func(s sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
-				s.ExpectQuery(`SELECT`).WillReturnRows(rows).WillReturnError(fmt.Errorf("scan error"))
+				s.ExpectExec(`DELETE FROM outbox`).WillReturnError(fmt.Errorf("delete error"))
 			},
 		},
-		{name: "Успешный select",
+		{name: "Успешный delete",
 			dr:      &DealRepo{DB: db},
-			args:    args{exchangeOrderID: 1, tx: tx1},
 			wantErr: false,
-			want:    10,
 			mockF: func(s sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"volume"}).AddRow(10)
-				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 		},
 	}
 	for _, tt := range tests {
 		tt.mockF(mock)
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.dr.OrderClosedVolume(tt.args.exchangeOrderID, tt.args.tx)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("DealRepo.OrderClosedVolume() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if got != tt.want {
-				t.Errorf("DealRepo.OrderClosedVolume() = %v, want %v", got, tt.want)
+			if err := tt.dr.DeleteOutboxCommand(1, tx1); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.DeleteOutboxCommand() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestDealRepo_UpdatePositionsByClientAndTicker(t *testing.T) {
+func TestDealRepo_DeleteUnsentCreate(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("cant create mock: %s", err)
@@ -552,48 +737,912 @@ func TestDealRepo_UpdatePositionsByClientAndTicker(t *testing.T) {
 		t.Errorf("tx error %v", err)
 	}
 
-	type args struct {
-		clientID int32
-		ticker   string
-		tx       *sql.Tx
-	}
 	tests := []struct {
 		name    string
 		dr      *DealRepo
-		args    args
+		want    bool
 		wantErr bool
 		mockF   func(sqlmock.Sqlmock)
 	}{
-		{name: "Ошибка update",
+		{name: "Ошибка delete",
 			dr:      &DealRepo{DB: db},
-			args:    args{clientID: 1, ticker: "ticker1", tx: tx1},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`INSERT INTO positions`).WillReturnError(fmt.Errorf("update error"))
+				s.ExpectExec(`DELETE FROM outbox`).WillReturnError(fmt.Errorf("delete error"))
 			},
 		},
-		{name: "Ошибка rows affected",
+		{name: "Команда уже отправлялась",
 			dr:      &DealRepo{DB: db},
-			args:    args{clientID: 1, ticker: "ticker1", tx: tx1},
-			wantErr: true,
+			want:    false,
+			wantErr: false,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`INSERT INTO positions`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(1, "create").WillReturnResult(sqlmock.NewResult(0, 0))
 			},
 		},
-		{name: "Успешный update",
+		{name: "Команда удалена",
 			dr:      &DealRepo{DB: db},
-			args:    args{clientID: 1, ticker: "ticker1", tx: tx1},
+			want:    true,
 			wantErr: false,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`INSERT INTO positions`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(1, "create").WillReturnResult(sqlmock.NewResult(0, 1))
 			},
 		},
 	}
 	for _, tt := range tests {
 		tt.mockF(mock)
 		t.Run(tt.name, func(t *testing.T) {
-			if err := tt.dr.UpdatePositionsByClientAndTicker(tt.args.clientID, tt.args.ticker, tt.args.tx); (err != nil) != tt.wantErr {
-				t.Errorf("DealRepo.UpdatePositionsByClientAndTicker() error = %v, wantErr %v", err, tt.wantErr)
+			got, err := tt.dr.DeleteUnsentCreate(1, tx1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.DeleteUnsentCreate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("DealRepo.DeleteUnsentCreate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealRepo_ReplaceOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	type args struct {
+		id           int64
+		price        float32
+		volume       int32
+		reservePrice float32
+	}
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		args    args
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка update",
+			dr:      &DealRepo{DB: db},
+			args:    args{id: 1, price: 100, volume: 5},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET`).WillReturnError(fmt.Errorf("update error"))
+			},
+		},
+		{name: "Ошибка rows affected",
+			dr:      &DealRepo{DB: db},
+			args:    args{id: 1, price: 100, volume: 5},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+			},
+		},
+		{name: "Успешный update пишет объем биржи как есть",
+			dr:      &DealRepo{DB: db},
+			args:    args{id: 1, price: 100, volume: 8, reservePrice: 100},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET(.+)price = \$1(.+)volume = \$2`).WithArgs(float64(100), 8, 1, float64(100)).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+		{name: "Резерв стоп-заявки не меняется",
+			dr:      &DealRepo{DB: db},
+			args:    args{id: 1, price: 100, volume: 3},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET`).WithArgs(float64(100), 3, 1, float64(0)).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dr.ReplaceOrder(tt.args.id, tt.args.price, tt.args.volume, tt.args.reservePrice, tx1); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.ReplaceOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDealRepo_TriggerOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	type args struct {
+		id int64
+		tx *sql.Tx
+	}
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		args    args
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка update",
+			dr:      &DealRepo{DB: db},
+			args:    args{id: 1, tx: tx1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET kind`).WillReturnError(fmt.Errorf("update error"))
+			},
+		},
+		{name: "Ошибка rows affected",
+			dr:      &DealRepo{DB: db},
+			args:    args{id: 1, tx: tx1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET kind`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+			},
+		},
+		{name: "Успешный update",
+			dr:      &DealRepo{DB: db},
+			args:    args{id: 1, tx: tx1},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET kind`).WithArgs("stop", "market", "stop_limit", "limit", 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dr.TriggerOrder(tt.args.id, tt.args.tx); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.TriggerOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDealRepo_WriteDeal(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	type args struct {
+		deal *dealPkg.Deal
+		tx   *sql.Tx
+	}
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		args    args
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			dr:      &DealRepo{DB: db},
+			args:    args{deal: &dealPkg.Deal{}, tx: tx1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO deals`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Ошибка rows affected",
+			dr:      &DealRepo{DB: db},
+			args:    args{deal: &dealPkg.Deal{}, tx: tx1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO deals`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+			},
+		},
+		{name: "Успешный insert",
+			dr:      &DealRepo{DB: db},
+			args:    args{deal: &dealPkg.Deal{ID: 1, ClientID: 1, Ticker: "ticker1", Volume: 100, Partial: false, Type: "sell", OrderID: 1}, tx: tx1},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO deals`).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dr.WriteDeal(tt.args.deal, tt.args.tx); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.WriteDeal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDealRepo_UpdateOrderClosedVolume(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	type args struct {
+		orderID         int64
+		completedVolume int32
+		tx              *sql.Tx
+	}
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		args    args
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка update",
+			dr:      &DealRepo{DB: db},
+			args:    args{orderID: 1, tx: tx1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET completedVolume`).WillReturnError(fmt.Errorf("update error"))
+			},
+		},
+		{name: "Ошибка rows affected",
+			dr:      &DealRepo{DB: db},
+			args:    args{orderID: 1, tx: tx1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET completedVolume`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+			},
+		},
+		{name: "Успешный update",
+			dr:      &DealRepo{DB: db},
+			args:    args{orderID: 1, tx: tx1},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE orders SET completedVolume`).WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dr.UpdateOrderClosedVolume(tt.args.orderID, tt.args.completedVolume, tt.args.tx); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.UpdateOrderClosedVolume() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDealRepo_OrderClosedVolume(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	type args struct {
+		exchangeOrderID int64
+		tx              *sql.Tx
+	}
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		args    args
+		want    int32
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка scan",
+			dr:      &DealRepo{DB: db},
+			args:    args{exchangeOrderID: 1, tx: tx1},
+			wantErr: true,
+			want:    0,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
+				s.ExpectQuery(`SELECT`).WillReturnRows(rows).WillReturnError(fmt.Errorf("scan error"))
+			},
+		},
+		{name: "Успешный select",
+			dr:      &DealRepo{DB: db},
+			args:    args{exchangeOrderID: 1, tx: tx1},
+			wantErr: false,
+			want:    10,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"volume"}).AddRow(10)
+				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dr.OrderClosedVolume(tt.args.exchangeOrderID, tt.args.tx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.OrderClosedVolume() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("DealRepo.OrderClosedVolume() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealRepo_DealExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	type args struct {
+		exchangeID int64
+		tx         *sql.Tx
+	}
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		args    args
+		want    bool
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			dr:      &DealRepo{DB: db},
+			args:    args{exchangeID: 1, tx: tx1},
+			wantErr: true,
+			want:    false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT EXISTS`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Сделка уже записана",
+			dr:      &DealRepo{DB: db},
+			args:    args{exchangeID: 1, tx: tx1},
+			wantErr: false,
+			want:    true,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"exists"}).AddRow(true)
+				s.ExpectQuery(`SELECT EXISTS`).WithArgs(1).WillReturnRows(rows)
+			},
+		},
+		{name: "Новая сделка",
+			dr:      &DealRepo{DB: db},
+			args:    args{exchangeID: 2, tx: tx1},
+			wantErr: false,
+			want:    false,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"exists"}).AddRow(false)
+				s.ExpectQuery(`SELECT EXISTS`).WithArgs(2).WillReturnRows(rows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dr.DealExists(tt.args.exchangeID, tt.args.tx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.DealExists() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("DealRepo.DealExists() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealRepo_LastExchangeDealID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		want    int64
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			want:    0,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT GREATEST`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Успешный select",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			want:    15,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"greatest"}).AddRow(15)
+				s.ExpectQuery(`SELECT GREATEST`).WillReturnRows(rows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dr.LastExchangeDealID()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.LastExchangeDealID() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("DealRepo.LastExchangeDealID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealRepo_SaveResultsCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		dealID  int64
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка upsert",
+			dr:      &DealRepo{DB: db},
+			dealID:  15,
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO resultsCursor`).WillReturnError(fmt.Errorf("upsert error"))
+			},
+		},
+		{name: "Успешный upsert",
+			dr:      &DealRepo{DB: db},
+			dealID:  15,
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO resultsCursor`).WithArgs(15).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dr.SaveResultsCursor(tt.dealID, tx1); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.SaveResultsCursor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDealRepo_AddDeadDeal(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	deal := &dealPkg.Deal{ID: 15, OrderID: 3, ClientID: 100, Ticker: "SPFB.RTS", Volume: 2, Time: 1670000000, Price: 101,
+		Type: dealPkg.TypeBuy, Event: dealPkg.EventFill}
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO deadDeals`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Успешный insert",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO deadDeals`).
+					WithArgs(15, 3, 100, "SPFB.RTS", 2, false, 1670000000, float64(101), dealPkg.TypeBuy, dealPkg.EventFill,
+						"order not found", 1670000100).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dr.AddDeadDeal(deal, "order not found", 1670000100, tx1); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.AddDeadDeal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDealRepo_LastPrice(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		want    float32
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT close FROM stats`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Цен еще не было",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			want:    0,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT close FROM stats`).WillReturnRows(sqlmock.NewRows([]string{"close"}))
+			},
+		},
+		{name: "Успешный select",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			want:    105.5,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT close FROM stats`).WithArgs("ticker1").WillReturnRows(sqlmock.NewRows([]string{"close"}).AddRow(105.5))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dr.LastPrice("ticker1")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.LastPrice() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("DealRepo.LastPrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealRepo_RiskPositions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		want    []*clientPkg.RiskPosition
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT t.ticker`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Ошибка scan",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT t.ticker`).WillReturnRows(sqlmock.NewRows([]string{"ticker", "volume"}).AddRow("ticker1", "one"))
+			},
+		},
+		{name: "Успешный select",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			want: []*clientPkg.RiskPosition{
+				{Ticker: "ticker1", Volume: 10, PendingBuy: 5, PendingSell: 0, Price: 100},
+				{Ticker: "ticker2", Volume: 0, PendingBuy: 0, PendingSell: 3, Price: 0},
+			},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT t.ticker`).WithArgs(1, "buy", "sell", "rejected").
+					WillReturnRows(sqlmock.NewRows([]string{"ticker", "volume", "pendingBuy", "pendingSell", "price"}).
+						AddRow("ticker1", 10, 5, 0, 100).AddRow("ticker2", 0, 0, 3, 0))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dr.RiskPositions(1, tx1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.RiskPositions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DealRepo.RiskPositions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealRepo_TradesByClient(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "exchangeID", "clientID", "ticker", "volume", "price", "type", "partial", "exchangeOrderID", "time"}
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		filter  *brokerDealPkg.TradeFilter
+		want    []*brokerDealPkg.Trade
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			dr:      &DealRepo{DB: db},
+			filter:  &brokerDealPkg.TradeFilter{ClientID: 1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id, COALESCE\(exchangeID, 0\)`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Ошибка scan",
+			dr:      &DealRepo{DB: db},
+			filter:  &brokerDealPkg.TradeFilter{ClientID: 1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id, COALESCE\(exchangeID, 0\)`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("one"))
+			},
+		},
+		{name: "Без фильтров",
+			dr:      &DealRepo{DB: db},
+			filter:  &brokerDealPkg.TradeFilter{ClientID: 1},
+			wantErr: false,
+			want:    []*brokerDealPkg.Trade{{ID: 1, ExchangeID: 10, ClientID: 1, Ticker: "ticker1", Volume: 5, Price: 100, Type: "buy", OrderID: 3, Time: 1000}},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`FROM deals WHERE clientID = \$1 AND id > \$2 ORDER BY id$`).WithArgs(1, 0).
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 10, 1, "ticker1", 5, 100, "buy", false, 3, 1000))
+			},
+		},
+		{name: "Период, инструмент и страница",
+			dr:      &DealRepo{DB: db},
+			filter:  &brokerDealPkg.TradeFilter{ClientID: 1, From: 500, To: 2000, Ticker: "ticker1", Cursor: 7, Limit: 2},
+			wantErr: false,
+			want:    []*brokerDealPkg.Trade{{ID: 8, ExchangeID: 11, ClientID: 1, Ticker: "ticker1", Volume: 5, Price: 100, Type: "sell", OrderID: 4, Time: 1500}},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`WHERE clientID = \$1 AND id > \$2 AND time >= \$3 AND time < \$4 AND ticker = \$5 ORDER BY id LIMIT 2`).
+					WithArgs(1, 7, 500, 2000, "ticker1").
+					WillReturnRows(sqlmock.NewRows(columns).AddRow(8, 11, 1, "ticker1", 5, 100, "sell", false, 4, 1500))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dr.TradesByClient(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.TradesByClient() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DealRepo.TradesByClient() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealRepo_PositionsAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		want    []*clientPkg.Position
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT deals.ticker`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Успешный select",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			want:    []*clientPkg.Position{{ClientID: 1, Ticker: "ticker1", Volume: -5, LastPrice: 120}},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT deals.ticker`).WithArgs("buy", 1, 2000).
+					WillReturnRows(sqlmock.NewRows([]string{"ticker", "volume", "close"}).AddRow("ticker1", -5, 120))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dr.PositionsAt(1, 2000)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.PositionsAt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DealRepo.PositionsAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealRepo_AddOrderHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx1, err := db.BeginTx(context.TODO(), nil)
+	if err != nil {
+		t.Errorf("tx error %v", err)
+	}
+
+	event := &dealPkg.OrderHistoryEvent{OrderID: 1, Event: "partially_filled", Volume: 3, Price: 100, Time: 1000}
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO orderHistory`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Успешный insert",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO orderHistory`).WithArgs(1, "partially_filled", 3, float64(100), 1000).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.dr.AddOrderHistory(event, tx1); (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.AddOrderHistory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDealRepo_OrderHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "orderID", "event", "volume", "price", "time"}
+
+	tests := []struct {
+		name    string
+		dr      *DealRepo
+		want    []*dealPkg.OrderHistoryEvent
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`FROM orderHistory`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Ошибка scan",
+			dr:      &DealRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`FROM orderHistory`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("one"))
+			},
+		},
+		{name: "Успешный select",
+			dr:      &DealRepo{DB: db},
+			wantErr: false,
+			want: []*dealPkg.OrderHistoryEvent{
+				{ID: 1, OrderID: 1, Event: "created", Volume: 10, Price: 100, Time: 1000},
+				{ID: 2, OrderID: 1, Event: "accepted", Volume: 10, Price: 100, Time: 1001},
+				{ID: 5, OrderID: 1, Event: "filled", Volume: 10, Price: 99, Time: 1010},
+			},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`FROM orderHistory`).WithArgs(1).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow(1, 1, "created", 10, 100, 1000).
+					AddRow(2, 1, "accepted", 10, 100, 1001).
+					AddRow(5, 1, "filled", 10, 99, 1010))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dr.OrderHistory(1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealRepo.OrderHistory() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DealRepo.OrderHistory() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,64 @@
+package deal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
+)
+
+// Statement - отчет клиента за период [From, To): сделки, движения денег и позиции на конец периода
+type Statement struct {
+	ClientID    int32
+	From        int32
+	To          int32
+	OpeningCash float32
+	ClosingCash float32
+	Trades      []*Trade
+	CashFlows   []*clientPkg.CashFlow
+	Positions   []*clientPkg.Position // Volume и LastPrice на конец периода
+}
+
+// WriteCSV выводит отчет секциями, каждая со своей строкой заголовков
+func (s *Statement) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	records := [][]string{
+		{"client", "from", "to", "opening cash", "closing cash"},
+		{itoa(int64(s.ClientID)), itoa(int64(s.From)), itoa(int64(s.To)), ftoa(s.OpeningCash), ftoa(s.ClosingCash)},
+		{},
+		{"trades"},
+		{"id", "time", "ticker", "type", "volume", "price", "amount", "order"},
+	}
+	for _, t := range s.Trades {
+		records = append(records, []string{itoa(t.ID), itoa(int64(t.Time)), t.Ticker, t.Type, itoa(int64(t.Volume)),
+			ftoa(t.Price), ftoa(t.Price * float32(t.Volume)), itoa(t.OrderID)})
+	}
+
+	records = append(records, []string{}, []string{"cash"}, []string{"id", "time", "reason", "amount", "deal"})
+	for _, f := range s.CashFlows {
+		records = append(records, []string{itoa(f.ID), itoa(int64(f.Time)), f.Reason, ftoa(f.Amount), itoa(f.DealID)})
+	}
+
+	records = append(records, []string{}, []string{"positions"}, []string{"ticker", "volume", "price", "value"})
+	for _, p := range s.Positions {
+		records = append(records, []string{p.Ticker, itoa(int64(p.Volume)), ftoa(p.LastPrice),
+			ftoa(p.LastPrice * float32(p.Volume))})
+	}
+
+	err := cw.WriteAll(records)
+	if err != nil {
+		return fmt.Errorf("write statement csv: %v", err)
+	}
+	return nil
+}
+
+func itoa(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func ftoa(v float32) string {
+	return strconv.FormatFloat(float64(v), 'f', 2, 32)
+}
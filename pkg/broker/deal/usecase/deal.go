@@ -4,22 +4,50 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
+	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
+	clientRepoPkg "github.com/KeynihAV/exchange/pkg/broker/client/repo"
+	brokerDealPkg "github.com/KeynihAV/exchange/pkg/broker/deal"
 	dealDeliveryPkg "github.com/KeynihAV/exchange/pkg/broker/deal/delivery"
 	dealRepoPkg "github.com/KeynihAV/exchange/pkg/broker/deal/repo"
+	depthUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/depth/usecase"
+	instrumentUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/instrument/usecase"
+	marketUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/market/usecase"
 	"github.com/KeynihAV/exchange/pkg/config"
 	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
 	exDealDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/delivery"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+const defaultMarketSlippage = 0.05
+
 type DealsManager struct {
 	DR       *dealRepoPkg.DealRepo
+	CR       *clientRepoPkg.ClientsRepo
 	ExClient exDealDeliveryPkg.ExchangeClient
+	//учет себестоимости позиций: clientPkg.CostFIFO или clientPkg.CostAverage
+	CostMethod string
+	//фаза торговой сессии биржи, заявки, которые биржа не примет, отклоняются сразу
+	Market *marketUsecasePkg.MarketManager
+	//справочник инструментов биржи, заявки с неверным шагом цены или лотом отклоняются сразу
+	Instruments *instrumentUsecasePkg.InstrumentsManager
+	//стакан биржи и запас к худшей цене для резерва рыночной покупки
+	Depth          *depthUsecasePkg.DepthManager
+	MarketSlippage float32
+	//сделка по заявке может прийти с биржи раньше, чем сохранен ее exchangeID
+	Mux *sync.Mutex
 }
 
-func NewDealsManager(db *sql.DB, config *config.Config) (*DealsManager, error) {
+// NewDealsManager - cr и depth общие с остальными менеджерами брокера
+func NewDealsManager(db *sql.DB, cr *clientRepoPkg.ClientsRepo, depth *depthUsecasePkg.DepthManager,
+	config *config.Config) (*DealsManager, error) {
 	dr, err := dealRepoPkg.NewDealRepo(db)
 	if err != nil {
 		return nil, err
@@ -35,23 +63,86 @@ func NewDealsManager(db *sql.DB, config *config.Config) (*DealsManager, error) {
 
 	exchClient := exDealDeliveryPkg.NewExchangeClient(grcpConn)
 
-	return &DealsManager{DR: dr, ExClient: exchClient}, nil
+	costMethod := clientPkg.CostFIFO
+	if config.Broker.CostMethod == clientPkg.CostAverage {
+		costMethod = clientPkg.CostAverage
+	}
+	marketSlippage := config.Broker.MarketSlippage
+	if marketSlippage <= 0 {
+		marketSlippage = defaultMarketSlippage
+	}
+
+	return &DealsManager{
+		DR:             dr,
+		CR:             cr,
+		ExClient:       exchClient,
+		CostMethod:     costMethod,
+		Market:         marketUsecasePkg.NewMarketManager(),
+		Instruments:    instrumentUsecasePkg.NewInstrumentsManager(),
+		Depth:          depth,
+		MarketSlippage: marketSlippage,
+		Mux:            &sync.Mutex{},
+	}, nil
 }
 
+// CreateOrder сохраняет заявку вместе с командой в outbox, на биржу ее отправит DispatchOutbox,
+// под покупку резервируются деньги клиента
 func (dm *DealsManager) CreateOrder(order *dealPkg.Order, config *config.Config) (int64, error) {
 	order.Time = int32(time.Now().Unix())
 	order.BrokerID = int32(config.Broker.ID)
-	id, err := dm.DR.AddOrder(order)
+	order.Status = dealPkg.OrderStatusPending
+
+	err := dm.Instruments.CheckOrder(order)
+	if err != nil {
+		return 0, err
+	}
+	err = dm.Market.CheckOrder(order)
 	if err != nil {
 		return 0, err
 	}
 
-	exchID, err := dealDeliveryPkg.CreateOrder(order, dm.ExClient)
+	reservePrice, err := dm.reservePrice(order)
 	if err != nil {
 		return 0, err
 	}
 
-	err = dm.DR.MarkOrderShipped(id, exchID)
+	tx, err := dm.DR.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	err = dm.checkBuyingPower(order.ClientID, reservePrice*float32(order.Volume), tx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = dm.checkRisk(order, order.Volume, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := dm.DR.AddOrder(order, reservePrice, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = dm.logOrder(id, dealPkg.HistoryCreated, order.Volume, order.Price, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = dm.DR.AddOutboxCommand(&brokerDealPkg.OutboxCommand{
+		OrderID:     id,
+		Command:     brokerDealPkg.CommandCreate,
+		NextAttempt: order.Time,
+	}, tx)
 	if err != nil {
 		return 0, err
 	}
@@ -59,37 +150,611 @@ func (dm *DealsManager) CreateOrder(order *dealPkg.Order, config *config.Config)
 	return id, nil
 }
 
+// CancelOrder ставит в outbox команду отмены, заявка удалится по событию отмены от биржи,
+// не дошедшая до биржи заявка удаляется сразу
 func (dm *DealsManager) CancelOrder(id int64, config *config.Config) error {
-	exchangeID, err := dm.DR.GetExchangeID(id)
+	dm.Mux.Lock()
+	defer dm.Mux.Unlock()
+
+	order, err := dm.DR.GetOrder(id)
 	if err != nil {
 		return err
 	}
 
-	err = dealDeliveryPkg.CancelOrder(exchangeID, dm.ExClient)
+	tx, err := dm.DR.DB.BeginTx(context.TODO(), &sql.TxOptions{})
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	deleteLocal := order.Status == dealPkg.OrderStatusRejected
+	if order.Status == dealPkg.OrderStatusPending {
+		deleteLocal, err = dm.DR.DeleteUnsentCreate(id, tx)
+		if err != nil {
+			return err
+		}
+	}
+	if deleteLocal {
+		err = dm.DR.DeleteOrder(id, tx)
+		if err != nil || order.Status == dealPkg.OrderStatusRejected {
+			return err
+		}
+		err = dm.logOrder(id, dealPkg.HistoryCancelled, order.RemainingVolume(), order.Price, tx)
+		return err
+	}
+
+	err = dm.DR.AddOutboxCommand(&brokerDealPkg.OutboxCommand{
+		OrderID:     id,
+		Command:     brokerDealPkg.CommandCancel,
+		NextAttempt: int32(time.Now().Unix()),
+	}, tx)
+	return err
+}
+
+// ReplaceOrder меняет цену и/или остаток заявки: изменение сначала сохраняется в outbox, затем отправляется на биржу
+// и применяется у брокера; если биржа недоступна, возвращается ReplacePending - изменение доставит и применит
+// DispatchOutbox, отказ биржи возвращается ошибкой
+func (dm *DealsManager) ReplaceOrder(id int64, price float32, volume int32, config *config.Config) (*brokerDealPkg.ReplaceResult, error) {
+	//сделки по новой цене могут прийти до обновления заявки у брокера
+	dm.Mux.Lock()
+	defer dm.Mux.Unlock()
+
+	order, err := dm.DR.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != dealPkg.OrderStatusAccepted {
+		return nil, fmt.Errorf("%w: order %v is %v", dealPkg.ErrInvalidOrder, id, order.Status)
+	}
+
+	command, err := dm.addReplace(order, price, volume)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced, rejected, err := dm.applyReplace(command)
+	if err != nil {
+		return &brokerDealPkg.ReplaceResult{OrderID: id, Status: brokerDealPkg.ReplacePending}, nil
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	if replaced == nil {
+		return nil, fmt.Errorf("%w: order %v is no longer active", dealPkg.ErrInvalidOrder, id)
+	}
+	return &brokerDealPkg.ReplaceResult{OrderID: id, Status: brokerDealPkg.ReplaceApplied, Price: replaced.Price,
+		Volume: replaced.Volume}, nil
+}
+
+// replaceTimeout - через сколько секунд изменение, не примененное в ReplaceOrder, подхватит диспетчер
+const replaceTimeout = 30
+
+// addReplace проверяет изменение заявки по деньгам и рискам клиента и сохраняет его в outbox
+func (dm *DealsManager) addReplace(order *dealPkg.Order, price float32, volume int32) (*brokerDealPkg.OutboxCommand, error) {
+	tx, err := dm.DR.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	newPrice, newRemaining := order.Price, order.RemainingVolume()
+	if price > 0 {
+		newPrice = price
+	}
+	if volume > 0 {
+		newRemaining = volume
+	}
+
+	replaced := *order
+	replaced.Price, replaced.Volume = newPrice, order.CompletedVolume+newRemaining
+	err = dm.Instruments.CheckOrder(&replaced)
+	if err != nil {
+		return nil, err
+	}
+
+	//увеличение покупки проверяется по свободным деньгам сверх текущего резерва заявки
+	var increase float32
+	if order.Type == dealPkg.TypeBuy {
+		var reserve float32
+		reserve, err = dm.DR.GetReservePrice(order.ID)
+		if err != nil {
+			return nil, err
+		}
+		newReserve := reserve
+		if replaced := replacedReserve(order, price); replaced > 0 {
+			newReserve = replaced
+		}
+		increase = newReserve*float32(newRemaining) - reserve*float32(order.RemainingVolume())
+	}
+	err = dm.checkBuyingPower(order.ClientID, increase, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if newRemaining > order.RemainingVolume() {
+		err = dm.checkRisk(order, newRemaining-order.RemainingVolume(), tx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//бирже уходит полный объем: остаток от исполненного брокером, сделки в пути биржа уже учла
+	command := &brokerDealPkg.OutboxCommand{
+		OrderID:     order.ID,
+		Command:     brokerDealPkg.CommandReplace,
+		NextAttempt: int32(time.Now().Unix()) + replaceTimeout,
+		Price:       price,
+	}
+	if volume > 0 {
+		command.Volume = order.CompletedVolume + volume
+	}
+	err = dm.DR.AddOutboxCommand(command, tx)
+	if err != nil {
+		return nil, err
+	}
+	return command, nil
+}
+
+// replacedReserve - новая цена резерва покупки при смене цены заявки на price, 0 - резерв остается прежним:
+// стоп-заявка зарезервирована как рыночная от стоп-цены, которая при изменении заявки не меняется
+func replacedReserve(order *dealPkg.Order, price float32) float32 {
+	if order.Type != dealPkg.TypeBuy || price <= 0 || order.Kind == dealPkg.KindStop || order.Kind == dealPkg.KindMarket {
+		return 0
+	}
+	return price
+}
+
+// applyReplace отправляет изменение заявки на биржу и в одной транзакции применяет его у брокера и удаляет команду,
+// цена и объем берутся из ответа биржи; replaced - заявка после изменения, nil - изменение не применялось,
+// rejected - отказ биржи, команда при этом удаляется, err означает, что команду нужно повторить
+func (dm *DealsManager) applyReplace(command *brokerDealPkg.OutboxCommand) (replaced *dealPkg.Order, rejected error, err error) {
+	order, err := dm.DR.GetOrder(command.OrderID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+
+	//закрытую или отмененную заявку менять уже не нужно
+	var result *exDealDeliveryPkg.ReplaceResult
+	apply := order != nil && order.Status == dealPkg.OrderStatusAccepted
+	if apply {
+		var exchangeID int64
+		exchangeID, err = dm.DR.GetExchangeID(order.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		result, err = dealDeliveryPkg.ReplaceOrder(exchangeID, command.Price, command.Volume, dm.ExClient)
+		code := status.Code(err)
+		if err != nil && code != codes.InvalidArgument && code != codes.FailedPrecondition && code != codes.NotFound {
+			return nil, nil, err
+		}
+		rejected, apply = err, err == nil
+	}
 
 	tx, err := dm.DR.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	if apply {
+		err = dm.DR.ReplaceOrder(order.ID, result.Price, result.Volume, replacedReserve(order, command.Price), tx)
+		if err != nil {
+			return nil, nil, err
+		}
+		//остаток по данным биржи, сделки в пути дойдут до брокера позже
+		err = dm.logOrder(order.ID, dealPkg.HistoryReplaced, result.Volume-result.CompletedVolume, result.Price, tx)
+		if err != nil {
+			return nil, nil, err
+		}
+		replaced = order
+		replaced.Price, replaced.Volume = result.Price, result.Volume
+	}
+
+	err = dm.DR.DeleteOutboxCommand(command.ID, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return replaced, rejected, nil
+}
+
+// reservePrice - цена, по которой резервируются деньги под покупку: цена лимитной и стоп-лимитной заявки
+// (выше нее заявка не исполнится), для рыночной - худшая цена, до которой она пройдет по стакану, но не ниже
+// последней, с запасом MarketSlippage; стоп-заявка после срабатывания становится рыночной, поэтому резервируется
+// так же, но не ниже стоп-цены; продажи не резервируются
+func (dm *DealsManager) reservePrice(order *dealPkg.Order) (float32, error) {
+	if order.Type != dealPkg.TypeBuy {
+		return 0, nil
+	}
+	if order.Kind != dealPkg.KindMarket && order.Kind != dealPkg.KindStop {
+		return order.Price, nil
+	}
+
+	lastPrice, err := dm.DR.LastPrice(order.Ticker)
+	if err != nil {
+		return 0, err
+	}
+	if dm.Depth != nil {
+		worstAsk, ok := dm.Depth.WorstAsk(order.Ticker, order.Volume)
+		if ok && worstAsk > lastPrice {
+			lastPrice = worstAsk
+		}
+	}
+	if order.Kind == dealPkg.KindStop && order.StopPrice > lastPrice {
+		lastPrice = order.StopPrice
+	}
+	if lastPrice <= 0 {
+		return 0, fmt.Errorf("%w: no last price for %v to reserve market order, use limit order",
+			clientPkg.ErrInsufficientFunds, order.Ticker)
+	}
+	return lastPrice * (1 + dm.MarketSlippage), nil
+}
+
+// checkBuyingPower блокирует деньги клиента до конца транзакции и проверяет, что свободных хватает на amount,
+// блокировка нужна и продажам: проверки рисков параллельных заявок клиента идут по очереди
+func (dm *DealsManager) checkBuyingPower(clientID int32, amount float32, tx *sql.Tx) error {
+	cash, err := dm.CR.LockCash(clientID, tx)
 	if err != nil {
 		return err
 	}
+	if amount > 0 && cash.Available < amount {
+		return fmt.Errorf("%w: available %.2f, required %.2f", clientPkg.ErrInsufficientFunds, cash.Available, amount)
+	}
+	return nil
+}
 
-	err = dm.DR.DeleteOrder(id, tx)
+// checkRisk проверяет, что добавление к заявкам клиента volume по стороне и инструменту order
+// не нарушит его риск-профиль с учетом текущих позиций и остальных неисполненных заявок
+func (dm *DealsManager) checkRisk(order *dealPkg.Order, volume int32, tx *sql.Tx) error {
+	profile, err := dm.CR.GetRiskProfile(order.ClientID)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
-	tx.Commit()
+	positions, err := dm.DR.RiskPositions(order.ClientID, tx)
+	if err != nil {
+		return err
+	}
+	return evaluateRisk(profile, positions, order, volume)
+}
+
+// evaluateRisk - проверки checkRisk по уже загруженным риск-профилю и позициям клиента
+func evaluateRisk(profile *clientPkg.RiskProfile, positions []*clientPkg.RiskPosition, order *dealPkg.Order, volume int32) error {
+	current := &clientPkg.RiskPosition{Ticker: order.Ticker}
+	var exposure float32
+	for _, position := range positions {
+		if position.Ticker == order.Ticker {
+			current = position
+			continue
+		}
+		exposure += position.Exposure()
+	}
+	if current.Price == 0 {
+		current.Price = order.Price
+	}
+	if current.Price == 0 {
+		current.Price = order.StopPrice
+	}
+
+	if order.Type == dealPkg.TypeBuy {
+		current.PendingBuy += volume
+	} else {
+		current.PendingSell += volume
+	}
 
-	return nil //вообще тут не очень, по идее нужен outbox pattern, чтобы сообщение писалось в бд и доставлялось отдельным потоком до победного
+	if !profile.AllowShort && current.Volume-current.PendingSell < 0 {
+		return &clientPkg.RiskError{
+			Code: clientPkg.CodeShortSelling,
+			Message: fmt.Sprintf("short selling is not allowed: position %v, pending sells %v",
+				current.Volume, current.PendingSell),
+		}
+	}
+	if profile.MaxPosition > 0 && current.MaxVolume() > profile.MaxPosition {
+		return &clientPkg.RiskError{
+			Code: clientPkg.CodePositionLimit,
+			Message: fmt.Sprintf("position limit exceeded for %v: %v with pending orders, limit %v",
+				current.Ticker, current.MaxVolume(), profile.MaxPosition),
+		}
+	}
+	exposure += current.Exposure()
+	if profile.MaxExposure > 0 && exposure > profile.MaxExposure {
+		return &clientPkg.RiskError{
+			Code:    clientPkg.CodeExposureLimit,
+			Message: fmt.Sprintf("gross exposure limit exceeded: %.2f with pending orders, limit %.2f", exposure, profile.MaxExposure),
+		}
+	}
+	return nil
+}
+
+// LastDealID - последняя обработанная сделка биржи, с нее биржа продолжит отправку при подключении
+func (dm *DealsManager) LastDealID() (int64, error) {
+	return dm.DR.LastExchangeDealID()
+}
+
+// DispatchOutbox раз в секунду отправляет на биржу команды из outbox
+func (dm *DealsManager) DispatchOutbox(logger *logging.Logger) {
+	tiker := time.NewTicker(time.Second)
+	for now := range tiker.C {
+		dm.dispatchOutbox(int32(now.Unix()), logger)
+	}
+}
+
+func (dm *DealsManager) dispatchOutbox(now int32, logger *logging.Logger) {
+	commands, err := dm.DR.GetOutboxCommands(now)
+	if err != nil {
+		logger.Zap.Error("get outbox commands",
+			zap.String("logger", "outbox"),
+			zap.String("err", err.Error()),
+		)
+		return
+	}
+
+	for _, command := range commands {
+		switch command.Command {
+		case brokerDealPkg.CommandCreate:
+			err = dm.dispatchCreate(command)
+		case brokerDealPkg.CommandReplace:
+			err = dm.dispatchReplace(command)
+		default:
+			err = dm.dispatchCancel(command)
+		}
+		if err == nil {
+			continue
+		}
+
+		logger.Zap.Warn("dispatch outbox command",
+			zap.String("logger", "outbox"),
+			zap.String("command", command.Command),
+			zap.Int64("orderID", command.OrderID),
+			zap.Int32("attempts", command.Attempts),
+			zap.String("err", err.Error()),
+		)
+		command.Attempts++
+		err = dm.DR.RetryOutboxCommand(command.ID, command.Attempts, now+retryDelay(command.Attempts))
+		if err != nil {
+			logger.Zap.Error("retry outbox command",
+				zap.String("logger", "outbox"),
+				zap.String("err", err.Error()),
+			)
+		}
+	}
+}
+
+// retryDelay - пауза перед следующей попыткой в секундах: 2, 4, 8... но не больше минуты
+func retryDelay(attempts int32) int32 {
+	if attempts >= 6 {
+		return 60
+	}
+	return 1 << attempts
+}
+
+// dispatchCreate отправляет заявку на биржу, ключ идемпотентности - ID заявки у брокера,
+// ошибка означает, что команду нужно повторить
+func (dm *DealsManager) dispatchCreate(command *brokerDealPkg.OutboxCommand) error {
+	//сделка по заявке может прийти с биржи раньше, чем сохранен ее exchangeID
+	dm.Mux.Lock()
+	defer dm.Mux.Unlock()
+
+	order, err := dm.DR.GetOrder(command.OrderID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	var exchID int64
+	var rejected bool
+	if order != nil {
+		exchID, err = dealDeliveryPkg.CreateOrder(order, strconv.FormatInt(order.ID, 10), dm.ExClient)
+		//FailedPrecondition - биржа не принимает заявку в текущей фазе сессии
+		rejected = status.Code(err) == codes.InvalidArgument || status.Code(err) == codes.FailedPrecondition
+		if err != nil && !rejected {
+			return err
+		}
+	}
+
+	tx, err := dm.DR.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	switch {
+	case order == nil:
+		//заявку отменили до отправки
+	case rejected:
+		err = dm.DR.SetOrderStatus(order.ID, dealPkg.OrderStatusRejected, tx)
+		if err == nil {
+			err = dm.logOrder(order.ID, dealPkg.HistoryRejected, order.Volume, order.Price, tx)
+		}
+	default:
+		err = dm.DR.MarkOrderShipped(order.ID, exchID, tx)
+		if err == nil {
+			err = dm.logOrder(order.ID, dealPkg.HistoryAccepted, order.Volume, order.Price, tx)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	err = dm.DR.DeleteOutboxCommand(command.ID, tx)
+	return err
+}
+
+// dispatchCancel отменяет заявку на бирже, ошибка означает, что команду нужно повторить
+func (dm *DealsManager) dispatchCancel(command *brokerDealPkg.OutboxCommand) error {
+	dm.Mux.Lock()
+	defer dm.Mux.Unlock()
+
+	order, err := dm.DR.GetOrder(command.OrderID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	//заявку без ответа биржи на создание отменять еще нечем
+	if order != nil && order.Status == dealPkg.OrderStatusPending {
+		return fmt.Errorf("order %v is not accepted yet", order.ID)
+	}
+
+	//отклоненную или уже закрытую заявку на бирже отменять не нужно
+	if order != nil && order.Status == dealPkg.OrderStatusAccepted {
+		var exchangeID int64
+		exchangeID, err = dm.DR.GetExchangeID(order.ID)
+		if err != nil {
+			return err
+		}
+		err = dealDeliveryPkg.CancelOrder(exchangeID, dm.ExClient)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+
+	tx, err := dm.DR.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	if order != nil && order.Status == dealPkg.OrderStatusRejected {
+		err = dm.DR.DeleteOrder(order.ID, tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = dm.DR.DeleteOutboxCommand(command.ID, tx)
+	return err
+}
+
+// dispatchReplace доставляет изменение заявки, не примененное в ReplaceOrder, при отказе биржи команда удаляется,
+// у брокера заявка остается прежней, ошибка означает, что команду нужно повторить
+func (dm *DealsManager) dispatchReplace(command *brokerDealPkg.OutboxCommand) error {
+	dm.Mux.Lock()
+	defer dm.Mux.Unlock()
+
+	_, _, err := dm.applyReplace(command)
+	return err
+}
+
+// OrderHistory - журнал заявки брокера, ErrOrderNotFound если событий по заявке нет
+func (dm *DealsManager) OrderHistory(orderID int64) ([]*dealPkg.OrderHistoryEvent, error) {
+	events, err := dm.DR.OrderHistory(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, dealPkg.ErrOrderNotFound
+	}
+	return events, nil
+}
+
+func (dm *DealsManager) logOrder(orderID int64, event string, volume int32, price float32, tx *sql.Tx) error {
+	return dm.DR.AddOrderHistory(&dealPkg.OrderHistoryEvent{
+		OrderID: orderID,
+		Event:   event,
+		Volume:  volume,
+		Price:   price,
+		Time:    int32(time.Now().Unix()),
+	}, tx)
 }
 
 func (dm *DealsManager) OrdersByClient(clientID int) ([]*dealPkg.Order, error) {
 	return dm.DR.OrdersByClient(clientID)
 }
 
+const (
+	defaultTradesPage = 100
+	maxTradesPage     = 1000
+)
+
+// TradesByClient - страница сделок клиента, следующая страница запрашивается с Cursor = NextCursor
+func (dm *DealsManager) TradesByClient(filter *brokerDealPkg.TradeFilter) (*brokerDealPkg.TradesPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultTradesPage
+	}
+	if filter.Limit > maxTradesPage {
+		filter.Limit = maxTradesPage
+	}
+	limit := filter.Limit
+
+	//лишняя сделка показывает, есть ли следующая страница
+	filter.Limit++
+	trades, err := dm.DR.TradesByClient(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &brokerDealPkg.TradesPage{Trades: trades}
+	if len(trades) > limit {
+		page.Trades = trades[:limit]
+		page.NextCursor = trades[limit-1].ID
+	}
+	return page, nil
+}
+
+// Statement - отчет клиента за [from, to), to = 0 - по текущий момент
+func (dm *DealsManager) Statement(clientID int32, from, to int32) (*brokerDealPkg.Statement, error) {
+	if to == 0 {
+		to = int32(time.Now().Unix()) + 1
+	}
+	statement := &brokerDealPkg.Statement{ClientID: clientID, From: from, To: to}
+
+	var err error
+	statement.Trades, err = dm.DR.TradesByClient(&brokerDealPkg.TradeFilter{ClientID: clientID, From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	statement.CashFlows, err = dm.CR.CashFlows(clientID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	statement.OpeningCash, err = dm.CR.CashAt(clientID, from)
+	if err != nil {
+		return nil, err
+	}
+	statement.ClosingCash, err = dm.CR.CashAt(clientID, to)
+	if err != nil {
+		return nil, err
+	}
+	statement.Positions, err = dm.DR.PositionsAt(clientID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return statement, nil
+}
+
+// DealProcessing применяет событие биржи и в той же транзакции сдвигает курсор обработанных событий
 func (dm *DealsManager) DealProcessing(deal *dealPkg.Deal) error {
+	dm.Mux.Lock()
+	defer dm.Mux.Unlock()
+
 	tx, err := dm.DR.DB.BeginTx(context.TODO(), &sql.TxOptions{})
 	if err != nil {
 		return err
@@ -102,14 +767,95 @@ func (dm *DealsManager) DealProcessing(deal *dealPkg.Deal) error {
 		}
 	}()
 
-	//Записать саму сделку
-	err = dm.DR.WriteDeal(deal, tx)
+	err = dm.processDeal(deal, tx)
+	if err != nil {
+		return err
+	}
+	err = dm.DR.SaveResultsCursor(deal.ID, tx)
+	return err
+}
+
+// DeadLetter откладывает событие биржи, которое не удалось обработать, и сдвигает курсор за него,
+// чтобы одно событие не останавливало поток
+func (dm *DealsManager) DeadLetter(deal *dealPkg.Deal, reason error) error {
+	dm.Mux.Lock()
+	defer dm.Mux.Unlock()
+
+	tx, err := dm.DR.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	err = dm.DR.AddDeadDeal(deal, reason.Error(), int32(time.Now().Unix()), tx)
 	if err != nil {
 		return err
 	}
+	err = dm.DR.SaveResultsCursor(deal.ID, tx)
+	return err
+}
+
+func (dm *DealsManager) processDeal(deal *dealPkg.Deal, tx *sql.Tx) error {
+	//биржа повторяет сделки, подтверждение которых не дошло, записанную сделку пропускаем
+	if deal.Event == dealPkg.EventFill {
+		processed, err := dm.DR.DealExists(deal.ID, tx)
+		if err != nil || processed {
+			return err
+		}
+	}
 
 	//ид заявки по сделке
 	orderID, err := dm.DR.GetOrderID(deal.OrderID)
+	if err == sql.ErrNoRows && deal.Event != dealPkg.EventFill {
+		//заявка уже удалена, повторное событие по ней
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = dm.logOrder(orderID, dealPkg.HistoryEventByDealEvent(deal.Event, deal.Partial), deal.Volume, deal.Price, tx)
+	if err != nil {
+		return err
+	}
+
+	//биржа сняла остаток заявки (ioc, fok, market) или заявка истекла
+	if deal.Event == dealPkg.EventCancel || deal.Event == dealPkg.EventExpire {
+		err = dm.DR.DeleteOrder(orderID, tx)
+		return err
+	}
+
+	//сработала стоп-заявка, сделки по ней придут отдельно
+	if deal.Event == dealPkg.EventTrigger {
+		err = dm.DR.TriggerOrder(orderID, tx)
+		return err
+	}
+
+	//Записать саму сделку
+	err = dm.DR.WriteDeal(deal, tx)
+	if err != nil {
+		return err
+	}
+
+	//Рассчитаться деньгами, резерв уменьшится вместе с остатком заявки
+	amount := deal.Price * float32(deal.Volume)
+	reason := clientPkg.CashSell
+	if deal.Type == dealPkg.TypeBuy {
+		amount, reason = -amount, clientPkg.CashBuy
+	}
+	err = dm.CR.AddCashFlow(&clientPkg.CashFlow{
+		ClientID: deal.ClientID,
+		Amount:   amount,
+		Reason:   reason,
+		DealID:   deal.ID,
+		Time:     deal.Time,
+	}, tx)
 	if err != nil {
 		return err
 	}
@@ -129,8 +875,28 @@ func (dm *DealsManager) DealProcessing(deal *dealPkg.Deal) error {
 		return err
 	}
 
-	//Обновить портфель
-	err = dm.DR.UpdatePositionsByClientAndTicker(deal.ClientID, deal.Ticker, tx)
+	//Обновить портфель: закрыть лоты и посчитать реализованный результат
+	lots, err := dm.CR.GetLots(deal.ClientID, deal.Ticker, tx)
+	if err != nil {
+		return err
+	}
+	fill := &clientPkg.Lot{
+		ClientID: deal.ClientID,
+		Ticker:   deal.Ticker,
+		Volume:   deal.Volume,
+		Price:    deal.Price,
+		DealID:   deal.ID,
+		Time:     deal.Time,
+	}
+	if deal.Type == dealPkg.TypeSell {
+		fill.Volume = -fill.Volume
+	}
+	lots, realized := clientPkg.ApplyFill(lots, fill, dm.CostMethod)
+	err = dm.CR.SaveLots(deal.ClientID, deal.Ticker, lots, tx)
+	if err != nil {
+		return err
+	}
+	err = dm.CR.UpdatePosition(deal.ClientID, deal.Ticker, lots, realized, tx)
 	if err != nil {
 		return err
 	}
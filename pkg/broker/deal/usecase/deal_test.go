@@ -0,0 +1,634 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+
+	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
+	clientRepoPkg "github.com/KeynihAV/exchange/pkg/broker/client/repo"
+	brokerDealPkg "github.com/KeynihAV/exchange/pkg/broker/deal"
+	dealRepoPkg "github.com/KeynihAV/exchange/pkg/broker/deal/repo"
+	depthPkg "github.com/KeynihAV/exchange/pkg/broker/depth"
+	depthUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/depth/usecase"
+	instrumentUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/instrument/usecase"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	exDealDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/delivery"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/DATA-DOG/go-sqlmock.v2"
+)
+
+// fakeExchange - биржа, отвечающая на команды заданными ошибками и запоминающая их,
+// Order - заявка на бирже, ее меняет Replace
+type fakeExchange struct {
+	exDealDeliveryPkg.ExchangeClient
+	CreateErr  error
+	CancelErr  error
+	ReplaceErr error
+	Order      dealPkg.Order
+	Creates    []*exDealDeliveryPkg.Deal
+	Cancels    []int64
+	Replaces   []*exDealDeliveryPkg.ReplaceRequest
+}
+
+func (fe *fakeExchange) Create(ctx context.Context, in *exDealDeliveryPkg.Deal, opts ...grpc.CallOption) (*exDealDeliveryPkg.DealID, error) {
+	fe.Creates = append(fe.Creates, in)
+	if fe.CreateErr != nil {
+		return nil, fe.CreateErr
+	}
+	return &exDealDeliveryPkg.DealID{ID: 55}, nil
+}
+
+func (fe *fakeExchange) Cancel(ctx context.Context, in *exDealDeliveryPkg.DealID, opts ...grpc.CallOption) (*exDealDeliveryPkg.CancelResult, error) {
+	fe.Cancels = append(fe.Cancels, in.ID)
+	if fe.CancelErr != nil {
+		return nil, fe.CancelErr
+	}
+	return &exDealDeliveryPkg.CancelResult{Success: true}, nil
+}
+
+func (fe *fakeExchange) Replace(ctx context.Context, in *exDealDeliveryPkg.ReplaceRequest, opts ...grpc.CallOption) (*exDealDeliveryPkg.ReplaceResult, error) {
+	fe.Replaces = append(fe.Replaces, in)
+	if fe.ReplaceErr != nil {
+		return nil, fe.ReplaceErr
+	}
+	if in.Price > 0 {
+		fe.Order.Price = in.Price
+	}
+	if in.Volume > 0 {
+		fe.Order.Volume = in.Volume
+	}
+	return &exDealDeliveryPkg.ReplaceResult{Success: true, Price: fe.Order.Price, Volume: fe.Order.Volume,
+		CompletedVolume: fe.Order.CompletedVolume}, nil
+}
+
+var orderColumns = []string{"id", "brokerID", "clientID", "ticker", "volume", "completedVolume", "time", "price", "type", "kind",
+	"stopPrice", "timeInForce", "expiresAt", "status"}
+
+func orderRow(status string) *sqlmock.Rows {
+	return sqlmock.NewRows(orderColumns).
+		AddRow(1, 1, 100, "SPFB.RTS", 10, 4, 0, 100, dealPkg.TypeBuy, dealPkg.KindLimit, 0, "", 0, status)
+}
+
+func TestDealsManager_ApplyReplace(t *testing.T) {
+	tests := []struct {
+		name         string
+		exchangeErr  error
+		status       string
+		wantRejected bool
+		wantErr      bool
+		wantSent     int
+		mockF        func(sqlmock.Sqlmock)
+	}{
+		{name: "Изменение подтверждено биржей и применено по ее ответу вместе с удалением команды",
+			status:   dealPkg.OrderStatusAccepted,
+			wantSent: 1,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT exchangeID`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exchangeID"}).AddRow(55))
+				s.ExpectBegin()
+				//у брокера исполнено 4, на бирже 6: объем заявки тот же, остаток по бирже
+				s.ExpectExec(`UPDATE orders SET`).WithArgs(float64(101), 9, 1, float64(101)).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectExec(`INSERT INTO orderHistory`).
+					WithArgs(1, dealPkg.HistoryReplaced, 3, float64(101), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Биржа недоступна - команда остается для повтора",
+			status:      dealPkg.OrderStatusAccepted,
+			exchangeErr: status.Error(codes.Unavailable, "unavailable"),
+			wantErr:     true,
+			wantSent:    1,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT exchangeID`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exchangeID"}).AddRow(55))
+			},
+		},
+		{name: "Отказ биржи - заявка не меняется, команда удаляется",
+			status:       dealPkg.OrderStatusAccepted,
+			exchangeErr:  status.Error(codes.InvalidArgument, "bad price"),
+			wantRejected: true,
+			wantSent:     1,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT exchangeID`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exchangeID"}).AddRow(55))
+				s.ExpectBegin()
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Ошибка сохранения после подтверждения биржи - команда остается для повтора",
+			status:   dealPkg.OrderStatusAccepted,
+			wantErr:  true,
+			wantSent: 1,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT exchangeID`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exchangeID"}).AddRow(55))
+				s.ExpectBegin()
+				s.ExpectExec(`UPDATE orders SET`).WillReturnError(fmt.Errorf("update error"))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Заявка уже не активна - команда удаляется без отправки",
+			status: dealPkg.OrderStatusRejected,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("cant create mock: %s", err)
+			}
+			defer db.Close()
+
+			exchange := &fakeExchange{ReplaceErr: tt.exchangeErr, Order: dealPkg.Order{Price: 100, Volume: 10, CompletedVolume: 6}}
+			dm := &DealsManager{DR: &dealRepoPkg.DealRepo{DB: db}, ExClient: exchange, Mux: &sync.Mutex{}}
+			mock.ExpectQuery(`SELECT id, brokerID`).WithArgs(1).WillReturnRows(orderRow(tt.status))
+			tt.mockF(mock)
+
+			command := &brokerDealPkg.OutboxCommand{ID: 7, OrderID: 1, Command: brokerDealPkg.CommandReplace, Price: 101, Volume: 9}
+			replaced, rejected, err := dm.applyReplace(command)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DealsManager.applyReplace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (rejected != nil) != tt.wantRejected {
+				t.Errorf("DealsManager.applyReplace() rejected = %v, wantRejected %v", rejected, tt.wantRejected)
+			}
+			if len(exchange.Replaces) != tt.wantSent {
+				t.Fatalf("sent to exchange %v, want %v", len(exchange.Replaces), tt.wantSent)
+			}
+			if tt.wantSent > 0 && (exchange.Replaces[0].ID != 55 || exchange.Replaces[0].Price != 101 || exchange.Replaces[0].Volume != 9) {
+				t.Errorf("sent to exchange %+v", exchange.Replaces[0])
+			}
+			if applied := !tt.wantErr && !tt.wantRejected && tt.wantSent > 0; applied != (replaced != nil) {
+				t.Errorf("DealsManager.applyReplace() replaced = %+v, want applied %v", replaced, applied)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestDealsManager_ReplaceOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		exchangeErr error
+		want        *brokerDealPkg.ReplaceResult
+		wantErr     bool
+		mockF       func(sqlmock.Sqlmock)
+	}{
+		{name: "Изменение применено биржей",
+			want: &brokerDealPkg.ReplaceResult{OrderID: 1, Status: brokerDealPkg.ReplaceApplied, Price: 100, Volume: 7},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT exchangeID`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exchangeID"}).AddRow(55))
+				s.ExpectBegin()
+				s.ExpectExec(`UPDATE orders SET`).WithArgs(float64(100), 7, 1, float64(0)).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectExec(`INSERT INTO orderHistory`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Биржа недоступна - изменение ждет в outbox",
+			exchangeErr: status.Error(codes.Unavailable, "unavailable"),
+			want:        &brokerDealPkg.ReplaceResult{OrderID: 1, Status: brokerDealPkg.ReplacePending},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT exchangeID`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exchangeID"}).AddRow(55))
+			},
+		},
+		{name: "Отказ биржи возвращается ошибкой",
+			exchangeErr: status.Error(codes.InvalidArgument, "filled"),
+			wantErr:     true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT exchangeID`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exchangeID"}).AddRow(55))
+				s.ExpectBegin()
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("cant create mock: %s", err)
+			}
+			defer db.Close()
+
+			exchange := &fakeExchange{ReplaceErr: tt.exchangeErr, Order: dealPkg.Order{Price: 100, Volume: 10, CompletedVolume: 4}}
+			dm := &DealsManager{
+				DR:          &dealRepoPkg.DealRepo{DB: db},
+				CR:          &clientRepoPkg.ClientsRepo{DB: db},
+				Instruments: &instrumentUsecasePkg.InstrumentsManager{Mux: &sync.RWMutex{}},
+				ExClient:    exchange,
+				Mux:         &sync.Mutex{},
+			}
+			mock.ExpectQuery(`SELECT id, brokerID`).WithArgs(1).WillReturnRows(orderRow(dealPkg.OrderStatusAccepted))
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT reservePrice`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"reservePrice"}).AddRow(100))
+			mock.ExpectQuery(`SELECT id FROM clients`).WithArgs(100).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(100))
+			mock.ExpectQuery(`SELECT clients.balance`).WillReturnRows(sqlmock.NewRows([]string{"balance", "reserved"}).AddRow(1000, 600))
+			//остаток 3 при исполненных у брокера 4 - бирже уходит полный объем 7
+			mock.ExpectQuery(`INSERT INTO outbox`).WithArgs(1, brokerDealPkg.CommandReplace, 0, sqlmock.AnyArg(), float64(0), 7).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+			mock.ExpectCommit()
+			mock.ExpectQuery(`SELECT id, brokerID`).WithArgs(1).WillReturnRows(orderRow(dealPkg.OrderStatusAccepted))
+			tt.mockF(mock)
+
+			got, err := dm.ReplaceOrder(1, 0, 3, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DealsManager.ReplaceOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DealsManager.ReplaceOrder() = %+v, want %+v", got, tt.want)
+			}
+			if len(exchange.Replaces) != 1 || exchange.Replaces[0].Volume != 7 {
+				t.Errorf("sent to exchange %+v", exchange.Replaces)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestDealsManager_DealProcessingCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		deal    *dealPkg.Deal
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Отмена остатка сдвигает курсор вместе с удалением заявки",
+			deal: &dealPkg.Deal{ID: 20, OrderID: 55, Volume: 6, Price: 100, Event: dealPkg.EventCancel},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id`).WithArgs(55).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectExec(`INSERT INTO orderHistory`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`DELETE FROM orders`).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectExec(`INSERT INTO resultsCursor`).WithArgs(20).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Повтор события по удаленной заявке тоже сдвигает курсор",
+			deal: &dealPkg.Deal{ID: 21, OrderID: 55, Event: dealPkg.EventExpire},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id`).WithArgs(55).WillReturnError(sql.ErrNoRows)
+				s.ExpectExec(`INSERT INTO resultsCursor`).WithArgs(21).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Ошибка обработки - курсор не сдвигается",
+			deal:    &dealPkg.Deal{ID: 22, OrderID: 55, Event: dealPkg.EventCancel},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT id`).WithArgs(55).WillReturnError(fmt.Errorf("select error"))
+				s.ExpectRollback()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("cant create mock: %s", err)
+			}
+			defer db.Close()
+
+			dm := &DealsManager{DR: &dealRepoPkg.DealRepo{DB: db}, Mux: &sync.Mutex{}}
+			mock.ExpectBegin()
+			tt.mockF(mock)
+
+			if err := dm.DealProcessing(tt.deal); (err != nil) != tt.wantErr {
+				t.Errorf("DealsManager.DealProcessing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestDealsManager_DeadLetter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	dm := &DealsManager{DR: &dealRepoPkg.DealRepo{DB: db}, Mux: &sync.Mutex{}}
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO deadDeals`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO resultsCursor`).WithArgs(30).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = dm.DeadLetter(&dealPkg.Deal{ID: 30, OrderID: 55, Event: dealPkg.EventFill}, fmt.Errorf("order not found"))
+	if err != nil {
+		t.Errorf("DealsManager.DeadLetter() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestDealsManager_DispatchOutbox(t *testing.T) {
+	const now = 1670000000
+	tests := []struct {
+		name        string
+		command     string
+		status      string
+		createErr   error
+		cancelErr   error
+		wantCreates int
+		wantCancels int
+		mockF       func(sqlmock.Sqlmock)
+	}{
+		{name: "Заявка принята биржей - сохраняется exchangeID, команда удаляется",
+			command:     brokerDealPkg.CommandCreate,
+			status:      dealPkg.OrderStatusPending,
+			wantCreates: 1,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec(`UPDATE orders SET exchangeID`).WithArgs(55, dealPkg.OrderStatusAccepted, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectExec(`INSERT INTO orderHistory`).
+					WithArgs(1, dealPkg.HistoryAccepted, 10, float64(100), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Заявка отклонена биржей - статус rejected, команда удаляется",
+			command:     brokerDealPkg.CommandCreate,
+			status:      dealPkg.OrderStatusPending,
+			createErr:   status.Error(codes.InvalidArgument, "bad ticker"),
+			wantCreates: 1,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec(`UPDATE orders SET status`).WithArgs(dealPkg.OrderStatusRejected, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectExec(`INSERT INTO orderHistory`).
+					WithArgs(1, dealPkg.HistoryRejected, 10, float64(100), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Биржа недоступна - повтор через 2 секунды",
+			command:     brokerDealPkg.CommandCreate,
+			status:      dealPkg.OrderStatusPending,
+			createErr:   status.Error(codes.Unavailable, "unavailable"),
+			wantCreates: 1,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE outbox SET attempts`).WithArgs(1, now+2, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{name: "Отмена заявки, не принятой биржей, ждет ответа на создание",
+			command: brokerDealPkg.CommandCancel,
+			status:  dealPkg.OrderStatusPending,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`UPDATE outbox SET attempts`).WithArgs(1, now+2, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+		{name: "Заявки уже нет на бирже - отмена считается выполненной",
+			command:     brokerDealPkg.CommandCancel,
+			status:      dealPkg.OrderStatusAccepted,
+			cancelErr:   status.Error(codes.NotFound, "not found"),
+			wantCancels: 1,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT exchangeID`).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"exchangeID"}).AddRow(55))
+				s.ExpectBegin()
+				s.ExpectExec(`DELETE FROM outbox`).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("cant create mock: %s", err)
+			}
+			defer db.Close()
+
+			exchange := &fakeExchange{CreateErr: tt.createErr, CancelErr: tt.cancelErr}
+			dm := &DealsManager{DR: &dealRepoPkg.DealRepo{DB: db}, ExClient: exchange, Mux: &sync.Mutex{}}
+			mock.ExpectQuery(`SELECT id, orderID, command`).WithArgs(now).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "orderID", "command", "attempts", "nextAttempt", "price", "volume"}).
+					AddRow(7, 1, tt.command, 0, now, 0, 0))
+			mock.ExpectQuery(`SELECT id, brokerID`).WithArgs(1).WillReturnRows(orderRow(tt.status))
+			tt.mockF(mock)
+
+			dm.dispatchOutbox(now, &logging.Logger{Zap: zap.NewNop()})
+			if len(exchange.Creates) != tt.wantCreates || len(exchange.Cancels) != tt.wantCancels {
+				t.Errorf("sent creates %v, cancels %v, want %v, %v", len(exchange.Creates), len(exchange.Cancels),
+					tt.wantCreates, tt.wantCancels)
+			}
+			if tt.wantCreates > 0 && exchange.Creates[0].IdempotencyKey != "1" {
+				t.Errorf("idempotency key %v, want broker order id", exchange.Creates[0].IdempotencyKey)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestDealsManager_ReservePrice(t *testing.T) {
+	depth := depthUsecasePkg.NewDepthManager()
+	err := depth.Apply(&depthPkg.Depth{Ticker: "SPFB.RTS", Sequence: 1,
+		Asks: []depthPkg.Level{{Price: 101, Volume: 2}, {Price: 103, Volume: 3}, {Price: 110, Volume: 1}}}, true)
+	if err != nil {
+		t.Fatalf("apply depth: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		order     *dealPkg.Order
+		lastPrice float32
+		want      float32
+		wantErr   bool
+	}{
+		{name: "Лимитная покупка резервируется по цене заявки",
+			order: &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeBuy, Kind: dealPkg.KindLimit, Price: 99, Volume: 1},
+			want:  99,
+		},
+		{name: "Стоп-покупка выше рынка - по стоп-цене с запасом",
+			order:     &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeBuy, Kind: dealPkg.KindStop, StopPrice: 120, Volume: 1},
+			lastPrice: 100,
+			want:      120 * 1.05,
+		},
+		{name: "Стоп-покупка ниже стакана - по худшей цене стакана с запасом",
+			order:     &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeBuy, Kind: dealPkg.KindStop, StopPrice: 102, Volume: 4},
+			lastPrice: 100,
+			want:      103 * 1.05,
+		},
+		{name: "Стоп-покупка ниже последней цены - по последней цене с запасом",
+			order:     &dealPkg.Order{Ticker: "SPFB.SI", Type: dealPkg.TypeBuy, Kind: dealPkg.KindStop, StopPrice: 90, Volume: 1},
+			lastPrice: 95,
+			want:      95 * 1.05,
+		},
+		{name: "Стоп-покупка без последней цены - по стоп-цене с запасом",
+			order: &dealPkg.Order{Ticker: "SPFB.SI", Type: dealPkg.TypeBuy, Kind: dealPkg.KindStop, StopPrice: 90, Volume: 1},
+			want:  90 * 1.05,
+		},
+		{name: "Стоп-лимитная покупка - по лимитной цене",
+			order: &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeBuy, Kind: dealPkg.KindStopLimit, StopPrice: 120,
+				Price: 125, Volume: 1},
+			want: 125,
+		},
+		{name: "Продажа не резервируется",
+			order: &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeSell, Kind: dealPkg.KindMarket, Volume: 1},
+			want:  0,
+		},
+		{name: "Рыночная покупка - по худшей цене стакана на весь объем с запасом",
+			order:     &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeBuy, Kind: dealPkg.KindMarket, Volume: 4},
+			lastPrice: 100,
+			want:      103 * 1.05,
+		},
+		{name: "Рыночная покупка больше стакана - по худшей цене в нем",
+			order:     &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeBuy, Kind: dealPkg.KindMarket, Volume: 10},
+			lastPrice: 100,
+			want:      110 * 1.05,
+		},
+		{name: "Последняя цена выше стакана - по последней цене",
+			order:     &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeBuy, Kind: dealPkg.KindMarket, Volume: 1},
+			lastPrice: 105,
+			want:      105 * 1.05,
+		},
+		{name: "Без стакана и последней цены рыночная покупка отклоняется",
+			order:   &dealPkg.Order{Ticker: "SPFB.SI", Type: dealPkg.TypeBuy, Kind: dealPkg.KindMarket, Volume: 1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("cant create mock: %s", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery(`SELECT close FROM stats`).WillReturnRows(sqlmock.NewRows([]string{"close"}).AddRow(tt.lastPrice))
+			dm := &DealsManager{DR: &dealRepoPkg.DealRepo{DB: db}, Depth: depth, MarketSlippage: defaultMarketSlippage}
+
+			got, err := dm.reservePrice(tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DealsManager.reservePrice() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(float64(got-tt.want)) > 1e-3 {
+				t.Errorf("DealsManager.reservePrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplacedReserve(t *testing.T) {
+	tests := []struct {
+		name  string
+		order *dealPkg.Order
+		price float32
+		want  float32
+	}{
+		{name: "Новая цена лимитной покупки",
+			order: &dealPkg.Order{Type: dealPkg.TypeBuy, Kind: dealPkg.KindLimit}, price: 101, want: 101},
+		{name: "Цена не меняется",
+			order: &dealPkg.Order{Type: dealPkg.TypeBuy, Kind: dealPkg.KindLimit}, price: 0, want: 0},
+		{name: "Стоп-покупка остается зарезервированной по стоп-цене",
+			order: &dealPkg.Order{Type: dealPkg.TypeBuy, Kind: dealPkg.KindStop}, price: 101, want: 0},
+		{name: "Стоп-лимит резервируется по цене лимита",
+			order: &dealPkg.Order{Type: dealPkg.TypeBuy, Kind: dealPkg.KindStopLimit}, price: 101, want: 101},
+		{name: "Продажа не резервируется",
+			order: &dealPkg.Order{Type: dealPkg.TypeSell, Kind: dealPkg.KindLimit}, price: 101, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replacedReserve(tt.order, tt.price); got != tt.want {
+				t.Errorf("replacedReserve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateRisk(t *testing.T) {
+	buy := &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeBuy, Price: 100}
+	sell := &dealPkg.Order{Ticker: "SPFB.RTS", Type: dealPkg.TypeSell, Price: 100}
+	tests := []struct {
+		name      string
+		profile   *clientPkg.RiskProfile
+		positions []*clientPkg.RiskPosition
+		order     *dealPkg.Order
+		volume    int32
+		wantCode  string
+	}{
+		{name: "Продажа в пределах позиции",
+			profile:   &clientPkg.RiskProfile{},
+			positions: []*clientPkg.RiskPosition{{Ticker: "SPFB.RTS", Volume: 5, PendingSell: 2, Price: 100}},
+			order:     sell,
+			volume:    3,
+		},
+		{name: "Продажа с учетом других заявок уходит в шорт",
+			profile:   &clientPkg.RiskProfile{},
+			positions: []*clientPkg.RiskPosition{{Ticker: "SPFB.RTS", Volume: 5, PendingSell: 3, Price: 100}},
+			order:     sell,
+			volume:    3,
+			wantCode:  clientPkg.CodeShortSelling,
+		},
+		{name: "Шорт разрешен профилем",
+			profile: &clientPkg.RiskProfile{AllowShort: true},
+			order:   sell,
+			volume:  3,
+		},
+		{name: "Покупка с открытыми заявками превышает лимит позиции",
+			profile:   &clientPkg.RiskProfile{MaxPosition: 10},
+			positions: []*clientPkg.RiskPosition{{Ticker: "SPFB.RTS", Volume: 6, PendingBuy: 3, Price: 100}},
+			order:     buy,
+			volume:    2,
+			wantCode:  clientPkg.CodePositionLimit,
+		},
+		{name: "Покупка ровно до лимита позиции",
+			profile:   &clientPkg.RiskProfile{MaxPosition: 10},
+			positions: []*clientPkg.RiskPosition{{Ticker: "SPFB.RTS", Volume: 6, PendingBuy: 3, Price: 100}},
+			order:     buy,
+			volume:    1,
+		},
+		{name: "Сумма по всем инструментам превышает лимит в деньгах",
+			profile: &clientPkg.RiskProfile{MaxExposure: 1000},
+			positions: []*clientPkg.RiskPosition{
+				{Ticker: "SPFB.SI", Volume: 8, Price: 100},
+				{Ticker: "SPFB.RTS", Volume: 1, Price: 100}},
+			order:    buy,
+			volume:   2,
+			wantCode: clientPkg.CodeExposureLimit,
+		},
+		{name: "Без позиции в инструменте экспозиция считается по цене заявки",
+			profile:  &clientPkg.RiskProfile{MaxExposure: 1000},
+			order:    buy,
+			volume:   11,
+			wantCode: clientPkg.CodeExposureLimit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := evaluateRisk(tt.profile, tt.positions, tt.order, tt.volume)
+			var riskErr *clientPkg.RiskError
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Errorf("evaluateRisk() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.As(err, &riskErr) || riskErr.Code != tt.wantCode {
+				t.Errorf("evaluateRisk() error = %v, want code %v", err, tt.wantCode)
+			}
+		})
+	}
+}
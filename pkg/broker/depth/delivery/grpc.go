@@ -0,0 +1,76 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+
+	depthPkg "github.com/KeynihAV/exchange/pkg/broker/depth"
+	depthUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/depth/usecase"
+	streamPkg "github.com/KeynihAV/exchange/pkg/broker/stream"
+	"github.com/KeynihAV/exchange/pkg/config"
+	dealDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/delivery"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ConsumeDepth получает стаканы от биржи, при обрыве или пропуске обновления supervisor подписывается заново
+func ConsumeDepth(depthManager *depthUsecasePkg.DepthManager, config *config.Config, supervisor *streamPkg.Supervisor, logger *logging.Logger) error {
+	grcpConn, err := grpc.Dial(
+		config.Broker.ExchangeEndpoint,
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		logger.Zap.Error("consume depth dial exchange",
+			zap.String("logger", "grpcClient"),
+			zap.String("err", err.Error()),
+		)
+		return err
+	}
+
+	exchClient := dealDeliveryPkg.NewExchangeClient(grcpConn)
+	supervisor.Run("depth", func(connected func()) error {
+		return consumeDepth(depthManager, exchClient, config, connected)
+	})
+	return nil
+}
+
+func consumeDepth(depthManager *depthUsecasePkg.DepthManager, exchClient dealDeliveryPkg.ExchangeClient, config *config.Config,
+	connected func()) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	md := metadata.Pairs()
+
+	depthStream, err := exchClient.OrderBook(metadata.NewOutgoingContext(ctx, md), &dealDeliveryPkg.DepthRequest{BrokerID: int64(config.Broker.ID)})
+	if err != nil {
+		return fmt.Errorf("get depth stream: %v", err)
+	}
+	//после подписки придут снимки всех стаканов, старые уровни могли устареть
+	depthManager.Reset()
+	connected()
+
+	for {
+		update, err := depthStream.Recv()
+		if err != nil {
+			return err
+		}
+		err = depthManager.Apply(&depthPkg.Depth{
+			Ticker:   update.Ticker,
+			Bids:     levelsFromProto(update.Bids),
+			Asks:     levelsFromProto(update.Asks),
+			Sequence: update.Sequence,
+		}, update.Snapshot)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func levelsFromProto(levels []*dealDeliveryPkg.PriceLevel) []depthPkg.Level {
+	result := make([]depthPkg.Level, len(levels))
+	for i, level := range levels {
+		result[i] = depthPkg.Level{Price: level.Price, Volume: level.Volume}
+	}
+	return result
+}
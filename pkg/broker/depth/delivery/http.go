@@ -0,0 +1,43 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	depthPkg "github.com/KeynihAV/exchange/pkg/broker/depth"
+	depthUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/depth/usecase"
+	"github.com/KeynihAV/exchange/pkg/common"
+	"github.com/gorilla/mux"
+)
+
+type DepthHandler struct {
+	DepthManager *depthUsecasePkg.DepthManager
+}
+
+// GetDepth - стакан инструмента, параметр запроса levels - число уровней с каждой стороны (по умолчанию 10)
+func (dh *DepthHandler) GetDepth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	var levels int
+	if value := r.URL.Query().Get("levels"); value != "" {
+		var err error
+		levels, err = strconv.Atoi(value)
+		if err != nil {
+			common.RespJSONError(w, http.StatusBadRequest, err, "bad levels: "+err.Error(), ctx)
+			return
+		}
+	}
+
+	depth, err := dh.DepthManager.Depth(vars["ticker"], levels)
+	if errors.Is(err, depthPkg.ErrUnknownTicker) {
+		common.RespJSONError(w, http.StatusNotFound, err, err.Error(), ctx)
+		return
+	}
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), ctx)
+		return
+	}
+	common.WriteStructToResponse(depth, ctx, w)
+}
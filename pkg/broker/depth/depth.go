@@ -0,0 +1,25 @@
+package depth
+
+import "errors"
+
+type Level struct {
+	Price  float32
+	Volume int32
+}
+
+// Depth - стакан инструмента по ценовым уровням: Bids от высокой цены, Asks от низкой,
+// Time - unix time последнего изменения
+type Depth struct {
+	Ticker   string
+	Bids     []Level
+	Asks     []Level
+	Sequence int64
+	Time     int32
+}
+
+var (
+	// ErrUnknownTicker - по инструменту не получено ни одного снимка стакана
+	ErrUnknownTicker = errors.New("no order book for ticker")
+	// ErrSequenceGap - пропущено обновление стакана, нужна переподписка за новым снимком
+	ErrSequenceGap = errors.New("order book sequence gap")
+)
@@ -0,0 +1,139 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	depthPkg "github.com/KeynihAV/exchange/pkg/broker/depth"
+)
+
+const (
+	defaultLevels = 10
+	maxLevels     = 100
+)
+
+// book - уровни стакана по цене
+type book struct {
+	Bids     map[float32]int32
+	Asks     map[float32]int32
+	Sequence int64
+	Time     int32
+}
+
+// DepthManager хранит стаканы, полученные от биржи: снимок при подписке и изменения уровней после него
+type DepthManager struct {
+	Books map[string]*book
+	Mux   *sync.RWMutex
+}
+
+func NewDepthManager() *DepthManager {
+	return &DepthManager{
+		Books: make(map[string]*book),
+		Mux:   &sync.RWMutex{},
+	}
+}
+
+// Reset забывает все стаканы перед новой подпиской, снимки придут заново
+func (dm *DepthManager) Reset() {
+	dm.Mux.Lock()
+	defer dm.Mux.Unlock()
+
+	dm.Books = make(map[string]*book)
+}
+
+// Apply применяет снимок или изменение уровней, после пропуска обновления стакан инструмента сбрасывается
+func (dm *DepthManager) Apply(update *depthPkg.Depth, snapshot bool) error {
+	dm.Mux.Lock()
+	defer dm.Mux.Unlock()
+
+	b, ok := dm.Books[update.Ticker]
+	if snapshot || !ok {
+		//стакан, которого не было при подписке, начинается с пустого
+		b = &book{Bids: make(map[float32]int32), Asks: make(map[float32]int32)}
+	}
+	if !snapshot && update.Sequence != b.Sequence+1 {
+		delete(dm.Books, update.Ticker)
+		return fmt.Errorf("%w: %v got %v after %v", depthPkg.ErrSequenceGap, update.Ticker, update.Sequence, b.Sequence)
+	}
+
+	applyLevels(b.Bids, update.Bids)
+	applyLevels(b.Asks, update.Asks)
+	b.Sequence = update.Sequence
+	b.Time = int32(time.Now().Unix())
+	dm.Books[update.Ticker] = b
+	return nil
+}
+
+func applyLevels(side map[float32]int32, levels []depthPkg.Level) {
+	for _, level := range levels {
+		if level.Volume == 0 {
+			delete(side, level.Price)
+			continue
+		}
+		side[level.Price] = level.Volume
+	}
+}
+
+// Depth - лучшие levels уровней с каждой стороны стакана, 0 - по умолчанию
+func (dm *DepthManager) Depth(ticker string, levels int) (*depthPkg.Depth, error) {
+	if levels <= 0 {
+		levels = defaultLevels
+	}
+	if levels > maxLevels {
+		levels = maxLevels
+	}
+
+	dm.Mux.RLock()
+	defer dm.Mux.RUnlock()
+
+	b, ok := dm.Books[ticker]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", depthPkg.ErrUnknownTicker, ticker)
+	}
+	return &depthPkg.Depth{
+		Ticker:   ticker,
+		Bids:     sortedLevels(b.Bids, levels, true),
+		Asks:     sortedLevels(b.Asks, levels, false),
+		Sequence: b.Sequence,
+		Time:     b.Time,
+	}, nil
+}
+
+// WorstAsk - цена, до которой покупка volume пройдет по стакану, если продавцов в стакане не хватает - худшая цена в нем,
+// false - стакана инструмента нет или продавцов в нем нет
+func (dm *DepthManager) WorstAsk(ticker string, volume int32) (float32, bool) {
+	dm.Mux.RLock()
+	defer dm.Mux.RUnlock()
+
+	b, ok := dm.Books[ticker]
+	if !ok || len(b.Asks) == 0 {
+		return 0, false
+	}
+	levels := sortedLevels(b.Asks, len(b.Asks), false)
+	for _, level := range levels {
+		volume -= level.Volume
+		if volume <= 0 {
+			return level.Price, true
+		}
+	}
+	return levels[len(levels)-1].Price, true
+}
+
+func sortedLevels(side map[float32]int32, limit int, descending bool) []depthPkg.Level {
+	levels := make([]depthPkg.Level, 0, len(side))
+	for price, volume := range side {
+		levels = append(levels, depthPkg.Level{Price: price, Volume: volume})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if descending {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+	if len(levels) > limit {
+		levels = levels[:limit]
+	}
+	return levels
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	depthPkg "github.com/KeynihAV/exchange/pkg/broker/depth"
+)
+
+const testTicker = "SPFB.RTS"
+
+type applied struct {
+	update   *depthPkg.Depth
+	snapshot bool
+}
+
+func TestDepthManager_Apply(t *testing.T) {
+	snapshot := applied{snapshot: true, update: &depthPkg.Depth{Ticker: testTicker, Sequence: 5,
+		Bids: []depthPkg.Level{{Price: 99, Volume: 3}, {Price: 100, Volume: 1}},
+		Asks: []depthPkg.Level{{Price: 101, Volume: 2}}}}
+	tests := []struct {
+		name    string
+		updates []applied
+		wantErr error
+		want    *depthPkg.Depth
+	}{
+		{name: "Снимок",
+			updates: []applied{snapshot},
+			want: &depthPkg.Depth{Ticker: testTicker, Sequence: 5,
+				Bids: []depthPkg.Level{{Price: 100, Volume: 1}, {Price: 99, Volume: 3}},
+				Asks: []depthPkg.Level{{Price: 101, Volume: 2}}},
+		},
+		{name: "Изменения уровней после снимка, нулевой объем удаляет уровень",
+			updates: []applied{snapshot,
+				{update: &depthPkg.Depth{Ticker: testTicker, Sequence: 6, Bids: []depthPkg.Level{{Price: 100, Volume: 0}}}},
+				{update: &depthPkg.Depth{Ticker: testTicker, Sequence: 7, Asks: []depthPkg.Level{{Price: 102, Volume: 4},
+					{Price: 101, Volume: 5}}}}},
+			want: &depthPkg.Depth{Ticker: testTicker, Sequence: 7,
+				Bids: []depthPkg.Level{{Price: 99, Volume: 3}},
+				Asks: []depthPkg.Level{{Price: 101, Volume: 5}, {Price: 102, Volume: 4}}},
+		},
+		{name: "Новый снимок заменяет стакан целиком",
+			updates: []applied{snapshot,
+				{snapshot: true, update: &depthPkg.Depth{Ticker: testTicker, Sequence: 9,
+					Asks: []depthPkg.Level{{Price: 105, Volume: 1}}}}},
+			want: &depthPkg.Depth{Ticker: testTicker, Sequence: 9,
+				Bids: []depthPkg.Level{},
+				Asks: []depthPkg.Level{{Price: 105, Volume: 1}}},
+		},
+		{name: "Стакан, которого не было при подписке, начинается с пустого",
+			updates: []applied{
+				{update: &depthPkg.Depth{Ticker: testTicker, Sequence: 1, Bids: []depthPkg.Level{{Price: 90, Volume: 1}}}}},
+			want: &depthPkg.Depth{Ticker: testTicker, Sequence: 1,
+				Bids: []depthPkg.Level{{Price: 90, Volume: 1}},
+				Asks: []depthPkg.Level{}},
+		},
+		{name: "Пропуск обновления сбрасывает стакан",
+			updates: []applied{snapshot,
+				{update: &depthPkg.Depth{Ticker: testTicker, Sequence: 7, Bids: []depthPkg.Level{{Price: 98, Volume: 1}}}}},
+			wantErr: depthPkg.ErrSequenceGap,
+		},
+		{name: "Повтор уже примененного обновления - тоже разрыв",
+			updates: []applied{snapshot,
+				{update: &depthPkg.Depth{Ticker: testTicker, Sequence: 5}}},
+			wantErr: depthPkg.ErrSequenceGap,
+		},
+		{name: "Первое обновление без снимка не с начала",
+			updates: []applied{
+				{update: &depthPkg.Depth{Ticker: testTicker, Sequence: 3}}},
+			wantErr: depthPkg.ErrSequenceGap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm := NewDepthManager()
+			var err error
+			for _, u := range tt.updates {
+				if err = dm.Apply(u.update, u.snapshot); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got, err := dm.Depth(testTicker, 0)
+			if tt.wantErr != nil {
+				if !errors.Is(err, depthPkg.ErrUnknownTicker) {
+					t.Errorf("Depth() after gap error = %v, want %v", err, depthPkg.ErrUnknownTicker)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Depth() error = %v", err)
+			}
+			got.Time = 0
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Depth() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDepthManager_Depth(t *testing.T) {
+	dm := NewDepthManager()
+	bids := make([]depthPkg.Level, 0, maxLevels+10)
+	for i := 1; i <= maxLevels+10; i++ {
+		bids = append(bids, depthPkg.Level{Price: float32(i), Volume: 1})
+	}
+	if err := dm.Apply(&depthPkg.Depth{Ticker: testTicker, Sequence: 1, Bids: bids}, true); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		levels    int
+		wantCount int
+	}{
+		{name: "По умолчанию", levels: 0, wantCount: defaultLevels},
+		{name: "Заданное число уровней", levels: 3, wantCount: 3},
+		{name: "Не больше максимума", levels: maxLevels * 2, wantCount: maxLevels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dm.Depth(testTicker, tt.levels)
+			if err != nil {
+				t.Fatalf("Depth() error = %v", err)
+			}
+			if len(got.Bids) != tt.wantCount {
+				t.Fatalf("Depth() bids = %v, want %v", len(got.Bids), tt.wantCount)
+			}
+			if got.Bids[0].Price != float32(maxLevels+10) {
+				t.Errorf("Depth() best bid = %v, want %v", got.Bids[0].Price, maxLevels+10)
+			}
+		})
+	}
+
+	if _, err := dm.Depth("SPFB.Si", 0); !errors.Is(err, depthPkg.ErrUnknownTicker) {
+		t.Errorf("Depth() unknown ticker error = %v, want %v", err, depthPkg.ErrUnknownTicker)
+	}
+	dm.Reset()
+	if _, err := dm.Depth(testTicker, 0); !errors.Is(err, depthPkg.ErrUnknownTicker) {
+		t.Errorf("Depth() after Reset error = %v, want %v", err, depthPkg.ErrUnknownTicker)
+	}
+}
+
+func TestDepthManager_WorstAsk(t *testing.T) {
+	dm := NewDepthManager()
+	err := dm.Apply(&depthPkg.Depth{Ticker: testTicker, Sequence: 1,
+		Bids: []depthPkg.Level{{Price: 99, Volume: 100}},
+		Asks: []depthPkg.Level{{Price: 101, Volume: 2}, {Price: 103, Volume: 3}, {Price: 102, Volume: 1}}}, true)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if err = dm.Apply(&depthPkg.Depth{Ticker: "SPFB.Si", Sequence: 1, Bids: []depthPkg.Level{{Price: 1, Volume: 1}}}, true); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		ticker string
+		volume int32
+		want   float32
+		wantOk bool
+	}{
+		{name: "Хватает лучшего уровня", ticker: testTicker, volume: 2, want: 101, wantOk: true},
+		{name: "Проходит несколько уровней", ticker: testTicker, volume: 4, want: 103, wantOk: true},
+		{name: "Продавцов не хватает - худшая цена", ticker: testTicker, volume: 50, want: 103, wantOk: true},
+		{name: "Продавцов нет", ticker: "SPFB.Si", volume: 1},
+		{name: "Стакана нет", ticker: "SPFB.BR", volume: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := dm.WorstAsk(tt.ticker, tt.volume)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("WorstAsk() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	instrumentUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/instrument/usecase"
+	streamPkg "github.com/KeynihAV/exchange/pkg/broker/stream"
+	"github.com/KeynihAV/exchange/pkg/config"
+	dealDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/delivery"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// syncInterval - как часто брокер перечитывает справочник инструментов биржи
+const syncInterval = time.Minute
+
+// SyncInstruments получает справочник инструментов при запуске и дальше раз в syncInterval,
+// при ошибке остается прежний справочник
+func SyncInstruments(instrumentsManager *instrumentUsecasePkg.InstrumentsManager, config *config.Config, logger *logging.Logger) error {
+	grcpConn, err := grpc.Dial(
+		config.Broker.ExchangeEndpoint,
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		logger.Zap.Error("sync instruments dial exchange",
+			zap.String("logger", "grpcClient"),
+			zap.String("err", err.Error()),
+		)
+		return err
+	}
+	exchClient := dealDeliveryPkg.NewExchangeClient(grcpConn)
+
+	tiker := time.NewTicker(syncInterval)
+	for {
+		err = syncInstruments(instrumentsManager, exchClient, config)
+		if err != nil {
+			logger.Zap.Error("sync instruments",
+				zap.String("logger", "grpcClient"),
+				zap.String("err", err.Error()),
+			)
+		}
+		<-tiker.C
+	}
+}
+
+func syncInstruments(instrumentsManager *instrumentUsecasePkg.InstrumentsManager, exchClient dealDeliveryPkg.ExchangeClient,
+	config *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	list, err := exchClient.ListInstruments(ctx, &dealDeliveryPkg.BrokerID{ID: int64(config.Broker.ID)})
+	if err != nil {
+		return err
+	}
+
+	instruments := make([]*instrumentPkg.Instrument, len(list.Instruments))
+	for i, instrument := range list.Instruments {
+		instruments[i] = &instrumentPkg.Instrument{
+			Ticker:      instrument.Ticker,
+			Description: instrument.Description,
+			TickSize:    instrument.TickSize,
+			LotSize:     instrument.LotSize,
+			Currency:    instrument.Currency,
+			PriceMin:    instrument.PriceMin,
+			PriceMax:    instrument.PriceMax,
+			Status:      dealDeliveryPkg.InstrumentStatusFromProto(instrument.Status),
+		}
+	}
+	instrumentsManager.Set(instruments)
+	return nil
+}
+
+// ConsumeHalts получает от биржи остановки торгов инструментами и их возобновления,
+// первыми после подписки приходят действующие остановки
+func ConsumeHalts(instrumentsManager *instrumentUsecasePkg.InstrumentsManager, config *config.Config, supervisor *streamPkg.Supervisor,
+	logger *logging.Logger) error {
+	grcpConn, err := grpc.Dial(
+		config.Broker.ExchangeEndpoint,
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		logger.Zap.Error("consume halts dial exchange",
+			zap.String("logger", "grpcClient"),
+			zap.String("err", err.Error()),
+		)
+		return err
+	}
+
+	exchClient := dealDeliveryPkg.NewExchangeClient(grcpConn)
+	supervisor.Run("halts", func(connected func()) error {
+		return consumeHalts(instrumentsManager, exchClient, config, connected, logger)
+	})
+	return nil
+}
+
+func consumeHalts(instrumentsManager *instrumentUsecasePkg.InstrumentsManager, exchClient dealDeliveryPkg.ExchangeClient, config *config.Config,
+	connected func(), logger *logging.Logger) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	md := metadata.Pairs()
+
+	haltsStream, err := exchClient.Halts(metadata.NewOutgoingContext(ctx, md), &dealDeliveryPkg.BrokerID{ID: int64(config.Broker.ID)})
+	if err != nil {
+		return fmt.Errorf("get halts stream: %v", err)
+	}
+	connected()
+
+	for {
+		event, err := haltsStream.Recv()
+		if err != nil {
+			return err
+		}
+		instrumentsManager.Halt(instrumentPkg.HaltEvent{
+			Ticker: event.Ticker,
+			Halted: event.Halted,
+			Time:   event.Time,
+			Until:  event.Until,
+		})
+		logger.Zap.Info("instrument halt",
+			zap.String("logger", "grpcClient"),
+			zap.String("ticker", event.Ticker),
+			zap.Bool("halted", event.Halted),
+		)
+	}
+}
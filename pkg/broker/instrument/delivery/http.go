@@ -0,0 +1,17 @@
+package delivery
+
+import (
+	"net/http"
+
+	instrumentUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/instrument/usecase"
+	"github.com/KeynihAV/exchange/pkg/common"
+)
+
+type InstrumentsHandler struct {
+	InstrumentsManager *instrumentUsecasePkg.InstrumentsManager
+}
+
+// List - инструменты биржи, которыми можно торговать или торги которыми приостановлены
+func (ih *InstrumentsHandler) List(w http.ResponseWriter, r *http.Request) {
+	common.WriteStructToResponse(ih.InstrumentsManager.List(), r.Context(), w)
+}
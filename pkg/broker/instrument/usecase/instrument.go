@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+)
+
+// InstrumentsManager - копия справочника инструментов биржи, Instruments nil - справочник еще не получен
+type InstrumentsManager struct {
+	Instruments map[string]*instrumentPkg.Instrument
+	Mux         *sync.RWMutex
+}
+
+func NewInstrumentsManager() *InstrumentsManager {
+	return &InstrumentsManager{Mux: &sync.RWMutex{}}
+}
+
+// Set заменяет справочник целиком
+func (im *InstrumentsManager) Set(instruments []*instrumentPkg.Instrument) {
+	byTicker := make(map[string]*instrumentPkg.Instrument, len(instruments))
+	for _, instrument := range instruments {
+		byTicker[instrument.Ticker] = instrument
+	}
+
+	im.Mux.Lock()
+	defer im.Mux.Unlock()
+	im.Instruments = byTicker
+}
+
+// List - инструменты по тикеру, delisted не показываются
+func (im *InstrumentsManager) List() []*instrumentPkg.Instrument {
+	im.Mux.RLock()
+	defer im.Mux.RUnlock()
+
+	result := make([]*instrumentPkg.Instrument, 0, len(im.Instruments))
+	for _, instrument := range im.Instruments {
+		if instrument.Status == instrumentPkg.StatusDelisted {
+			continue
+		}
+		copied := *instrument
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Ticker < result[j].Ticker
+	})
+	return result
+}
+
+// Halt меняет статус инструмента при остановке торгов биржей и их возобновлении, прекращенные торги не возобновляются
+func (im *InstrumentsManager) Halt(event instrumentPkg.HaltEvent) {
+	im.Mux.Lock()
+	defer im.Mux.Unlock()
+
+	instrument, ok := im.Instruments[event.Ticker]
+	if !ok || instrument.Status == instrumentPkg.StatusDelisted {
+		return
+	}
+	//справочник отдается копиями, поэтому инструмент заменяется целиком
+	changed := *instrument
+	changed.Status = instrumentPkg.StatusTrading
+	if event.Halted {
+		changed.Status = instrumentPkg.StatusHalted
+	}
+	im.Instruments[event.Ticker] = &changed
+}
+
+// CheckOrder проверяет заявку по справочнику до отправки на биржу, пока справочника нет - решает биржа
+func (im *InstrumentsManager) CheckOrder(order *dealPkg.Order) error {
+	im.Mux.RLock()
+	defer im.Mux.RUnlock()
+
+	if im.Instruments == nil {
+		return nil
+	}
+	instrument, ok := im.Instruments[order.Ticker]
+	if !ok {
+		return fmt.Errorf("%w: %v: %v", dealPkg.ErrInvalidOrder, instrumentPkg.ErrUnknownInstrument, order.Ticker)
+	}
+	return instrument.CheckOrder(order)
+}
@@ -0,0 +1,71 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+
+	marketPkg "github.com/KeynihAV/exchange/pkg/broker/market"
+	marketUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/market/usecase"
+	streamPkg "github.com/KeynihAV/exchange/pkg/broker/stream"
+	"github.com/KeynihAV/exchange/pkg/config"
+	dealDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/delivery"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// ConsumeSession получает фазы торговой сессии от биржи, первой после подписки приходит текущая фаза
+func ConsumeSession(marketManager *marketUsecasePkg.MarketManager, config *config.Config, supervisor *streamPkg.Supervisor, logger *logging.Logger) error {
+	grcpConn, err := grpc.Dial(
+		config.Broker.ExchangeEndpoint,
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		logger.Zap.Error("consume session dial exchange",
+			zap.String("logger", "grpcClient"),
+			zap.String("err", err.Error()),
+		)
+		return err
+	}
+
+	exchClient := dealDeliveryPkg.NewExchangeClient(grcpConn)
+	supervisor.Run("session", func(connected func()) error {
+		return consumeSession(marketManager, exchClient, config, connected, logger)
+	})
+	return nil
+}
+
+func consumeSession(marketManager *marketUsecasePkg.MarketManager, exchClient dealDeliveryPkg.ExchangeClient, config *config.Config,
+	connected func(), logger *logging.Logger) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	md := metadata.Pairs()
+
+	sessionStream, err := exchClient.Session(metadata.NewOutgoingContext(ctx, md), &dealDeliveryPkg.BrokerID{ID: int64(config.Broker.ID)})
+	if err != nil {
+		return fmt.Errorf("get session stream: %v", err)
+	}
+	connected()
+
+	for {
+		event, err := sessionStream.Recv()
+		if err != nil {
+			return err
+		}
+		status := &marketPkg.Status{
+			Phase:     dealDeliveryPkg.PhaseFromProto(event.Phase),
+			Time:      event.Time,
+			NextPhase: dealDeliveryPkg.PhaseFromProto(event.NextPhase),
+			NextTime:  event.NextTime,
+		}
+		if status.NextTime == 0 {
+			status.NextPhase = ""
+		}
+		marketManager.SetStatus(status)
+		logger.Zap.Info("session phase",
+			zap.String("logger", "grpcClient"),
+			zap.String("phase", status.Phase),
+		)
+	}
+}
@@ -0,0 +1,30 @@
+package delivery
+
+import (
+	"errors"
+	"net/http"
+
+	marketPkg "github.com/KeynihAV/exchange/pkg/broker/market"
+	marketUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/market/usecase"
+	"github.com/KeynihAV/exchange/pkg/common"
+)
+
+type MarketHandler struct {
+	MarketManager *marketUsecasePkg.MarketManager
+}
+
+// GetStatus - текущая фаза торговой сессии биржи
+func (mh *MarketHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	status, err := mh.MarketManager.GetStatus()
+	if errors.Is(err, marketPkg.ErrUnknownPhase) {
+		common.RespJSONError(w, http.StatusServiceUnavailable, err, err.Error(), ctx)
+		return
+	}
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), ctx)
+		return
+	}
+	common.WriteStructToResponse(status, ctx, w)
+}
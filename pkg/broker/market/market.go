@@ -0,0 +1,30 @@
+package market
+
+import "errors"
+
+// коды отказа в заявке по фазе торговой сессии, отдаются клиенту вместе с текстом ошибки
+const (
+	CodeMarketClosed = "market_closed"
+	CodeLimitOnly    = "limit_only"
+)
+
+// ErrUnknownPhase - от биржи еще не получена фаза сессии
+var ErrUnknownPhase = errors.New("session phase is not known yet")
+
+// Status - фаза торговой сессии биржи: Phase с Time, следующая фаза NextPhase с NextTime (0 - не ожидается)
+type Status struct {
+	Phase     string
+	Time      int32
+	NextPhase string
+	NextTime  int32
+}
+
+// PhaseError - заявка не принимается биржей в текущей фазе сессии
+type PhaseError struct {
+	Code    string
+	Message string
+}
+
+func (e *PhaseError) Error() string {
+	return e.Message
+}
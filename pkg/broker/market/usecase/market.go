@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"fmt"
+	"sync"
+
+	marketPkg "github.com/KeynihAV/exchange/pkg/broker/market"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+)
+
+// MarketManager хранит последнюю фазу сессии, полученную от биржи
+type MarketManager struct {
+	Status *marketPkg.Status
+	Mux    *sync.RWMutex
+}
+
+func NewMarketManager() *MarketManager {
+	return &MarketManager{Mux: &sync.RWMutex{}}
+}
+
+func (mm *MarketManager) SetStatus(status *marketPkg.Status) {
+	mm.Mux.Lock()
+	defer mm.Mux.Unlock()
+	mm.Status = status
+}
+
+func (mm *MarketManager) GetStatus() (*marketPkg.Status, error) {
+	mm.Mux.RLock()
+	defer mm.Mux.RUnlock()
+
+	if mm.Status == nil {
+		return nil, marketPkg.ErrUnknownPhase
+	}
+	status := *mm.Status
+	return &status, nil
+}
+
+// CheckOrder отклоняет заявку, которую биржа не примет в текущей фазе, пока фаза неизвестна - решает биржа
+func (mm *MarketManager) CheckOrder(order *dealPkg.Order) error {
+	status, err := mm.GetStatus()
+	if err != nil {
+		return nil
+	}
+
+	switch {
+	case status.Phase == dealPkg.PhaseClosed:
+		return &marketPkg.PhaseError{
+			Code:    marketPkg.CodeMarketClosed,
+			Message: "market is closed",
+		}
+	case status.Phase != dealPkg.PhaseContinuous && order.Kind != dealPkg.KindLimit && !dealPkg.IsStopKind(order.Kind):
+		return &marketPkg.PhaseError{
+			Code:    marketPkg.CodeLimitOnly,
+			Message: fmt.Sprintf("%v orders are not accepted in %v", order.Kind, status.Phase),
+		}
+	}
+	return nil
+}
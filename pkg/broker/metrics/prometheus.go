@@ -16,10 +16,46 @@ var (
 		},
 		[]string{"method"},
 	)
+	streamConnected = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "exchange_stream_connected",
+			Help: "Exchange stream connection state, 1 - connected",
+		},
+		[]string{"stream"},
+	)
+	streamReconnects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_stream_reconnects_total",
+			Help: "Exchange stream reconnects",
+		},
+		[]string{"stream"},
+	)
+	deadDeals = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "exchange_dead_deals_total",
+			Help: "Exchange results moved to dead letters after processing error",
+		},
+	)
 )
 
 func init() {
-	prometheus.MustRegister(timings)
+	prometheus.MustRegister(timings, streamConnected, streamReconnects, deadDeals)
+}
+
+func SetStreamConnected(stream string, connected bool) {
+	value := 0.0
+	if connected {
+		value = 1
+	}
+	streamConnected.WithLabelValues(stream).Set(value)
+}
+
+func StreamReconnected(stream string) {
+	streamReconnects.WithLabelValues(stream).Inc()
+}
+
+func DealDeadLettered() {
+	deadDeals.Inc()
 }
 
 func TimeTrackingMiddleware(next http.Handler) http.Handler {
@@ -3,10 +3,10 @@ package delivery
 import (
 	"context"
 	"fmt"
-	"io"
 
 	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
-	statsRepoPkg "github.com/KeynihAV/exchange/pkg/broker/stats/repo"
+	statsUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/stats/usecase"
+	streamPkg "github.com/KeynihAV/exchange/pkg/broker/stream"
 	"github.com/KeynihAV/exchange/pkg/config"
 	dealDeliveryPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/delivery"
 	"github.com/KeynihAV/exchange/pkg/logging"
@@ -15,7 +15,8 @@ import (
 	"google.golang.org/grpc/metadata"
 )
 
-func ConsumeStats(statsRepo *statsRepoPkg.StatsRepo, config *config.Config, logger *logging.Logger) error {
+// ConsumeStats получает цены от биржи, при обрыве supervisor подписывается заново
+func ConsumeStats(statsManager *statsUsecasePkg.StatsManager, config *config.Config, supervisor *streamPkg.Supervisor, logger *logging.Logger) error {
 	grcpConn, err := grpc.Dial(
 		config.Broker.ExchangeEndpoint,
 		grpc.WithInsecure(),
@@ -28,27 +29,35 @@ func ConsumeStats(statsRepo *statsRepoPkg.StatsRepo, config *config.Config, logg
 		return err
 	}
 
-	ctx := context.Background()
+	exchClient := dealDeliveryPkg.NewExchangeClient(grcpConn)
+	supervisor.Run("stats", func(connected func()) error {
+		return consumeStats(statsManager, exchClient, config, connected, logger)
+	})
+	return nil
+}
+
+func consumeStats(statsManager *statsUsecasePkg.StatsManager, exchClient dealDeliveryPkg.ExchangeClient, config *config.Config,
+	connected func(), logger *logging.Logger) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	md := metadata.Pairs()
 
-	exchClient := dealDeliveryPkg.NewExchangeClient(grcpConn)
-	statsStream, err := exchClient.Statistic(metadata.NewOutgoingContext(ctx, md), &dealDeliveryPkg.BrokerID{ID: int64(config.Broker.ID)})
+	statsStream, err := exchClient.Statistic(metadata.NewOutgoingContext(ctx, md), &dealDeliveryPkg.StatisticRequest{
+		BrokerID: int64(config.Broker.ID),
+		Tickers:  config.Broker.Tickers,
+		Interval: statsPkg.SecondInterval,
+	})
 	if err != nil {
-		logger.Zap.Error("get stats stream",
-			zap.String("logger", "grpcClient"),
-			zap.String("err", err.Error()),
-		)
-		return err
+		return fmt.Errorf("get stats stream: %v", err)
 	}
+	connected()
 
 	for {
 		stat, err := statsStream.Recv()
-		if err != nil && err != io.EOF {
-			fmt.Printf("unexpected error %v\n", err)
-		} else if err == io.EOF {
-			break
+		if err != nil {
+			return err
 		}
-		err = statsRepo.Add(&statsPkg.OHLCV{
+		err = statsManager.AddStat(&statsPkg.OHLCV{
 			TimeInt:  stat.Time,
 			Interval: stat.Interval,
 			Open:     stat.Open,
@@ -66,5 +75,58 @@ func ConsumeStats(statsRepo *statsRepoPkg.StatsRepo, config *config.Config, logg
 			continue
 		}
 	}
+}
+
+// ConsumeTrades сохраняет ленту обезличенных сделок биржи, сделки за время обрыва не восстанавливаются
+func ConsumeTrades(statsManager *statsUsecasePkg.StatsManager, config *config.Config, supervisor *streamPkg.Supervisor, logger *logging.Logger) error {
+	grcpConn, err := grpc.Dial(
+		config.Broker.ExchangeEndpoint,
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		logger.Zap.Error("consume trades dial exchange",
+			zap.String("logger", "grpcClient"),
+			zap.String("err", err.Error()),
+		)
+		return err
+	}
+
+	exchClient := dealDeliveryPkg.NewExchangeClient(grcpConn)
+	supervisor.Run("trades", func(connected func()) error {
+		return consumeTrades(statsManager, exchClient, config, connected, logger)
+	})
 	return nil
 }
+
+func consumeTrades(statsManager *statsUsecasePkg.StatsManager, exchClient dealDeliveryPkg.ExchangeClient, config *config.Config,
+	connected func(), logger *logging.Logger) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	md := metadata.Pairs()
+
+	tradesStream, err := exchClient.Trades(metadata.NewOutgoingContext(ctx, md), &dealDeliveryPkg.BrokerID{ID: int64(config.Broker.ID)})
+	if err != nil {
+		return fmt.Errorf("get trades stream: %v", err)
+	}
+	connected()
+
+	for {
+		trade, err := tradesStream.Recv()
+		if err != nil {
+			return err
+		}
+		err = statsManager.SR.AddPrint(&statsPkg.TradePrint{
+			Ticker: trade.Ticker,
+			Price:  trade.Price,
+			Volume: trade.Volume,
+			Type:   dealDeliveryPkg.SideFromProto(trade.Side, ""),
+			Time:   trade.Time,
+		})
+		if err != nil {
+			logger.Zap.Warn("write trades stream",
+				zap.String("logger", "grpcClient"),
+				zap.String("err", err.Error()),
+			)
+		}
+	}
+}
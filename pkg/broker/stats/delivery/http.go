@@ -1,24 +1,111 @@
 package delivery
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
-	statsRepoPkg "github.com/KeynihAV/exchange/pkg/broker/stats/repo"
+	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
+	indicatorsPkg "github.com/KeynihAV/exchange/pkg/broker/stats/indicators"
+	statsUsecasePkg "github.com/KeynihAV/exchange/pkg/broker/stats/usecase"
 	"github.com/KeynihAV/exchange/pkg/common"
 	"github.com/gorilla/mux"
 )
 
 type StatsHandler struct {
-	StatsRepo *statsRepoPkg.StatsRepo
+	StatsManager *statsUsecasePkg.StatsManager
 }
 
+// GeStatsByTicker - свечи инструмента, параметры запроса: interval (1s, 1m, 5m, 15m, 1h, 1d; по умолчанию 1m),
+// from и to (unix time)
 func (sh *StatsHandler) GeStatsByTicker(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
-	stats, err := sh.StatsRepo.GeStatsByTicker(vars["ticker"])
+
+	timeframe := r.URL.Query().Get("interval")
+	if timeframe == "" {
+		timeframe = "1m"
+	}
+	from, to, err := common.PeriodFromQuery(r)
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), ctx)
+		return
+	}
+
+	stats, err := sh.StatsManager.Candles(vars["ticker"], timeframe, from, to)
+	if errors.Is(err, statsPkg.ErrUnknownTimeframe) {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), ctx)
+		return
+	}
 	if err != nil {
 		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), ctx)
 		return
 	}
 	common.WriteStructToResponse(stats, ctx, w)
 }
+
+// Indicators - значение индикатора по минутным свечам, параметры запроса: name (sma, ema, rsi, macd, bollinger, vwap)
+// и period (по умолчанию свой для каждого индикатора)
+func (sh *StatsHandler) Indicators(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	var period int
+	if value := r.URL.Query().Get("period"); value != "" {
+		var err error
+		period, err = strconv.Atoi(value)
+		if err != nil {
+			common.RespJSONError(w, http.StatusBadRequest, err, "bad period: "+err.Error(), ctx)
+			return
+		}
+	}
+
+	result, err := sh.StatsManager.Indicators.Calculate(vars["ticker"], r.URL.Query().Get("name"), period)
+	switch {
+	case errors.Is(err, indicatorsPkg.ErrUnknownIndicator) || errors.Is(err, indicatorsPkg.ErrBadPeriod):
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), ctx)
+		return
+	case errors.Is(err, indicatorsPkg.ErrNotEnoughData):
+		common.RespJSONError(w, http.StatusNotFound, err, err.Error(), ctx)
+		return
+	case err != nil:
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), ctx)
+		return
+	}
+	common.WriteStructToResponse(result, ctx, w)
+}
+
+// TradesByTicker - лента обезличенных сделок, параметры запроса: from, to (unix time), cursor, limit
+func (sh *StatsHandler) TradesByTicker(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	filter := &statsPkg.PrintsFilter{Ticker: vars["ticker"]}
+	from, to, err := common.PeriodFromQuery(r)
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), ctx)
+		return
+	}
+	filter.From, filter.To = from, to
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		filter.Cursor, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			common.RespJSONError(w, http.StatusBadRequest, err, "bad cursor: "+err.Error(), ctx)
+			return
+		}
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			common.RespJSONError(w, http.StatusBadRequest, err, "bad limit: "+err.Error(), ctx)
+			return
+		}
+	}
+
+	page, err := sh.StatsManager.PrintsByTicker(filter)
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, err.Error(), ctx)
+		return
+	}
+	common.WriteStructToResponse(page, ctx, w)
+}
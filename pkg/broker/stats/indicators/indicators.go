@@ -0,0 +1,201 @@
+package indicators
+
+import (
+	"errors"
+	"math"
+
+	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
+)
+
+const (
+	SMA       = "sma"
+	EMA       = "ema"
+	RSI       = "rsi"
+	MACD      = "macd"
+	Bollinger = "bollinger"
+	VWAP      = "vwap"
+)
+
+// DefaultPeriods - период по умолчанию, если в запросе не указан
+var DefaultPeriods = map[string]int{
+	SMA:       20,
+	EMA:       20,
+	RSI:       14,
+	MACD:      macdSlow,
+	Bollinger: 20,
+	VWAP:      20,
+}
+
+const (
+	// MaxPeriod - ограничение периода, чтобы на расчет хватало хранимых свечей
+	MaxPeriod = 200
+
+	macdFast   = 12
+	macdSlow   = 26
+	macdSignal = 9
+	// bollingerWidth - ширина полос в стандартных отклонениях
+	bollingerWidth = 2
+)
+
+var (
+	ErrUnknownIndicator = errors.New("unknown indicator")
+	ErrBadPeriod        = errors.New("bad period")
+	ErrNotEnoughData    = errors.New("not enough candles")
+)
+
+// Result - значение индикатора на последней свече, для MACD и полос Боллинджера несколько линий
+type Result struct {
+	Ticker   string
+	Name     string
+	Period   int
+	Interval int32
+	Time     int32
+	Values   map[string]float32
+}
+
+// Calculate считает индикатор по свечам, упорядоченным по времени; для MACD период не используется
+func Calculate(name string, period int, candles []*statsPkg.OHLCV) (map[string]float32, error) {
+	if _, ok := DefaultPeriods[name]; !ok {
+		return nil, ErrUnknownIndicator
+	}
+	if period < 1 || period > MaxPeriod {
+		return nil, ErrBadPeriod
+	}
+
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		closes[i] = float64(candle.Close)
+	}
+
+	switch name {
+	case SMA:
+		if len(closes) < period {
+			return nil, ErrNotEnoughData
+		}
+		return map[string]float32{"value": float32(average(closes[len(closes)-period:]))}, nil
+	case EMA:
+		ema := emaSeries(closes, period)
+		if len(ema) == 0 {
+			return nil, ErrNotEnoughData
+		}
+		return map[string]float32{"value": float32(ema[len(ema)-1])}, nil
+	case RSI:
+		return rsi(closes, period)
+	case MACD:
+		return macd(closes)
+	case Bollinger:
+		return bollinger(closes, period)
+	default:
+		return vwap(candles, period)
+	}
+}
+
+func average(series []float64) float64 {
+	var sum float64
+	for _, value := range series {
+		sum += value
+	}
+	return sum / float64(len(series))
+}
+
+// emaSeries - EMA начиная с period-й точки, первое значение - SMA первых period точек
+func emaSeries(series []float64, period int) []float64 {
+	if len(series) < period {
+		return nil
+	}
+	alpha := 2 / float64(period+1)
+	ema := make([]float64, 0, len(series)-period+1)
+	ema = append(ema, average(series[:period]))
+	for _, value := range series[period:] {
+		prev := ema[len(ema)-1]
+		ema = append(ema, prev+alpha*(value-prev))
+	}
+	return ema
+}
+
+// rsi - индекс относительной силы со сглаживанием Уайлдера
+func rsi(closes []float64, period int) (map[string]float32, error) {
+	if len(closes) < period+1 {
+		return nil, ErrNotEnoughData
+	}
+
+	var gain, loss float64
+	for i := 1; i < len(closes); i++ {
+		change := closes[i] - closes[i-1]
+		up, down := math.Max(change, 0), math.Max(-change, 0)
+		if i <= period {
+			gain += up / float64(period)
+			loss += down / float64(period)
+			continue
+		}
+		gain = (gain*float64(period-1) + up) / float64(period)
+		loss = (loss*float64(period-1) + down) / float64(period)
+	}
+
+	if loss == 0 {
+		return map[string]float32{"value": 100}, nil
+	}
+	return map[string]float32{"value": float32(100 - 100/(1+gain/loss))}, nil
+}
+
+func macd(closes []float64) (map[string]float32, error) {
+	fast, slow := emaSeries(closes, macdFast), emaSeries(closes, macdSlow)
+	if len(slow) == 0 {
+		return nil, ErrNotEnoughData
+	}
+
+	//быстрая EMA начинается раньше медленной, выравниваем по концу
+	fast = fast[len(fast)-len(slow):]
+	line := make([]float64, len(slow))
+	for i := range slow {
+		line[i] = fast[i] - slow[i]
+	}
+
+	signal := emaSeries(line, macdSignal)
+	if len(signal) == 0 {
+		return nil, ErrNotEnoughData
+	}
+	last, lastSignal := line[len(line)-1], signal[len(signal)-1]
+	return map[string]float32{
+		"macd":      float32(last),
+		"signal":    float32(lastSignal),
+		"histogram": float32(last - lastSignal),
+	}, nil
+}
+
+func bollinger(closes []float64, period int) (map[string]float32, error) {
+	if len(closes) < period {
+		return nil, ErrNotEnoughData
+	}
+
+	window := closes[len(closes)-period:]
+	middle := average(window)
+	var variance float64
+	for _, value := range window {
+		variance += (value - middle) * (value - middle)
+	}
+	deviation := math.Sqrt(variance/float64(period)) * bollingerWidth
+	return map[string]float32{
+		"middle": float32(middle),
+		"upper":  float32(middle + deviation),
+		"lower":  float32(middle - deviation),
+	}, nil
+}
+
+// vwap - средняя цена, взвешенная по объему, за последние period свечей по типичной цене (high+low+close)/3
+func vwap(candles []*statsPkg.OHLCV, period int) (map[string]float32, error) {
+	if len(candles) < period {
+		return nil, ErrNotEnoughData
+	}
+
+	var turnover, volume float64
+	for _, candle := range candles[len(candles)-period:] {
+		typical := float64(candle.High+candle.Low+candle.Close) / 3
+		turnover += typical * float64(candle.Volume)
+		volume += float64(candle.Volume)
+	}
+	if volume == 0 {
+		return nil, ErrNotEnoughData
+	}
+	return map[string]float32{"value": float32(turnover / volume)}, nil
+}
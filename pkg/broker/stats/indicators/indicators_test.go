@@ -0,0 +1,128 @@
+package indicators
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
+)
+
+func candlesFromCloses(closes ...float32) []*statsPkg.OHLCV {
+	candles := make([]*statsPkg.OHLCV, len(closes))
+	for i, price := range closes {
+		candles[i] = &statsPkg.OHLCV{Open: price, High: price, Low: price, Close: price, Volume: 1}
+	}
+	return candles
+}
+
+func linearCandles(n int) []*statsPkg.OHLCV {
+	closes := make([]float32, n)
+	for i := range closes {
+		closes[i] = float32(i + 1)
+	}
+	return candlesFromCloses(closes...)
+}
+
+// wilderCloses - пример расчета RSI(14) из методички StockCharts, значения посчитаны без округления средних
+var wilderCloses = []float32{44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08, 45.89, 46.03, 45.61,
+	46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name    string
+		period  int
+		candles []*statsPkg.OHLCV
+		want    map[string]float32
+		wantErr error
+	}{
+		{name: SMA,
+			period:  3,
+			candles: candlesFromCloses(1, 2, 3, 4, 5),
+			want:    map[string]float32{"value": 4},
+		},
+		{name: EMA,
+			period:  3,
+			candles: candlesFromCloses(2, 4, 6, 8, 10),
+			//SMA(2, 4, 6) = 4, дальше с alpha = 0.5: 6, 8
+			want: map[string]float32{"value": 8},
+		},
+		{name: RSI,
+			period:  14,
+			candles: candlesFromCloses(wilderCloses[:15]...),
+			want:    map[string]float32{"value": 70.464},
+		},
+		{name: RSI,
+			period:  14,
+			candles: candlesFromCloses(wilderCloses...),
+			want:    map[string]float32{"value": 57.915},
+		},
+		{name: RSI,
+			period:  3,
+			candles: candlesFromCloses(1, 2, 3, 4),
+			want:    map[string]float32{"value": 100},
+		},
+		{name: MACD,
+			period:  DefaultPeriods[MACD],
+			candles: linearCandles(60),
+			//EMA линейного ряда отстает на (n-1)/2: 12.5 - 5.5
+			want: map[string]float32{"macd": 7, "signal": 7, "histogram": 0},
+		},
+		{name: Bollinger,
+			period:  8,
+			candles: candlesFromCloses(2, 4, 4, 4, 5, 5, 7, 9),
+			//среднее 5, стандартное отклонение 2
+			want: map[string]float32{"middle": 5, "upper": 9, "lower": 1},
+		},
+		{name: VWAP,
+			period: 2,
+			candles: []*statsPkg.OHLCV{
+				{High: 1000, Low: 1000, Close: 1000, Volume: 100},
+				{High: 12, Low: 9, Close: 9, Volume: 1},
+				{High: 21, Low: 18, Close: 21, Volume: 3}},
+			//типичные цены 10 и 20 с объемами 1 и 3
+			want: map[string]float32{"value": 17.5},
+		},
+		{name: "adx",
+			period:  14,
+			candles: linearCandles(30),
+			wantErr: ErrUnknownIndicator,
+		},
+		{name: SMA,
+			period:  MaxPeriod + 1,
+			candles: linearCandles(30),
+			wantErr: ErrBadPeriod,
+		},
+		{name: RSI,
+			period:  14,
+			candles: linearCandles(14),
+			wantErr: ErrNotEnoughData,
+		},
+		{name: MACD,
+			period:  DefaultPeriods[MACD],
+			candles: linearCandles(macdSlow + macdSignal - 2),
+			wantErr: ErrNotEnoughData,
+		},
+		{name: VWAP,
+			period:  2,
+			candles: []*statsPkg.OHLCV{{Close: 10}, {Close: 11}},
+			wantErr: ErrNotEnoughData,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Calculate(tt.name, tt.period, tt.candles)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Calculate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Calculate() = %v, want %v", got, tt.want)
+			}
+			for line, want := range tt.want {
+				if math.Abs(float64(got[line]-want)) > 1e-3 {
+					t.Errorf("Calculate() %v = %v, want %v", line, got[line], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package indicators
+
+import (
+	"sync"
+	"time"
+
+	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
+	statsRepoPkg "github.com/KeynihAV/exchange/pkg/broker/stats/repo"
+)
+
+const (
+	// Interval - индикаторы считаются по минутным свечам
+	Interval = 60
+	// windowSize - сколько последних свечей хранится по инструменту, с запасом на сглаживание EMA и RSI
+	windowSize = 500
+)
+
+// series - минутные свечи инструмента по возрастанию времени
+type series struct {
+	Candles []*statsPkg.OHLCV
+	// LastTime - время последней учтенной секундной свечи, повторно она не добавляется
+	LastTime int32
+}
+
+// Tracker хранит свечи инструментов в памяти и дополняет их по мере поступления цен от биржи
+type Tracker struct {
+	SR     *statsRepoPkg.StatsRepo
+	Series map[string]*series
+	Mux    *sync.Mutex
+}
+
+func NewTracker(sr *statsRepoPkg.StatsRepo) *Tracker {
+	return &Tracker{
+		SR:     sr,
+		Series: make(map[string]*series),
+		Mux:    &sync.Mutex{},
+	}
+}
+
+// Update добавляет секундную свечу биржи; инструмент, по которому индикаторы еще не запрашивались, пропускается:
+// при первом запросе его свечи загрузятся из stats
+func (t *Tracker) Update(stat *statsPkg.OHLCV) {
+	t.Mux.Lock()
+	defer t.Mux.Unlock()
+
+	if s, ok := t.Series[stat.Ticker]; ok {
+		s.add(stat)
+	}
+}
+
+// Calculate - значение индикатора по последним свечам инструмента, period 0 - период по умолчанию
+func (t *Tracker) Calculate(ticker, name string, period int) (*Result, error) {
+	if _, ok := DefaultPeriods[name]; !ok {
+		return nil, ErrUnknownIndicator
+	}
+	if period == 0 {
+		period = DefaultPeriods[name]
+	}
+
+	t.Mux.Lock()
+	defer t.Mux.Unlock()
+
+	s, ok := t.Series[ticker]
+	if !ok {
+		var err error
+		s, err = t.load(ticker)
+		if err != nil {
+			return nil, err
+		}
+		//пустые не запоминаем, иначе любой запрос с несуществующим тикером останется в памяти
+		if len(s.Candles) > 0 {
+			t.Series[ticker] = s
+		}
+	}
+
+	values, err := Calculate(name, period, s.Candles)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Ticker:   ticker,
+		Name:     name,
+		Period:   period,
+		Interval: Interval,
+		Time:     s.Candles[len(s.Candles)-1].TimeInt,
+		Values:   values,
+	}, nil
+}
+
+// load собирает свечи из свернутых минут и секунд, которые еще не свернуты
+func (t *Tracker) load(ticker string) (*series, error) {
+	now := int32(time.Now().Unix())
+	since, err := t.SR.LastRollup(Interval)
+	if err != nil {
+		return nil, err
+	}
+	if since < now-windowSize*Interval {
+		since = now - now%Interval - windowSize*Interval
+	}
+
+	s := &series{}
+	s.Candles, err = t.SR.GeStatsByTicker(ticker, Interval, since-windowSize*Interval, since)
+	if err != nil {
+		return nil, err
+	}
+	seconds, err := t.SR.GeStatsByTicker(ticker, statsPkg.SecondInterval, since, now+1)
+	if err != nil {
+		return nil, err
+	}
+	for _, stat := range seconds {
+		s.add(stat)
+	}
+	return s, nil
+}
+
+func (s *series) add(stat *statsPkg.OHLCV) {
+	if stat.TimeInt <= s.LastTime {
+		return
+	}
+	s.LastTime = stat.TimeInt
+
+	start := stat.TimeInt - stat.TimeInt%Interval
+	if len(s.Candles) > 0 {
+		last := s.Candles[len(s.Candles)-1]
+		if last.TimeInt == start {
+			if stat.High > last.High {
+				last.High = stat.High
+			}
+			if stat.Low < last.Low {
+				last.Low = stat.Low
+			}
+			last.Close = stat.Close
+			last.Volume += stat.Volume
+			return
+		}
+	}
+
+	s.Candles = append(s.Candles, &statsPkg.OHLCV{
+		Time:     time.Unix(int64(start), 0),
+		TimeInt:  start,
+		Interval: Interval,
+		Open:     stat.Open,
+		High:     stat.High,
+		Low:      stat.Low,
+		Close:    stat.Close,
+		Volume:   stat.Volume,
+		Ticker:   stat.Ticker,
+	})
+	if len(s.Candles) > windowSize {
+		s.Candles = s.Candles[len(s.Candles)-windowSize:]
+	}
+}
@@ -1,8 +1,11 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
@@ -25,7 +28,43 @@ func NewStatsRepo(db *sql.DB) (*StatsRepo, error) {
 			close float8 NOT NULL,
 			volume int NOT NULL,
 			ticker varchar(150));
-		CREATE INDEX IF NOT EXISTS ticker_idx ON stats (ticker);`)
+		CREATE INDEX IF NOT EXISTS ticker_idx ON stats (ticker);
+		CREATE INDEX IF NOT EXISTS stats_ticker_time_idx ON stats (ticker, time);
+		CREATE INDEX IF NOT EXISTS stats_time_idx ON stats (time);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS candles(
+			ticker varchar(150) NOT NULL,
+			interval int NOT NULL,
+			time int NOT NULL,
+			open float8 NOT NULL,
+			high float8 NOT NULL,
+			low float8 NOT NULL,
+			close float8 NOT NULL,
+			volume int NOT NULL,
+			PRIMARY KEY (ticker, interval, time));
+		CREATE SEQUENCE IF NOT EXISTS candles_seq;
+		ALTER TABLE candles ADD COLUMN IF NOT EXISTS seq bigint NOT NULL DEFAULT nextval('candles_seq');
+		CREATE INDEX IF NOT EXISTS candles_interval_seq_idx ON candles (interval, seq);
+		CREATE TABLE IF NOT EXISTS rollupWatermarks(
+			interval int PRIMARY KEY,
+			lastSeq bigint NOT NULL);`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS tradePrints(
+			id BIGSERIAL PRIMARY KEY,
+			ticker varchar(150) NOT NULL,
+			price float8 NOT NULL,
+			volume int NOT NULL,
+			type varchar(10) NOT NULL,
+			time int NOT NULL);
+		CREATE INDEX IF NOT EXISTS tradePrints_ticker_id_idx ON tradePrints (ticker, id);`)
 	if err != nil {
 		return nil, err
 	}
@@ -54,15 +93,18 @@ func (sr *StatsRepo) Add(ohlcv *statsPkg.OHLCV) error {
 	return nil
 }
 
-func (sr *StatsRepo) GeStatsByTicker(ticker string) ([]*statsPkg.OHLCV, error) {
-	result, err := sr.DB.Query(`
-		SELECT 
-			date_trunc('minute', to_timestamp(time)) as time, 
-			MIN(open), MAX(high), MIN(low), MIN(close), SUM(volume), ticker
-		FROM stats 
-		WHERE ticker = $1 AND time > $2
-		GROUP BY date_trunc('minute', to_timestamp(time)), ticker
-		ORDER BY time;`, ticker, time.Now().Add(time.Minute*-5).Unix())
+// GeStatsByTicker - свечи инструмента с началом в [from, to): секундные из stats, остальные из свернутых candles
+func (sr *StatsRepo) GeStatsByTicker(ticker string, interval, from, to int32) ([]*statsPkg.OHLCV, error) {
+	query := `SELECT time, interval, open, high, low, close, volume, ticker
+		FROM candles WHERE ticker = $1 AND interval = $2 AND time >= $3 AND time < $4
+		ORDER BY time`
+	if interval == statsPkg.SecondInterval {
+		query = `SELECT time, interval, open, high, low, close, volume, ticker
+			FROM stats WHERE ticker = $1 AND interval = $2 AND time >= $3 AND time < $4
+			ORDER BY time, id`
+	}
+
+	result, err := sr.DB.Query(query, ticker, interval, from, to)
 	if err != nil {
 		return nil, err
 	}
@@ -71,12 +113,134 @@ func (sr *StatsRepo) GeStatsByTicker(ticker string) ([]*statsPkg.OHLCV, error) {
 	stats := make([]*statsPkg.OHLCV, 0)
 	for result.Next() {
 		ohlcv := &statsPkg.OHLCV{}
-		err = result.Scan(&ohlcv.Time, &ohlcv.Open, &ohlcv.High, &ohlcv.Low, &ohlcv.Close, &ohlcv.Volume, &ohlcv.Ticker)
+		err = result.Scan(&ohlcv.TimeInt, &ohlcv.Interval, &ohlcv.Open, &ohlcv.High, &ohlcv.Low, &ohlcv.Close, &ohlcv.Volume, &ohlcv.Ticker)
 		if err != nil {
 			return nil, err
 		}
+		ohlcv.Time = time.Unix(int64(ohlcv.TimeInt), 0)
 		stats = append(stats, ohlcv)
 	}
 
 	return stats, nil
 }
+
+// LastRollup - начало последней свернутой свечи интервала, более поздние есть только в секундах
+func (sr *StatsRepo) LastRollup(interval int32) (int32, error) {
+	qr := sr.DB.QueryRow(`SELECT COALESCE(MAX(time), 0) FROM candles WHERE interval = $1`, interval)
+
+	var last int32
+	err := qr.Scan(&last)
+	if err != nil {
+		return 0, err
+	}
+	return last, nil
+}
+
+// rollupQuery сворачивает свечи источника по корзинам интервала: open первой свечи, close последней
+const rollupQuery = `INSERT INTO candles(ticker, interval, time, open, high, low, close, volume)
+	SELECT ticker, $1, time - time %% $1,
+		(array_agg(open ORDER BY time, id))[1],
+		MAX(high), MIN(low),
+		(array_agg(close ORDER BY time DESC, id DESC))[1],
+		SUM(volume)
+	FROM (%v) AS source
+	GROUP BY ticker, time - time %% $1
+	ON CONFLICT (ticker, interval, time) DO UPDATE
+		SET open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low,
+			close = EXCLUDED.close, volume = EXCLUDED.volume, seq = nextval('candles_seq')`
+
+// RollupCandles пересчитывает свечи rollup.Interval из свечей rollup.Source, изменившихся с прошлой свертки,
+// начиная с корзины самой ранней из них: опоздавшие секунды попадают и в уже свернутые корзины.
+// Изменения отслеживаются по id секунд и seq свернутых свечей, отметка сдвигается в той же транзакции
+func (sr *StatsRepo) RollupCandles(rollup statsPkg.Rollup) error {
+	tx, err := sr.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	var watermark int64
+	err = tx.QueryRow(`SELECT COALESCE((SELECT lastSeq FROM rollupWatermarks WHERE interval = $1), 0)`,
+		rollup.Interval).Scan(&watermark)
+	if err != nil {
+		return err
+	}
+
+	changes := tx.QueryRow(`SELECT MIN(time), MAX(id) FROM stats WHERE id > $1`, watermark)
+	if rollup.Source != statsPkg.SecondInterval {
+		changes = tx.QueryRow(`SELECT MIN(time), MAX(seq) FROM candles WHERE interval = $2 AND seq > $1`, watermark, rollup.Source)
+	}
+	var firstChanged sql.NullInt32
+	var lastSeq sql.NullInt64
+	err = changes.Scan(&firstChanged, &lastSeq)
+	if err != nil || !lastSeq.Valid {
+		return err
+	}
+	since := firstChanged.Int32 - firstChanged.Int32%rollup.Interval
+
+	source := `SELECT ticker, time, id, open, high, low, close, volume FROM stats WHERE time >= $2`
+	args := []interface{}{rollup.Interval, since}
+	if rollup.Source != statsPkg.SecondInterval {
+		source = `SELECT ticker, time, 0 AS id, open, high, low, close, volume FROM candles WHERE interval = $3 AND time >= $2`
+		args = append(args, rollup.Source)
+	}
+	_, err = tx.Exec(fmt.Sprintf(rollupQuery, source), args...)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO rollupWatermarks(interval, lastSeq) values($1, $2)
+		ON CONFLICT (interval) DO UPDATE SET lastSeq = EXCLUDED.lastSeq`, rollup.Interval, lastSeq.Int64)
+	return err
+}
+
+func (sr *StatsRepo) AddPrint(trade *statsPkg.TradePrint) error {
+	_, err := sr.DB.Exec(`INSERT INTO tradePrints(ticker, price, volume, type, time) VALUES($1, $2, $3, $4, $5)`,
+		trade.Ticker, trade.Price, trade.Volume, trade.Type, trade.Time)
+	return err
+}
+
+func (sr *StatsRepo) PrintsByTicker(filter *statsPkg.PrintsFilter) ([]*statsPkg.TradePrint, error) {
+	conditions := []string{"ticker = $1", "id > $2"}
+	values := []interface{}{filter.Ticker, filter.Cursor}
+	addCondition := func(condition string, value interface{}) {
+		values = append(values, value)
+		conditions = append(conditions, condition+" $"+strconv.Itoa(len(values)))
+	}
+	if filter.From > 0 {
+		addCondition("time >=", filter.From)
+	}
+	if filter.To > 0 {
+		addCondition("time <", filter.To)
+	}
+
+	queryString := fmt.Sprintf(`SELECT id, ticker, price, volume, type, time
+		FROM tradePrints WHERE %v ORDER BY id`, strings.Join(conditions, " AND "))
+	if filter.Limit > 0 {
+		queryString += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	result, err := sr.DB.Query(queryString, values...)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	prints := make([]*statsPkg.TradePrint, 0)
+	for result.Next() {
+		trade := &statsPkg.TradePrint{}
+		err = result.Scan(&trade.ID, &trade.Ticker, &trade.Price, &trade.Volume, &trade.Type, &trade.Time)
+		if err != nil {
+			return nil, err
+		}
+		prints = append(prints, trade)
+	}
+
+	return prints, nil
+}
@@ -79,9 +79,11 @@ func TestStatsRepo_GeStatsByTicker(t *testing.T) {
 	defer db.Close()
 
 	type args struct {
-		ticker string
+		ticker   string
+		interval int32
 	}
-	currTime := time.Now()
+	currTime := time.Unix(time.Now().Unix(), 0)
+	columns := []string{"time", "interval", "open", "high", "low", "close", "volume", "ticker"}
 	tests := []struct {
 		name    string
 		sr      *StatsRepo
@@ -92,44 +94,296 @@ func TestStatsRepo_GeStatsByTicker(t *testing.T) {
 	}{
 		{name: "Ошибка select",
 			sr:      &StatsRepo{DB: db},
-			args:    args{ticker: "ticker1"},
+			args:    args{ticker: "ticker1", interval: 60},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectQuery("SELECT").WithArgs("ticker1", sqlmock.AnyArg()).WillReturnError(fmt.Errorf("select error"))
+				s.ExpectQuery("SELECT").WithArgs("ticker1", 60, 0, 3600).WillReturnError(fmt.Errorf("select error"))
 			},
 		},
 		{name: "Ошибка scan",
 			sr:      &StatsRepo{DB: db},
-			args:    args{ticker: "ticker1"},
+			args:    args{ticker: "ticker1", interval: 60},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"id", "brokerID"}).AddRow(0, "one").AddRow(1, "two")
 				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
 			},
 		},
-		{name: "Корректный select",
-			sr:      &StatsRepo{DB: db},
-			args:    args{ticker: "ticker1"},
-			want:    []*statsPkg.OHLCV{{Time: currTime, Open: 10, High: 20, Low: 10, Close: 15, Volume: 100, Ticker: "ticker1"}},
+		{name: "Секундные свечи из stats",
+			sr:   &StatsRepo{DB: db},
+			args: args{ticker: "ticker1", interval: 1},
+			want: []*statsPkg.OHLCV{{Time: currTime, TimeInt: int32(currTime.Unix()), Interval: 1, Open: 10, High: 20, Low: 10,
+				Close: 15, Volume: 100, Ticker: "ticker1"}},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).AddRow(currTime.Unix(), 1, 10, 20, 10, 15, 100, "ticker1")
+				s.ExpectQuery(`FROM stats`).WithArgs("ticker1", 1, 0, 3600).WillReturnRows(rows)
+			},
+		},
+		{name: "Свернутые свечи из candles",
+			sr:   &StatsRepo{DB: db},
+			args: args{ticker: "ticker1", interval: 300},
+			want: []*statsPkg.OHLCV{{Time: currTime, TimeInt: int32(currTime.Unix()), Interval: 300, Open: 10, High: 25, Low: 8,
+				Close: 12, Volume: 1000, Ticker: "ticker1"}},
 			wantErr: false,
 			mockF: func(s sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"time", "open", "high", "low", "close", "volume", "ticker"}).
-					AddRow(currTime, 10, 20, 10, 15, 100, "ticker1")
-				s.ExpectQuery(`SELECT`).WithArgs("ticker1", sqlmock.AnyArg()).WillReturnRows(rows)
+				rows := sqlmock.NewRows(columns).AddRow(currTime.Unix(), 300, 10, 25, 8, 12, 1000, "ticker1")
+				s.ExpectQuery(`FROM candles`).WithArgs("ticker1", 300, 0, 3600).WillReturnRows(rows)
 			},
 		},
 	}
 	for _, tt := range tests {
 		tt.mockF(mock)
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.sr.GeStatsByTicker(tt.args.ticker)
+			got, err := tt.sr.GeStatsByTicker(tt.args.ticker, tt.args.interval, 0, 3600)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("StatsRepo.GeStatsByTicker() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if !reflect.DeepEqual(got, tt.want) {
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("StatsRepo.GeStatsByTicker() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestStatsRepo_LastRollup(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		sr      *StatsRepo
+		want    int32
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			sr:      &StatsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT COALESCE\(MAX\(time\), 0\) FROM candles`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Корректный select",
+			sr:      &StatsRepo{DB: db},
+			want:    3600,
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT COALESCE\(MAX\(time\), 0\) FROM candles`).WithArgs(300).
+					WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(3600))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.sr.LastRollup(300)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("StatsRepo.LastRollup() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("StatsRepo.LastRollup() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsRepo_RollupCandles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		sr      *StatsRepo
+		rollup  statsPkg.Rollup
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			sr:      &StatsRepo{DB: db},
+			rollup:  statsPkg.Rollup{Interval: 60, Source: 1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectQuery(`SELECT COALESCE\(\(SELECT lastSeq FROM rollupWatermarks`).WithArgs(60).
+					WillReturnRows(sqlmock.NewRows([]string{"lastSeq"}).AddRow(100))
+				s.ExpectQuery(`SELECT MIN\(time\), MAX\(id\) FROM stats WHERE id > \$1`).WithArgs(100).
+					WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(3650, 120))
+				s.ExpectExec(`INSERT INTO candles`).WillReturnError(fmt.Errorf("insert error"))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Новых секунд нет - свертка не нужна",
+			sr:      &StatsRepo{DB: db},
+			rollup:  statsPkg.Rollup{Interval: 60, Source: 1},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectQuery(`SELECT COALESCE\(\(SELECT lastSeq FROM rollupWatermarks`).WithArgs(60).
+					WillReturnRows(sqlmock.NewRows([]string{"lastSeq"}).AddRow(120))
+				s.ExpectQuery(`SELECT MIN\(time\), MAX\(id\) FROM stats`).WithArgs(120).
+					WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(nil, nil))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Опоздавшая секунда пересчитывает свою уже свернутую корзину",
+			sr:      &StatsRepo{DB: db},
+			rollup:  statsPkg.Rollup{Interval: 60, Source: 1},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectQuery(`SELECT COALESCE\(\(SELECT lastSeq FROM rollupWatermarks`).WithArgs(60).
+					WillReturnRows(sqlmock.NewRows([]string{"lastSeq"}).AddRow(120))
+				s.ExpectQuery(`SELECT MIN\(time\), MAX\(id\) FROM stats`).WithArgs(120).
+					WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(3605, 125))
+				s.ExpectExec(`INSERT INTO candles.* FROM \(SELECT ticker, time, id, .* FROM stats WHERE time >= \$2\)`).
+					WithArgs(60, 3600).WillReturnResult(sqlmock.NewResult(0, 2))
+				s.ExpectExec(`INSERT INTO rollupWatermarks`).WithArgs(60, 125).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Свертка свернутых свечей по их seq",
+			sr:      &StatsRepo{DB: db},
+			rollup:  statsPkg.Rollup{Interval: 300, Source: 60},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectQuery(`SELECT COALESCE\(\(SELECT lastSeq FROM rollupWatermarks`).WithArgs(300).
+					WillReturnRows(sqlmock.NewRows([]string{"lastSeq"}).AddRow(0))
+				s.ExpectQuery(`SELECT MIN\(time\), MAX\(seq\) FROM candles WHERE interval = \$2 AND seq > \$1`).WithArgs(0, 60).
+					WillReturnRows(sqlmock.NewRows([]string{"min", "max"}).AddRow(3660, 7))
+				s.ExpectExec(`INSERT INTO candles.* FROM candles WHERE interval = \$3 AND time >= \$2\)`).
+					WithArgs(300, 3600, 60).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectExec(`INSERT INTO rollupWatermarks`).WithArgs(300, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+				s.ExpectCommit()
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sr.RollupCandles(tt.rollup); (err != nil) != tt.wantErr {
+				t.Errorf("StatsRepo.RollupCandles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatsRepo_AddPrint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	trade := &statsPkg.TradePrint{Ticker: "ticker1", Price: 10, Volume: 5, Type: "buy", Time: 100}
+	tests := []struct {
+		name    string
+		sr      *StatsRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			sr:      &StatsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO tradePrints`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Корректный insert",
+			sr:      &StatsRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO tradePrints`).WithArgs("ticker1", float32(10), 5, "buy", 100).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sr.AddPrint(trade); (err != nil) != tt.wantErr {
+				t.Errorf("StatsRepo.AddPrint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatsRepo_PrintsByTicker(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "ticker", "price", "volume", "type", "time"}
+	tests := []struct {
+		name    string
+		sr      *StatsRepo
+		filter  *statsPkg.PrintsFilter
+		want    []*statsPkg.TradePrint
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			sr:      &StatsRepo{DB: db},
+			filter:  &statsPkg.PrintsFilter{Ticker: "ticker1"},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Ошибка scan",
+			sr:      &StatsRepo{DB: db},
+			filter:  &statsPkg.PrintsFilter{Ticker: "ticker1"},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "ticker"}).AddRow(1, "ticker1")
+				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
+			},
+		},
+		{name: "Без фильтров по времени",
+			sr:     &StatsRepo{DB: db},
+			filter: &statsPkg.PrintsFilter{Ticker: "ticker1", Cursor: 5},
+			want: []*statsPkg.TradePrint{{ID: 6, Ticker: "ticker1", Price: 10, Volume: 5, Type: "buy", Time: 100},
+				{ID: 7, Ticker: "ticker1", Price: 9, Volume: 1, Type: "", Time: 101}},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(columns).AddRow(6, "ticker1", 10, 5, "buy", 100).AddRow(7, "ticker1", 9, 1, "", 101)
+				s.ExpectQuery(`FROM tradePrints WHERE ticker = \$1 AND id > \$2 ORDER BY id$`).
+					WithArgs("ticker1", 5).WillReturnRows(rows)
+			},
+		},
+		{name: "С периодом и лимитом",
+			sr:      &StatsRepo{DB: db},
+			filter:  &statsPkg.PrintsFilter{Ticker: "ticker1", From: 100, To: 200, Limit: 11},
+			want:    []*statsPkg.TradePrint{},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`WHERE ticker = \$1 AND id > \$2 AND time >= \$3 AND time < \$4 ORDER BY id LIMIT 11`).
+					WithArgs("ticker1", 0, 100, 200).WillReturnRows(sqlmock.NewRows(columns))
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt.mockF(mock)
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.sr.PrintsByTicker(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("StatsRepo.PrintsByTicker() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("StatsRepo.PrintsByTicker() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
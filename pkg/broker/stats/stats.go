@@ -1,6 +1,40 @@
 package stats
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// SecondInterval - интервал свечей, которые присылает биржа и которые пишутся в stats
+const SecondInterval = 1
+
+// Timeframes - длительность свечи в секундах по обозначению в запросе
+var Timeframes = map[string]int32{
+	"1s":  SecondInterval,
+	"1m":  60,
+	"5m":  300,
+	"15m": 900,
+	"1h":  3600,
+	"1d":  86400,
+}
+
+// Rollup - свертка свечей интервала Source в свечи интервала Interval
+type Rollup struct {
+	Interval int32
+	Source   int32
+}
+
+// Rollups выполняются по порядку, каждая следующая сворачивает результат предыдущей
+var Rollups = []Rollup{
+	{Interval: 60, Source: SecondInterval},
+	{Interval: 300, Source: 60},
+	{Interval: 900, Source: 300},
+	{Interval: 3600, Source: 900},
+	{Interval: 86400, Source: 3600},
+}
+
+// ErrUnknownTimeframe - интервал не из Timeframes
+var ErrUnknownTimeframe = errors.New("unknown timeframe")
 
 type OHLCV struct {
 	Time     time.Time
@@ -13,3 +47,29 @@ type OHLCV struct {
 	Volume   int32
 	Ticker   string
 }
+
+// TradePrint - обезличенная сделка из ленты биржи, Type - сторона инициатора, пустая - неизвестна
+type TradePrint struct {
+	ID     int64
+	Ticker string
+	Price  float32
+	Volume int32
+	Type   string
+	Time   int32
+}
+
+// PrintsFilter - выборка ленты инструмента за [From, To), 0 в границе - без ограничения,
+// Cursor - ID последней полученной сделки, Limit 0 - все сделки
+type PrintsFilter struct {
+	Ticker string
+	From   int32
+	To     int32
+	Cursor int64
+	Limit  int
+}
+
+// PrintsPage - страница ленты, NextCursor 0 - сделок больше нет
+type PrintsPage struct {
+	Prints     []*TradePrint
+	NextCursor int64
+}
@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
+	indicatorsPkg "github.com/KeynihAV/exchange/pkg/broker/stats/indicators"
+	statsRepoPkg "github.com/KeynihAV/exchange/pkg/broker/stats/repo"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultCandles - сколько последних свечей отдается, если начало периода не задано
+	defaultCandles = 5
+	maxCandles     = 1000
+	rollupPeriod   = 5 * time.Second
+
+	defaultPrintsPage = 100
+	maxPrintsPage     = 1000
+)
+
+type StatsManager struct {
+	SR         *statsRepoPkg.StatsRepo
+	Indicators *indicatorsPkg.Tracker
+}
+
+func NewStatsManager(db *sql.DB) (*StatsManager, error) {
+	sr, err := statsRepoPkg.NewStatsRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsManager{SR: sr, Indicators: indicatorsPkg.NewTracker(sr)}, nil
+}
+
+// AddStat сохраняет секундную свечу биржи и дополняет ей свечи индикаторов
+func (sm *StatsManager) AddStat(stat *statsPkg.OHLCV) error {
+	err := sm.SR.Add(stat)
+	if err != nil {
+		return err
+	}
+	sm.Indicators.Update(stat)
+	return nil
+}
+
+// Candles - свечи инструмента по обозначению интервала за [from, to), 0 в границах - последние свечи
+func (sm *StatsManager) Candles(ticker string, timeframe string, from, to int32) ([]*statsPkg.OHLCV, error) {
+	interval, ok := statsPkg.Timeframes[timeframe]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", statsPkg.ErrUnknownTimeframe, timeframe)
+	}
+
+	if to == 0 {
+		to = int32(time.Now().Unix()) + 1
+	}
+	if from == 0 {
+		from = to - interval*defaultCandles
+	}
+	if (to-from)/interval > maxCandles {
+		from = to - interval*maxCandles
+	}
+	//свеча, в которую попадает from, тоже нужна
+	from -= from % interval
+
+	return sm.SR.GeStatsByTicker(ticker, interval, from, to)
+}
+
+// PrintsByTicker - страница ленты инструмента, следующая страница запрашивается с Cursor = NextCursor
+func (sm *StatsManager) PrintsByTicker(filter *statsPkg.PrintsFilter) (*statsPkg.PrintsPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPrintsPage
+	}
+	if filter.Limit > maxPrintsPage {
+		filter.Limit = maxPrintsPage
+	}
+	limit := filter.Limit
+
+	//лишняя сделка показывает, есть ли следующая страница
+	filter.Limit++
+	prints, err := sm.SR.PrintsByTicker(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &statsPkg.PrintsPage{Prints: prints}
+	if len(prints) > limit {
+		page.Prints = prints[:limit]
+		page.NextCursor = prints[limit-1].ID
+	}
+	return page, nil
+}
+
+// RollupCandles периодически сворачивает секундные свечи в более длинные интервалы
+func (sm *StatsManager) RollupCandles(logger *logging.Logger) {
+	tiker := time.NewTicker(rollupPeriod)
+	for range tiker.C {
+		sm.rollupCandles(logger)
+	}
+}
+
+func (sm *StatsManager) rollupCandles(logger *logging.Logger) {
+	for _, rollup := range statsPkg.Rollups {
+		err := sm.SR.RollupCandles(rollup)
+		if err != nil {
+			//следующие свертки строятся из этой, без нее они не изменятся
+			logger.Zap.Error("rollup candles",
+				zap.String("logger", "stats"),
+				zap.Int32("interval", rollup.Interval),
+				zap.String("err", err.Error()),
+			)
+			return
+		}
+	}
+}
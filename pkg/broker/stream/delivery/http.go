@@ -0,0 +1,37 @@
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	streamPkg "github.com/KeynihAV/exchange/pkg/broker/stream"
+	"github.com/KeynihAV/exchange/pkg/common"
+)
+
+type HealthHandler struct {
+	Supervisor *streamPkg.Supervisor
+}
+
+// Health - 200 и состояния потоков, если все потоки от биржи подключены, иначе 503
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	states := h.Supervisor.States()
+
+	disconnected := make([]string, 0)
+	for name, state := range states {
+		if !state.Connected {
+			disconnected = append(disconnected, name)
+		}
+	}
+	if len(states) == 0 {
+		disconnected = append(disconnected, "not started")
+	}
+	if len(disconnected) > 0 {
+		sort.Strings(disconnected)
+		err := fmt.Errorf("streams disconnected: %v", strings.Join(disconnected, ", "))
+		common.RespJSONError(w, http.StatusServiceUnavailable, nil, err.Error(), r.Context())
+		return
+	}
+	common.WriteStructToResponse(states, r.Context(), w)
+}
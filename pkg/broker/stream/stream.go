@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	metricsPkg "github.com/KeynihAV/exchange/pkg/broker/metrics"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// State - состояние потока от биржи, Since - unix time последнего подключения или обрыва
+type State struct {
+	Connected  bool
+	Since      int32
+	Reconnects int
+	LastError  string
+}
+
+// Supervisor держит потоки от биржи: при обрыве переподключает их с экспоненциальной паузой и джиттером
+type Supervisor struct {
+	Streams map[string]*State
+	Mux     *sync.RWMutex
+	Logger  *logging.Logger
+	//пауза перед переподключением, в тестах подменяется
+	Sleep func(time.Duration)
+}
+
+func NewSupervisor(logger *logging.Logger) *Supervisor {
+	return &Supervisor{
+		Streams: make(map[string]*State),
+		Mux:     &sync.RWMutex{},
+		Logger:  logger,
+		Sleep:   time.Sleep,
+	}
+}
+
+// Run бесконечно вызывает session, session вызывает connected после подписки и возвращает ошибку при обрыве
+func (s *Supervisor) Run(name string, session func(connected func()) error) {
+	s.setDisconnected(name, "")
+	backoff := minBackoff
+	reconnect := false
+	for {
+		err := session(func() {
+			s.setConnected(name, reconnect)
+			reconnect = true
+			backoff = minBackoff
+		})
+		errTxt := "stream closed"
+		if err != nil {
+			errTxt = err.Error()
+		}
+		s.setDisconnected(name, errTxt)
+
+		pause := jitter(backoff)
+		s.Logger.Zap.Warn("stream disconnected",
+			zap.String("logger", "streamSupervisor"),
+			zap.String("stream", name),
+			zap.Duration("reconnectIn", pause),
+			zap.String("err", errTxt),
+		)
+		s.Sleep(pause)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// States - копия состояний всех потоков
+func (s *Supervisor) States() map[string]State {
+	s.Mux.RLock()
+	defer s.Mux.RUnlock()
+
+	states := make(map[string]State, len(s.Streams))
+	for name, state := range s.Streams {
+		states[name] = *state
+	}
+	return states
+}
+
+func (s *Supervisor) setConnected(name string, reconnect bool) {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	state := s.state(name)
+	state.Connected = true
+	state.Since = int32(time.Now().Unix())
+	if reconnect {
+		state.Reconnects++
+		metricsPkg.StreamReconnected(name)
+	}
+	metricsPkg.SetStreamConnected(name, true)
+}
+
+func (s *Supervisor) setDisconnected(name string, lastError string) {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	state := s.state(name)
+	state.Connected = false
+	state.Since = int32(time.Now().Unix())
+	if lastError != "" {
+		state.LastError = lastError
+	}
+	metricsPkg.SetStreamConnected(name, false)
+}
+
+// state - состояние потока по имени, вызывать под Mux
+func (s *Supervisor) state(name string) *State {
+	state, ok := s.Streams[name]
+	if !ok {
+		state = &State{}
+		s.Streams[name] = state
+	}
+	return state
+}
+
+// jitter - случайная пауза от половины до полного backoff, чтобы брокеры не переподключались одновременно
+func jitter(backoff time.Duration) time.Duration {
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
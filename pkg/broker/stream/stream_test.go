@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+func TestJitter(t *testing.T) {
+	for _, backoff := range []time.Duration{minBackoff, 4 * time.Second, maxBackoff} {
+		for i := 0; i < 100; i++ {
+			pause := jitter(backoff)
+			if pause < backoff/2 || pause > backoff {
+				t.Fatalf("jitter(%v) = %v, want from %v to %v", backoff, pause, backoff/2, backoff)
+			}
+		}
+	}
+}
+
+func TestSupervisor_Run(t *testing.T) {
+	tests := []struct {
+		name string
+		//подключилась ли сессия перед обрывом, по одной на каждую сессию
+		connects       []bool
+		wantBackoffs   []time.Duration
+		wantReconnects int
+		wantLastError  string
+	}{
+		{name: "Без подключения пауза растет вдвое до 30 секунд",
+			connects: []bool{false, false, false, false, false, false, false},
+			wantBackoffs: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second,
+				30 * time.Second, 30 * time.Second},
+			wantLastError: "session 7",
+		},
+		{name: "Успешное подключение сбрасывает паузу, переподключения считаются",
+			connects:       []bool{true, false, false, true, true},
+			wantBackoffs:   []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, time.Second, time.Second},
+			wantReconnects: 2,
+			wantLastError:  "session 5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pauses []time.Duration
+			supervisor := NewSupervisor(&logging.Logger{Zap: zap.NewNop()})
+			supervisor.Sleep = func(pause time.Duration) {
+				pauses = append(pauses, pause)
+			}
+
+			//после последней сессии Run останавливается на done, сессии идут последовательно
+			done := make(chan struct{})
+			sessions := 0
+			go supervisor.Run("results", func(connected func()) error {
+				if sessions == len(tt.connects) {
+					close(done)
+					select {}
+				}
+				if tt.connects[sessions] {
+					connected()
+				}
+				sessions++
+				return fmt.Errorf("session %v", sessions)
+			})
+			<-done
+
+			if len(pauses) != len(tt.wantBackoffs) {
+				t.Fatalf("pauses %v, want %v", pauses, tt.wantBackoffs)
+			}
+			for i, pause := range pauses {
+				if pause < tt.wantBackoffs[i]/2 || pause > tt.wantBackoffs[i] {
+					t.Errorf("pause %v = %v, want from %v to %v", i, pause, tt.wantBackoffs[i]/2, tt.wantBackoffs[i])
+				}
+			}
+			state := supervisor.States()["results"]
+			if state.Connected || state.Reconnects != tt.wantReconnects || state.LastError != tt.wantLastError {
+				t.Errorf("state %+v, want disconnected with %v reconnects and %q", state, tt.wantReconnects, tt.wantLastError)
+			}
+		})
+	}
+}
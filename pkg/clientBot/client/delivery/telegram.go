@@ -1,22 +1,68 @@
 package delivery
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
+	brokerDealPkg "github.com/KeynihAV/exchange/pkg/broker/deal"
+	marketPkg "github.com/KeynihAV/exchange/pkg/broker/market"
+	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
+	indicatorsPkg "github.com/KeynihAV/exchange/pkg/broker/stats/indicators"
 	clientRepoPkg "github.com/KeynihAV/exchange/pkg/clientBot/client/repo"
 	dealRepoPkg "github.com/KeynihAV/exchange/pkg/clientBot/deal/repo"
+	chartPkg "github.com/KeynihAV/exchange/pkg/clientBot/stats/chart"
 	statsRepoPkg "github.com/KeynihAV/exchange/pkg/clientBot/stats/repo"
+	"github.com/KeynihAV/exchange/pkg/common"
 	configPkg "github.com/KeynihAV/exchange/pkg/config"
 	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
 	"github.com/KeynihAV/exchange/pkg/logging"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 	"go.uber.org/zap"
 )
 
+// editOrderPrefix - префикс callback кнопки изменения заявки, без него callback означает отмену
+const editOrderPrefix = "edit:"
+
+// chartPrefix - префикс callback кнопок графика, дальше через ":" тикер, интервал и число свечей
+const chartPrefix = "chart:"
+
+const (
+	defaultChartTimeframe = "1m"
+	defaultChartCandles   = 60
+)
+
+var (
+	chartTimeframes = []string{"1m", "5m", "15m", "1h", "1d"}
+	chartCandles    = []int32{30, 60, 120}
+)
+
+// rejectReasons - понятные пользователю причины отказа брокера в заявке по коду ошибки
+var rejectReasons = map[string]string{
+	clientPkg.CodeInsufficientFunds: "недостаточно свободных денег на счете",
+	clientPkg.CodeShortSelling:      "продажа бумаг, которых нет на счете, вам не разрешена",
+	clientPkg.CodePositionLimit:     "превышен лимит позиции по инструменту",
+	clientPkg.CodeExposureLimit:     "превышен лимит общей позиции по всем инструментам",
+	marketPkg.CodeMarketClosed:      "биржа закрыта",
+	marketPkg.CodeLimitOnly:         "до начала непрерывных торгов принимаются только лимитные и стоп-заявки",
+	instrumentPkg.CodeNotTrading:    "торги инструментом приостановлены",
+}
+
+// phaseNames - фазы торговой сессии для пользователя
+var phaseNames = map[string]string{
+	dealPkg.PhaseClosed:         "биржа закрыта",
+	dealPkg.PhasePreOpen:        "предторговый период, заявки принимаются без исполнения",
+	dealPkg.PhaseOpeningAuction: "аукцион открытия, заявки нельзя снять или изменить",
+	dealPkg.PhaseContinuous:     "идут торги",
+	dealPkg.PhaseClosingAuction: "аукцион закрытия, заявки нельзя снять или изменить",
+}
+
 type brokerTgBot struct {
 	clientsRepo   *clientRepoPkg.ClientsRepo
 	dealsRepo     *dealRepoPkg.DealsRepo
@@ -52,7 +98,7 @@ func StartTgBot(
 
 	chUpdates := bot.ListenForWebhook("/")
 	for update := range chUpdates {
-		var messages []tgbotapi.MessageConfig
+		var messages []tgbotapi.Chattable
 		var chatID int64
 		var inputMsg, processing string
 
@@ -97,9 +143,9 @@ func StartTgBot(
 	return nil
 }
 
-func (tgBot *brokerTgBot) processingCommand(chatID int64, userName string, inputMsg string, config *configPkg.Config) ([]tgbotapi.MessageConfig, error) {
+func (tgBot *brokerTgBot) processingCommand(chatID int64, userName string, inputMsg string, config *configPkg.Config) ([]tgbotapi.Chattable, error) {
 	dialog := &clientPkg.Dialog{}
-	var messages []tgbotapi.MessageConfig
+	var messages []tgbotapi.Chattable
 
 	client, err := tgBot.clientsRepo.CheckAuth(userName, chatID)
 	if err != nil {
@@ -113,10 +159,24 @@ func (tgBot *brokerTgBot) processingCommand(chatID int64, userName string, input
 	}
 
 	switch cmdTxt := inputMsg; {
-	case cmdTxt == "stats" || cmdTxt == "buy" || cmdTxt == "sell":
-		msg := tgbotapi.NewMessage(chatID, "Выберите инструмент")
-		msg.ReplyMarkup = tickersKeyboard(config)
+	case (cmdTxt == "buy" || cmdTxt == "sell") && tgBot.marketClosed():
+		msg, err := tgBot.getMarket()
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, tgbotapi.NewMessage(chatID, msg))
+	case cmdTxt == "market":
+		msg, err := tgBot.getMarket()
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, tgbotapi.NewMessage(chatID, msg))
+	case cmdTxt == "stats" || cmdTxt == "indicators" || cmdTxt == "depth" || cmdTxt == "buy" || cmdTxt == "sell":
+		msg, ok := chooseTickerMsg(chatID, tgBot.tradingTickers(config))
 		messages = append(messages, msg)
+		if !ok {
+			return messages, nil
+		}
 		if cmdTxt == "buy" || cmdTxt == "sell" {
 			dialog.CurrentOrder = &dealPkg.Order{
 				Type:     cmdTxt,
@@ -131,9 +191,18 @@ func (tgBot *brokerTgBot) processingCommand(chatID int64, userName string, input
 			return messages, fmt.Errorf("cancel order %v", err.Error())
 		}
 
-		msg := tgbotapi.NewMessage(chatID, "Ваши заявки: (нажмите на заявку для отмены)")
+		msg := tgbotapi.NewMessage(chatID, "Ваши заявки: (нажмите на заявку для отмены или \"Изменить\" для смены цены и объема)")
 		msg.ReplyMarkup = replyMarkup
 		messages = append(messages, msg)
+	case cmdTxt == "history":
+		statement, err := tgBot.dealsRepo.Statement(client.ID)
+		if err != nil {
+			return messages, fmt.Errorf("не удалось получить историю сделок: %v", err)
+		}
+		messages = append(messages, tgbotapi.NewDocumentUpload(chatID, tgbotapi.FileBytes{
+			Name:  fmt.Sprintf("statement_%v.csv", client.ID),
+			Bytes: statement,
+		}))
 	case cmdTxt == "balance":
 		msgs, err := tgBot.getBalance(client)
 		if err != nil {
@@ -150,10 +219,24 @@ func (tgBot *brokerTgBot) processingCommand(chatID int64, userName string, input
 	return messages, nil
 }
 
-func (tgBot *brokerTgBot) processingMessages(chatID int64, inputMsg string, config *configPkg.Config) ([]tgbotapi.MessageConfig, error) {
-	dialog := tgBot.ActiveDialogs[chatID]
-	var messages []tgbotapi.MessageConfig
+func (tgBot *brokerTgBot) processingMessages(chatID int64, inputMsg string, config *configPkg.Config) ([]tgbotapi.Chattable, error) {
+	var messages []tgbotapi.Chattable
+	dialog, ok := tgBot.ActiveDialogs[chatID]
+	if !ok {
+		return append(messages, tgbotapi.NewMessage(chatID, staleDialogMsg)), nil
+	}
 	switch dialog.LastMsg {
+	case "Укажите стоп-цену":
+		stopPrice, err := strconv.ParseFloat(inputMsg, 32)
+		if err != nil {
+			return messages, fmt.Errorf("не правильно введена стоп-цена: %v\n Попробуйте еще", err.Error())
+		}
+		dialog.CurrentOrder.StopPrice = float32(stopPrice)
+		dialog.LastMsg = "Укажите объем"
+		if dialog.CurrentOrder.Kind == dealPkg.KindStopLimit {
+			dialog.LastMsg = "Укажите цену"
+		}
+		messages = append(messages, tgbotapi.NewMessage(chatID, dialog.LastMsg))
 	case "Укажите цену":
 		price, err := strconv.ParseFloat(inputMsg, 32)
 		if err != nil {
@@ -170,30 +253,112 @@ func (tgBot *brokerTgBot) processingMessages(chatID int64, inputMsg string, conf
 		dialog.CurrentOrder.Volume = int32(volume)
 		orderID, err := tgBot.dealsRepo.CreateOrder(dialog.CurrentOrder)
 		if err != nil {
-			return messages, fmt.Errorf("не удалось создать заявку: %vе", err.Error())
+			return messages, fmt.Errorf("не удалось создать заявку: %v", explainReject(err))
 		}
 		messages = append(messages, tgbotapi.NewMessage(chatID, fmt.Sprintf("Создана заявка с номером %v", orderID)))
+	case "Укажите новую цену (0 - без изменений)":
+		price, err := strconv.ParseFloat(inputMsg, 32)
+		if err != nil || price < 0 {
+			return messages, fmt.Errorf("не правильно введена цена: %v\n Попробуйте еще", inputMsg)
+		}
+		dialog.CurrentOrder.Price = float32(price)
+		dialog.LastMsg = "Укажите новый остаток (0 - без изменений)"
+		messages = append(messages, tgbotapi.NewMessage(chatID, dialog.LastMsg))
+	case "Укажите новый остаток (0 - без изменений)":
+		volume, err := strconv.ParseInt(inputMsg, 10, 32)
+		if err != nil || volume < 0 {
+			return messages, fmt.Errorf("не правильно введен объем: %v\n Попробуйте еще", inputMsg)
+		}
+		dialog.CurrentOrder.Volume = int32(volume)
+		dialog.LastMsg = ""
+		if dialog.CurrentOrder.Price == 0 && dialog.CurrentOrder.Volume == 0 {
+			messages = append(messages, tgbotapi.NewMessage(chatID, "Заявка не изменена"))
+			return messages, nil
+		}
+		result, err := tgBot.dealsRepo.ReplaceOrder(dialog.CurrentOrder.ID, dialog.CurrentOrder.Price, dialog.CurrentOrder.Volume)
+		if err != nil {
+			return messages, fmt.Errorf("не удалось изменить заявку: %v", explainReject(err))
+		}
+		if result.Status == brokerDealPkg.ReplacePending {
+			messages = append(messages, tgbotapi.NewMessage(chatID,
+				fmt.Sprintf("Биржа недоступна, изменение заявки %v будет отправлено позже", dialog.CurrentOrder.ID)))
+			return messages, nil
+		}
+		messages = append(messages, tgbotapi.NewMessage(chatID,
+			fmt.Sprintf("Заявка %v изменена: цена %v, объем %v", dialog.CurrentOrder.ID, result.Price, result.Volume)))
 	}
 	return messages, nil
 }
 
-func (tgBot *brokerTgBot) processingCallback(chatID int64, inputMsg string, config *configPkg.Config) ([]tgbotapi.MessageConfig, error) {
-	var messages []tgbotapi.MessageConfig
+// staleDialogMsg - ответ на кнопку или сообщение без диалога: диалоги в памяти и теряются при перезапуске бота
+const staleDialogMsg = "Диалог устарел, выберите команду из меню заново"
+
+func (tgBot *brokerTgBot) processingCallback(chatID int64, inputMsg string, config *configPkg.Config) ([]tgbotapi.Chattable, error) {
+	var messages []tgbotapi.Chattable
 	var err error
-	dialog := tgBot.ActiveDialogs[chatID]
+	//кнопки графика несут все параметры в себе, остальным кнопкам нужен диалог
+	if strings.HasPrefix(inputMsg, chartPrefix) {
+		msg, err := tgBot.statsChart(chatID, inputMsg)
+		if err != nil {
+			return messages, err
+		}
+		return append(messages, msg), nil
+	}
+	dialog, ok := tgBot.ActiveDialogs[chatID]
+	if !ok {
+		return append(messages, tgbotapi.NewMessage(chatID, staleDialogMsg)), nil
+	}
 	switch cmdTxt := dialog.CurrentCommand; {
 	case cmdTxt == "stats":
-		msgs, err := tgBot.getStats(inputMsg)
+		msg, err := tgBot.statsChart(chatID, chartCallback(inputMsg, defaultChartTimeframe, defaultChartCandles))
 		if err != nil {
 			return messages, err
 		}
-		for _, msg := range msgs {
-			messages = append(messages, tgbotapi.NewMessage(chatID, msg))
+		messages = append(messages, msg)
+		dialog.CurrentCommand = ""
+	case cmdTxt == "depth":
+		msg, err := tgBot.getDepth(inputMsg)
+		if err != nil {
+			return messages, err
 		}
+		messages = append(messages, tgbotapi.NewMessage(chatID, msg))
 		dialog.CurrentCommand = ""
-	case cmdTxt == "buy" || cmdTxt == "sell":
+	case cmdTxt == "indicators" && dialog.Ticker == "":
+		dialog.Ticker = inputMsg
+		msg := tgbotapi.NewMessage(chatID, "Выберите индикатор")
+		msg.ReplyMarkup = indicatorsKeyboard()
+		messages = append(messages, msg)
+	case cmdTxt == "indicators":
+		msg, err := tgBot.getIndicator(dialog.Ticker, inputMsg)
+		if err != nil {
+			return messages, err
+		}
+		messages = append(messages, tgbotapi.NewMessage(chatID, msg))
+		dialog.CurrentCommand = ""
+	case (cmdTxt == "buy" || cmdTxt == "sell") && dialog.CurrentOrder.Ticker == "":
 		dialog.CurrentOrder.Ticker = inputMsg
-		dialog.LastMsg = "Укажите цену"
+		dialog.LastMsg = "Выберите тип заявки"
+		msg := tgbotapi.NewMessage(chatID, dialog.LastMsg)
+		msg.ReplyMarkup = kindsKeyboard()
+		messages = append(messages, msg)
+	case cmdTxt == "buy" || cmdTxt == "sell":
+		dialog.CurrentOrder.Kind = inputMsg
+		switch inputMsg {
+		case dealPkg.KindMarket:
+			dialog.LastMsg = "Укажите объем"
+		case dealPkg.KindStop, dealPkg.KindStopLimit:
+			dialog.LastMsg = "Укажите стоп-цену"
+		default:
+			dialog.LastMsg = "Укажите цену"
+		}
+		messages = append(messages, tgbotapi.NewMessage(chatID, dialog.LastMsg))
+	case cmdTxt == "orders" && strings.HasPrefix(inputMsg, editOrderPrefix):
+		orderID, err := strconv.ParseInt(strings.TrimPrefix(inputMsg, editOrderPrefix), 10, 64)
+		if err != nil {
+			return messages, fmt.Errorf("parseInt in edit order %v", err)
+		}
+		dialog.CurrentOrder = &dealPkg.Order{ID: orderID}
+		dialog.LastMsg = "Укажите новую цену (0 - без изменений)"
 		messages = append(messages, tgbotapi.NewMessage(chatID, dialog.LastMsg))
 	case cmdTxt == "orders":
 		msgs, err := tgBot.cancelOrder(inputMsg, config)
@@ -216,20 +381,121 @@ func listenWebhook(addr string, logger *logging.Logger) {
 	}
 }
 
-func (tgBot *brokerTgBot) getStats(ticker string) ([]string, error) {
-	stats, err := tgBot.statsRepo.GeStatsByTicker(ticker)
+// statsChart - график по данным callback кнопки, кнопки под ним переключают интервал и число свечей
+func (tgBot *brokerTgBot) statsChart(chatID int64, callbackData string) (tgbotapi.Chattable, error) {
+	params := strings.Split(strings.TrimPrefix(callbackData, chartPrefix), ":")
+	if len(params) != 3 {
+		return nil, fmt.Errorf("bad chart callback %v", callbackData)
+	}
+	ticker, timeframe := params[0], params[1]
+	interval, ok := statsPkg.Timeframes[timeframe]
+	if !ok {
+		return nil, fmt.Errorf("bad chart timeframe %v", timeframe)
+	}
+	candles, err := strconv.ParseInt(params[2], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("parseInt in chart candles %v", err)
+	}
+
+	from := int32(time.Now().Unix()) - interval*int32(candles)
+	stats, err := tgBot.statsRepo.GeStatsByTicker(ticker, timeframe, from)
 	if err != nil {
 		return nil, fmt.Errorf("get stats %v", err)
 	}
+	if len(stats) == 0 {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Нет сделок по %v за выбранный период", ticker))
+		msg.ReplyMarkup = chartKeyboard(ticker, timeframe, int32(candles))
+		return msg, nil
+	}
+	image, err := chartPkg.Render(stats)
+	if err != nil {
+		return nil, fmt.Errorf("render chart %v", err)
+	}
+
+	photo := tgbotapi.NewPhotoUpload(chatID, tgbotapi.FileBytes{Name: ticker + ".png", Bytes: image})
+	photo.Caption = fmt.Sprintf("%v, %v, последняя цена: %v", ticker, timeframe, stats[len(stats)-1].Close)
+	photo.ReplyMarkup = chartKeyboard(ticker, timeframe, int32(candles))
+	return photo, nil
+}
 
-	messages := make([]string, 0)
-	for _, stat := range stats {
-		msgTxt := fmt.Sprintf("time: %v, open: %v, high: %v, low: %v, close: %v, volume:%v",
-			stat.Time, stat.Open, stat.High, stat.Low, stat.Close, stat.Volume)
-		messages = append(messages, msgTxt)
+func chartCallback(ticker, timeframe string, candles int32) string {
+	return fmt.Sprintf("%v%v:%v:%v", chartPrefix, ticker, timeframe, candles)
+}
+
+func chartKeyboard(ticker, timeframe string, candles int32) tgbotapi.InlineKeyboardMarkup {
+	timeframes := tgbotapi.NewInlineKeyboardRow()
+	for _, tf := range chartTimeframes {
+		text := tf
+		if tf == timeframe {
+			text = "[" + tf + "]"
+		}
+		timeframes = append(timeframes, tgbotapi.NewInlineKeyboardButtonData(text, chartCallback(ticker, tf, candles)))
 	}
+	ranges := tgbotapi.NewInlineKeyboardRow()
+	for _, count := range chartCandles {
+		text := fmt.Sprintf("%v свечей", count)
+		if count == candles {
+			text = "[" + text + "]"
+		}
+		ranges = append(ranges, tgbotapi.NewInlineKeyboardButtonData(text, chartCallback(ticker, timeframe, count)))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(timeframes, ranges)
+}
 
-	return messages, nil
+// getDepth - стакан текстом: продажи сверху от дальних цен к лучшей, под ними покупки от лучшей цены
+func (tgBot *brokerTgBot) getDepth(ticker string) (string, error) {
+	depth, err := tgBot.statsRepo.Depth(ticker)
+	if err != nil {
+		return "", fmt.Errorf("get depth %v", err)
+	}
+	if len(depth.Bids) == 0 && len(depth.Asks) == 0 {
+		return fmt.Sprintf("Стакан %v пуст", ticker), nil
+	}
+
+	lines := []string{fmt.Sprintf("Стакан %v:", ticker)}
+	for i := len(depth.Asks) - 1; i >= 0; i-- {
+		lines = append(lines, fmt.Sprintf("продажа %.2f - %vшт", depth.Asks[i].Price, depth.Asks[i].Volume))
+	}
+	lines = append(lines, "------")
+	for _, level := range depth.Bids {
+		lines = append(lines, fmt.Sprintf("покупка %.2f - %vшт", level.Price, level.Volume))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// marketClosed - биржа точно закрыта, если фаза неизвестна, заявку проверит брокер
+func (tgBot *brokerTgBot) marketClosed() bool {
+	status, err := tgBot.statsRepo.Market()
+	return err == nil && status.Phase == dealPkg.PhaseClosed
+}
+
+func (tgBot *brokerTgBot) getMarket() (string, error) {
+	status, err := tgBot.statsRepo.Market()
+	if err != nil {
+		return "", fmt.Errorf("get market %v", err)
+	}
+
+	msg := phaseNames[status.Phase]
+	if next, ok := phaseNames[status.NextPhase]; ok && status.NextTime > 0 {
+		msg += fmt.Sprintf("\nс %v: %v", time.Unix(int64(status.NextTime), 0).Format("02 Jan 06 15:04"), next)
+	}
+	return msg, nil
+}
+
+func (tgBot *brokerTgBot) getIndicator(ticker, name string) (string, error) {
+	result, err := tgBot.statsRepo.Indicator(ticker, name)
+	if err != nil {
+		return "", fmt.Errorf("get indicator %v", err)
+	}
+
+	lines := make([]string, 0, len(result.Values))
+	for line, value := range result.Values {
+		lines = append(lines, fmt.Sprintf("%v: %.2f", line, value))
+	}
+	sort.Strings(lines)
+
+	return fmt.Sprintf("%v %v(%v) на %v: %v", result.Ticker, result.Name, result.Period,
+		time.Unix(int64(result.Time), 0).Format("02 Jan 06 15:04"), strings.Join(lines, ", ")), nil
 }
 
 func (tgBot *brokerTgBot) cancelOrder(callbackData string, config *configPkg.Config) ([]string, error) {
@@ -243,17 +509,78 @@ func (tgBot *brokerTgBot) cancelOrder(callbackData string, config *configPkg.Con
 		return nil, fmt.Errorf("cancel order %v", err)
 	}
 
-	return []string{fmt.Sprintf("Заявка %v удалена", orderID)}, nil
+	return []string{fmt.Sprintf("Заявка %v будет снята", orderID)}, nil
 }
 
-func tickersKeyboard(config *configPkg.Config) tgbotapi.InlineKeyboardMarkup {
+// noTradingTickersMsg - ответ вместо выбора инструмента, когда торги всеми инструментами остановлены
+const noTradingTickersMsg = "Торги всеми инструментами сейчас приостановлены, попробуйте позже"
+
+// tradingTickers - инструменты, которыми сейчас идут торги, если справочник недоступен - из конфига
+func (tgBot *brokerTgBot) tradingTickers(config *configPkg.Config) []string {
+	instruments, err := tgBot.statsRepo.Instruments()
+	if err != nil || len(instruments) == 0 {
+		return config.Broker.Tickers
+	}
+
+	tickers := make([]string, 0, len(instruments))
+	for _, instrument := range instruments {
+		if instrument.Status == instrumentPkg.StatusTrading {
+			tickers = append(tickers, instrument.Ticker)
+		}
+	}
+	return tickers
+}
+
+// chooseTickerMsg - выбор инструмента кнопками, без инструментов - только текст, пустую клавиатуру телеграм не показывает
+func chooseTickerMsg(chatID int64, tickers []string) (tgbotapi.MessageConfig, bool) {
+	if len(tickers) == 0 {
+		return tgbotapi.NewMessage(chatID, noTradingTickersMsg), false
+	}
+	msg := tgbotapi.NewMessage(chatID, "Выберите инструмент")
+	msg.ReplyMarkup = tickersKeyboard(tickers)
+	return msg, true
+}
+
+func tickersKeyboard(tickers []string) tgbotapi.InlineKeyboardMarkup {
 	row := tgbotapi.NewInlineKeyboardRow()
-	for _, ticker := range config.Broker.Tickers {
+	for _, ticker := range tickers {
 		row = append(row, tgbotapi.NewInlineKeyboardButtonData(ticker, ticker))
 	}
 	return tgbotapi.NewInlineKeyboardMarkup(row)
 }
 
+func kindsKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Лимитная", dealPkg.KindLimit),
+			tgbotapi.NewInlineKeyboardButtonData("Рыночная", dealPkg.KindMarket),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Исполнить или снять (IOC)", dealPkg.KindIOC),
+			tgbotapi.NewInlineKeyboardButtonData("Все или ничего (FOK)", dealPkg.KindFOK),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Стоп", dealPkg.KindStop),
+			tgbotapi.NewInlineKeyboardButtonData("Стоп-лимит", dealPkg.KindStopLimit),
+		),
+	)
+}
+
+func indicatorsKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("SMA", indicatorsPkg.SMA),
+			tgbotapi.NewInlineKeyboardButtonData("EMA", indicatorsPkg.EMA),
+			tgbotapi.NewInlineKeyboardButtonData("RSI", indicatorsPkg.RSI),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("MACD", indicatorsPkg.MACD),
+			tgbotapi.NewInlineKeyboardButtonData("Боллинджер", indicatorsPkg.Bollinger),
+			tgbotapi.NewInlineKeyboardButtonData("VWAP", indicatorsPkg.VWAP),
+		),
+	)
+}
+
 func (tgBot *brokerTgBot) ordersKeyboard(clientID int) (tgbotapi.InlineKeyboardMarkup, error) {
 	orders, err := tgBot.dealsRepo.OrdersByClient(clientID)
 	if err != nil {
@@ -263,7 +590,12 @@ func (tgBot *brokerTgBot) ordersKeyboard(clientID int) (tgbotapi.InlineKeyboardM
 	markup := tgbotapi.NewInlineKeyboardMarkup()
 	for _, order := range orders {
 		status := "ожидает исполнения"
-		if order.CompletedVolume > 0 {
+		switch {
+		case order.Status == dealPkg.OrderStatusPending:
+			status = "отправляется на биржу"
+		case order.Status == dealPkg.OrderStatusRejected:
+			status = "отклонена биржей"
+		case order.CompletedVolume > 0:
 			status = "частично исполнена"
 		}
 		orderType := "Покупка"
@@ -272,7 +604,10 @@ func (tgBot *brokerTgBot) ordersKeyboard(clientID int) (tgbotapi.InlineKeyboardM
 		}
 		orderDescr := fmt.Sprintf("%v №:%v от %v %v %vшт (%v)",
 			orderType, order.ID, time.Unix(int64(order.Time), 0).Format("02 Jan 06 15:04"), order.Ticker, order.Volume, status)
-		row := tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(orderDescr, strconv.FormatInt(order.ID, 10)))
+		row := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(orderDescr, strconv.FormatInt(order.ID, 10)),
+			tgbotapi.NewInlineKeyboardButtonData("Изменить", editOrderPrefix+strconv.FormatInt(order.ID, 10)),
+		)
 		markup.InlineKeyboard = append(markup.InlineKeyboard, row)
 	}
 	return markup, nil
@@ -286,9 +621,22 @@ func (tgBot *brokerTgBot) getBalance(client *clientPkg.Client) ([]string, error)
 	messages := make([]string, len(positions))
 
 	for _, position := range positions {
-		messages = append(messages, fmt.Sprintf("ticker: %v, volume: %v, total: %.2f, price(avg): %.2f",
-			position.Ticker, position.Volume, position.Total, position.Price))
+		messages = append(messages, fmt.Sprintf("ticker: %v, volume: %v, total: %.2f, price(avg): %.2f, last: %.2f, "+
+			"P&L realized: %.2f, unrealized: %.2f",
+			position.Ticker, position.Volume, position.Total, position.Price, position.LastPrice,
+			position.Realized, position.Unrealized))
 	}
 
 	return messages, nil
 }
+
+// explainReject заменяет ошибку брокера с известным кодом на объяснение для пользователя
+func explainReject(err error) string {
+	var respErr *common.ResponseError
+	if errors.As(err, &respErr) {
+		if reason, ok := rejectReasons[respErr.Code]; ok {
+			return fmt.Sprintf("%v (%v)", reason, respErr.Message)
+		}
+	}
+	return err.Error()
+}
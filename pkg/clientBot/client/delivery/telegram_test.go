@@ -0,0 +1,136 @@
+package delivery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	clientPkg "github.com/KeynihAV/exchange/pkg/broker/client"
+	marketPkg "github.com/KeynihAV/exchange/pkg/broker/market"
+	statsRepoPkg "github.com/KeynihAV/exchange/pkg/clientBot/stats/repo"
+	"github.com/KeynihAV/exchange/pkg/common"
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// после перезапуска бота диалогов в памяти нет, кнопки и сообщения из старых диалогов не должны ронять бота
+func TestBrokerTgBot_StaleDialog(t *testing.T) {
+	tgBot := &brokerTgBot{ActiveDialogs: make(map[int64]*clientPkg.Dialog)}
+	tests := []struct {
+		name    string
+		process func(chatID int64, inputMsg string, config *configPkg.Config) ([]tgbotapi.Chattable, error)
+	}{
+		{name: "Кнопка", process: tgBot.processingCallback},
+		{name: "Сообщение", process: tgBot.processingMessages},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := tt.process(1, "SPFB.RTS", &configPkg.Config{})
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if len(messages) != 1 || messages[0].(tgbotapi.MessageConfig).Text != staleDialogMsg {
+				t.Errorf("messages = %v, want %q", messages, staleDialogMsg)
+			}
+		})
+	}
+}
+
+func TestBrokerTgBot_TradingTickers(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		instruments []*instrumentPkg.Instrument
+		want        []string
+		wantOk      bool
+	}{
+		{name: "Только торгуемые инструменты",
+			status: http.StatusOK,
+			instruments: []*instrumentPkg.Instrument{
+				{Ticker: "SPFB.RTS", Status: instrumentPkg.StatusTrading},
+				{Ticker: "SPFB.Si", Status: instrumentPkg.StatusHalted}},
+			want:   []string{"SPFB.RTS"},
+			wantOk: true,
+		},
+		{name: "Все остановлены - текст вместо пустой клавиатуры",
+			status: http.StatusOK,
+			instruments: []*instrumentPkg.Instrument{
+				{Ticker: "SPFB.RTS", Status: instrumentPkg.StatusHalted},
+				{Ticker: "SPFB.Si", Status: instrumentPkg.StatusDelisted}},
+			want: []string{},
+		},
+		{name: "Справочник пуст - инструменты из конфига",
+			status:      http.StatusOK,
+			instruments: []*instrumentPkg.Instrument{},
+			want:        []string{"SPFB.BR"},
+			wantOk:      true,
+		},
+		{name: "Справочник недоступен - инструменты из конфига",
+			status: http.StatusInternalServerError,
+			want:   []string{"SPFB.BR"},
+			wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+					return
+				}
+				common.WriteStructToResponse(tt.instruments, r.Context(), w)
+			}))
+			defer server.Close()
+			config := &configPkg.Config{}
+			config.Bot.BrokerEndpoint = server.URL
+			config.Broker.Tickers = []string{"SPFB.BR"}
+			tgBot := &brokerTgBot{statsRepo: statsRepoPkg.NewStatsRepo(config)}
+
+			tickers := tgBot.tradingTickers(config)
+			if !reflect.DeepEqual(tickers, tt.want) {
+				t.Fatalf("tradingTickers() = %v, want %v", tickers, tt.want)
+			}
+			msg, ok := chooseTickerMsg(1, tickers)
+			if ok != tt.wantOk {
+				t.Fatalf("chooseTickerMsg() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if !ok && (msg.Text != noTradingTickersMsg || msg.ReplyMarkup != nil) {
+				t.Errorf("chooseTickerMsg() = %q with %v, want %q without keyboard", msg.Text, msg.ReplyMarkup, noTradingTickersMsg)
+			}
+			if keyboard, isKeyboard := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup); ok &&
+				(!isKeyboard || len(keyboard.InlineKeyboard[0]) != len(tickers)) {
+				t.Errorf("chooseTickerMsg() keyboard = %v, want %v buttons", msg.ReplyMarkup, len(tickers))
+			}
+		})
+	}
+}
+
+func TestExplainReject(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "Остановка торгов инструментом",
+			err:  &common.ResponseError{Code: instrumentPkg.CodeNotTrading, Message: "SPFB.RTS is halted"},
+			want: "торги инструментом приостановлены (SPFB.RTS is halted)",
+		},
+		{name: "Биржа закрыта",
+			err:  &common.ResponseError{Code: marketPkg.CodeMarketClosed, Message: "market is closed"},
+			want: "биржа закрыта (market is closed)",
+		},
+		{name: "Неизвестный код",
+			err:  &common.ResponseError{Code: "unknown", Message: "something went wrong"},
+			want: "something went wrong",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := explainReject(tt.err); got != tt.want {
+				t.Errorf("explainReject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
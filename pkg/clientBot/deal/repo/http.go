@@ -3,11 +3,13 @@ package repo
 import (
 	"bytes"
 	"encoding/json"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strconv"
 	"time"
 
+	brokerDealPkg "github.com/KeynihAV/exchange/pkg/broker/deal"
 	"github.com/KeynihAV/exchange/pkg/common"
 	"github.com/KeynihAV/exchange/pkg/config"
 	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
@@ -103,3 +105,53 @@ func (cr *DealsRepo) CancelOrder(orderID int64) error {
 
 	return nil
 }
+
+// ReplaceOrder - итог изменения заявки: применено биржей или ждет доставки на биржу
+func (cr *DealsRepo) ReplaceOrder(orderID int64, price float32, volume int32) (*brokerDealPkg.ReplaceResult, error) {
+	method := "/api/v1/order/"
+
+	reqData, err := json.Marshal(&dealPkg.Order{Price: price, Volume: volume})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, cr.config.Bot.BrokerEndpoint+method+strconv.FormatInt(orderID, 10), bytes.NewBuffer(reqData))
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cr.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &brokerDealPkg.ReplaceResult{}
+	err = common.GetStructFromResponse(result, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Statement - отчет клиента за все время в csv
+func (cr *DealsRepo) Statement(clientID int) ([]byte, error) {
+	method := "/api/v1/statement/"
+
+	req, err := http.NewRequest(http.MethodGet, cr.config.Bot.BrokerEndpoint+method+strconv.Itoa(clientID)+"?format=csv", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cr.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, common.GetStructFromResponse(struct{}{}, resp)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
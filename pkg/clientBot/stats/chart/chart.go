@@ -0,0 +1,137 @@
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
+)
+
+const (
+	Width  = 800
+	Height = 480
+
+	// поля под подписи цен справа и времени снизу
+	marginRight  = 70
+	marginBottom = 20
+	padding      = 10
+	// volumeShare - доля высоты графика под объемы
+	volumeShare = 0.2
+	gridLines   = 4
+)
+
+var (
+	background = color.RGBA{255, 255, 255, 255}
+	gridColor  = color.RGBA{225, 225, 225, 255}
+	textColor  = color.RGBA{60, 60, 60, 255}
+	upColor    = color.RGBA{38, 166, 91, 255}
+	downColor  = color.RGBA{214, 69, 65, 255}
+)
+
+var ErrNoData = errors.New("no candles to draw")
+
+// Render рисует свечной график с объемами под ним и возвращает PNG
+func Render(candles []*statsPkg.OHLCV) ([]byte, error) {
+	if len(candles) == 0 {
+		return nil, ErrNoData
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+
+	plotWidth := Width - marginRight - padding
+	plotHeight := Height - marginBottom - padding
+	volumeHeight := int(float64(plotHeight) * volumeShare)
+	priceTop, priceBottom := padding, padding+plotHeight-volumeHeight-padding
+	volumeBottom := padding + plotHeight
+
+	low, high := candles[0].Low, candles[0].High
+	var maxVolume int32
+	for _, candle := range candles {
+		if candle.Low < low {
+			low = candle.Low
+		}
+		if candle.High > high {
+			high = candle.High
+		}
+		if candle.Volume > maxVolume {
+			maxVolume = candle.Volume
+		}
+	}
+	if high == low {
+		high, low = high+1, low-1
+	}
+	priceY := func(price float32) int {
+		return priceBottom - int(float32(priceBottom-priceTop)*(price-low)/(high-low))
+	}
+
+	for i := 0; i <= gridLines; i++ {
+		price := low + (high-low)*float32(i)/gridLines
+		y := priceY(price)
+		hLine(img, padding, padding+plotWidth, y, gridColor)
+		drawText(img, padding+plotWidth+6, y-fontHeight, fmt.Sprintf("%.2f", price), textColor)
+	}
+	hLine(img, padding, padding+plotWidth, volumeBottom, gridColor)
+
+	step := float64(plotWidth) / float64(len(candles))
+	bodyWidth := int(step * 0.7)
+	if bodyWidth < 1 {
+		bodyWidth = 1
+	}
+	for i, candle := range candles {
+		center := padding + int(step*float64(i)+step/2)
+		clr := upColor
+		if candle.Close < candle.Open {
+			clr = downColor
+		}
+
+		vLine(img, center, priceY(candle.High), priceY(candle.Low), clr)
+		top, bottom := priceY(candle.Open), priceY(candle.Close)
+		if top > bottom {
+			top, bottom = bottom, top
+		}
+		fillRect(img, center-bodyWidth/2, top, center-bodyWidth/2+bodyWidth, bottom+1, clr)
+
+		if maxVolume > 0 {
+			barHeight := int(float64(volumeHeight) * float64(candle.Volume) / float64(maxVolume))
+			fillRect(img, center-bodyWidth/2, volumeBottom-barHeight, center-bodyWidth/2+bodyWidth, volumeBottom, clr)
+		}
+	}
+
+	//время первой и последней свечи по краям оси
+	layout := "15:04"
+	if candles[0].Interval >= 86400 {
+		layout = "02.01"
+	}
+	first, last := candles[0].Time.Format(layout), candles[len(candles)-1].Time.Format(layout)
+	drawText(img, padding, Height-marginBottom+4, first, textColor)
+	drawText(img, padding+plotWidth-textWidth(last), Height-marginBottom+4, last, textColor)
+
+	buf := &bytes.Buffer{}
+	err := png.Encode(buf, img)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func hLine(img *image.RGBA, x1, x2, y int, clr color.Color) {
+	for x := x1; x < x2; x++ {
+		img.Set(x, y, clr)
+	}
+}
+
+func vLine(img *image.RGBA, x, y1, y2 int, clr color.Color) {
+	for y := y1; y <= y2; y++ {
+		img.Set(x, y, clr)
+	}
+}
+
+func fillRect(img *image.RGBA, x1, y1, x2, y2 int, clr color.Color) {
+	draw.Draw(img, image.Rect(x1, y1, x2, y2), &image.Uniform{clr}, image.Point{}, draw.Src)
+}
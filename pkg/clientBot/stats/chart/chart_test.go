@@ -0,0 +1,102 @@
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+
+	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
+)
+
+func decode(t *testing.T, data []byte) image.Image {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode png: %v", err)
+	}
+	return img
+}
+
+// columnHas - есть ли в столбце x пиксель цвета clr
+func columnHas(img image.Image, x int, clr color.RGBA) bool {
+	for y := 0; y < Height; y++ {
+		if color.RGBAModel.Convert(img.At(x, y)) == clr {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRender(t *testing.T) {
+	start := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		candles []*statsPkg.OHLCV
+		wantErr error
+		check   func(t *testing.T, img image.Image)
+	}{
+		{name: "Без свечей",
+			wantErr: ErrNoData,
+		},
+		{name: "Растущая и падающая свечи своими цветами",
+			candles: []*statsPkg.OHLCV{
+				{Time: start, Interval: 60, Open: 10, High: 25, Low: 5, Close: 20, Volume: 10},
+				{Time: start.Add(time.Minute), Interval: 60, Open: 20, High: 22, Low: 8, Close: 12, Volume: 5}},
+			check: func(t *testing.T, img image.Image) {
+				//по 360 точек на свечу, центры на 190 и 550
+				if !columnHas(img, 190, upColor) || columnHas(img, 190, downColor) {
+					t.Errorf("first candle is not drawn as rising")
+				}
+				if !columnHas(img, 550, downColor) || columnHas(img, 550, upColor) {
+					t.Errorf("second candle is not drawn as falling")
+				}
+				volumeBottom := Height - marginBottom
+				if color.RGBAModel.Convert(img.At(190, volumeBottom-1)) != upColor {
+					t.Errorf("no volume bar under first candle")
+				}
+			},
+		},
+		{name: "Свечи без изменения цены и объема",
+			candles: []*statsPkg.OHLCV{
+				{Time: start, Interval: 86400, Open: 10, High: 10, Low: 10, Close: 10},
+				{Time: start.Add(24 * time.Hour), Interval: 86400, Open: 10, High: 10, Low: 10, Close: 10}},
+			check: func(t *testing.T, img image.Image) {
+				if !columnHas(img, 190, upColor) {
+					t.Errorf("flat candle is not drawn")
+				}
+			},
+		},
+		{name: "Свечей больше, чем точек по ширине",
+			candles: func() []*statsPkg.OHLCV {
+				candles := make([]*statsPkg.OHLCV, 2000)
+				for i := range candles {
+					price := float32(100 + i%7)
+					candles[i] = &statsPkg.OHLCV{Time: start.Add(time.Duration(i) * time.Second), Interval: 1,
+						Open: price, High: price + 1, Low: price - 1, Close: price + 0.5, Volume: int32(i % 10)}
+				}
+				return candles
+			}(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.candles)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			img := decode(t, got)
+			if img.Bounds().Dx() != Width || img.Bounds().Dy() != Height {
+				t.Errorf("Render() size = %v, want %vx%v", img.Bounds(), Width, Height)
+			}
+			if tt.check != nil {
+				tt.check(t, img)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+)
+
+// шрифт 3x5 только для подписей осей: цифры и разделители, масштабируется fontScale
+const (
+	glyphWidth  = 3
+	glyphHeight = 5
+	fontScale   = 2
+	fontHeight  = glyphHeight * fontScale
+	// между символами пустой столбец
+	charWidth = (glyphWidth + 1) * fontScale
+)
+
+var glyphs = map[rune][glyphHeight]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'-': {"...", "...", "###", "...", "..."},
+}
+
+// drawText пишет text с левого верхнего угла (x, y), символы без глифа пропускаются пробелом
+func drawText(img *image.RGBA, x, y int, text string, clr color.Color) {
+	for _, char := range text {
+		glyph, ok := glyphs[char]
+		if ok {
+			for row, line := range glyph {
+				for col, pixel := range line {
+					if pixel == '#' {
+						fillRect(img, x+col*fontScale, y+row*fontScale, x+(col+1)*fontScale, y+(row+1)*fontScale, clr)
+					}
+				}
+			}
+		}
+		x += charWidth
+	}
+}
+
+func textWidth(text string) int {
+	return len([]rune(text)) * charWidth
+}
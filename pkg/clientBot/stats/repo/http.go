@@ -3,11 +3,16 @@ package repo
 import (
 	"net"
 	"net/http"
+	"strconv"
 	"time"
 
+	depthPkg "github.com/KeynihAV/exchange/pkg/broker/depth"
+	marketPkg "github.com/KeynihAV/exchange/pkg/broker/market"
 	statsPkg "github.com/KeynihAV/exchange/pkg/broker/stats"
+	indicatorsPkg "github.com/KeynihAV/exchange/pkg/broker/stats/indicators"
 	"github.com/KeynihAV/exchange/pkg/common"
 	"github.com/KeynihAV/exchange/pkg/config"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
 )
 
 type StatsRepo struct {
@@ -32,10 +37,12 @@ func NewStatsRepo(config *config.Config) *StatsRepo {
 		config: config}
 }
 
-func (cr *StatsRepo) GeStatsByTicker(ticker string) ([]*statsPkg.OHLCV, error) {
+// GeStatsByTicker - свечи инструмента интервала timeframe (1m, 5m, ...) начиная с from
+func (cr *StatsRepo) GeStatsByTicker(ticker string, timeframe string, from int32) ([]*statsPkg.OHLCV, error) {
 	method := "/api/v1/stats/"
 
-	req, err := http.NewRequest(http.MethodGet, cr.config.Bot.BrokerEndpoint+method+ticker, nil)
+	query := "?interval=" + timeframe + "&from=" + strconv.Itoa(int(from))
+	req, err := http.NewRequest(http.MethodGet, cr.config.Bot.BrokerEndpoint+method+ticker+query, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -53,3 +60,95 @@ func (cr *StatsRepo) GeStatsByTicker(ticker string) ([]*statsPkg.OHLCV, error) {
 
 	return stats, nil
 }
+
+// Indicator - значение индикатора инструмента с периодом по умолчанию
+func (cr *StatsRepo) Indicator(ticker, name string) (*indicatorsPkg.Result, error) {
+	method := "/api/v1/indicators/"
+
+	req, err := http.NewRequest(http.MethodGet, cr.config.Bot.BrokerEndpoint+method+ticker+"?name="+name, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cr.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &indicatorsPkg.Result{}
+	err = common.GetStructFromResponse(result, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Depth - лучшие уровни стакана инструмента
+func (cr *StatsRepo) Depth(ticker string) (*depthPkg.Depth, error) {
+	method := "/api/v1/depth/"
+
+	req, err := http.NewRequest(http.MethodGet, cr.config.Bot.BrokerEndpoint+method+ticker, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cr.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	depth := &depthPkg.Depth{}
+	err = common.GetStructFromResponse(depth, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return depth, nil
+}
+
+// Market - фаза торговой сессии биржи
+func (cr *StatsRepo) Market() (*marketPkg.Status, error) {
+	method := "/api/v1/market"
+
+	req, err := http.NewRequest(http.MethodGet, cr.config.Bot.BrokerEndpoint+method, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cr.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &marketPkg.Status{}
+	err = common.GetStructFromResponse(status, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// Instruments - справочник инструментов биржи
+func (cr *StatsRepo) Instruments() ([]*instrumentPkg.Instrument, error) {
+	method := "/api/v1/instruments"
+
+	req, err := http.NewRequest(http.MethodGet, cr.config.Bot.BrokerEndpoint+method, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cr.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	instruments := []*instrumentPkg.Instrument{}
+	err = common.GetStructFromResponse(&instruments, resp)
+	if err != nil {
+		return nil, err
+	}
+
+	return instruments, nil
+}
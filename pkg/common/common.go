@@ -2,10 +2,12 @@ package common
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
 	. "github.com/KeynihAV/exchange/pkg/logging"
 )
@@ -13,9 +15,41 @@ import (
 type MyResponse struct {
 	Body  interface{} `json:"body,omitempty"`
 	Error string      `json:"error,omitempty"`
+	Code  string      `json:"code,omitempty"`
+}
+
+// ResponseError - ошибка с кодом из ответа, по коду клиент может объяснить причину пользователю
+type ResponseError struct {
+	Code    string
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+// AdminHeader - заголовок с токеном администратора брокера
+const AdminHeader = "X-Admin-Token"
+
+// AdminOnly пропускает запрос только с токеном администратора в AdminHeader, пустой token запрещает все запросы,
+// чтобы запросы, меняющие деньги и лимиты клиентов, не оказались открыты из-за незаполненного конфига
+func AdminOnly(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get(AdminHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			err := fmt.Errorf("admin token required")
+			RespJSONError(w, http.StatusForbidden, err, err.Error(), r.Context())
+			return
+		}
+		next(w, r)
+	}
 }
 
 func RespJSONError(w http.ResponseWriter, status int, err error, resp string, ctx context.Context) {
+	RespJSONErrorCode(w, status, "", err, resp, ctx)
+}
+
+func RespJSONErrorCode(w http.ResponseWriter, status int, code string, err error, resp string, ctx context.Context) {
 	if err != nil {
 		Sl(ctx).Error(err.Error())
 	}
@@ -23,6 +57,7 @@ func RespJSONError(w http.ResponseWriter, status int, err error, resp string, ct
 	w.Header().Add("Content-Type", "application/json")
 	respJSON, _ := json.Marshal(&MyResponse{
 		Error: resp,
+		Code:  code,
 	})
 	w.Write(respJSON)
 }
@@ -61,6 +96,9 @@ func GetStructFromResponse(in interface{}, resp *http.Response) error {
 		return fmt.Errorf("error parsing response: %v, status: %v, txt: %v", err, resp.StatusCode, string(body))
 	}
 
+	if resp.StatusCode != http.StatusOK && myResp.Code != "" {
+		return &ResponseError{Code: myResp.Code, Message: myResp.Error}
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf(myResp.Error)
 	}
@@ -82,3 +120,20 @@ func WriteStructToResponse(in interface{}, ctx context.Context, w http.ResponseW
 
 	return true
 }
+
+// PeriodFromQuery - границы периода из параметров запроса from и to (unix time), 0 - параметр не задан
+func PeriodFromQuery(r *http.Request) (int32, int32, error) {
+	var period [2]int32
+	for i, param := range []string{"from", "to"} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return 0, 0, fmt.Errorf("bad %v: %v", param, err)
+		}
+		period[i] = int32(parsed)
+	}
+	return period[0], period[1], nil
+}
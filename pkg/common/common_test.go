@@ -0,0 +1,41 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KeynihAV/exchange/pkg/logging"
+)
+
+func TestAdminOnly(t *testing.T) {
+	logging.New()
+	tests := []struct {
+		name       string
+		token      string
+		header     string
+		wantStatus int
+	}{
+		{name: "Верный токен", token: "secret", header: "secret", wantStatus: http.StatusOK},
+		{name: "Неверный токен", token: "secret", header: "guess", wantStatus: http.StatusForbidden},
+		{name: "Без токена", token: "secret", wantStatus: http.StatusForbidden},
+		{name: "Токен не задан в конфиге", header: "", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := AdminOnly(tt.token, func(w http.ResponseWriter, r *http.Request) { called = true })
+
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/cash/1/deposit", nil)
+			if tt.header != "" {
+				r.Header.Set(AdminHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+
+			if w.Code != tt.wantStatus || called != (tt.wantStatus == http.StatusOK) {
+				t.Errorf("status = %v, called %v, want %v", w.Code, called, tt.wantStatus)
+			}
+		})
+	}
+}
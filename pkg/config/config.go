@@ -35,10 +35,84 @@ type Config struct {
 		ID               int
 		Tickers          []string
 		ExchangeEndpoint string
+		CostMethod       string // fifo (по умолчанию) или average
+		// запас резерва рыночной покупки к худшей цене стакана или последней цене, доля: по умолчанию 0.05 - 5%
+		MarketSlippage float32
+		// токен в заголовке X-Admin-Token для пополнения и вывода денег и изменения риск-профиля клиентов, пусто - эти запросы запрещены;
+		// остальные запросы HTTP API брокера не проверяют, чей clientID в пути, поэтому порт не должен быть
+		// доступен никому, кроме бота и администратора
+		AdminToken string
 	}
 	Exchange struct {
-		DealsFlowFile   string
+		DealsFlowFile string
+		DealsFlowDir  string // каталог файлов ленты по инструментам, если задан - вместо DealsFlowFile
+		Source        struct {
+			Type    string // csv (по умолчанию), jsonl или synthetic; сжатые gzip файлы распаковываются
+			Columns struct {
+				// заголовки колонок csv, если не заданы - формат Финама: TICKER, DATE, TIME, LAST, VOL
+				Ticker string
+				Date   string
+				Time   string // пусто - время вместе с датой в колонке Date
+				Price  string
+				Volume string
+			}
+			TimeLayout string // время сделки в csv и jsonl, по умолчанию 20060102150405
+			Comma      string // разделитель колонок csv, по умолчанию запятая
+			Synthetic  struct {
+				Model   string // walk (по умолчанию) или gbm
+				Step    int    // секунд между сделками, по умолчанию 1
+				Seed    int64  // 0 - случайный
+				Tickers []struct {
+					Ticker     string
+					Price      float64 // начальная цена
+					Drift      float64 // за шаг: walk - в цене, gbm - доля цены
+					Volatility float64 // за шаг: walk - в цене, gbm - доля цены
+					Volume     int     // максимальный объем сделки, по умолчанию 1
+				}
+			}
+		}
+		Replay struct {
+			Speed string // множитель скорости: 0.5, 1 (по умолчанию), 10 или max - без пауз
+			Start string // 20060102150405, пусто - с начала ленты
+			End   string // 20060102150405, пусто - до конца ленты
+			Loop  bool
+		}
+		AdminPort int // управление воспроизведением ленты и справочником инструментов, 0 - не запускается
+		// true - пока справочник инструментов пуст, заявки принимаются без проверок, как до его появления;
+		// по умолчанию при пустом справочнике заявки отклоняются
+		AllowUnlisted bool
+		Instruments   []struct {
+			// добавляются в справочник при запуске, если их там нет; дальше справочник меняется через AdminPort;
+			// заявки по инструментам не из справочника отклоняются
+			Ticker      string
+			Description string
+			TickSize    float32
+			LotSize     int32
+			Currency    string
+			PriceMin    float32
+			PriceMax    float32
+			Band        float32 // динамические границы в % от последней цены ленты, 0 - из PriceBands
+		}
+		PriceBands struct {
+			Band            float32 // динамические границы по умолчанию в % от последней цены ленты, 0 - без них
+			BreakerMove     float32 // остановка торгов инструментом при движении цены больше чем на % за BreakerWindow, 0 - без остановок
+			BreakerWindow   int     // секунды ленты, по умолчанию 60
+			BreakerCooldown int     // секунды ленты до возобновления торгов, но не дольше стольких же секунд по часам, по умолчанию 300
+		}
 		TradingInterval int
+		SessionEnd      string // 15:04, окончание торгов, по умолчанию 23:50
+		Session         struct {
+			Timezone       string   // по умолчанию локальная зона биржи
+			PreOpen        string   // 15:04, пусто - торги круглосуточно без аукционов
+			OpeningAuction string   // пусто - без открывающего аукциона
+			Continuous     string   // 15:04, начало непрерывных торгов
+			ClosingAuction string   // пусто - без закрывающего аукциона, торги до SessionEnd
+			Weekdays       []int    // торговые дни, 0 - воскресенье, по умолчанию пн-пт
+			Holidays       []string // 2006-01-02
+		}
+		MetricsPort        int    // 0 - метрики не отдаются
+		StatsQueueSize     int    // очередь свечей на подписчика, по умолчанию 10000
+		SlowConsumerPolicy string // drop (по умолчанию) - пропускать свечи, disconnect - отключать подписчика
 	}
 }
 
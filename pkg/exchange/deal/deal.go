@@ -1,5 +1,75 @@
 package deal
 
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	TypeBuy  = "buy"
+	TypeSell = "sell"
+)
+
+const (
+	KindLimit     = "limit"
+	KindMarket    = "market"
+	KindIOC       = "ioc"
+	KindFOK       = "fok"
+	KindStop      = "stop"
+	KindStopLimit = "stop_limit"
+)
+
+const (
+	EventFill    = "fill"
+	EventCancel  = "cancel"
+	EventTrigger = "trigger"
+	EventExpire  = "expire"
+)
+
+const (
+	TimeInForceGTC = "gtc"
+	TimeInForceDay = "day"
+	TimeInForceGTD = "gtd"
+)
+
+const (
+	OrderStatusPending  = "pending"
+	OrderStatusAccepted = "accepted"
+	OrderStatusRejected = "rejected"
+)
+
+// события журнала заявки, журнал только дополняется и хранится после удаления заявки
+const (
+	HistoryCreated         = "created"
+	HistoryAccepted        = "accepted"
+	HistoryRejected        = "rejected"
+	HistoryPartiallyFilled = "partially_filled"
+	HistoryFilled          = "filled"
+	HistoryCancelled       = "cancelled"
+	HistoryExpired         = "expired"
+	HistoryReplaced        = "replaced"
+	HistoryTriggered       = "triggered"
+)
+
+// фазы торговой сессии: в предторговом периоде и на аукционах заявки копятся без исполнения,
+// аукцион заканчивается исполнением накопленных заявок по единой цене
+const (
+	PhaseClosed         = "closed"
+	PhasePreOpen        = "pre_open"
+	PhaseOpeningAuction = "opening_auction"
+	PhaseContinuous     = "continuous"
+	PhaseClosingAuction = "closing_auction"
+)
+
+var (
+	// ErrInvalidOrder - заявка отклонена по параметрам, повторная отправка не поможет
+	ErrInvalidOrder = errors.New("invalid order")
+	// ErrOrderNotFound - заявки нет среди активных: исполнена, снята или не существовала
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrSessionPhase - действие не разрешено в текущей фазе сессии, повторить можно после ее смены
+	ErrSessionPhase = errors.New("not allowed in current session phase")
+)
+
 type Deal struct {
 	ID       int64
 	BrokerID int32
@@ -11,6 +81,34 @@ type Deal struct {
 	Time     int32
 	Price    float32
 	Type     string
+	Event    string
+}
+
+// OrderHistoryEvent - запись журнала заявки: Volume и Price - объем и цена заявки при создании и изменении,
+// сделки при исполнении, снятый остаток при отмене и истечении
+type OrderHistoryEvent struct {
+	ID      int64
+	OrderID int64
+	Event   string
+	Volume  int32
+	Price   float32
+	Time    int32
+}
+
+// HistoryEventByDealEvent - событие журнала по событию заявки, которое биржа отправляет брокеру
+func HistoryEventByDealEvent(event string, partial bool) string {
+	switch event {
+	case EventCancel:
+		return HistoryCancelled
+	case EventExpire:
+		return HistoryExpired
+	case EventTrigger:
+		return HistoryTriggered
+	}
+	if partial {
+		return HistoryPartiallyFilled
+	}
+	return HistoryFilled
 }
 
 type Order struct {
@@ -23,6 +121,103 @@ type Order struct {
 	Time            int32
 	Price           float32
 	Type            string
+	Kind            string
+	StopPrice       float32
+	TimeInForce     string
+	ExpiresAt       int32
+	Status          string
+	Seq             int64 // место в очереди внутри цены на бирже, 0 - заявка встанет в конец очереди
+}
+
+func ValidKind(kind string) bool {
+	switch kind {
+	case KindLimit, KindMarket, KindIOC, KindFOK, KindStop, KindStopLimit:
+		return true
+	}
+	return false
+}
+
+func IsStopKind(kind string) bool {
+	return kind == KindStop || kind == KindStopLimit
+}
+
+// TriggeredKind - во что превращается стоп-заявка после срабатывания
+func TriggeredKind(kind string) string {
+	switch kind {
+	case KindStop:
+		return KindMarket
+	case KindStopLimit:
+		return KindLimit
+	}
+	return kind
+}
+
+func (o *Order) Validate() error {
+	if !ValidKind(o.Kind) {
+		return fmt.Errorf("%w: unknown order kind %v", ErrInvalidOrder, o.Kind)
+	}
+	if o.Type != TypeBuy && o.Type != TypeSell {
+		return fmt.Errorf("%w: unknown order side %v", ErrInvalidOrder, o.Type)
+	}
+	if o.Volume <= 0 {
+		return fmt.Errorf("%w: order volume must be positive", ErrInvalidOrder)
+	}
+	if IsStopKind(o.Kind) && o.StopPrice <= 0 {
+		return fmt.Errorf("%w: stop price must be positive for %v order", ErrInvalidOrder, o.Kind)
+	}
+	if o.Kind != KindMarket && o.Kind != KindStop && o.Price <= 0 {
+		return fmt.Errorf("%w: price must be positive for %v order", ErrInvalidOrder, o.Kind)
+	}
+	switch o.TimeInForce {
+	case TimeInForceGTC, TimeInForceDay:
+	case TimeInForceGTD:
+		if o.ExpiresAt <= 0 {
+			return fmt.Errorf("%w: expiration time must be set for %v order", ErrInvalidOrder, o.TimeInForce)
+		}
+	default:
+		return fmt.Errorf("%w: unknown time in force %v", ErrInvalidOrder, o.TimeInForce)
+	}
+	return nil
+}
+
+// RemainingVolume - неисполненный остаток заявки
+func (o *Order) RemainingVolume() int32 {
+	return o.Volume - o.CompletedVolume
+}
+
+// TradePrint - обезличенная сделка ленты, Type - сторона инициатора,
+// Time - время биржи по часам, как у сделок брокеров, и для сделок из внешнего потока
+type TradePrint struct {
+	Ticker string
+	Price  float32
+	Volume int32
+	Type   string
+	Time   int32
+}
+
+// SessionEvent - смена фазы торговой сессии: Phase с Time, следующая фаза NextPhase с NextTime,
+// NextTime 0 - смены фазы не будет
+type SessionEvent struct {
+	Phase     string
+	Time      int32
+	NextPhase string
+	NextTime  int32
+}
+
+// PriceLevel - ценовой уровень стакана, Volume - суммарный остаток заявок по цене, 0 - уровень пуст
+type PriceLevel struct {
+	Price  float32
+	Volume int32
+}
+
+// DepthUpdate - снимок стакана инструмента (Snapshot) или изменившиеся уровни,
+// Sequence растет на 1 с каждым изменением уровня
+type DepthUpdate struct {
+	Ticker   string
+	Snapshot bool
+	Bids     []PriceLevel
+	Asks     []PriceLevel
+	Sequence int64
 }
 
 type OHLCV struct {
@@ -0,0 +1,129 @@
+package delivery
+
+import (
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+)
+
+// преобразования между строковыми полями dealPkg и enum из exchange.proto
+
+func SideToProto(orderType string) Side {
+	switch orderType {
+	case dealPkg.TypeBuy:
+		return Side_SIDE_BUY
+	case dealPkg.TypeSell:
+		return Side_SIDE_SELL
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func SideFromProto(side Side, orderType string) string {
+	switch side {
+	case Side_SIDE_BUY:
+		return dealPkg.TypeBuy
+	case Side_SIDE_SELL:
+		return dealPkg.TypeSell
+	}
+	return orderType
+}
+
+var kindsToProto = map[string]OrderKind{
+	dealPkg.KindLimit:     OrderKind_KIND_LIMIT,
+	dealPkg.KindMarket:    OrderKind_KIND_MARKET,
+	dealPkg.KindIOC:       OrderKind_KIND_IOC,
+	dealPkg.KindFOK:       OrderKind_KIND_FOK,
+	dealPkg.KindStop:      OrderKind_KIND_STOP,
+	dealPkg.KindStopLimit: OrderKind_KIND_STOP_LIMIT,
+}
+
+func KindToProto(kind string) OrderKind {
+	return kindsToProto[kind]
+}
+
+func KindFromProto(kind OrderKind) string {
+	for k, v := range kindsToProto {
+		if v == kind {
+			return k
+		}
+	}
+	return dealPkg.KindLimit
+}
+
+var timesInForceToProto = map[string]TimeInForce{
+	dealPkg.TimeInForceGTC: TimeInForce_TIF_GTC,
+	dealPkg.TimeInForceDay: TimeInForce_TIF_DAY,
+	dealPkg.TimeInForceGTD: TimeInForce_TIF_GTD,
+}
+
+func TimeInForceToProto(timeInForce string) TimeInForce {
+	return timesInForceToProto[timeInForce]
+}
+
+func TimeInForceFromProto(timeInForce TimeInForce) string {
+	for k, v := range timesInForceToProto {
+		if v == timeInForce {
+			return k
+		}
+	}
+	return dealPkg.TimeInForceGTC
+}
+
+var eventsToProto = map[string]DealEvent{
+	dealPkg.EventFill:    DealEvent_EVENT_FILL,
+	dealPkg.EventCancel:  DealEvent_EVENT_CANCEL,
+	dealPkg.EventTrigger: DealEvent_EVENT_TRIGGER,
+	dealPkg.EventExpire:  DealEvent_EVENT_EXPIRE,
+}
+
+func EventToProto(event string) DealEvent {
+	return eventsToProto[event]
+}
+
+func EventFromProto(event DealEvent) string {
+	for k, v := range eventsToProto {
+		if v == event {
+			return k
+		}
+	}
+	return dealPkg.EventFill
+}
+
+var phasesToProto = map[string]SessionPhase{
+	dealPkg.PhaseClosed:         SessionPhase_PHASE_CLOSED,
+	dealPkg.PhasePreOpen:        SessionPhase_PHASE_PRE_OPEN,
+	dealPkg.PhaseOpeningAuction: SessionPhase_PHASE_OPENING_AUCTION,
+	dealPkg.PhaseContinuous:     SessionPhase_PHASE_CONTINUOUS,
+	dealPkg.PhaseClosingAuction: SessionPhase_PHASE_CLOSING_AUCTION,
+}
+
+func PhaseToProto(phase string) SessionPhase {
+	return phasesToProto[phase]
+}
+
+func PhaseFromProto(phase SessionPhase) string {
+	for k, v := range phasesToProto {
+		if v == phase {
+			return k
+		}
+	}
+	return dealPkg.PhaseClosed
+}
+
+var instrumentStatusesToProto = map[string]InstrumentStatus{
+	instrumentPkg.StatusTrading:  InstrumentStatus_STATUS_TRADING,
+	instrumentPkg.StatusHalted:   InstrumentStatus_STATUS_HALTED,
+	instrumentPkg.StatusDelisted: InstrumentStatus_STATUS_DELISTED,
+}
+
+func InstrumentStatusToProto(status string) InstrumentStatus {
+	return instrumentStatusesToProto[status]
+}
+
+func InstrumentStatusFromProto(status InstrumentStatus) string {
+	for k, v := range instrumentStatusesToProto {
+		if v == status {
+			return k
+		}
+	}
+	return instrumentPkg.StatusTrading
+}
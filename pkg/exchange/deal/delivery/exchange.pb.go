@@ -20,6 +20,318 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type Side int32
+
+const (
+	Side_SIDE_UNSPECIFIED Side = 0 // для старых клиентов сторона берется из Type
+	Side_SIDE_BUY         Side = 1
+	Side_SIDE_SELL        Side = 2
+)
+
+// Enum value maps for Side.
+var (
+	Side_name = map[int32]string{
+		0: "SIDE_UNSPECIFIED",
+		1: "SIDE_BUY",
+		2: "SIDE_SELL",
+	}
+	Side_value = map[string]int32{
+		"SIDE_UNSPECIFIED": 0,
+		"SIDE_BUY":         1,
+		"SIDE_SELL":        2,
+	}
+)
+
+func (x Side) Enum() *Side {
+	p := new(Side)
+	*p = x
+	return p
+}
+
+func (x Side) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Side) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[0].Descriptor()
+}
+
+func (Side) Type() protoreflect.EnumType {
+	return &file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[0]
+}
+
+func (x Side) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Side.Descriptor instead.
+func (Side) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{0}
+}
+
+type OrderKind int32
+
+const (
+	OrderKind_KIND_LIMIT      OrderKind = 0 // встает в стакан остатком
+	OrderKind_KIND_MARKET     OrderKind = 1 // по любой цене, остаток отменяется
+	OrderKind_KIND_IOC        OrderKind = 2 // немедленно в пределах цены, остаток отменяется
+	OrderKind_KIND_FOK        OrderKind = 3 // целиком немедленно или отмена
+	OrderKind_KIND_STOP       OrderKind = 4 // при достижении StopPrice становится рыночной
+	OrderKind_KIND_STOP_LIMIT OrderKind = 5 // при достижении StopPrice становится лимитной по Price
+)
+
+// Enum value maps for OrderKind.
+var (
+	OrderKind_name = map[int32]string{
+		0: "KIND_LIMIT",
+		1: "KIND_MARKET",
+		2: "KIND_IOC",
+		3: "KIND_FOK",
+		4: "KIND_STOP",
+		5: "KIND_STOP_LIMIT",
+	}
+	OrderKind_value = map[string]int32{
+		"KIND_LIMIT":      0,
+		"KIND_MARKET":     1,
+		"KIND_IOC":        2,
+		"KIND_FOK":        3,
+		"KIND_STOP":       4,
+		"KIND_STOP_LIMIT": 5,
+	}
+)
+
+func (x OrderKind) Enum() *OrderKind {
+	p := new(OrderKind)
+	*p = x
+	return p
+}
+
+func (x OrderKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[1].Descriptor()
+}
+
+func (OrderKind) Type() protoreflect.EnumType {
+	return &file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[1]
+}
+
+func (x OrderKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderKind.Descriptor instead.
+func (OrderKind) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{1}
+}
+
+type TimeInForce int32
+
+const (
+	TimeInForce_TIF_GTC TimeInForce = 0 // до исполнения или отмены
+	TimeInForce_TIF_DAY TimeInForce = 1 // до конца торговой сессии
+	TimeInForce_TIF_GTD TimeInForce = 2 // до ExpiresAt
+)
+
+// Enum value maps for TimeInForce.
+var (
+	TimeInForce_name = map[int32]string{
+		0: "TIF_GTC",
+		1: "TIF_DAY",
+		2: "TIF_GTD",
+	}
+	TimeInForce_value = map[string]int32{
+		"TIF_GTC": 0,
+		"TIF_DAY": 1,
+		"TIF_GTD": 2,
+	}
+)
+
+func (x TimeInForce) Enum() *TimeInForce {
+	p := new(TimeInForce)
+	*p = x
+	return p
+}
+
+func (x TimeInForce) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TimeInForce) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[2].Descriptor()
+}
+
+func (TimeInForce) Type() protoreflect.EnumType {
+	return &file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[2]
+}
+
+func (x TimeInForce) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TimeInForce.Descriptor instead.
+func (TimeInForce) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{2}
+}
+
+type DealEvent int32
+
+const (
+	DealEvent_EVENT_FILL    DealEvent = 0
+	DealEvent_EVENT_CANCEL  DealEvent = 1 // отмена остатка заявки биржей
+	DealEvent_EVENT_TRIGGER DealEvent = 2 // срабатывание стоп-заявки
+	DealEvent_EVENT_EXPIRE  DealEvent = 3 // снятие заявки по истечении срока
+)
+
+// Enum value maps for DealEvent.
+var (
+	DealEvent_name = map[int32]string{
+		0: "EVENT_FILL",
+		1: "EVENT_CANCEL",
+		2: "EVENT_TRIGGER",
+		3: "EVENT_EXPIRE",
+	}
+	DealEvent_value = map[string]int32{
+		"EVENT_FILL":    0,
+		"EVENT_CANCEL":  1,
+		"EVENT_TRIGGER": 2,
+		"EVENT_EXPIRE":  3,
+	}
+)
+
+func (x DealEvent) Enum() *DealEvent {
+	p := new(DealEvent)
+	*p = x
+	return p
+}
+
+func (x DealEvent) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DealEvent) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[3].Descriptor()
+}
+
+func (DealEvent) Type() protoreflect.EnumType {
+	return &file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[3]
+}
+
+func (x DealEvent) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DealEvent.Descriptor instead.
+func (DealEvent) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{3}
+}
+
+type SessionPhase int32
+
+const (
+	SessionPhase_PHASE_CLOSED          SessionPhase = 0 // заявки не принимаются
+	SessionPhase_PHASE_PRE_OPEN        SessionPhase = 1 // лимитные заявки принимаются без исполнения
+	SessionPhase_PHASE_OPENING_AUCTION SessionPhase = 2 // как PRE_OPEN, но заявки нельзя снять или изменить, в конце - исполнение по единой цене
+	SessionPhase_PHASE_CONTINUOUS      SessionPhase = 3 // непрерывные торги
+	SessionPhase_PHASE_CLOSING_AUCTION SessionPhase = 4 // как OPENING_AUCTION перед закрытием
+)
+
+// Enum value maps for SessionPhase.
+var (
+	SessionPhase_name = map[int32]string{
+		0: "PHASE_CLOSED",
+		1: "PHASE_PRE_OPEN",
+		2: "PHASE_OPENING_AUCTION",
+		3: "PHASE_CONTINUOUS",
+		4: "PHASE_CLOSING_AUCTION",
+	}
+	SessionPhase_value = map[string]int32{
+		"PHASE_CLOSED":          0,
+		"PHASE_PRE_OPEN":        1,
+		"PHASE_OPENING_AUCTION": 2,
+		"PHASE_CONTINUOUS":      3,
+		"PHASE_CLOSING_AUCTION": 4,
+	}
+)
+
+func (x SessionPhase) Enum() *SessionPhase {
+	p := new(SessionPhase)
+	*p = x
+	return p
+}
+
+func (x SessionPhase) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SessionPhase) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[4].Descriptor()
+}
+
+func (SessionPhase) Type() protoreflect.EnumType {
+	return &file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[4]
+}
+
+func (x SessionPhase) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SessionPhase.Descriptor instead.
+func (SessionPhase) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{4}
+}
+
+type InstrumentStatus int32
+
+const (
+	InstrumentStatus_STATUS_TRADING  InstrumentStatus = 0
+	InstrumentStatus_STATUS_HALTED   InstrumentStatus = 1 // торги приостановлены, заявки не принимаются
+	InstrumentStatus_STATUS_DELISTED InstrumentStatus = 2 // торги прекращены
+)
+
+// Enum value maps for InstrumentStatus.
+var (
+	InstrumentStatus_name = map[int32]string{
+		0: "STATUS_TRADING",
+		1: "STATUS_HALTED",
+		2: "STATUS_DELISTED",
+	}
+	InstrumentStatus_value = map[string]int32{
+		"STATUS_TRADING":  0,
+		"STATUS_HALTED":   1,
+		"STATUS_DELISTED": 2,
+	}
+)
+
+func (x InstrumentStatus) Enum() *InstrumentStatus {
+	p := new(InstrumentStatus)
+	*p = x
+	return p
+}
+
+func (x InstrumentStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (InstrumentStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[5].Descriptor()
+}
+
+func (InstrumentStatus) Type() protoreflect.EnumType {
+	return &file_pkg_exchange_deal_delivery_exchange_proto_enumTypes[5]
+}
+
+func (x InstrumentStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use InstrumentStatus.Descriptor instead.
+func (InstrumentStatus) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{5}
+}
+
 type OHLCV struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -136,16 +448,23 @@ type Deal struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ID       int64   `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"` // DealID который вернулся вам при простановке заявки
-	BrokerID int32   `protobuf:"varint,2,opt,name=BrokerID,proto3" json:"BrokerID,omitempty"`
-	ClientID int32   `protobuf:"varint,3,opt,name=ClientID,proto3" json:"ClientID,omitempty"`
-	Ticker   string  `protobuf:"bytes,4,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
-	Volume   int32   `protobuf:"varint,5,opt,name=Volume,proto3" json:"Volume,omitempty"`   // сколько купили-продали
-	Partial  bool    `protobuf:"varint,6,opt,name=Partial,proto3" json:"Partial,omitempty"` // флаг что сделка клиента исполнилсь частично
-	Time     int32   `protobuf:"varint,7,opt,name=Time,proto3" json:"Time,omitempty"`
-	Price    float32 `protobuf:"fixed32,8,opt,name=Price,proto3" json:"Price,omitempty"`
-	Type     string  `protobuf:"bytes,9,opt,name=Type,proto3" json:"Type,omitempty"`
-	OrderID  int64   `protobuf:"varint,10,opt,name=OrderID,proto3" json:"OrderID,omitempty"`
+	ID             int64       `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"` // DealID который вернулся вам при простановке заявки
+	BrokerID       int32       `protobuf:"varint,2,opt,name=BrokerID,proto3" json:"BrokerID,omitempty"`
+	ClientID       int32       `protobuf:"varint,3,opt,name=ClientID,proto3" json:"ClientID,omitempty"`
+	Ticker         string      `protobuf:"bytes,4,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	Volume         int32       `protobuf:"varint,5,opt,name=Volume,proto3" json:"Volume,omitempty"`   // сколько купили-продали
+	Partial        bool        `protobuf:"varint,6,opt,name=Partial,proto3" json:"Partial,omitempty"` // флаг что сделка клиента исполнилсь частично
+	Time           int32       `protobuf:"varint,7,opt,name=Time,proto3" json:"Time,omitempty"`
+	Price          float32     `protobuf:"fixed32,8,opt,name=Price,proto3" json:"Price,omitempty"`
+	Type           string      `protobuf:"bytes,9,opt,name=Type,proto3" json:"Type,omitempty"`
+	OrderID        int64       `protobuf:"varint,10,opt,name=OrderID,proto3" json:"OrderID,omitempty"`
+	Side           Side        `protobuf:"varint,11,opt,name=Side,proto3,enum=Side" json:"Side,omitempty"`
+	Kind           OrderKind   `protobuf:"varint,12,opt,name=Kind,proto3,enum=OrderKind" json:"Kind,omitempty"`
+	Event          DealEvent   `protobuf:"varint,13,opt,name=Event,proto3,enum=DealEvent" json:"Event,omitempty"`
+	StopPrice      float32     `protobuf:"fixed32,14,opt,name=StopPrice,proto3" json:"StopPrice,omitempty"`
+	TimeInForce    TimeInForce `protobuf:"varint,15,opt,name=TimeInForce,proto3,enum=TimeInForce" json:"TimeInForce,omitempty"`
+	ExpiresAt      int32       `protobuf:"varint,16,opt,name=ExpiresAt,proto3" json:"ExpiresAt,omitempty"`          // для TIF_GTD, unix time
+	IdempotencyKey string      `protobuf:"bytes,17,opt,name=IdempotencyKey,proto3" json:"IdempotencyKey,omitempty"` // для Create: повторная отправка с тем же ключом вернет уже созданную заявку
 }
 
 func (x *Deal) Reset() {
@@ -250,6 +569,55 @@ func (x *Deal) GetOrderID() int64 {
 	return 0
 }
 
+func (x *Deal) GetSide() Side {
+	if x != nil {
+		return x.Side
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func (x *Deal) GetKind() OrderKind {
+	if x != nil {
+		return x.Kind
+	}
+	return OrderKind_KIND_LIMIT
+}
+
+func (x *Deal) GetEvent() DealEvent {
+	if x != nil {
+		return x.Event
+	}
+	return DealEvent_EVENT_FILL
+}
+
+func (x *Deal) GetStopPrice() float32 {
+	if x != nil {
+		return x.StopPrice
+	}
+	return 0
+}
+
+func (x *Deal) GetTimeInForce() TimeInForce {
+	if x != nil {
+		return x.TimeInForce
+	}
+	return TimeInForce_TIF_GTC
+}
+
+func (x *Deal) GetExpiresAt() int32 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *Deal) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
 type DealID struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -305,18 +673,82 @@ func (x *DealID) GetBrokerID() int64 {
 	return 0
 }
 
+type StatisticRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BrokerID int64    `protobuf:"varint,1,opt,name=BrokerID,proto3" json:"BrokerID,omitempty"` // совпадает с BrokerID.ID, старые брокеры получают все инструменты с интервалом торгов
+	Tickers  []string `protobuf:"bytes,2,rep,name=Tickers,proto3" json:"Tickers,omitempty"`    // пустой - все инструменты
+	Interval int32    `protobuf:"varint,3,opt,name=Interval,proto3" json:"Interval,omitempty"` // длительность свечи в секундах, 0 - интервал торгов биржи
+}
+
+func (x *StatisticRequest) Reset() {
+	*x = StatisticRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatisticRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatisticRequest) ProtoMessage() {}
+
+func (x *StatisticRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatisticRequest.ProtoReflect.Descriptor instead.
+func (*StatisticRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StatisticRequest) GetBrokerID() int64 {
+	if x != nil {
+		return x.BrokerID
+	}
+	return 0
+}
+
+func (x *StatisticRequest) GetTickers() []string {
+	if x != nil {
+		return x.Tickers
+	}
+	return nil
+}
+
+func (x *StatisticRequest) GetInterval() int32 {
+	if x != nil {
+		return x.Interval
+	}
+	return 0
+}
+
 type BrokerID struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ID int64 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	ID         int64 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	LastDealID int64 `protobuf:"varint,2,opt,name=LastDealID,proto3" json:"LastDealID,omitempty"` // для Results: последняя подтвержденная брокером сделка
 }
 
 func (x *BrokerID) Reset() {
 	*x = BrokerID{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[3]
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -329,7 +761,7 @@ func (x *BrokerID) String() string {
 func (*BrokerID) ProtoMessage() {}
 
 func (x *BrokerID) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[3]
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -342,7 +774,7 @@ func (x *BrokerID) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BrokerID.ProtoReflect.Descriptor instead.
 func (*BrokerID) Descriptor() ([]byte, []int) {
-	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{3}
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *BrokerID) GetID() int64 {
@@ -352,6 +784,13 @@ func (x *BrokerID) GetID() int64 {
 	return 0
 }
 
+func (x *BrokerID) GetLastDealID() int64 {
+	if x != nil {
+		return x.LastDealID
+	}
+	return 0
+}
+
 type CancelResult struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -363,7 +802,7 @@ type CancelResult struct {
 func (x *CancelResult) Reset() {
 	*x = CancelResult{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[4]
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -376,7 +815,7 @@ func (x *CancelResult) String() string {
 func (*CancelResult) ProtoMessage() {}
 
 func (x *CancelResult) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[4]
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -389,7 +828,7 @@ func (x *CancelResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CancelResult.ProtoReflect.Descriptor instead.
 func (*CancelResult) Descriptor() ([]byte, []int) {
-	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{4}
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *CancelResult) GetSuccess() bool {
@@ -399,59 +838,1012 @@ func (x *CancelResult) GetSuccess() bool {
 	return false
 }
 
-var File_pkg_exchange_deal_delivery_exchange_proto protoreflect.FileDescriptor
+type ReplaceRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_pkg_exchange_deal_delivery_exchange_proto_rawDesc = []byte{
-	0x0a, 0x29, 0x70, 0x6b, 0x67, 0x2f, 0x65, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2f, 0x64,
-	0x65, 0x61, 0x6c, 0x2f, 0x64, 0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x2f, 0x65, 0x78, 0x63,
-	0x68, 0x61, 0x6e, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xc7, 0x01, 0x0a, 0x05,
-	0x4f, 0x48, 0x4c, 0x43, 0x56, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x49, 0x6e, 0x74,
-	0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x49, 0x6e, 0x74,
-	0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x4f, 0x70, 0x65, 0x6e, 0x18, 0x04, 0x20,
-	0x01, 0x28, 0x02, 0x52, 0x04, 0x4f, 0x70, 0x65, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x48, 0x69, 0x67,
-	0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x02, 0x52, 0x04, 0x48, 0x69, 0x67, 0x68, 0x12, 0x10, 0x0a,
-	0x03, 0x4c, 0x6f, 0x77, 0x18, 0x06, 0x20, 0x01, 0x28, 0x02, 0x52, 0x03, 0x4c, 0x6f, 0x77, 0x12,
-	0x14, 0x0a, 0x05, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05,
-	0x43, 0x6c, 0x6f, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18,
-	0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x16, 0x0a,
-	0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54,
-	0x69, 0x63, 0x6b, 0x65, 0x72, 0x22, 0xf0, 0x01, 0x0a, 0x04, 0x44, 0x65, 0x61, 0x6c, 0x12, 0x0e,
-	0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x1a,
-	0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x43, 0x6c,
-	0x69, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x43, 0x6c,
-	0x69, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72,
-	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x16,
-	0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06,
-	0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61,
-	0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c,
-	0x12, 0x12, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04,
-	0x54, 0x69, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x08, 0x20,
-	0x01, 0x28, 0x02, 0x52, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x79,
-	0x70, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18,
+	ID     int64   `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`         // DealID заявки
+	Price  float32 `protobuf:"fixed32,2,opt,name=Price,proto3" json:"Price,omitempty"`  // 0 - цена не меняется
+	Volume int32   `protobuf:"varint,4,opt,name=Volume,proto3" json:"Volume,omitempty"` // новый объем заявки вместе с исполненным, 0 - не меняется
+}
+
+func (x *ReplaceRequest) Reset() {
+	*x = ReplaceRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReplaceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplaceRequest) ProtoMessage() {}
+
+func (x *ReplaceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplaceRequest.ProtoReflect.Descriptor instead.
+func (*ReplaceRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ReplaceRequest) GetID() int64 {
+	if x != nil {
+		return x.ID
+	}
+	return 0
+}
+
+func (x *ReplaceRequest) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *ReplaceRequest) GetVolume() int32 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+// ReplaceResult - заявка после изменения, брокер сверяет с ней свою
+type ReplaceResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success         bool    `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Price           float32 `protobuf:"fixed32,2,opt,name=Price,proto3" json:"Price,omitempty"`
+	Volume          int32   `protobuf:"varint,3,opt,name=Volume,proto3" json:"Volume,omitempty"`                   // объем заявки вместе с исполненным
+	CompletedVolume int32   `protobuf:"varint,4,opt,name=CompletedVolume,proto3" json:"CompletedVolume,omitempty"` // исполнено на бирже к моменту изменения
+}
+
+func (x *ReplaceResult) Reset() {
+	*x = ReplaceResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReplaceResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplaceResult) ProtoMessage() {}
+
+func (x *ReplaceResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplaceResult.ProtoReflect.Descriptor instead.
+func (*ReplaceResult) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ReplaceResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReplaceResult) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *ReplaceResult) GetVolume() int32 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *ReplaceResult) GetCompletedVolume() int32 {
+	if x != nil {
+		return x.CompletedVolume
+	}
+	return 0
+}
+
+type DealAck struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BrokerID int64 `protobuf:"varint,1,opt,name=BrokerID,proto3" json:"BrokerID,omitempty"`
+	DealID   int64 `protobuf:"varint,2,opt,name=DealID,proto3" json:"DealID,omitempty"` // подтверждает эту и все предыдущие сделки брокера
+}
+
+func (x *DealAck) Reset() {
+	*x = DealAck{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DealAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DealAck) ProtoMessage() {}
+
+func (x *DealAck) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DealAck.ProtoReflect.Descriptor instead.
+func (*DealAck) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DealAck) GetBrokerID() int64 {
+	if x != nil {
+		return x.BrokerID
+	}
+	return 0
+}
+
+func (x *DealAck) GetDealID() int64 {
+	if x != nil {
+		return x.DealID
+	}
+	return 0
+}
+
+type AckResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success bool `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+}
+
+func (x *AckResult) Reset() {
+	*x = AckResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AckResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AckResult) ProtoMessage() {}
+
+func (x *AckResult) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AckResult.ProtoReflect.Descriptor instead.
+func (*AckResult) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AckResult) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+type TradePrint struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticker string  `protobuf:"bytes,1,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	Price  float32 `protobuf:"fixed32,2,opt,name=Price,proto3" json:"Price,omitempty"`
+	Volume int32   `protobuf:"varint,3,opt,name=Volume,proto3" json:"Volume,omitempty"`
+	Side   Side    `protobuf:"varint,4,opt,name=Side,proto3,enum=Side" json:"Side,omitempty"` // сторона инициатора сделки
+	Time   int32   `protobuf:"varint,5,opt,name=Time,proto3" json:"Time,omitempty"`           // время биржи по часам, и у сделок из внешнего потока
+}
+
+func (x *TradePrint) Reset() {
+	*x = TradePrint{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TradePrint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TradePrint) ProtoMessage() {}
+
+func (x *TradePrint) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TradePrint.ProtoReflect.Descriptor instead.
+func (*TradePrint) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TradePrint) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *TradePrint) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *TradePrint) GetVolume() int32 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *TradePrint) GetSide() Side {
+	if x != nil {
+		return x.Side
+	}
+	return Side_SIDE_UNSPECIFIED
+}
+
+func (x *TradePrint) GetTime() int32 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+type SessionEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Phase     SessionPhase `protobuf:"varint,1,opt,name=Phase,proto3,enum=SessionPhase" json:"Phase,omitempty"`
+	Time      int32        `protobuf:"varint,2,opt,name=Time,proto3" json:"Time,omitempty"` // начало фазы
+	NextPhase SessionPhase `protobuf:"varint,3,opt,name=NextPhase,proto3,enum=SessionPhase" json:"NextPhase,omitempty"`
+	NextTime  int32        `protobuf:"varint,4,opt,name=NextTime,proto3" json:"NextTime,omitempty"` // 0 - смены фазы не будет
+}
+
+func (x *SessionEvent) Reset() {
+	*x = SessionEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SessionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionEvent) ProtoMessage() {}
+
+func (x *SessionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionEvent.ProtoReflect.Descriptor instead.
+func (*SessionEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SessionEvent) GetPhase() SessionPhase {
+	if x != nil {
+		return x.Phase
+	}
+	return SessionPhase_PHASE_CLOSED
+}
+
+func (x *SessionEvent) GetTime() int32 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *SessionEvent) GetNextPhase() SessionPhase {
+	if x != nil {
+		return x.NextPhase
+	}
+	return SessionPhase_PHASE_CLOSED
+}
+
+func (x *SessionEvent) GetNextTime() int32 {
+	if x != nil {
+		return x.NextTime
+	}
+	return 0
+}
+
+type Instrument struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticker      string           `protobuf:"bytes,1,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	Description string           `protobuf:"bytes,2,opt,name=Description,proto3" json:"Description,omitempty"`
+	TickSize    float32          `protobuf:"fixed32,3,opt,name=TickSize,proto3" json:"TickSize,omitempty"` // цены заявки кратны шагу
+	LotSize     int32            `protobuf:"varint,4,opt,name=LotSize,proto3" json:"LotSize,omitempty"`    // объем заявки кратен лоту
+	Currency    string           `protobuf:"bytes,5,opt,name=Currency,proto3" json:"Currency,omitempty"`
+	PriceMin    float32          `protobuf:"fixed32,6,opt,name=PriceMin,proto3" json:"PriceMin,omitempty"` // статические ценовые границы, 0 - без ограничения
+	PriceMax    float32          `protobuf:"fixed32,7,opt,name=PriceMax,proto3" json:"PriceMax,omitempty"`
+	Status      InstrumentStatus `protobuf:"varint,8,opt,name=Status,proto3,enum=InstrumentStatus" json:"Status,omitempty"`
+}
+
+func (x *Instrument) Reset() {
+	*x = Instrument{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Instrument) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Instrument) ProtoMessage() {}
+
+func (x *Instrument) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Instrument.ProtoReflect.Descriptor instead.
+func (*Instrument) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Instrument) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *Instrument) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Instrument) GetTickSize() float32 {
+	if x != nil {
+		return x.TickSize
+	}
+	return 0
+}
+
+func (x *Instrument) GetLotSize() int32 {
+	if x != nil {
+		return x.LotSize
+	}
+	return 0
+}
+
+func (x *Instrument) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Instrument) GetPriceMin() float32 {
+	if x != nil {
+		return x.PriceMin
+	}
+	return 0
+}
+
+func (x *Instrument) GetPriceMax() float32 {
+	if x != nil {
+		return x.PriceMax
+	}
+	return 0
+}
+
+func (x *Instrument) GetStatus() InstrumentStatus {
+	if x != nil {
+		return x.Status
+	}
+	return InstrumentStatus_STATUS_TRADING
+}
+
+type InstrumentList struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Instruments []*Instrument `protobuf:"bytes,1,rep,name=Instruments,proto3" json:"Instruments,omitempty"`
+}
+
+func (x *InstrumentList) Reset() {
+	*x = InstrumentList{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InstrumentList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstrumentList) ProtoMessage() {}
+
+func (x *InstrumentList) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstrumentList.ProtoReflect.Descriptor instead.
+func (*InstrumentList) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *InstrumentList) GetInstruments() []*Instrument {
+	if x != nil {
+		return x.Instruments
+	}
+	return nil
+}
+
+type HaltEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticker string `protobuf:"bytes,1,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	Halted bool   `protobuf:"varint,2,opt,name=Halted,proto3" json:"Halted,omitempty"` // false - торги возобновлены
+	Time   int32  `protobuf:"varint,3,opt,name=Time,proto3" json:"Time,omitempty"`
+	Until  int32  `protobuf:"varint,4,opt,name=Until,proto3" json:"Until,omitempty"` // время возобновления торгов
+}
+
+func (x *HaltEvent) Reset() {
+	*x = HaltEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HaltEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HaltEvent) ProtoMessage() {}
+
+func (x *HaltEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HaltEvent.ProtoReflect.Descriptor instead.
+func (*HaltEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *HaltEvent) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *HaltEvent) GetHalted() bool {
+	if x != nil {
+		return x.Halted
+	}
+	return false
+}
+
+func (x *HaltEvent) GetTime() int32 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *HaltEvent) GetUntil() int32 {
+	if x != nil {
+		return x.Until
+	}
+	return 0
+}
+
+type DepthRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BrokerID int64  `protobuf:"varint,1,opt,name=BrokerID,proto3" json:"BrokerID,omitempty"`
+	Ticker   string `protobuf:"bytes,2,opt,name=Ticker,proto3" json:"Ticker,omitempty"` // пустой - все инструменты
+}
+
+func (x *DepthRequest) Reset() {
+	*x = DepthRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DepthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DepthRequest) ProtoMessage() {}
+
+func (x *DepthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DepthRequest.ProtoReflect.Descriptor instead.
+func (*DepthRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DepthRequest) GetBrokerID() int64 {
+	if x != nil {
+		return x.BrokerID
+	}
+	return 0
+}
+
+func (x *DepthRequest) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+type PriceLevel struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Price  float32 `protobuf:"fixed32,1,opt,name=Price,proto3" json:"Price,omitempty"`
+	Volume int32   `protobuf:"varint,2,opt,name=Volume,proto3" json:"Volume,omitempty"` // суммарный остаток заявок на уровне, 0 - уровень удален
+}
+
+func (x *PriceLevel) Reset() {
+	*x = PriceLevel{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PriceLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceLevel) ProtoMessage() {}
+
+func (x *PriceLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceLevel.ProtoReflect.Descriptor instead.
+func (*PriceLevel) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PriceLevel) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *PriceLevel) GetVolume() int32 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+type DepthUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticker   string        `protobuf:"bytes,1,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	Snapshot bool          `protobuf:"varint,2,opt,name=Snapshot,proto3" json:"Snapshot,omitempty"` // полный стакан инструмента, ранее полученные уровни сбрасываются
+	Bids     []*PriceLevel `protobuf:"bytes,3,rep,name=Bids,proto3" json:"Bids,omitempty"`
+	Asks     []*PriceLevel `protobuf:"bytes,4,rep,name=Asks,proto3" json:"Asks,omitempty"`
+	Sequence int64         `protobuf:"varint,5,opt,name=Sequence,proto3" json:"Sequence,omitempty"` // растет на 1 с каждым изменением стакана инструмента, по пропуску видна потеря обновлений
+}
+
+func (x *DepthUpdate) Reset() {
+	*x = DepthUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DepthUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DepthUpdate) ProtoMessage() {}
+
+func (x *DepthUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DepthUpdate.ProtoReflect.Descriptor instead.
+func (*DepthUpdate) Descriptor() ([]byte, []int) {
+	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DepthUpdate) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *DepthUpdate) GetSnapshot() bool {
+	if x != nil {
+		return x.Snapshot
+	}
+	return false
+}
+
+func (x *DepthUpdate) GetBids() []*PriceLevel {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+func (x *DepthUpdate) GetAsks() []*PriceLevel {
+	if x != nil {
+		return x.Asks
+	}
+	return nil
+}
+
+func (x *DepthUpdate) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+var File_pkg_exchange_deal_delivery_exchange_proto protoreflect.FileDescriptor
+
+var file_pkg_exchange_deal_delivery_exchange_proto_rawDesc = []byte{
+	0x0a, 0x29, 0x70, 0x6b, 0x67, 0x2f, 0x65, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2f, 0x64,
+	0x65, 0x61, 0x6c, 0x2f, 0x64, 0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x2f, 0x65, 0x78, 0x63,
+	0x68, 0x61, 0x6e, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xc7, 0x01, 0x0a, 0x05,
+	0x4f, 0x48, 0x4c, 0x43, 0x56, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x49, 0x6e, 0x74,
+	0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x49, 0x6e, 0x74,
+	0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x4f, 0x70, 0x65, 0x6e, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x02, 0x52, 0x04, 0x4f, 0x70, 0x65, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x48, 0x69, 0x67,
+	0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x02, 0x52, 0x04, 0x48, 0x69, 0x67, 0x68, 0x12, 0x10, 0x0a,
+	0x03, 0x4c, 0x6f, 0x77, 0x18, 0x06, 0x20, 0x01, 0x28, 0x02, 0x52, 0x03, 0x4c, 0x6f, 0x77, 0x12,
+	0x14, 0x0a, 0x05, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05,
+	0x43, 0x6c, 0x6f, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54,
+	0x69, 0x63, 0x6b, 0x65, 0x72, 0x22, 0xe1, 0x03, 0x0a, 0x04, 0x44, 0x65, 0x61, 0x6c, 0x12, 0x0e,
+	0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x1a,
+	0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x43, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x43, 0x6c,
+	0x69, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x16,
+	0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06,
+	0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61,
+	0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c,
+	0x12, 0x12, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04,
+	0x54, 0x69, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x02, 0x52, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x79,
+	0x70, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18,
 	0x0a, 0x07, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x44, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x52,
-	0x07, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x44, 0x22, 0x34, 0x0a, 0x06, 0x44, 0x65, 0x61, 0x6c,
-	0x49, 0x44, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02,
-	0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x22, 0x1a,
-	0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x22, 0x28, 0x0a, 0x0c, 0x43, 0x61,
-	0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75,
-	0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63,
-	0x63, 0x65, 0x73, 0x73, 0x32, 0x8f, 0x01, 0x0a, 0x08, 0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67,
-	0x65, 0x12, 0x22, 0x0a, 0x09, 0x53, 0x74, 0x61, 0x74, 0x69, 0x73, 0x74, 0x69, 0x63, 0x12, 0x09,
-	0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x06, 0x2e, 0x4f, 0x48, 0x4c, 0x43,
-	0x56, 0x22, 0x00, 0x30, 0x01, 0x12, 0x1a, 0x0a, 0x06, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x12,
-	0x05, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x1a, 0x07, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x49, 0x44, 0x22,
-	0x00, 0x12, 0x22, 0x0a, 0x06, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x12, 0x07, 0x2e, 0x44, 0x65,
-	0x61, 0x6c, 0x49, 0x44, 0x1a, 0x0d, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x73,
-	0x75, 0x6c, 0x74, 0x22, 0x00, 0x12, 0x1f, 0x0a, 0x07, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73,
-	0x12, 0x09, 0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x05, 0x2e, 0x44, 0x65,
-	0x61, 0x6c, 0x22, 0x00, 0x30, 0x01, 0x42, 0x1c, 0x5a, 0x1a, 0x70, 0x6b, 0x67, 0x2f, 0x65, 0x78,
-	0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2f, 0x64, 0x65, 0x61, 0x6c, 0x2f, 0x64, 0x65, 0x6c, 0x69,
-	0x76, 0x65, 0x72, 0x79, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x07, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x44, 0x12, 0x19, 0x0a, 0x04, 0x53, 0x69, 0x64, 0x65,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x05, 0x2e, 0x53, 0x69, 0x64, 0x65, 0x52, 0x04, 0x53,
+	0x69, 0x64, 0x65, 0x12, 0x1e, 0x0a, 0x04, 0x4b, 0x69, 0x6e, 0x64, 0x18, 0x0c, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x0a, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x4b, 0x69, 0x6e, 0x64, 0x52, 0x04, 0x4b,
+	0x69, 0x6e, 0x64, 0x12, 0x20, 0x0a, 0x05, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x18, 0x0d, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x0a, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x05,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x53, 0x74, 0x6f, 0x70, 0x50, 0x72, 0x69,
+	0x63, 0x65, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x02, 0x52, 0x09, 0x53, 0x74, 0x6f, 0x70, 0x50, 0x72,
+	0x69, 0x63, 0x65, 0x12, 0x2e, 0x0a, 0x0b, 0x54, 0x69, 0x6d, 0x65, 0x49, 0x6e, 0x46, 0x6f, 0x72,
+	0x63, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0c, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x49,
+	0x6e, 0x46, 0x6f, 0x72, 0x63, 0x65, 0x52, 0x0b, 0x54, 0x69, 0x6d, 0x65, 0x49, 0x6e, 0x46, 0x6f,
+	0x72, 0x63, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74,
+	0x18, 0x10, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41,
+	0x74, 0x12, 0x26, 0x0a, 0x0e, 0x49, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79,
+	0x4b, 0x65, 0x79, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x49, 0x64, 0x65, 0x6d, 0x70,
+	0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4b, 0x65, 0x79, 0x22, 0x34, 0x0a, 0x06, 0x44, 0x65, 0x61,
+	0x6c, 0x49, 0x44, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x02, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x22,
+	0x64, 0x0a, 0x10, 0x53, 0x74, 0x61, 0x74, 0x69, 0x73, 0x74, 0x69, 0x63, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x12,
+	0x18, 0x0a, 0x07, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x07, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x49, 0x6e, 0x74,
+	0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x49, 0x6e, 0x74,
+	0x65, 0x72, 0x76, 0x61, 0x6c, 0x22, 0x3a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49,
+	0x44, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49,
+	0x44, 0x12, 0x1e, 0x0a, 0x0a, 0x4c, 0x61, 0x73, 0x74, 0x44, 0x65, 0x61, 0x6c, 0x49, 0x44, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x4c, 0x61, 0x73, 0x74, 0x44, 0x65, 0x61, 0x6c, 0x49,
+	0x44, 0x22, 0x28, 0x0a, 0x0c, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x22, 0x54, 0x0a, 0x0e, 0x52,
+	0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a,
+	0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x14, 0x0a,
+	0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x50, 0x72,
+	0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x4a, 0x04, 0x08, 0x03, 0x10,
+	0x04, 0x22, 0x81, 0x01, 0x0a, 0x0d, 0x52, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x14, 0x0a,
+	0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x50, 0x72,
+	0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x28, 0x0a, 0x0f, 0x43,
+	0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x56,
+	0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x22, 0x3d, 0x0a, 0x07, 0x44, 0x65, 0x61, 0x6c, 0x41, 0x63, 0x6b,
+	0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06,
+	0x44, 0x65, 0x61, 0x6c, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x44, 0x65,
+	0x61, 0x6c, 0x49, 0x44, 0x22, 0x25, 0x0a, 0x09, 0x41, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x22, 0x81, 0x01, 0x0a, 0x0a,
+	0x54, 0x72, 0x61, 0x64, 0x65, 0x50, 0x72, 0x69, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69,
+	0x63, 0x6b, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b,
+	0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x02, 0x52, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75,
+	0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65,
+	0x12, 0x19, 0x0a, 0x04, 0x53, 0x69, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x05,
+	0x2e, 0x53, 0x69, 0x64, 0x65, 0x52, 0x04, 0x53, 0x69, 0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x54,
+	0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x22,
+	0x90, 0x01, 0x0a, 0x0c, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x12, 0x23, 0x0a, 0x05, 0x50, 0x68, 0x61, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x0d, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x52, 0x05,
+	0x50, 0x68, 0x61, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x2b, 0x0a, 0x09, 0x4e, 0x65, 0x78,
+	0x74, 0x50, 0x68, 0x61, 0x73, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x53,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x52, 0x09, 0x4e, 0x65, 0x78,
+	0x74, 0x50, 0x68, 0x61, 0x73, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x4e, 0x65, 0x78, 0x74, 0x54, 0x69,
+	0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x4e, 0x65, 0x78, 0x74, 0x54, 0x69,
+	0x6d, 0x65, 0x22, 0xfb, 0x01, 0x0a, 0x0a, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x6d, 0x65, 0x6e,
+	0x74, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x20, 0x0a, 0x0b, 0x44, 0x65, 0x73,
+	0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b,
+	0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x54,
+	0x69, 0x63, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x54,
+	0x69, 0x63, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x4c, 0x6f, 0x74, 0x53, 0x69,
+	0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x4c, 0x6f, 0x74, 0x53, 0x69, 0x7a,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x43, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x1a, 0x0a,
+	0x08, 0x50, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x02, 0x52,
+	0x08, 0x50, 0x72, 0x69, 0x63, 0x65, 0x4d, 0x69, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x50, 0x72, 0x69,
+	0x63, 0x65, 0x4d, 0x61, 0x78, 0x18, 0x07, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x50, 0x72, 0x69,
+	0x63, 0x65, 0x4d, 0x61, 0x78, 0x12, 0x29, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x6d, 0x65,
+	0x6e, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x22, 0x3f, 0x0a, 0x0e, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x4c, 0x69,
+	0x73, 0x74, 0x12, 0x2d, 0x0a, 0x0b, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75,
+	0x6d, 0x65, 0x6e, 0x74, 0x52, 0x0b, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74,
+	0x73, 0x22, 0x65, 0x0a, 0x09, 0x48, 0x61, 0x6c, 0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x16,
+	0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x48, 0x61, 0x6c, 0x74, 0x65, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x48, 0x61, 0x6c, 0x74, 0x65, 0x64, 0x12, 0x12,
+	0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69,
+	0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x55, 0x6e, 0x74, 0x69, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x05, 0x55, 0x6e, 0x74, 0x69, 0x6c, 0x22, 0x42, 0x0a, 0x0c, 0x44, 0x65, 0x70, 0x74,
+	0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b,
+	0x65, 0x72, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b,
+	0x65, 0x72, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x22, 0x3a, 0x0a, 0x0a,
+	0x50, 0x72, 0x69, 0x63, 0x65, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x12, 0x14, 0x0a, 0x05, 0x50, 0x72,
+	0x69, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x22, 0x9f, 0x01, 0x0a, 0x0b, 0x44, 0x65, 0x70,
+	0x74, 0x68, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b,
+	0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72,
+	0x12, 0x1a, 0x0a, 0x08, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x08, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x1f, 0x0a, 0x04,
+	0x42, 0x69, 0x64, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x50, 0x72, 0x69,
+	0x63, 0x65, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x52, 0x04, 0x42, 0x69, 0x64, 0x73, 0x12, 0x1f, 0x0a,
+	0x04, 0x41, 0x73, 0x6b, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x50, 0x72,
+	0x69, 0x63, 0x65, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x52, 0x04, 0x41, 0x73, 0x6b, 0x73, 0x12, 0x1a,
+	0x0a, 0x08, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x65, 0x2a, 0x39, 0x0a, 0x04, 0x53, 0x69,
+	0x64, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x53, 0x49, 0x44, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45,
+	0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x49, 0x44, 0x45,
+	0x5f, 0x42, 0x55, 0x59, 0x10, 0x01, 0x12, 0x0d, 0x0a, 0x09, 0x53, 0x49, 0x44, 0x45, 0x5f, 0x53,
+	0x45, 0x4c, 0x4c, 0x10, 0x02, 0x2a, 0x6c, 0x0a, 0x09, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x4b, 0x69,
+	0x6e, 0x64, 0x12, 0x0e, 0x0a, 0x0a, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x4c, 0x49, 0x4d, 0x49, 0x54,
+	0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x4d, 0x41, 0x52, 0x4b, 0x45,
+	0x54, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x49, 0x4f, 0x43, 0x10,
+	0x02, 0x12, 0x0c, 0x0a, 0x08, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x46, 0x4f, 0x4b, 0x10, 0x03, 0x12,
+	0x0d, 0x0a, 0x09, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x53, 0x54, 0x4f, 0x50, 0x10, 0x04, 0x12, 0x13,
+	0x0a, 0x0f, 0x4b, 0x49, 0x4e, 0x44, 0x5f, 0x53, 0x54, 0x4f, 0x50, 0x5f, 0x4c, 0x49, 0x4d, 0x49,
+	0x54, 0x10, 0x05, 0x2a, 0x34, 0x0a, 0x0b, 0x54, 0x69, 0x6d, 0x65, 0x49, 0x6e, 0x46, 0x6f, 0x72,
+	0x63, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x54, 0x49, 0x46, 0x5f, 0x47, 0x54, 0x43, 0x10, 0x00, 0x12,
+	0x0b, 0x0a, 0x07, 0x54, 0x49, 0x46, 0x5f, 0x44, 0x41, 0x59, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07,
+	0x54, 0x49, 0x46, 0x5f, 0x47, 0x54, 0x44, 0x10, 0x02, 0x2a, 0x52, 0x0a, 0x09, 0x44, 0x65, 0x61,
+	0x6c, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x0e, 0x0a, 0x0a, 0x45, 0x56, 0x45, 0x4e, 0x54, 0x5f,
+	0x46, 0x49, 0x4c, 0x4c, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x45, 0x56, 0x45, 0x4e, 0x54, 0x5f,
+	0x43, 0x41, 0x4e, 0x43, 0x45, 0x4c, 0x10, 0x01, 0x12, 0x11, 0x0a, 0x0d, 0x45, 0x56, 0x45, 0x4e,
+	0x54, 0x5f, 0x54, 0x52, 0x49, 0x47, 0x47, 0x45, 0x52, 0x10, 0x02, 0x12, 0x10, 0x0a, 0x0c, 0x45,
+	0x56, 0x45, 0x4e, 0x54, 0x5f, 0x45, 0x58, 0x50, 0x49, 0x52, 0x45, 0x10, 0x03, 0x2a, 0x80, 0x01,
+	0x0a, 0x0c, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x12, 0x10,
+	0x0a, 0x0c, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x43, 0x4c, 0x4f, 0x53, 0x45, 0x44, 0x10, 0x00,
+	0x12, 0x12, 0x0a, 0x0e, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x50, 0x52, 0x45, 0x5f, 0x4f, 0x50,
+	0x45, 0x4e, 0x10, 0x01, 0x12, 0x19, 0x0a, 0x15, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x4f, 0x50,
+	0x45, 0x4e, 0x49, 0x4e, 0x47, 0x5f, 0x41, 0x55, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x10, 0x02, 0x12,
+	0x14, 0x0a, 0x10, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x43, 0x4f, 0x4e, 0x54, 0x49, 0x4e, 0x55,
+	0x4f, 0x55, 0x53, 0x10, 0x03, 0x12, 0x19, 0x0a, 0x15, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x43,
+	0x4c, 0x4f, 0x53, 0x49, 0x4e, 0x47, 0x5f, 0x41, 0x55, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x10, 0x04,
+	0x2a, 0x4e, 0x0a, 0x10, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x0e, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x54,
+	0x52, 0x41, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x11, 0x0a, 0x0d, 0x53, 0x54, 0x41, 0x54,
+	0x55, 0x53, 0x5f, 0x48, 0x41, 0x4c, 0x54, 0x45, 0x44, 0x10, 0x01, 0x12, 0x13, 0x0a, 0x0f, 0x53,
+	0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x44, 0x45, 0x4c, 0x49, 0x53, 0x54, 0x45, 0x44, 0x10, 0x02,
+	0x32, 0xb6, 0x03, 0x0a, 0x08, 0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x2a, 0x0a,
+	0x09, 0x53, 0x74, 0x61, 0x74, 0x69, 0x73, 0x74, 0x69, 0x63, 0x12, 0x11, 0x2e, 0x53, 0x74, 0x61,
+	0x74, 0x69, 0x73, 0x74, 0x69, 0x63, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x06, 0x2e,
+	0x4f, 0x48, 0x4c, 0x43, 0x56, 0x22, 0x00, 0x30, 0x01, 0x12, 0x1a, 0x0a, 0x06, 0x43, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x12, 0x05, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x1a, 0x07, 0x2e, 0x44, 0x65, 0x61,
+	0x6c, 0x49, 0x44, 0x22, 0x00, 0x12, 0x22, 0x0a, 0x06, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x12,
+	0x07, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x49, 0x44, 0x1a, 0x0d, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65,
+	0x6c, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0x00, 0x12, 0x2c, 0x0a, 0x07, 0x52, 0x65, 0x70,
+	0x6c, 0x61, 0x63, 0x65, 0x12, 0x0f, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0x00, 0x12, 0x1f, 0x0a, 0x07, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x73, 0x12, 0x09, 0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x05, 0x2e,
+	0x44, 0x65, 0x61, 0x6c, 0x22, 0x00, 0x30, 0x01, 0x12, 0x1d, 0x0a, 0x03, 0x41, 0x63, 0x6b, 0x12,
+	0x08, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x41, 0x63, 0x6b, 0x1a, 0x0a, 0x2e, 0x41, 0x63, 0x6b, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0x00, 0x12, 0x2c, 0x0a, 0x09, 0x4f, 0x72, 0x64, 0x65, 0x72,
+	0x42, 0x6f, 0x6f, 0x6b, 0x12, 0x0d, 0x2e, 0x44, 0x65, 0x70, 0x74, 0x68, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x44, 0x65, 0x70, 0x74, 0x68, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x22, 0x00, 0x30, 0x01, 0x12, 0x24, 0x0a, 0x06, 0x54, 0x72, 0x61, 0x64, 0x65, 0x73, 0x12,
+	0x09, 0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x0b, 0x2e, 0x54, 0x72, 0x61,
+	0x64, 0x65, 0x50, 0x72, 0x69, 0x6e, 0x74, 0x22, 0x00, 0x30, 0x01, 0x12, 0x27, 0x0a, 0x07, 0x53,
+	0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x09, 0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49,
+	0x44, 0x1a, 0x0d, 0x2e, 0x53, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x22, 0x00, 0x30, 0x01, 0x12, 0x2f, 0x0a, 0x0f, 0x4c, 0x69, 0x73, 0x74, 0x49, 0x6e, 0x73, 0x74,
+	0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x09, 0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72,
+	0x49, 0x44, 0x1a, 0x0f, 0x2e, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x4c,
+	0x69, 0x73, 0x74, 0x22, 0x00, 0x12, 0x22, 0x0a, 0x05, 0x48, 0x61, 0x6c, 0x74, 0x73, 0x12, 0x09,
+	0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x0a, 0x2e, 0x48, 0x61, 0x6c, 0x74,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x30, 0x01, 0x42, 0x1c, 0x5a, 0x1a, 0x70, 0x6b, 0x67,
+	0x2f, 0x65, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2f, 0x64, 0x65, 0x61, 0x6c, 0x2f, 0x64,
+	0x65, 0x6c, 0x69, 0x76, 0x65, 0x72, 0x79, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -466,28 +1858,73 @@ func file_pkg_exchange_deal_delivery_exchange_proto_rawDescGZIP() []byte {
 	return file_pkg_exchange_deal_delivery_exchange_proto_rawDescData
 }
 
-var file_pkg_exchange_deal_delivery_exchange_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_pkg_exchange_deal_delivery_exchange_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
+var file_pkg_exchange_deal_delivery_exchange_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
 var file_pkg_exchange_deal_delivery_exchange_proto_goTypes = []interface{}{
-	(*OHLCV)(nil),        // 0: OHLCV
-	(*Deal)(nil),         // 1: Deal
-	(*DealID)(nil),       // 2: DealID
-	(*BrokerID)(nil),     // 3: BrokerID
-	(*CancelResult)(nil), // 4: CancelResult
+	(Side)(0),                // 0: Side
+	(OrderKind)(0),           // 1: OrderKind
+	(TimeInForce)(0),         // 2: TimeInForce
+	(DealEvent)(0),           // 3: DealEvent
+	(SessionPhase)(0),        // 4: SessionPhase
+	(InstrumentStatus)(0),    // 5: InstrumentStatus
+	(*OHLCV)(nil),            // 6: OHLCV
+	(*Deal)(nil),             // 7: Deal
+	(*DealID)(nil),           // 8: DealID
+	(*StatisticRequest)(nil), // 9: StatisticRequest
+	(*BrokerID)(nil),         // 10: BrokerID
+	(*CancelResult)(nil),     // 11: CancelResult
+	(*ReplaceRequest)(nil),   // 12: ReplaceRequest
+	(*ReplaceResult)(nil),    // 13: ReplaceResult
+	(*DealAck)(nil),          // 14: DealAck
+	(*AckResult)(nil),        // 15: AckResult
+	(*TradePrint)(nil),       // 16: TradePrint
+	(*SessionEvent)(nil),     // 17: SessionEvent
+	(*Instrument)(nil),       // 18: Instrument
+	(*InstrumentList)(nil),   // 19: InstrumentList
+	(*HaltEvent)(nil),        // 20: HaltEvent
+	(*DepthRequest)(nil),     // 21: DepthRequest
+	(*PriceLevel)(nil),       // 22: PriceLevel
+	(*DepthUpdate)(nil),      // 23: DepthUpdate
 }
 var file_pkg_exchange_deal_delivery_exchange_proto_depIdxs = []int32{
-	3, // 0: Exchange.Statistic:input_type -> BrokerID
-	1, // 1: Exchange.Create:input_type -> Deal
-	2, // 2: Exchange.Cancel:input_type -> DealID
-	3, // 3: Exchange.Results:input_type -> BrokerID
-	0, // 4: Exchange.Statistic:output_type -> OHLCV
-	2, // 5: Exchange.Create:output_type -> DealID
-	4, // 6: Exchange.Cancel:output_type -> CancelResult
-	1, // 7: Exchange.Results:output_type -> Deal
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: Deal.Side:type_name -> Side
+	1,  // 1: Deal.Kind:type_name -> OrderKind
+	3,  // 2: Deal.Event:type_name -> DealEvent
+	2,  // 3: Deal.TimeInForce:type_name -> TimeInForce
+	0,  // 4: TradePrint.Side:type_name -> Side
+	4,  // 5: SessionEvent.Phase:type_name -> SessionPhase
+	4,  // 6: SessionEvent.NextPhase:type_name -> SessionPhase
+	5,  // 7: Instrument.Status:type_name -> InstrumentStatus
+	18, // 8: InstrumentList.Instruments:type_name -> Instrument
+	22, // 9: DepthUpdate.Bids:type_name -> PriceLevel
+	22, // 10: DepthUpdate.Asks:type_name -> PriceLevel
+	9,  // 11: Exchange.Statistic:input_type -> StatisticRequest
+	7,  // 12: Exchange.Create:input_type -> Deal
+	8,  // 13: Exchange.Cancel:input_type -> DealID
+	12, // 14: Exchange.Replace:input_type -> ReplaceRequest
+	10, // 15: Exchange.Results:input_type -> BrokerID
+	14, // 16: Exchange.Ack:input_type -> DealAck
+	21, // 17: Exchange.OrderBook:input_type -> DepthRequest
+	10, // 18: Exchange.Trades:input_type -> BrokerID
+	10, // 19: Exchange.Session:input_type -> BrokerID
+	10, // 20: Exchange.ListInstruments:input_type -> BrokerID
+	10, // 21: Exchange.Halts:input_type -> BrokerID
+	6,  // 22: Exchange.Statistic:output_type -> OHLCV
+	8,  // 23: Exchange.Create:output_type -> DealID
+	11, // 24: Exchange.Cancel:output_type -> CancelResult
+	13, // 25: Exchange.Replace:output_type -> ReplaceResult
+	7,  // 26: Exchange.Results:output_type -> Deal
+	15, // 27: Exchange.Ack:output_type -> AckResult
+	23, // 28: Exchange.OrderBook:output_type -> DepthUpdate
+	16, // 29: Exchange.Trades:output_type -> TradePrint
+	17, // 30: Exchange.Session:output_type -> SessionEvent
+	19, // 31: Exchange.ListInstruments:output_type -> InstrumentList
+	20, // 32: Exchange.Halts:output_type -> HaltEvent
+	22, // [22:33] is the sub-list for method output_type
+	11, // [11:22] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
 }
 
 func init() { file_pkg_exchange_deal_delivery_exchange_proto_init() }
@@ -533,7 +1970,7 @@ func file_pkg_exchange_deal_delivery_exchange_proto_init() {
 			}
 		}
 		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BrokerID); i {
+			switch v := v.(*StatisticRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -545,6 +1982,18 @@ func file_pkg_exchange_deal_delivery_exchange_proto_init() {
 			}
 		}
 		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BrokerID); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*CancelResult); i {
 			case 0:
 				return &v.state
@@ -556,19 +2005,164 @@ func file_pkg_exchange_deal_delivery_exchange_proto_init() {
 				return nil
 			}
 		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReplaceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReplaceResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DealAck); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AckResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TradePrint); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SessionEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Instrument); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InstrumentList); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HaltEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DepthRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PriceLevel); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_pkg_exchange_deal_delivery_exchange_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DepthUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_pkg_exchange_deal_delivery_exchange_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   5,
+			NumEnums:      6,
+			NumMessages:   18,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_pkg_exchange_deal_delivery_exchange_proto_goTypes,
 		DependencyIndexes: file_pkg_exchange_deal_delivery_exchange_proto_depIdxs,
+		EnumInfos:         file_pkg_exchange_deal_delivery_exchange_proto_enumTypes,
 		MessageInfos:      file_pkg_exchange_deal_delivery_exchange_proto_msgTypes,
 	}.Build()
 	File_pkg_exchange_deal_delivery_exchange_proto = out.File
@@ -24,15 +24,38 @@ const _ = grpc.SupportPackageIsVersion7
 type ExchangeClient interface {
 	// поток ценовых данных от биржи к брокеру
 	// мы каждую секнуду будем получать отсюда событие с ценами, которые броке аггрегирует у себя в минуты и показывает клиентам
-	// устанавливается 1 раз брокером
-	Statistic(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_StatisticClient, error)
+	// устанавливается 1 раз брокером, свечи только по запрошенным инструментам и с запрошенным интервалом
+	// подписчик, не успевающий читать, теряет свечи или отключается в зависимости от настройки биржи
+	Statistic(ctx context.Context, in *StatisticRequest, opts ...grpc.CallOption) (Exchange_StatisticClient, error)
 	// отправка на биржу заявки от брокера
 	Create(ctx context.Context, in *Deal, opts ...grpc.CallOption) (*DealID, error)
 	// отмена заявки
 	Cancel(ctx context.Context, in *DealID, opts ...grpc.CallOption) (*CancelResult, error)
+	// изменение цены и/или остатка заявки
+	// при уменьшении остатка заявка сохраняет место в очереди, при смене цены или увеличении остатка - теряет
+	Replace(ctx context.Context, in *ReplaceRequest, opts ...grpc.CallOption) (*ReplaceResult, error)
 	// исполнение заявок от биржи к брокеру
 	// устанавливается 1 раз брокером и при исполнении какой-то заявки
+	// при подключении сначала повторно отправляются все неподтвержденные сделки брокера, сделки идут по возрастанию ID
 	Results(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_ResultsClient, error)
+	// подтверждение обработки сделок из Results, неподтвержденные сделки придут при следующем подключении
+	Ack(ctx context.Context, in *DealAck, opts ...grpc.CallOption) (*AckResult, error)
+	// стакан заявок по ценовым уровням: при подписке полный снимок по каждому инструменту,
+	// дальше изменения уровней при постановке, снятии и исполнении заявок
+	OrderBook(ctx context.Context, in *DepthRequest, opts ...grpc.CallOption) (Exchange_OrderBookClient, error)
+	// лента обезличенных сделок: сделки из внешнего потока и исполнения заявок брокеров между собой
+	Trades(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_TradesClient, error)
+	// фазы торговой сессии: при подписке текущая фаза, дальше каждая смена
+	// вне непрерывных торгов Create отвечает FAILED_PRECONDITION на рыночные, IOC и FOK заявки, при закрытой бирже - на любые
+	Session(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_SessionClient, error)
+	// справочник инструментов, Create отклоняет заявки по инструментам не из справочника,
+	// с ценой не кратной шагу или объемом не кратным лоту
+	// у инструмента с остановленными торгами статус STATUS_HALTED
+	ListInstruments(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (*InstrumentList, error)
+	// остановки торгов инструментами при резком движении цены и их возобновления:
+	// при подписке действующие остановки, дальше каждая остановка и возобновление
+	// во время остановки Create отвечает FAILED_PRECONDITION, заявки вне динамических ценовых границ - INVALID_ARGUMENT
+	Halts(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_HaltsClient, error)
 }
 
 type exchangeClient struct {
@@ -43,7 +66,7 @@ func NewExchangeClient(cc grpc.ClientConnInterface) ExchangeClient {
 	return &exchangeClient{cc}
 }
 
-func (c *exchangeClient) Statistic(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_StatisticClient, error) {
+func (c *exchangeClient) Statistic(ctx context.Context, in *StatisticRequest, opts ...grpc.CallOption) (Exchange_StatisticClient, error) {
 	stream, err := c.cc.NewStream(ctx, &Exchange_ServiceDesc.Streams[0], "/Exchange/Statistic", opts...)
 	if err != nil {
 		return nil, err
@@ -93,6 +116,15 @@ func (c *exchangeClient) Cancel(ctx context.Context, in *DealID, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *exchangeClient) Replace(ctx context.Context, in *ReplaceRequest, opts ...grpc.CallOption) (*ReplaceResult, error) {
+	out := new(ReplaceResult)
+	err := c.cc.Invoke(ctx, "/Exchange/Replace", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *exchangeClient) Results(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_ResultsClient, error) {
 	stream, err := c.cc.NewStream(ctx, &Exchange_ServiceDesc.Streams[1], "/Exchange/Results", opts...)
 	if err != nil {
@@ -125,21 +157,190 @@ func (x *exchangeResultsClient) Recv() (*Deal, error) {
 	return m, nil
 }
 
+func (c *exchangeClient) Ack(ctx context.Context, in *DealAck, opts ...grpc.CallOption) (*AckResult, error) {
+	out := new(AckResult)
+	err := c.cc.Invoke(ctx, "/Exchange/Ack", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeClient) OrderBook(ctx context.Context, in *DepthRequest, opts ...grpc.CallOption) (Exchange_OrderBookClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Exchange_ServiceDesc.Streams[2], "/Exchange/OrderBook", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exchangeOrderBookClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Exchange_OrderBookClient interface {
+	Recv() (*DepthUpdate, error)
+	grpc.ClientStream
+}
+
+type exchangeOrderBookClient struct {
+	grpc.ClientStream
+}
+
+func (x *exchangeOrderBookClient) Recv() (*DepthUpdate, error) {
+	m := new(DepthUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *exchangeClient) Trades(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_TradesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Exchange_ServiceDesc.Streams[3], "/Exchange/Trades", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exchangeTradesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Exchange_TradesClient interface {
+	Recv() (*TradePrint, error)
+	grpc.ClientStream
+}
+
+type exchangeTradesClient struct {
+	grpc.ClientStream
+}
+
+func (x *exchangeTradesClient) Recv() (*TradePrint, error) {
+	m := new(TradePrint)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *exchangeClient) Session(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_SessionClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Exchange_ServiceDesc.Streams[4], "/Exchange/Session", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exchangeSessionClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Exchange_SessionClient interface {
+	Recv() (*SessionEvent, error)
+	grpc.ClientStream
+}
+
+type exchangeSessionClient struct {
+	grpc.ClientStream
+}
+
+func (x *exchangeSessionClient) Recv() (*SessionEvent, error) {
+	m := new(SessionEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *exchangeClient) ListInstruments(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (*InstrumentList, error) {
+	out := new(InstrumentList)
+	err := c.cc.Invoke(ctx, "/Exchange/ListInstruments", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeClient) Halts(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_HaltsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Exchange_ServiceDesc.Streams[5], "/Exchange/Halts", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exchangeHaltsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Exchange_HaltsClient interface {
+	Recv() (*HaltEvent, error)
+	grpc.ClientStream
+}
+
+type exchangeHaltsClient struct {
+	grpc.ClientStream
+}
+
+func (x *exchangeHaltsClient) Recv() (*HaltEvent, error) {
+	m := new(HaltEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ExchangeServer is the server API for Exchange service.
 // All implementations must embed UnimplementedExchangeServer
 // for forward compatibility
 type ExchangeServer interface {
 	// поток ценовых данных от биржи к брокеру
 	// мы каждую секнуду будем получать отсюда событие с ценами, которые броке аггрегирует у себя в минуты и показывает клиентам
-	// устанавливается 1 раз брокером
-	Statistic(*BrokerID, Exchange_StatisticServer) error
+	// устанавливается 1 раз брокером, свечи только по запрошенным инструментам и с запрошенным интервалом
+	// подписчик, не успевающий читать, теряет свечи или отключается в зависимости от настройки биржи
+	Statistic(*StatisticRequest, Exchange_StatisticServer) error
 	// отправка на биржу заявки от брокера
 	Create(context.Context, *Deal) (*DealID, error)
 	// отмена заявки
 	Cancel(context.Context, *DealID) (*CancelResult, error)
+	// изменение цены и/или остатка заявки
+	// при уменьшении остатка заявка сохраняет место в очереди, при смене цены или увеличении остатка - теряет
+	Replace(context.Context, *ReplaceRequest) (*ReplaceResult, error)
 	// исполнение заявок от биржи к брокеру
 	// устанавливается 1 раз брокером и при исполнении какой-то заявки
+	// при подключении сначала повторно отправляются все неподтвержденные сделки брокера, сделки идут по возрастанию ID
 	Results(*BrokerID, Exchange_ResultsServer) error
+	// подтверждение обработки сделок из Results, неподтвержденные сделки придут при следующем подключении
+	Ack(context.Context, *DealAck) (*AckResult, error)
+	// стакан заявок по ценовым уровням: при подписке полный снимок по каждому инструменту,
+	// дальше изменения уровней при постановке, снятии и исполнении заявок
+	OrderBook(*DepthRequest, Exchange_OrderBookServer) error
+	// лента обезличенных сделок: сделки из внешнего потока и исполнения заявок брокеров между собой
+	Trades(*BrokerID, Exchange_TradesServer) error
+	// фазы торговой сессии: при подписке текущая фаза, дальше каждая смена
+	// вне непрерывных торгов Create отвечает FAILED_PRECONDITION на рыночные, IOC и FOK заявки, при закрытой бирже - на любые
+	Session(*BrokerID, Exchange_SessionServer) error
+	// справочник инструментов, Create отклоняет заявки по инструментам не из справочника,
+	// с ценой не кратной шагу или объемом не кратным лоту
+	// у инструмента с остановленными торгами статус STATUS_HALTED
+	ListInstruments(context.Context, *BrokerID) (*InstrumentList, error)
+	// остановки торгов инструментами при резком движении цены и их возобновления:
+	// при подписке действующие остановки, дальше каждая остановка и возобновление
+	// во время остановки Create отвечает FAILED_PRECONDITION, заявки вне динамических ценовых границ - INVALID_ARGUMENT
+	Halts(*BrokerID, Exchange_HaltsServer) error
 	mustEmbedUnimplementedExchangeServer()
 }
 
@@ -147,7 +348,7 @@ type ExchangeServer interface {
 type UnimplementedExchangeServer struct {
 }
 
-func (UnimplementedExchangeServer) Statistic(*BrokerID, Exchange_StatisticServer) error {
+func (UnimplementedExchangeServer) Statistic(*StatisticRequest, Exchange_StatisticServer) error {
 	return status.Errorf(codes.Unimplemented, "method Statistic not implemented")
 }
 func (UnimplementedExchangeServer) Create(context.Context, *Deal) (*DealID, error) {
@@ -156,9 +357,30 @@ func (UnimplementedExchangeServer) Create(context.Context, *Deal) (*DealID, erro
 func (UnimplementedExchangeServer) Cancel(context.Context, *DealID) (*CancelResult, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
 }
+func (UnimplementedExchangeServer) Replace(context.Context, *ReplaceRequest) (*ReplaceResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Replace not implemented")
+}
 func (UnimplementedExchangeServer) Results(*BrokerID, Exchange_ResultsServer) error {
 	return status.Errorf(codes.Unimplemented, "method Results not implemented")
 }
+func (UnimplementedExchangeServer) Ack(context.Context, *DealAck) (*AckResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Ack not implemented")
+}
+func (UnimplementedExchangeServer) OrderBook(*DepthRequest, Exchange_OrderBookServer) error {
+	return status.Errorf(codes.Unimplemented, "method OrderBook not implemented")
+}
+func (UnimplementedExchangeServer) Trades(*BrokerID, Exchange_TradesServer) error {
+	return status.Errorf(codes.Unimplemented, "method Trades not implemented")
+}
+func (UnimplementedExchangeServer) Session(*BrokerID, Exchange_SessionServer) error {
+	return status.Errorf(codes.Unimplemented, "method Session not implemented")
+}
+func (UnimplementedExchangeServer) ListInstruments(context.Context, *BrokerID) (*InstrumentList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInstruments not implemented")
+}
+func (UnimplementedExchangeServer) Halts(*BrokerID, Exchange_HaltsServer) error {
+	return status.Errorf(codes.Unimplemented, "method Halts not implemented")
+}
 func (UnimplementedExchangeServer) mustEmbedUnimplementedExchangeServer() {}
 
 // UnsafeExchangeServer may be embedded to opt out of forward compatibility for this service.
@@ -173,7 +395,7 @@ func RegisterExchangeServer(s grpc.ServiceRegistrar, srv ExchangeServer) {
 }
 
 func _Exchange_Statistic_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(BrokerID)
+	m := new(StatisticRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
@@ -229,6 +451,24 @@ func _Exchange_Cancel_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Exchange_Replace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).Replace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Exchange/Replace",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).Replace(ctx, req.(*ReplaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Exchange_Results_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(BrokerID)
 	if err := stream.RecvMsg(m); err != nil {
@@ -250,6 +490,126 @@ func (x *exchangeResultsServer) Send(m *Deal) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Exchange_Ack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DealAck)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).Ack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Exchange/Ack",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).Ack(ctx, req.(*DealAck))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Exchange_OrderBook_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DepthRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExchangeServer).OrderBook(m, &exchangeOrderBookServer{stream})
+}
+
+type Exchange_OrderBookServer interface {
+	Send(*DepthUpdate) error
+	grpc.ServerStream
+}
+
+type exchangeOrderBookServer struct {
+	grpc.ServerStream
+}
+
+func (x *exchangeOrderBookServer) Send(m *DepthUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Exchange_Trades_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BrokerID)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExchangeServer).Trades(m, &exchangeTradesServer{stream})
+}
+
+type Exchange_TradesServer interface {
+	Send(*TradePrint) error
+	grpc.ServerStream
+}
+
+type exchangeTradesServer struct {
+	grpc.ServerStream
+}
+
+func (x *exchangeTradesServer) Send(m *TradePrint) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Exchange_Session_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BrokerID)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExchangeServer).Session(m, &exchangeSessionServer{stream})
+}
+
+type Exchange_SessionServer interface {
+	Send(*SessionEvent) error
+	grpc.ServerStream
+}
+
+type exchangeSessionServer struct {
+	grpc.ServerStream
+}
+
+func (x *exchangeSessionServer) Send(m *SessionEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Exchange_ListInstruments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BrokerID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).ListInstruments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Exchange/ListInstruments",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).ListInstruments(ctx, req.(*BrokerID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Exchange_Halts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BrokerID)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExchangeServer).Halts(m, &exchangeHaltsServer{stream})
+}
+
+type Exchange_HaltsServer interface {
+	Send(*HaltEvent) error
+	grpc.ServerStream
+}
+
+type exchangeHaltsServer struct {
+	grpc.ServerStream
+}
+
+func (x *exchangeHaltsServer) Send(m *HaltEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Exchange_ServiceDesc is the grpc.ServiceDesc for Exchange service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -265,6 +625,18 @@ var Exchange_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Cancel",
 			Handler:    _Exchange_Cancel_Handler,
 		},
+		{
+			MethodName: "Replace",
+			Handler:    _Exchange_Replace_Handler,
+		},
+		{
+			MethodName: "Ack",
+			Handler:    _Exchange_Ack_Handler,
+		},
+		{
+			MethodName: "ListInstruments",
+			Handler:    _Exchange_ListInstruments_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -277,6 +649,26 @@ var Exchange_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Exchange_Results_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "OrderBook",
+			Handler:       _Exchange_OrderBook_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Trades",
+			Handler:       _Exchange_Trades_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Session",
+			Handler:       _Exchange_Session_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Halts",
+			Handler:       _Exchange_Halts_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "pkg/exchange/deal/delivery/exchange.proto",
 }
@@ -3,12 +3,16 @@ package delivery
 import (
 	context "context"
 	"database/sql"
+	"errors"
 
 	configPkg "github.com/KeynihAV/exchange/pkg/config"
 	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
 	dealUsecasePkg "github.com/KeynihAV/exchange/pkg/exchange/deal/usecase"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
 	"github.com/KeynihAV/exchange/pkg/logging"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type MyExchangeServer struct {
@@ -29,21 +33,25 @@ func NewExchangeServer(db *sql.DB, config *configPkg.Config, logger *logging.Log
 func (es *MyExchangeServer) Create(ctx context.Context, deal *Deal) (*DealID, error) {
 
 	newOrder := &dealPkg.Order{
-		ID:       deal.ID,
-		BrokerID: deal.BrokerID,
-		ClientID: deal.ClientID,
-		Ticker:   deal.Ticker,
-		Volume:   deal.Volume,
-		Price:    deal.Price,
-		Type:     deal.Type,
+		ID:          deal.ID,
+		BrokerID:    deal.BrokerID,
+		ClientID:    deal.ClientID,
+		Ticker:      deal.Ticker,
+		Volume:      deal.Volume,
+		Price:       deal.Price,
+		Type:        SideFromProto(deal.Side, deal.Type),
+		Kind:        KindFromProto(deal.Kind),
+		StopPrice:   deal.StopPrice,
+		TimeInForce: TimeInForceFromProto(deal.TimeInForce),
+		ExpiresAt:   deal.ExpiresAt,
 	}
-	dealID, err := es.DealsManager.CreateOrder(newOrder)
+	dealID, err := es.DealsManager.CreateOrder(newOrder, deal.IdempotencyKey, es.Logger)
 	if err != nil {
 		es.Logger.Zap.Error("create order",
 			zap.String("logger", "grpcServer"),
 			zap.String("err", err.Error()),
 		)
-		return nil, err
+		return nil, statusError(err)
 	}
 
 	return &DealID{ID: dealID}, nil
@@ -56,28 +64,41 @@ func (es *MyExchangeServer) Cancel(ctx context.Context, dealID *DealID) (*Cancel
 			zap.String("logger", "grpcServer"),
 			zap.String("err", err.Error()),
 		)
-		return nil, err
+		return nil, statusError(err)
 	}
 	return &CancelResult{Success: true}, nil
 }
 
-func (es *MyExchangeServer) Statistic(broker *BrokerID, ess Exchange_StatisticServer) error {
-	chanOHLCV := make(chan dealPkg.OHLCV, 10000)
-	defer func() {
-		es.DealsManager.StatsConsumers.Mux.Lock()
-		delete(es.DealsManager.StatsConsumers.Channels, chanOHLCV)
-		es.DealsManager.StatsConsumers.Mux.Unlock()
-	}()
+func (es *MyExchangeServer) Replace(ctx context.Context, req *ReplaceRequest) (*ReplaceResult, error) {
+	order, err := es.DealsManager.ReplaceOrder(req.ID, req.Price, req.Volume, es.Logger)
+	if err != nil {
+		es.Logger.Zap.Error("replace order",
+			zap.String("logger", "grpcServer"),
+			zap.String("err", err.Error()),
+		)
+		return nil, statusError(err)
+	}
+	return &ReplaceResult{
+		Success:         true,
+		Price:           order.Price,
+		Volume:          order.Volume,
+		CompletedVolume: order.CompletedVolume,
+	}, nil
+}
 
-	es.DealsManager.StatsConsumers.Mux.Lock()
-	es.DealsManager.StatsConsumers.Channels[chanOHLCV] = struct{}{}
-	es.DealsManager.StatsConsumers.Mux.Unlock()
+// Statistic - свечи по запрошенным инструментам, подписка закрывается биржей, если брокер не успевает читать
+func (es *MyExchangeServer) Statistic(req *StatisticRequest, ess Exchange_StatisticServer) error {
+	sub := es.DealsManager.SubscribeStats(req.BrokerID, req.Tickers, req.Interval)
+	defer es.DealsManager.UnsubscribeStats(sub)
 
 	for {
 		select {
 		case <-ess.Context().Done():
 			return nil
-		case ohlcv := <-chanOHLCV:
+		case ohlcv, ok := <-sub.Ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "statistic consumer is too slow, subscribe again")
+			}
 			err := ess.Send(&OHLCV{
 				ID:       ohlcv.ID,
 				Time:     ohlcv.Time,
@@ -104,32 +125,65 @@ func (es *MyExchangeServer) Results(broker *BrokerID, ers Exchange_ResultsServer
 	chanResults := make(chan dealPkg.Deal, 10000)
 	defer func() {
 		es.DealsManager.ResultsConsumers.Mux.Lock()
-		delete(es.DealsManager.ResultsConsumers.Channels, broker.ID)
+		//брокер мог уже переподключиться с новым каналом
+		if es.DealsManager.ResultsConsumers.Channels[broker.ID] == chanResults {
+			delete(es.DealsManager.ResultsConsumers.Channels, broker.ID)
+		}
 		es.DealsManager.ResultsConsumers.Mux.Unlock()
 	}()
 
-	//при подключении можно проверить наличие недоставленных брокеру сделок
+	//подтверждение могло потеряться, брокер присылает последнюю обработанную сделку
+	if broker.LastDealID > 0 {
+		err := es.DealsManager.MarkDealShipped(int32(broker.ID), broker.LastDealID)
+		if err != nil {
+			es.Logger.Zap.Error("mark deal shipped",
+				zap.String("logger", "grpcServer"),
+				zap.String("err", err.Error()),
+			)
+		}
+	}
+
+	//канал регистрируется до чтения недоставленных сделок, чтобы не потерять новые,
+	//сделки приходят по возрастанию ID, поэтому уже отправленные отсекаются по lastSentID
 	es.DealsManager.ResultsConsumers.Mux.Lock()
 	es.DealsManager.ResultsConsumers.Channels[broker.ID] = chanResults
 	es.DealsManager.ResultsConsumers.Mux.Unlock()
 
+	unshipped, err := es.DealsManager.UnshippedDeals(int32(broker.ID))
+	if err != nil {
+		es.Logger.Zap.Error("unshipped deals",
+			zap.String("logger", "grpcServer"),
+			zap.String("err", err.Error()),
+		)
+		return err
+	}
+
+	var lastSentID int64
+	for _, deal := range unshipped {
+		err = ers.Send(dealToProto(deal))
+		if err != nil {
+			es.Logger.Zap.Error("results replay",
+				zap.String("logger", "grpcServer"),
+				zap.String("err", err.Error()),
+			)
+			return err
+		}
+		lastSentID = deal.ID
+	}
+
 	for {
 		select {
 		case <-ers.Context().Done():
 			return nil
-		case deal := <-chanResults:
-			err := ers.Send(&Deal{
-				ID:       deal.ID,
-				BrokerID: deal.BrokerID,
-				ClientID: deal.ClientID,
-				OrderID:  deal.OrderID,
-				Ticker:   deal.Ticker,
-				Volume:   deal.Volume,
-				Partial:  deal.Partial,
-				Time:     deal.Time,
-				Price:    deal.Price,
-				Type:     deal.Type,
-			})
+		case deal, ok := <-chanResults:
+			if !ok {
+				//очередь брокера переполнилась, недоставленные сделки он получит из БД при переподключении
+				return status.Error(codes.ResourceExhausted, "results queue overflow")
+			}
+			if deal.ID <= lastSentID {
+				continue
+			}
+			err := ers.Send(dealToProto(&deal))
 			if err != nil {
 				es.Logger.Zap.Error("results",
 					zap.String("logger", "grpcServer"),
@@ -137,13 +191,222 @@ func (es *MyExchangeServer) Results(broker *BrokerID, ers Exchange_ResultsServer
 				)
 				return err
 			}
-			err = es.DealsManager.MarkDealShipped(deal.ID)
+			lastSentID = deal.ID
+		}
+	}
+}
+
+// Ack - брокер обработал сделку, до подтверждения сделка считается недоставленной
+func (es *MyExchangeServer) Ack(ctx context.Context, ack *DealAck) (*AckResult, error) {
+	err := es.DealsManager.MarkDealShipped(int32(ack.BrokerID), ack.DealID)
+	if err != nil {
+		es.Logger.Zap.Error("mark deal shipped",
+			zap.String("logger", "grpcServer"),
+			zap.String("err", err.Error()),
+		)
+		return nil, err
+	}
+	return &AckResult{Success: true}, nil
+}
+
+// Trades - лента обезличенных сделок с момента подписки
+func (es *MyExchangeServer) Trades(broker *BrokerID, ets Exchange_TradesServer) error {
+	chanTrades := make(chan dealPkg.TradePrint, 10000)
+	defer func() {
+		es.DealsManager.TradesConsumers.Mux.Lock()
+		delete(es.DealsManager.TradesConsumers.Channels, chanTrades)
+		es.DealsManager.TradesConsumers.Mux.Unlock()
+	}()
+
+	es.DealsManager.TradesConsumers.Mux.Lock()
+	es.DealsManager.TradesConsumers.Channels[chanTrades] = struct{}{}
+	es.DealsManager.TradesConsumers.Mux.Unlock()
+
+	for {
+		select {
+		case <-ets.Context().Done():
+			return nil
+		case trade := <-chanTrades:
+			err := ets.Send(&TradePrint{
+				Ticker: trade.Ticker,
+				Price:  trade.Price,
+				Volume: trade.Volume,
+				Side:   SideToProto(trade.Type),
+				Time:   trade.Time,
+			})
+			if err != nil {
+				es.Logger.Zap.Error("trades",
+					zap.String("logger", "grpcServer"),
+					zap.String("err", err.Error()),
+				)
+				return err
+			}
+		}
+	}
+}
+
+// OrderBook - снимки стаканов при подписке, затем изменения ценовых уровней
+func (es *MyExchangeServer) OrderBook(req *DepthRequest, obs Exchange_OrderBookServer) error {
+	chanDepth, snapshots := es.DealsManager.OrderBooks.Subscribe(req.Ticker)
+	defer es.DealsManager.OrderBooks.Unsubscribe(chanDepth)
+
+	for i := range snapshots {
+		err := obs.Send(depthToProto(&snapshots[i]))
+		if err != nil {
+			es.Logger.Zap.Error("order book snapshot",
+				zap.String("logger", "grpcServer"),
+				zap.String("err", err.Error()),
+			)
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-obs.Context().Done():
+			return nil
+		case update, ok := <-chanDepth:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "order book consumer is too slow, subscribe again")
+			}
+			err := obs.Send(depthToProto(&update))
+			if err != nil {
+				es.Logger.Zap.Error("order book",
+					zap.String("logger", "grpcServer"),
+					zap.String("err", err.Error()),
+				)
+				return err
+			}
+		}
+	}
+}
+
+// Session - текущая фаза торговой сессии при подписке, дальше ее смены
+func (es *MyExchangeServer) Session(broker *BrokerID, ss Exchange_SessionServer) error {
+	chanSession, current := es.DealsManager.Session.Subscribe()
+	defer es.DealsManager.Session.Unsubscribe(chanSession)
+
+	event := current
+	for {
+		err := ss.Send(&SessionEvent{
+			Phase:     PhaseToProto(event.Phase),
+			Time:      event.Time,
+			NextPhase: PhaseToProto(event.NextPhase),
+			NextTime:  event.NextTime,
+		})
+		if err != nil {
+			es.Logger.Zap.Error("session",
+				zap.String("logger", "grpcServer"),
+				zap.String("err", err.Error()),
+			)
+			return err
+		}
+
+		select {
+		case <-ss.Context().Done():
+			return nil
+		case event = <-chanSession:
+		}
+	}
+}
+
+// ListInstruments - справочник инструментов биржи
+func (es *MyExchangeServer) ListInstruments(ctx context.Context, broker *BrokerID) (*InstrumentList, error) {
+	instruments := es.DealsManager.Instruments.List()
+	result := &InstrumentList{Instruments: make([]*Instrument, len(instruments))}
+	for i, instrument := range instruments {
+		result.Instruments[i] = &Instrument{
+			Ticker:      instrument.Ticker,
+			Description: instrument.Description,
+			TickSize:    instrument.TickSize,
+			LotSize:     instrument.LotSize,
+			Currency:    instrument.Currency,
+			PriceMin:    instrument.PriceMin,
+			PriceMax:    instrument.PriceMax,
+			Status:      InstrumentStatusToProto(instrument.Status),
+		}
+		if instrument.Status == instrumentPkg.StatusTrading && es.DealsManager.Breaker.Halted(instrument.Ticker) {
+			result.Instruments[i].Status = InstrumentStatus_STATUS_HALTED
+		}
+	}
+	return result, nil
+}
+
+// Halts - действующие остановки торгов при подписке, дальше остановки и возобновления
+func (es *MyExchangeServer) Halts(broker *BrokerID, hs Exchange_HaltsServer) error {
+	chanHalts, current := es.DealsManager.Breaker.Subscribe()
+	defer es.DealsManager.Breaker.Unsubscribe(chanHalts)
+
+	for {
+		for _, event := range current {
+			err := hs.Send(&HaltEvent{
+				Ticker: event.Ticker,
+				Halted: event.Halted,
+				Time:   event.Time,
+				Until:  event.Until,
+			})
 			if err != nil {
-				es.Logger.Zap.Error("mark deal shipped",
+				es.Logger.Zap.Error("halts",
 					zap.String("logger", "grpcServer"),
 					zap.String("err", err.Error()),
 				)
+				return err
 			}
 		}
+
+		select {
+		case <-hs.Context().Done():
+			return nil
+		case event := <-chanHalts:
+			current = []instrumentPkg.HaltEvent{event}
+		}
+	}
+}
+
+func depthToProto(update *dealPkg.DepthUpdate) *DepthUpdate {
+	return &DepthUpdate{
+		Ticker:   update.Ticker,
+		Snapshot: update.Snapshot,
+		Bids:     levelsToProto(update.Bids),
+		Asks:     levelsToProto(update.Asks),
+		Sequence: update.Sequence,
+	}
+}
+
+func levelsToProto(levels []dealPkg.PriceLevel) []*PriceLevel {
+	result := make([]*PriceLevel, len(levels))
+	for i, level := range levels {
+		result[i] = &PriceLevel{Price: level.Price, Volume: level.Volume}
+	}
+	return result
+}
+
+func dealToProto(deal *dealPkg.Deal) *Deal {
+	return &Deal{
+		ID:       deal.ID,
+		BrokerID: deal.BrokerID,
+		ClientID: deal.ClientID,
+		OrderID:  deal.OrderID,
+		Ticker:   deal.Ticker,
+		Volume:   deal.Volume,
+		Partial:  deal.Partial,
+		Time:     deal.Time,
+		Price:    deal.Price,
+		Type:     deal.Type,
+		Side:     SideToProto(deal.Type),
+		Event:    EventToProto(deal.Event),
+	}
+}
+
+// statusError отделяет для брокера окончательный отказ по заявке от временной ошибки, которую стоит повторить
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, dealPkg.ErrInvalidOrder):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, dealPkg.ErrOrderNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, dealPkg.ErrSessionPhase), errors.Is(err, instrumentPkg.ErrNotTrading):
+		return status.Error(codes.FailedPrecondition, err.Error())
 	}
+	return err
 }
@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"time"
 
 	configPkg "github.com/KeynihAV/exchange/pkg/config"
@@ -26,8 +27,16 @@ func NewExchangeDB(db *sql.DB, config *configPkg.Config) (*ExchangeDB, error) {
 			completedVolume int NOT NULL,
 			time int NOT NULL,
 			price float8 NOT NULL,
-			type varchar(10) NOT NULL);
-		CREATE UNIQUE INDEX IF NOT EXISTS sell_idx ON orders (ticker, type, price, time, id);`)
+			type varchar(10) NOT NULL,
+			kind varchar(10) NOT NULL DEFAULT 'limit',
+			stopPrice float8 NOT NULL DEFAULT 0,
+			timeInForce varchar(3) NOT NULL DEFAULT 'gtc',
+			expiresAt int NOT NULL DEFAULT 0);
+		CREATE UNIQUE INDEX IF NOT EXISTS sell_idx ON orders (ticker, type, price, time, id);
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS kind varchar(10) NOT NULL DEFAULT 'limit';
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS stopPrice float8 NOT NULL DEFAULT 0;
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS timeInForce varchar(3) NOT NULL DEFAULT 'gtc';
+		ALTER TABLE orders ADD COLUMN IF NOT EXISTS expiresAt int NOT NULL DEFAULT 0;`)
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +53,33 @@ func NewExchangeDB(db *sql.DB, config *configPkg.Config) (*ExchangeDB, error) {
 			time int NOT NULL,
 			price float8 NOT NULL,
 			type varchar(10) NOT NULL,
-			shipped int);`)
+			event varchar(10) NOT NULL DEFAULT 'fill',
+			shipped int);
+		CREATE INDEX IF NOT EXISTS unshipped_idx ON deals (brokerID, id) WHERE shipped IS NULL;
+		ALTER TABLE deals ADD COLUMN IF NOT EXISTS event varchar(10) NOT NULL DEFAULT 'fill';`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS idempotencyKeys(
+			brokerID int NOT NULL,
+			idempotencyKey varchar(64) NOT NULL,
+			orderID int NOT NULL,
+			PRIMARY KEY (brokerID, idempotencyKey));`)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(
+		`CREATE TABLE IF NOT EXISTS orderHistory(
+			id SERIAL PRIMARY KEY,
+			orderID int NOT NULL,
+			event varchar(20) NOT NULL,
+			volume int NOT NULL,
+			price float8 NOT NULL,
+			time int NOT NULL);
+		CREATE INDEX IF NOT EXISTS orderHistory_order_idx ON orderHistory (orderID);`)
 	if err != nil {
 		return nil, err
 	}
@@ -54,18 +89,47 @@ func NewExchangeDB(db *sql.DB, config *configPkg.Config) (*ExchangeDB, error) {
 	}, nil
 }
 
-func (ed *ExchangeDB) AddOrder(deal *dealPkg.Order) (int64, error) {
+// AddOrder сохраняет заявку, непустой idempotencyKey запоминается в той же транзакции
+func (ed *ExchangeDB) AddOrder(deal *dealPkg.Order, idempotencyKey string) (int64, error) {
+	var err error
+
+	tx, err := ed.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
 
-	query := `INSERT INTO orders(brokerID, clientID, ticker, volume, completedVolume, time, price, type)
-	values($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id;`
-	statement, err := ed.DB.Prepare(query)
+	query := `INSERT INTO orders(brokerID, clientID, ticker, volume, completedVolume, time, price, type, kind, stopPrice,
+		timeInForce, expiresAt)
+	values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id;`
+	statement, err := tx.Prepare(query)
 	if err != nil {
 		return 0, err
 	}
 	defer statement.Close()
 
 	var lastID int64
-	err = statement.QueryRow(deal.BrokerID, deal.ClientID, deal.Ticker, deal.Volume, 0, deal.Time, deal.Price, deal.Type).Scan(&lastID)
+	err = statement.QueryRow(deal.BrokerID, deal.ClientID, deal.Ticker, deal.Volume, 0, deal.Time, deal.Price, deal.Type, deal.Kind, deal.StopPrice,
+		deal.TimeInForce, deal.ExpiresAt).Scan(&lastID)
+	if err != nil {
+		return 0, err
+	}
+
+	if idempotencyKey != "" {
+		_, err = tx.Exec(`INSERT INTO idempotencyKeys(brokerID, idempotencyKey, orderID) values($1, $2, $3)`,
+			deal.BrokerID, idempotencyKey, lastID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = logOrderHistory(tx, lastID, dealPkg.HistoryCreated, deal.Volume, deal.Price)
 	if err != nil {
 		return 0, err
 	}
@@ -73,31 +137,76 @@ func (ed *ExchangeDB) AddOrder(deal *dealPkg.Order) (int64, error) {
 	return lastID, nil
 }
 
-func (ed *ExchangeDB) DeleteOrder(dealID int64) error {
-	_, err := ed.DB.Exec(`DELETE FROM orders WHERE id = $1`, dealID)
+// GetOrderIDByKey - ID заявки, ранее созданной брокером с этим ключом, 0 если такой не было
+func (ed *ExchangeDB) GetOrderIDByKey(brokerID int32, idempotencyKey string) (int64, error) {
+	qr := ed.DB.QueryRow(`SELECT orderID FROM idempotencyKeys WHERE brokerID = $1 AND idempotencyKey = $2`,
+		brokerID, idempotencyKey)
+
+	var orderID int64
+	err := qr.Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return orderID, nil
+}
+
+// ReplaceOrder одним update меняет цену, объем и время (место в очереди) заявки
+func (ed *ExchangeDB) ReplaceOrder(order *dealPkg.Order) error {
+	var err error
+
+	tx, err := ed.DB.BeginTx(context.TODO(), &sql.TxOptions{})
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
 
-	return nil
+	result, err := tx.Exec(`UPDATE orders SET price = $1, volume = $2, time = $3 WHERE id = $4`,
+		order.Price, order.Volume, order.Time, order.ID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows != 1 {
+		err = fmt.Errorf("order %v not found", order.ID)
+		return err
+	}
+
+	err = logOrderHistory(tx, order.ID, dealPkg.HistoryReplaced, order.RemainingVolume(), order.Price)
+	return err
 }
 
-func (ed *ExchangeDB) GetOrdersForClose(ticker string, price float32) ([]*dealPkg.Order, error) {
+func (ed *ExchangeDB) GetOpenOrders() ([]*dealPkg.Order, error) {
 	queryResult, err := ed.DB.Query(`
 	SELECT 
 		Orders.id,
 		Orders.brokerid,
 		Orders.clientid,
 		Orders.ticker,
-		Orders.volume -	Orders.completedVolume as volume,
+		Orders.volume,
 		Orders.time,
 		Orders.type,
 		Orders.price,
-		Orders.completedVolume as completedVolume
+		Orders.completedVolume,
+		Orders.kind,
+		Orders.stopPrice,
+		Orders.timeInForce,
+		Orders.expiresAt
 	FROM orders as Orders
-	WHERE Orders.ticker = $1 AND Orders.price = $2
 	ORDER BY 
-		Orders.time, Orders.id`, ticker, price)
+		Orders.time, Orders.id`)
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +216,8 @@ func (ed *ExchangeDB) GetOrdersForClose(ticker string, price float32) ([]*dealPk
 	for queryResult.Next() {
 		order := &dealPkg.Order{}
 		err = queryResult.Scan(&order.ID, &order.BrokerID, &order.ClientID, &order.Ticker, &order.Volume, &order.Time,
-			&order.Type, &order.Price, &order.CompletedVolume)
+			&order.Type, &order.Price, &order.CompletedVolume, &order.Kind, &order.StopPrice,
+			&order.TimeInForce, &order.ExpiresAt)
 		if err != nil {
 			return nil, err
 		}
@@ -117,8 +227,7 @@ func (ed *ExchangeDB) GetOrdersForClose(ticker string, price float32) ([]*dealPk
 	return result, nil
 }
 
-func (ed *ExchangeDB) MakeDeal(order *dealPkg.Order, volumeToClose int32) (*dealPkg.Deal, error) {
-	var result sql.Result
+func (ed *ExchangeDB) MakeDeal(order *dealPkg.Order, volumeToClose int32, price float32) (*dealPkg.Deal, error) {
 	var err error
 
 	tx, err := ed.DB.BeginTx(context.TODO(), &sql.TxOptions{})
@@ -133,7 +242,48 @@ func (ed *ExchangeDB) MakeDeal(order *dealPkg.Order, volumeToClose int32) (*deal
 		}
 	}()
 
-	partialClose := order.Volume-volumeToClose != 0
+	var newDeal *dealPkg.Deal
+	newDeal, err = fillOrder(tx, order, volumeToClose, price)
+	return newDeal, err
+}
+
+// MakeDeals фиксирует исполнение двух встречных заявок одной транзакцией: либо обе сделки, либо ни одной
+func (ed *ExchangeDB) MakeDeals(first, second *dealPkg.Order, volumeToClose int32, price float32) ([]*dealPkg.Deal, error) {
+	var err error
+
+	tx, err := ed.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	deals := make([]*dealPkg.Deal, 0, 2)
+	for _, order := range []*dealPkg.Order{first, second} {
+		var newDeal *dealPkg.Deal
+		newDeal, err = fillOrder(tx, order, volumeToClose, price)
+		if err != nil {
+			return nil, err
+		}
+		deals = append(deals, newDeal)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return deals, nil
+}
+
+// fillOrder уменьшает остаток заявки (исполненную удаляет) и пишет сделку по ней в транзакции tx
+func fillOrder(tx *sql.Tx, order *dealPkg.Order, volumeToClose int32, price float32) (*dealPkg.Deal, error) {
+	var result sql.Result
+	var err error
+
+	partialClose := order.RemainingVolume() != 0
 	if partialClose {
 		result, err = tx.Exec(`UPDATE orders SET completedVolume = $1 WHERE id = $2;`, order.CompletedVolume, order.ID)
 	} else {
@@ -153,13 +303,24 @@ func (ed *ExchangeDB) MakeDeal(order *dealPkg.Order, volumeToClose int32) (*deal
 	if err != nil {
 		return nil, err
 	}
+	defer statement.Close()
+	dealTime := int32(time.Now().Unix())
 	var lastID int64
-	err = statement.QueryRow(order.ID, order.BrokerID, order.ClientID, order.Ticker, volumeToClose, partialClose, order.Time, order.Price, order.Type).Scan(&lastID)
+	err = statement.QueryRow(order.ID, order.BrokerID, order.ClientID, order.Ticker, volumeToClose, partialClose, dealTime, price, order.Type).Scan(&lastID)
+	if err != nil {
+		return nil, err
+	}
+
+	historyEvent := dealPkg.HistoryFilled
+	if partialClose {
+		historyEvent = dealPkg.HistoryPartiallyFilled
+	}
+	err = logOrderHistory(tx, order.ID, historyEvent, volumeToClose, price)
 	if err != nil {
 		return nil, err
 	}
 
-	newDeal := &dealPkg.Deal{
+	return &dealPkg.Deal{
 		ID:       lastID,
 		BrokerID: order.BrokerID,
 		ClientID: order.ClientID,
@@ -167,15 +328,119 @@ func (ed *ExchangeDB) MakeDeal(order *dealPkg.Order, volumeToClose int32) (*deal
 		Ticker:   order.Ticker,
 		Volume:   volumeToClose,
 		Partial:  partialClose,
-		Time:     int32(time.Now().Unix()),
-		Price:    order.Price,
+		Time:     dealTime,
+		Price:    price,
 		Type:     order.Type,
+		Event:    dealPkg.EventFill,
+	}, nil
+}
+
+// CloseOrder снимает неисполненный остаток заявки и пишет событие для брокера
+func (ed *ExchangeDB) CloseOrder(order *dealPkg.Order, event string) (*dealPkg.Deal, error) {
+	var err error
+
+	tx, err := ed.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.Exec(`DELETE FROM orders WHERE id = $1`, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var orderEvent *dealPkg.Deal
+	orderEvent, err = insertOrderEvent(tx, order, event, order.RemainingVolume(), order.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	err = logOrderHistory(tx, order.ID, dealPkg.HistoryEventByDealEvent(event, false), order.RemainingVolume(), order.Price)
+	if err != nil {
+		return nil, err
+	}
+
+	return orderEvent, nil
+}
+
+// TriggerOrder сохраняет сработавшую стоп-заявку с новым видом и временем и пишет событие для брокера
+func (ed *ExchangeDB) TriggerOrder(order *dealPkg.Order) (*dealPkg.Deal, error) {
+	var err error
+
+	tx, err := ed.DB.BeginTx(context.TODO(), &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.Exec(`UPDATE orders SET kind = $1, time = $2 WHERE id = $3`, order.Kind, order.Time, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var orderEvent *dealPkg.Deal
+	orderEvent, err = insertOrderEvent(tx, order, dealPkg.EventTrigger, order.RemainingVolume(), order.StopPrice)
+	if err != nil {
+		return nil, err
+	}
+
+	err = logOrderHistory(tx, order.ID, dealPkg.HistoryTriggered, order.RemainingVolume(), order.StopPrice)
+	if err != nil {
+		return nil, err
 	}
-	return newDeal, nil
+
+	return orderEvent, nil
 }
 
-func (ed *ExchangeDB) MarkDealShipped(dealID int64) error {
-	result, err := ed.DB.Exec(`UPDATE deals SET shipped = $1 WHERE id = $2`, time.Now().Unix(), dealID)
+func insertOrderEvent(tx *sql.Tx, order *dealPkg.Order, event string, volume int32, price float32) (*dealPkg.Deal, error) {
+	dealTime := int32(time.Now().Unix())
+	var lastID int64
+	err := tx.QueryRow(`INSERT INTO deals(orderID, brokerID, clientID, ticker, volume, partial, time, price, type, event) 
+	values($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id;`,
+		order.ID, order.BrokerID, order.ClientID, order.Ticker, volume, false, dealTime, price, order.Type, event).
+		Scan(&lastID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dealPkg.Deal{
+		ID:       lastID,
+		BrokerID: order.BrokerID,
+		ClientID: order.ClientID,
+		OrderID:  order.ID,
+		Ticker:   order.Ticker,
+		Volume:   volume,
+		Time:     dealTime,
+		Price:    price,
+		Type:     order.Type,
+		Event:    event,
+	}, nil
+}
+
+// logOrderHistory дописывает событие в журнал заявки в транзакции изменения заявки
+func logOrderHistory(tx *sql.Tx, orderID int64, event string, volume int32, price float32) error {
+	_, err := tx.Exec(`INSERT INTO orderHistory(orderID, event, volume, price, time) values($1, $2, $3, $4, $5)`,
+		orderID, event, volume, price, int32(time.Now().Unix()))
+	return err
+}
+
+// MarkDealShipped помечает доставленными сделку dealID и все предыдущие неподтвержденные сделки брокера
+func (ed *ExchangeDB) MarkDealShipped(brokerID int32, dealID int64) error {
+	result, err := ed.DB.Exec(`UPDATE deals SET shipped = $1 WHERE brokerID = $2 AND id <= $3 AND shipped IS NULL`,
+		time.Now().Unix(), brokerID, dealID)
 	if err != nil {
 		return err
 	}
@@ -185,3 +450,29 @@ func (ed *ExchangeDB) MarkDealShipped(dealID int64) error {
 	}
 	return nil
 }
+
+// GetUnshippedDeals - неподтвержденные брокером сделки и события по возрастанию ID
+func (ed *ExchangeDB) GetUnshippedDeals(brokerID int32) ([]*dealPkg.Deal, error) {
+	queryResult, err := ed.DB.Query(`
+	SELECT id, orderID, brokerID, clientID, ticker, volume, partial, time, price, type, event
+	FROM deals
+	WHERE brokerID = $1 AND shipped IS NULL
+	ORDER BY id`, brokerID)
+	if err != nil {
+		return nil, err
+	}
+	defer queryResult.Close()
+
+	result := make([]*dealPkg.Deal, 0)
+	for queryResult.Next() {
+		deal := &dealPkg.Deal{}
+		err = queryResult.Scan(&deal.ID, &deal.OrderID, &deal.BrokerID, &deal.ClientID, &deal.Ticker, &deal.Volume,
+			&deal.Partial, &deal.Time, &deal.Price, &deal.Type, &deal.Event)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, deal)
+	}
+
+	return result, nil
+}
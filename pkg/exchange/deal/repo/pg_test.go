@@ -19,7 +19,8 @@ func TestExchangeDB_AddOrder(t *testing.T) {
 	defer db.Close()
 
 	type args struct {
-		deal *dealPkg.Order
+		deal           *dealPkg.Order
+		idempotencyKey string
 	}
 	tests := []struct {
 		name    string
@@ -29,13 +30,24 @@ func TestExchangeDB_AddOrder(t *testing.T) {
 		wantErr bool
 		mockF   func(sqlmock.Sqlmock)
 	}{
+		{name: "Ошибка открытия транзакции",
+			ed:      &ExchangeDB{DB: db},
+			args:    args{deal: &dealPkg.Order{}},
+			want:    0,
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin().WillReturnError(fmt.Errorf("error begin"))
+			},
+		},
 		{name: "Ошибка подготовки",
 			ed:      &ExchangeDB{DB: db},
 			args:    args{deal: &dealPkg.Order{}},
 			want:    0,
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
 				s.ExpectPrepare(`INSERT INTO orders`).WillReturnError(fmt.Errorf("insert error"))
+				s.ExpectRollback()
 			},
 		},
 		{name: "Ошибка получения lastID",
@@ -44,8 +56,36 @@ func TestExchangeDB_AddOrder(t *testing.T) {
 			want:    0,
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
 				s.ExpectPrepare(`INSERT INTO orders`).WillReturnError(nil)
 				s.ExpectQuery(`INSERT INTO orders`).WillReturnError(fmt.Errorf("insert error"))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Ошибка сохранения ключа",
+			ed:      &ExchangeDB{DB: db},
+			args:    args{deal: &dealPkg.Order{BrokerID: 1}, idempotencyKey: "5"},
+			want:    0,
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectPrepare(`INSERT INTO orders`).WillReturnError(nil)
+				s.ExpectQuery(`INSERT INTO orders`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectExec(`INSERT INTO idempotencyKeys`).WillReturnError(fmt.Errorf("duplicate key"))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Ошибка записи журнала",
+			ed:      &ExchangeDB{DB: db},
+			args:    args{deal: &dealPkg.Order{}},
+			want:    0,
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectPrepare(`INSERT INTO orders`).WillReturnError(nil)
+				s.ExpectQuery(`INSERT INTO orders`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectExec(`INSERT INTO orderHistory`).WillReturnError(fmt.Errorf("insert error"))
+				s.ExpectRollback()
 			},
 		},
 		{name: "Успешный insert",
@@ -54,15 +94,33 @@ func TestExchangeDB_AddOrder(t *testing.T) {
 			want:    1,
 			wantErr: false,
 			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
 				s.ExpectPrepare(`INSERT INTO orders`).WillReturnError(nil)
 				s.ExpectQuery(`INSERT INTO orders`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectExec(`INSERT INTO orderHistory`).WithArgs(1, "created", 0, float64(0), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectCommit()
+			},
+		},
+		{name: "Успешный insert с ключом",
+			ed:      &ExchangeDB{DB: db},
+			args:    args{deal: &dealPkg.Order{BrokerID: 1}, idempotencyKey: "5"},
+			want:    1,
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectPrepare(`INSERT INTO orders`).WillReturnError(nil)
+				s.ExpectQuery(`INSERT INTO orders`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectExec(`INSERT INTO idempotencyKeys`).WithArgs(1, "5", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`INSERT INTO orderHistory`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectCommit()
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockF(mock)
-			got, err := tt.ed.AddOrder(tt.args.deal)
+			got, err := tt.ed.AddOrder(tt.args.deal, tt.args.idempotencyKey)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ExchangeDB.AddOrder() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -74,11 +132,62 @@ func TestExchangeDB_AddOrder(t *testing.T) {
 	}
 }
 
-func TestExchangeDB_DeleteOrder(t *testing.T) {
-	type args struct {
-		dealID int64
+func TestExchangeDB_GetOrderIDByKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
 	}
+	defer db.Close()
 
+	tests := []struct {
+		name    string
+		ed      *ExchangeDB
+		want    int64
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			ed:      &ExchangeDB{DB: db},
+			want:    0,
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT orderID FROM idempotencyKeys`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Ключ не найден",
+			ed:      &ExchangeDB{DB: db},
+			want:    0,
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT orderID FROM idempotencyKeys`).WillReturnRows(sqlmock.NewRows([]string{"orderID"}))
+			},
+		},
+		{name: "Ключ найден",
+			ed:      &ExchangeDB{DB: db},
+			want:    7,
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT orderID FROM idempotencyKeys`).WithArgs(1, "5").
+					WillReturnRows(sqlmock.NewRows([]string{"orderID"}).AddRow(7))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.ed.GetOrderIDByKey(1, "5")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExchangeDB.GetOrderIDByKey() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ExchangeDB.GetOrderIDByKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeDB_ReplaceOrder(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("cant create mock: %s", err)
@@ -88,32 +197,59 @@ func TestExchangeDB_DeleteOrder(t *testing.T) {
 	tests := []struct {
 		name    string
 		ed      *ExchangeDB
-		args    args
+		order   *dealPkg.Order
 		wantErr bool
 		mockF   func(sqlmock.Sqlmock)
 	}{
-		{name: "Ошибка delete",
+		{name: "Ошибка update",
 			ed:      &ExchangeDB{DB: db},
-			args:    args{dealID: 1},
+			order:   &dealPkg.Order{ID: 1},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`DELETE FROM orders WHERE`).WillReturnError(fmt.Errorf("delete error"))
+				s.ExpectBegin()
+				s.ExpectExec(`UPDATE orders SET price`).WillReturnError(fmt.Errorf("update error"))
+				s.ExpectRollback()
 			},
 		},
-		{name: "Успешный delete",
+		{name: "Ошибка rows affected",
 			ed:      &ExchangeDB{DB: db},
-			args:    args{dealID: 1},
+			order:   &dealPkg.Order{ID: 1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec(`UPDATE orders SET price`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Заявка не найдена",
+			ed:      &ExchangeDB{DB: db},
+			order:   &dealPkg.Order{ID: 1},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec(`UPDATE orders SET price`).WillReturnResult(sqlmock.NewResult(0, 0))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Успешный update",
+			ed:      &ExchangeDB{DB: db},
+			order:   &dealPkg.Order{ID: 1, Price: 101, Volume: 5, CompletedVolume: 2, Time: 12345678},
 			wantErr: false,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`DELETE FROM orders WHERE`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectBegin()
+				s.ExpectExec(`UPDATE orders SET price`).WithArgs(float64(101), 5, 12345678, 1).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`INSERT INTO orderHistory`).WithArgs(1, "replaced", 3, float64(101), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectCommit()
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockF(mock)
-			if err := tt.ed.DeleteOrder(tt.args.dealID); (err != nil) != tt.wantErr {
-				t.Errorf("ExchangeDB.DeleteOrder() error = %v, wantErr %v", err, tt.wantErr)
+			if err := tt.ed.ReplaceOrder(tt.order); (err != nil) != tt.wantErr {
+				t.Errorf("ExchangeDB.ReplaceOrder() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
@@ -127,7 +263,8 @@ func TestExchangeDB_MarkDealShipped(t *testing.T) {
 	defer db.Close()
 
 	type args struct {
-		dealID int64
+		brokerID int32
+		dealID   int64
 	}
 	tests := []struct {
 		name    string
@@ -138,7 +275,7 @@ func TestExchangeDB_MarkDealShipped(t *testing.T) {
 	}{
 		{name: "Ошибка update",
 			ed:      &ExchangeDB{DB: db},
-			args:    args{dealID: 1},
+			args:    args{brokerID: 1, dealID: 1},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
 				s.ExpectExec(`UPDATE deals SET shipped`).WillReturnError(fmt.Errorf("update error"))
@@ -146,7 +283,7 @@ func TestExchangeDB_MarkDealShipped(t *testing.T) {
 		},
 		{name: "Ошибка rows affected",
 			ed:      &ExchangeDB{DB: db},
-			args:    args{dealID: 1},
+			args:    args{brokerID: 1, dealID: 1},
 			wantErr: true,
 			mockF: func(s sqlmock.Sqlmock) {
 				s.ExpectExec(`UPDATE deals SET shipped`).WillReturnResult(sqlmock.NewErrorResult(fmt.Errorf("error result")))
@@ -154,45 +291,102 @@ func TestExchangeDB_MarkDealShipped(t *testing.T) {
 		},
 		{name: "Успешный update",
 			ed:      &ExchangeDB{DB: db},
-			args:    args{dealID: 1},
+			args:    args{brokerID: 1, dealID: 1},
 			wantErr: false,
 			mockF: func(s sqlmock.Sqlmock) {
-				s.ExpectExec(`UPDATE deals SET shipped`).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec(`UPDATE deals SET shipped`).WithArgs(sqlmock.AnyArg(), 1, 1).WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockF(mock)
-			if err := tt.ed.MarkDealShipped(tt.args.dealID); (err != nil) != tt.wantErr {
+			if err := tt.ed.MarkDealShipped(tt.args.brokerID, tt.args.dealID); (err != nil) != tt.wantErr {
 				t.Errorf("ExchangeDB.MarkDealShipped() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestExchangeDB_GetOrdersForClose(t *testing.T) {
+func TestExchangeDB_GetUnshippedDeals(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("cant create mock: %s", err)
 	}
 	defer db.Close()
 
-	type args struct {
-		ticker string
-		price  float32
+	tests := []struct {
+		name    string
+		ed      *ExchangeDB
+		want    []*dealPkg.Deal
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			ed:      &ExchangeDB{DB: db},
+			wantErr: true,
+			want:    nil,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Ошибка scan",
+			ed:      &ExchangeDB{DB: db},
+			wantErr: true,
+			want:    nil,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "orderID"}).AddRow(0, "one")
+				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
+			},
+		},
+		{name: "Успешный select",
+			ed:      &ExchangeDB{DB: db},
+			wantErr: false,
+			want: []*dealPkg.Deal{
+				{ID: 3, OrderID: 1, BrokerID: 1, ClientID: 1, Ticker: "ticker1", Volume: 2, Partial: true, Time: 12345678,
+					Price: 100, Type: "buy", Event: "fill"},
+				{ID: 5, OrderID: 1, BrokerID: 1, ClientID: 1, Ticker: "ticker1", Volume: 8, Partial: false, Time: 12345679,
+					Price: 100, Type: "buy", Event: "cancel"}},
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "orderID", "brokerID", "clientID", "ticker", "volume", "partial", "time",
+					"price", "type", "event"}).
+					AddRow(3, 1, 1, 1, "ticker1", 2, true, 12345678, 100, "buy", "fill").
+					AddRow(5, 1, 1, 1, "ticker1", 8, false, 12345679, 100, "buy", "cancel")
+				s.ExpectQuery(`SELECT`).WithArgs(1).WillReturnRows(rows)
+			},
+		},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.ed.GetUnshippedDeals(1)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExchangeDB.GetUnshippedDeals() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExchangeDB.GetUnshippedDeals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeDB_GetOpenOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
 	tests := []struct {
 		name    string
 		ed      *ExchangeDB
-		args    args
 		want    []*dealPkg.Order
 		wantErr bool
 		mockF   func(sqlmock.Sqlmock)
 	}{
 		{name: "Ошибка select",
 			ed:      &ExchangeDB{DB: db},
-			args:    args{ticker: "123", price: 10},
 			wantErr: true,
 			want:    nil,
 			mockF: func(s sqlmock.Sqlmock) {
@@ -201,7 +395,6 @@ func TestExchangeDB_GetOrdersForClose(t *testing.T) {
 		},
 		{name: "Ошибка scan",
 			ed:      &ExchangeDB{DB: db},
-			args:    args{ticker: "123", price: 10},
 			wantErr: true,
 			want:    nil,
 			mockF: func(s sqlmock.Sqlmock) {
@@ -211,13 +404,14 @@ func TestExchangeDB_GetOrdersForClose(t *testing.T) {
 		},
 		{name: "Успешный select",
 			ed:      &ExchangeDB{DB: db},
-			args:    args{ticker: "ticker1", price: 10},
 			wantErr: false,
 			want: []*dealPkg.Order{{ID: 1, BrokerID: 1, ClientID: 1, Ticker: "ticker1",
-				Volume: 10, Time: 12345678, Type: "buy", Price: 100, CompletedVolume: 8}},
+				Volume: 10, Time: 12345678, Type: "buy", Price: 100, CompletedVolume: 8, Kind: "stop_limit", StopPrice: 105,
+				TimeInForce: "gtd", ExpiresAt: 12349999}},
 			mockF: func(s sqlmock.Sqlmock) {
-				rows := sqlmock.NewRows([]string{"id", "brokerid", "clientid", "ticker", "volume", "time", "type", "price", "completedVolume"}).
-					AddRow(1, 1, 1, "ticker1", 10, 12345678, "buy", 100, 8)
+				rows := sqlmock.NewRows([]string{"id", "brokerid", "clientid", "ticker", "volume", "time", "type", "price", "completedVolume", "kind", "stopPrice",
+					"timeInForce", "expiresAt"}).
+					AddRow(1, 1, 1, "ticker1", 10, 12345678, "buy", 100, 8, "stop_limit", 105, "gtd", 12349999)
 				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
 			},
 		},
@@ -225,13 +419,13 @@ func TestExchangeDB_GetOrdersForClose(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockF(mock)
-			got, err := tt.ed.GetOrdersForClose(tt.args.ticker, tt.args.price)
+			got, err := tt.ed.GetOpenOrders()
 			if (err != nil) != tt.wantErr {
-				t.Errorf("ExchangeDB.GetOrdersForClose() error = %v, wantErr %v", err, tt.wantErr)
+				t.Errorf("ExchangeDB.GetOpenOrders() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 			if !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("ExchangeDB.GetOrdersForClose() = %v, want %v", got, tt.want)
+				t.Errorf("ExchangeDB.GetOpenOrders() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -247,6 +441,7 @@ func TestExchangeDB_MakeDeal(t *testing.T) {
 	type args struct {
 		order         *dealPkg.Order
 		volumeToClose int32
+		price         float32
 	}
 	tNow := int32(time.Now().Unix())
 
@@ -313,10 +508,10 @@ func TestExchangeDB_MakeDeal(t *testing.T) {
 		{name: "Корректный update orders, insert deals",
 			ed: &ExchangeDB{DB: db},
 			args: args{order: &dealPkg.Order{ID: 1, BrokerID: 1, ClientID: 1,
-				Ticker: "ticker1", Price: 100, Type: "sell", Volume: 100, CompletedVolume: 10, Time: tNow}, volumeToClose: 1},
+				Ticker: "ticker1", Price: 100, Type: "sell", Volume: 100, CompletedVolume: 10, Time: tNow}, volumeToClose: 1, price: 100},
 			wantErr: false,
 			want: &dealPkg.Deal{ID: 1, BrokerID: 1, ClientID: 1, OrderID: 1, Ticker: "ticker1", Volume: 1,
-				Partial: true, Time: tNow, Price: 100, Type: "sell"},
+				Partial: true, Time: tNow, Price: 100, Type: "sell", Event: "fill"},
 			mockF: func(s sqlmock.Sqlmock) {
 				s.ExpectBegin()
 				s.ExpectExec("UPDATE orders SET completedVolume").WithArgs(10, 1).
@@ -325,30 +520,34 @@ func TestExchangeDB_MakeDeal(t *testing.T) {
 				s.ExpectQuery("INSERT INTO deals").
 					WithArgs(1, 1, 1, "ticker1", 1, true, tNow, float64(100), "sell").
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectExec("INSERT INTO orderHistory").WithArgs(1, "partially_filled", 1, float64(100), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 		},
 		{name: "Корректный delete orders, insert deals",
 			ed: &ExchangeDB{DB: db},
 			args: args{order: &dealPkg.Order{ID: 1, BrokerID: 1, ClientID: 1,
-				Ticker: "ticker1", Price: 100, Type: "sell", Volume: 100, CompletedVolume: 100, Time: tNow}, volumeToClose: 100},
+				Ticker: "ticker1", Price: 100, Type: "sell", Volume: 100, CompletedVolume: 100, Time: tNow}, volumeToClose: 100, price: 95},
 			wantErr: false,
 			want: &dealPkg.Deal{ID: 1, BrokerID: 1, ClientID: 1, OrderID: 1, Ticker: "ticker1", Volume: 100,
-				Partial: false, Time: tNow, Price: 100, Type: "sell"},
+				Partial: false, Time: tNow, Price: 95, Type: "sell", Event: "fill"},
 			mockF: func(s sqlmock.Sqlmock) {
 				s.ExpectBegin()
 				s.ExpectExec("DELETE FROM orders").WithArgs(1).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				s.ExpectPrepare("INSERT INTO deals").WillReturnError(nil)
 				s.ExpectQuery("INSERT INTO deals").
-					WithArgs(1, 1, 1, "ticker1", 100, false, tNow, float64(100), "sell").
+					WithArgs(1, 1, 1, "ticker1", 100, false, tNow, float64(95), "sell").
 					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectExec("INSERT INTO orderHistory").WithArgs(1, "filled", 100, float64(95), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockF(mock)
-			got, err := tt.ed.MakeDeal(tt.args.order, tt.args.volumeToClose)
+			got, err := tt.ed.MakeDeal(tt.args.order, tt.args.volumeToClose, tt.args.price)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ExchangeDB.MakeDeal() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -359,3 +558,269 @@ func TestExchangeDB_MakeDeal(t *testing.T) {
 		})
 	}
 }
+
+func TestExchangeDB_MakeDeals(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	type args struct {
+		first         *dealPkg.Order
+		second        *dealPkg.Order
+		volumeToClose int32
+		price         float32
+	}
+	tNow := int32(time.Now().Unix())
+
+	tests := []struct {
+		name    string
+		ed      *ExchangeDB
+		args    args
+		want    []*dealPkg.Deal
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка открытия транзакции",
+			ed:      &ExchangeDB{DB: db},
+			args:    args{first: &dealPkg.Order{}, second: &dealPkg.Order{}, volumeToClose: 1},
+			wantErr: true,
+			want:    nil,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin().WillReturnError(fmt.Errorf("error begin"))
+			},
+		},
+		{name: "Ошибка по второй заявке откатывает первую",
+			ed: &ExchangeDB{DB: db},
+			args: args{first: &dealPkg.Order{ID: 1, Volume: 10, CompletedVolume: 1},
+				second: &dealPkg.Order{ID: 2, Volume: 1, CompletedVolume: 1}, volumeToClose: 1, price: 100},
+			wantErr: true,
+			want:    nil,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec("UPDATE orders SET completedVolume").WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectPrepare("INSERT INTO deals").WillReturnError(nil)
+				s.ExpectQuery("INSERT INTO deals").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				s.ExpectExec("INSERT INTO orderHistory").WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec("DELETE FROM orders").WithArgs(2).WillReturnError(fmt.Errorf("delete error"))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Ошибка commit",
+			ed: &ExchangeDB{DB: db},
+			args: args{first: &dealPkg.Order{ID: 1, Volume: 1, CompletedVolume: 1},
+				second: &dealPkg.Order{ID: 2, Volume: 1, CompletedVolume: 1}, volumeToClose: 1, price: 100},
+			wantErr: true,
+			want:    nil,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				for id := 1; id <= 2; id++ {
+					s.ExpectExec("DELETE FROM orders").WithArgs(id).WillReturnResult(sqlmock.NewResult(1, 1))
+					s.ExpectPrepare("INSERT INTO deals").WillReturnError(nil)
+					s.ExpectQuery("INSERT INTO deals").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+					s.ExpectExec("INSERT INTO orderHistory").WillReturnResult(sqlmock.NewResult(1, 1))
+				}
+				s.ExpectCommit().WillReturnError(fmt.Errorf("commit error"))
+			},
+		},
+		{name: "Корректное исполнение обеих заявок",
+			ed: &ExchangeDB{DB: db},
+			args: args{first: &dealPkg.Order{ID: 1, BrokerID: 1, ClientID: 1, Ticker: "ticker1", Type: "sell", Volume: 10, CompletedVolume: 5},
+				second:        &dealPkg.Order{ID: 2, BrokerID: 2, ClientID: 3, Ticker: "ticker1", Type: "buy", Volume: 5, CompletedVolume: 5},
+				volumeToClose: 5, price: 100},
+			wantErr: false,
+			want: []*dealPkg.Deal{
+				{ID: 10, BrokerID: 1, ClientID: 1, OrderID: 1, Ticker: "ticker1", Volume: 5,
+					Partial: true, Time: tNow, Price: 100, Type: "sell", Event: "fill"},
+				{ID: 11, BrokerID: 2, ClientID: 3, OrderID: 2, Ticker: "ticker1", Volume: 5,
+					Partial: false, Time: tNow, Price: 100, Type: "buy", Event: "fill"},
+			},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec("UPDATE orders SET completedVolume").WithArgs(5, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectPrepare("INSERT INTO deals").WillReturnError(nil)
+				s.ExpectQuery("INSERT INTO deals").
+					WithArgs(1, 1, 1, "ticker1", 5, true, tNow, float64(100), "sell").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+				s.ExpectExec("INSERT INTO orderHistory").WithArgs(1, "partially_filled", 5, float64(100), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectExec("DELETE FROM orders").WithArgs(2).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectPrepare("INSERT INTO deals").WillReturnError(nil)
+				s.ExpectQuery("INSERT INTO deals").
+					WithArgs(2, 2, 3, "ticker1", 5, false, tNow, float64(100), "buy").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+				s.ExpectExec("INSERT INTO orderHistory").WithArgs(2, "filled", 5, float64(100), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectCommit()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.ed.MakeDeals(tt.args.first, tt.args.second, tt.args.volumeToClose, tt.args.price)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExchangeDB.MakeDeals() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExchangeDB.MakeDeals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeDB_CloseOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	type args struct {
+		order *dealPkg.Order
+		event string
+	}
+	tNow := int32(time.Now().Unix())
+
+	tests := []struct {
+		name    string
+		ed      *ExchangeDB
+		args    args
+		want    *dealPkg.Deal
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка открытия транзакции",
+			ed:      &ExchangeDB{DB: db},
+			args:    args{order: &dealPkg.Order{}, event: "cancel"},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin().WillReturnError(fmt.Errorf("error begin"))
+			},
+		},
+		{name: "Ошибка delete",
+			ed:      &ExchangeDB{DB: db},
+			args:    args{order: &dealPkg.Order{ID: 1}, event: "cancel"},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec("DELETE FROM orders").WillReturnError(fmt.Errorf("delete error"))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Ошибка insert",
+			ed:      &ExchangeDB{DB: db},
+			args:    args{order: &dealPkg.Order{ID: 1}, event: "cancel"},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec("DELETE FROM orders").WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectQuery("INSERT INTO deals").WillReturnError(fmt.Errorf("insert error"))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Корректная отмена остатка",
+			ed: &ExchangeDB{DB: db},
+			args: args{order: &dealPkg.Order{ID: 1, BrokerID: 1, ClientID: 1, Ticker: "ticker1", Price: 100,
+				Type: "buy", Kind: "ioc", Volume: 10, CompletedVolume: 4}, event: "cancel"},
+			want: &dealPkg.Deal{ID: 2, BrokerID: 1, ClientID: 1, OrderID: 1, Ticker: "ticker1", Volume: 6,
+				Time: tNow, Price: 100, Type: "buy", Event: "cancel"},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec("DELETE FROM orders").WithArgs(1).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectQuery("INSERT INTO deals").
+					WithArgs(1, 1, 1, "ticker1", 6, false, tNow, float64(100), "buy", "cancel").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+				s.ExpectExec("INSERT INTO orderHistory").WithArgs(1, "cancelled", 6, float64(100), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectCommit()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.ed.CloseOrder(tt.args.order, tt.args.event)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExchangeDB.CloseOrder() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExchangeDB.CloseOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExchangeDB_TriggerOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tNow := int32(time.Now().Unix())
+
+	tests := []struct {
+		name    string
+		ed      *ExchangeDB
+		order   *dealPkg.Order
+		want    *dealPkg.Deal
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка update",
+			ed:      &ExchangeDB{DB: db},
+			order:   &dealPkg.Order{ID: 1, Kind: "market"},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec("UPDATE orders SET kind").WillReturnError(fmt.Errorf("update error"))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Ошибка insert",
+			ed:      &ExchangeDB{DB: db},
+			order:   &dealPkg.Order{ID: 1, Kind: "market"},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec("UPDATE orders SET kind").WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectQuery("INSERT INTO deals").WillReturnError(fmt.Errorf("insert error"))
+				s.ExpectRollback()
+			},
+		},
+		{name: "Корректное срабатывание",
+			ed: &ExchangeDB{DB: db},
+			order: &dealPkg.Order{ID: 1, BrokerID: 1, ClientID: 1, Ticker: "ticker1", Price: 100, StopPrice: 98,
+				Type: "sell", Kind: "limit", Volume: 10, Time: tNow},
+			want: &dealPkg.Deal{ID: 3, BrokerID: 1, ClientID: 1, OrderID: 1, Ticker: "ticker1", Volume: 10,
+				Time: tNow, Price: 98, Type: "sell", Event: "trigger"},
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectBegin()
+				s.ExpectExec("UPDATE orders SET kind").WithArgs("limit", tNow, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectQuery("INSERT INTO deals").
+					WithArgs(1, 1, 1, "ticker1", 10, false, tNow, float64(98), "sell", "trigger").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+				s.ExpectExec("INSERT INTO orderHistory").WithArgs(1, "triggered", 10, float64(98), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				s.ExpectCommit()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.ed.TriggerOrder(tt.order)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExchangeDB.TriggerOrder() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExchangeDB.TriggerOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
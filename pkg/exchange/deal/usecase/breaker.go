@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+	metricsPkg "github.com/KeynihAV/exchange/pkg/exchange/metrics"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBreakerWindow   = 60
+	defaultBreakerCooldown = 300
+	// haltsBuffer - сколько остановок и возобновлений может ждать отправки подписчику, лишние пропускаются
+	// и видны в метрике
+	haltsBuffer = 100
+)
+
+// tapePoint - цена ленты в момент At
+type tapePoint struct {
+	Price float32
+	At    time.Time
+}
+
+// Breaker - динамические ценовые границы от последней цены ленты и остановка торгов инструментом
+// при движении цены больше чем на Move % за Window; окно и время остановки считаются по времени ленты,
+// поэтому при воспроизведении истории с любой скоростью они те же, что были на рынке. Если лента
+// остановлена, на паузе или идет медленнее часов, остановка снимается через Cooldown по часам от HaltedAt
+type Breaker struct {
+	Band      float32
+	Bands     map[string]float32 // границы инструментов, отличающиеся от Band
+	Move      float32
+	Window    time.Duration
+	Cooldown  time.Duration
+	Last      map[string]float32
+	Points    map[string][]tapePoint
+	Halts     map[string]instrumentPkg.HaltEvent
+	HaltedAt  map[string]time.Time // начало остановки по часам
+	Consumers map[chan instrumentPkg.HaltEvent]struct{}
+	Mux       *sync.RWMutex
+}
+
+func NewBreaker(config *configPkg.Config) *Breaker {
+	cfg := config.Exchange.PriceBands
+	b := &Breaker{
+		Band:      cfg.Band,
+		Bands:     make(map[string]float32),
+		Move:      cfg.BreakerMove,
+		Window:    time.Duration(cfg.BreakerWindow) * time.Second,
+		Cooldown:  time.Duration(cfg.BreakerCooldown) * time.Second,
+		Last:      make(map[string]float32),
+		Points:    make(map[string][]tapePoint),
+		Halts:     make(map[string]instrumentPkg.HaltEvent),
+		HaltedAt:  make(map[string]time.Time),
+		Consumers: make(map[chan instrumentPkg.HaltEvent]struct{}),
+		Mux:       &sync.RWMutex{},
+	}
+	if b.Window <= 0 {
+		b.Window = defaultBreakerWindow * time.Second
+	}
+	if b.Cooldown <= 0 {
+		b.Cooldown = defaultBreakerCooldown * time.Second
+	}
+	for _, instrument := range config.Exchange.Instruments {
+		if instrument.Band > 0 {
+			b.Bands[instrument.Ticker] = instrument.Band
+		}
+	}
+	return b
+}
+
+// CheckOrder - инструмент не остановлен, цены заявки в динамических границах,
+// пока по инструменту не было сделок в ленте, границ нет
+func (b *Breaker) CheckOrder(order *dealPkg.Order) error {
+	b.Mux.RLock()
+	defer b.Mux.RUnlock()
+
+	if _, ok := b.Halts[order.Ticker]; ok {
+		return fmt.Errorf("%w: %v is halted, resumes in %v at most", instrumentPkg.ErrNotTrading, order.Ticker,
+			b.remaining(order.Ticker, time.Now()))
+	}
+
+	band, ok := b.Bands[order.Ticker]
+	if !ok {
+		band = b.Band
+	}
+	last := b.Last[order.Ticker]
+	if band <= 0 || last == 0 {
+		return nil
+	}
+	low, high := last*(1-band/100), last*(1+band/100)
+	for _, price := range []float32{order.Price, order.StopPrice} {
+		if price != 0 && (price < low || price > high) {
+			return fmt.Errorf("%w: price %v is outside of dynamic band %v-%v", dealPkg.ErrInvalidOrder, price, low, high)
+		}
+	}
+	return nil
+}
+
+// remaining - сколько по часам осталось до возобновления торгов инструментом; по ленте они могут возобновиться раньше
+func (b *Breaker) remaining(ticker string, now time.Time) time.Duration {
+	left := b.HaltedAt[ticker].Add(b.Cooldown).Sub(now).Round(time.Second)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+// Halted - торги инструментом остановлены
+func (b *Breaker) Halted(ticker string) bool {
+	b.Mux.RLock()
+	defer b.Mux.RUnlock()
+	_, ok := b.Halts[ticker]
+	return ok
+}
+
+// onTrade запоминает цену ленты и останавливает торги инструментом, если за Window цена ушла больше чем на Move %,
+// возвращает остановку, если она произошла; now - время сделки ленты
+func (b *Breaker) onTrade(ticker string, price float32, now time.Time) (instrumentPkg.HaltEvent, bool) {
+	b.Mux.Lock()
+	defer b.Mux.Unlock()
+
+	b.Last[ticker] = price
+	if b.Move <= 0 || price <= 0 {
+		return instrumentPkg.HaltEvent{}, false
+	}
+	//во время остановки окно не копится, после нее отсчет начинается заново
+	if _, ok := b.Halts[ticker]; ok {
+		return instrumentPkg.HaltEvent{}, false
+	}
+
+	points := b.Points[ticker]
+	//после перемотки ленты назад окно начинается заново
+	if len(points) > 0 && now.Before(points[len(points)-1].At) {
+		points = nil
+	}
+	for len(points) > 0 && now.Sub(points[0].At) > b.Window {
+		points = points[1:]
+	}
+	points = append(points, tapePoint{Price: price, At: now})
+	b.Points[ticker] = points
+
+	for _, point := range points {
+		move := math.Abs(float64(price-point.Price)) / float64(point.Price) * 100
+		if move > float64(b.Move) {
+			halt := instrumentPkg.HaltEvent{
+				Ticker: ticker,
+				Halted: true,
+				Time:   int32(now.Unix()),
+				Until:  int32(now.Add(b.Cooldown).Unix()),
+			}
+			b.Halts[ticker] = halt
+			b.HaltedAt[ticker] = time.Now()
+			delete(b.Points, ticker)
+			return halt, true
+		}
+	}
+	return instrumentPkg.HaltEvent{}, false
+}
+
+// resume снимает остановки, время которых вышло к моменту now по ленте,
+// и остановки, случившиеся позже now, если ленту перемотали назад
+func (b *Breaker) resume(now time.Time) []instrumentPkg.HaltEvent {
+	b.Mux.Lock()
+	defer b.Mux.Unlock()
+
+	resumed := make([]instrumentPkg.HaltEvent, 0)
+	for ticker, halt := range b.Halts {
+		if int64(halt.Until) > now.Unix() && int64(halt.Time) <= now.Unix() {
+			continue
+		}
+		delete(b.Halts, ticker)
+		delete(b.HaltedAt, ticker)
+		resumed = append(resumed, instrumentPkg.HaltEvent{Ticker: ticker, Time: int32(now.Unix())})
+	}
+	return resumed
+}
+
+// expire снимает остановки, которые к моменту now по часам длятся дольше Cooldown, например, на паузе ленты;
+// время возобновления в событии - Until остановки по ленте
+func (b *Breaker) expire(now time.Time) []instrumentPkg.HaltEvent {
+	b.Mux.Lock()
+	defer b.Mux.Unlock()
+
+	resumed := make([]instrumentPkg.HaltEvent, 0)
+	for ticker, halt := range b.Halts {
+		if b.HaltedAt[ticker].Add(b.Cooldown).After(now) {
+			continue
+		}
+		delete(b.Halts, ticker)
+		delete(b.HaltedAt, ticker)
+		resumed = append(resumed, instrumentPkg.HaltEvent{Ticker: ticker, Time: halt.Until})
+	}
+	return resumed
+}
+
+// Subscribe регистрирует подписчика и возвращает действующие остановки под одной блокировкой
+func (b *Breaker) Subscribe() (chan instrumentPkg.HaltEvent, []instrumentPkg.HaltEvent) {
+	b.Mux.Lock()
+	defer b.Mux.Unlock()
+
+	ch := make(chan instrumentPkg.HaltEvent, haltsBuffer)
+	b.Consumers[ch] = struct{}{}
+	halts := make([]instrumentPkg.HaltEvent, 0, len(b.Halts))
+	for _, halt := range b.Halts {
+		halts = append(halts, halt)
+	}
+	return ch, halts
+}
+
+func (b *Breaker) Unsubscribe(ch chan instrumentPkg.HaltEvent) {
+	b.Mux.Lock()
+	defer b.Mux.Unlock()
+	delete(b.Consumers, ch)
+}
+
+// publish отправляет остановку или возобновление подписчикам, переполненный канал пропускает событие,
+// чтобы медленный подписчик не останавливал ленту, пропуски видны в метрике
+func (b *Breaker) publish(event instrumentPkg.HaltEvent, logger *logging.Logger) {
+	logger.Zap.Info("circuit breaker",
+		zap.String("logger", "breaker"),
+		zap.String("ticker", event.Ticker),
+		zap.Bool("halted", event.Halted),
+	)
+
+	b.Mux.RLock()
+	defer b.Mux.RUnlock()
+	for ch := range b.Consumers {
+		select {
+		case ch <- event:
+		default:
+			metricsPkg.HaltEventDropped(event.Ticker)
+		}
+	}
+}
+
+// breakerOnTrade проверяет остановки торгов по сделке ленты: сначала возобновляет инструменты,
+// время остановки которых вышло к моменту сделки, потом учитывает цену сделки
+func (dm *DealsManager) breakerOnTrade(deal *dealPkg.Deal, logger *logging.Logger) {
+	now := time.Unix(int64(deal.Time), 0)
+	for _, event := range dm.Breaker.resume(now) {
+		dm.Breaker.publish(event, logger)
+	}
+	halt, halted := dm.Breaker.onTrade(deal.Ticker, deal.Price, now)
+	if halted {
+		dm.Breaker.publish(halt, logger)
+	}
+}
+
+// RunBreaker раз в секунду снимает остановки, время которых вышло по часам, чтобы они не оставались навсегда,
+// если сделок ленты больше нет: лента на паузе, остановлена или закончилась
+func (dm *DealsManager) RunBreaker(logger *logging.Logger) {
+	tiker := time.NewTicker(time.Second)
+	for now := range tiker.C {
+		for _, event := range dm.Breaker.expire(now) {
+			dm.Breaker.publish(event, logger)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+)
+
+var tapeStart = time.Date(2018, 5, 18, 13, 0, 0, 0, time.UTC)
+
+// breakerConfig - остановка при движении больше 5 % за 60 секунд на 300 секунд
+func breakerConfig() *configPkg.Config {
+	config := &configPkg.Config{}
+	config.Exchange.PriceBands.Band = 10
+	config.Exchange.PriceBands.BreakerMove = 5
+	config.Exchange.PriceBands.BreakerWindow = 60
+	config.Exchange.PriceBands.BreakerCooldown = 300
+	return config
+}
+
+// tapeDeal - сделка ленты через seconds секунд от начала ленты
+func tapeDeal(ticker string, price float32, seconds int) *dealPkg.Deal {
+	return &dealPkg.Deal{Ticker: ticker, Price: price, Volume: 1,
+		Time: int32(tapeStart.Add(time.Duration(seconds) * time.Second).Unix())}
+}
+
+func TestBreaker_onTrade(t *testing.T) {
+	tests := []struct {
+		name       string
+		deals      []*dealPkg.Deal
+		wantHalted bool
+	}{
+		{name: "Движение в пределах Move",
+			deals:      []*dealPkg.Deal{tapeDeal(testTicker, 100, 0), tapeDeal(testTicker, 104, 30)},
+			wantHalted: false,
+		},
+		{name: "Движение больше Move внутри окна",
+			deals:      []*dealPkg.Deal{tapeDeal(testTicker, 100, 0), tapeDeal(testTicker, 103, 30), tapeDeal(testTicker, 106, 60)},
+			wantHalted: true,
+		},
+		{name: "Падение больше Move внутри окна",
+			deals:      []*dealPkg.Deal{tapeDeal(testTicker, 100, 0), tapeDeal(testTicker, 94, 10)},
+			wantHalted: true,
+		},
+		{name: "Старые цены уходят из окна",
+			deals:      []*dealPkg.Deal{tapeDeal(testTicker, 100, 0), tapeDeal(testTicker, 103, 40), tapeDeal(testTicker, 106, 61)},
+			wantHalted: false,
+		},
+		{name: "Окно считается по времени ленты, а не по часам",
+			deals:      []*dealPkg.Deal{tapeDeal(testTicker, 100, 0), tapeDeal(testTicker, 106, 3600)},
+			wantHalted: false,
+		},
+		{name: "Перемотка ленты назад начинает окно заново",
+			deals:      []*dealPkg.Deal{tapeDeal(testTicker, 100, 30), tapeDeal(testTicker, 106, 0)},
+			wantHalted: false,
+		},
+		{name: "Окна инструментов не смешиваются",
+			deals:      []*dealPkg.Deal{tapeDeal("SPFB.Si", 100, 0), tapeDeal(testTicker, 106, 10)},
+			wantHalted: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, _ := newTestManager()
+			dm.Breaker = NewBreaker(breakerConfig())
+			ch, _ := dm.Breaker.Subscribe()
+
+			for _, deal := range tt.deals {
+				dm.breakerOnTrade(deal, testLogger())
+			}
+
+			last := tt.deals[len(tt.deals)-1]
+			if got := dm.Breaker.Halted(testTicker); got != tt.wantHalted {
+				t.Fatalf("Halted() = %v, want %v", got, tt.wantHalted)
+			}
+			if !tt.wantHalted {
+				return
+			}
+			want := instrumentPkg.HaltEvent{Ticker: testTicker, Halted: true, Time: last.Time, Until: last.Time + 300}
+			select {
+			case got := <-ch:
+				if got != want {
+					t.Errorf("halt = %+v, want %+v", got, want)
+				}
+			default:
+				t.Errorf("halt is not published")
+			}
+		})
+	}
+}
+
+func TestBreaker_Cooldown(t *testing.T) {
+	dm, _ := newTestManager()
+	dm.Breaker = NewBreaker(breakerConfig())
+	ch, _ := dm.Breaker.Subscribe()
+	logger := testLogger()
+
+	dm.breakerOnTrade(tapeDeal(testTicker, 100, 0), logger)
+	dm.breakerOnTrade(tapeDeal(testTicker, 110, 10), logger)
+	order := limitOrder(dealPkg.TypeBuy, 110, 1)
+	if err := dm.Breaker.CheckOrder(order); !errors.Is(err, instrumentPkg.ErrNotTrading) {
+		t.Fatalf("CheckOrder() while halted error = %v, want %v", err, instrumentPkg.ErrNotTrading)
+	}
+
+	//во время остановки окно не копится, движение цены не продлевает остановку
+	dm.breakerOnTrade(tapeDeal(testTicker, 130, 100), logger)
+	dm.breakerOnTrade(tapeDeal("SPFB.Si", 60000, 309), logger)
+	if !dm.Breaker.Halted(testTicker) {
+		t.Fatalf("resumed before cooldown")
+	}
+
+	//торги возобновляет сделка ленты после конца остановки, даже по другому инструменту
+	dm.breakerOnTrade(tapeDeal("SPFB.Si", 60000, 310), logger)
+	if dm.Breaker.Halted(testTicker) {
+		t.Fatalf("halted after cooldown")
+	}
+	if err := dm.Breaker.CheckOrder(limitOrder(dealPkg.TypeBuy, 130, 1)); err != nil {
+		t.Errorf("CheckOrder() after cooldown error = %v", err)
+	}
+
+	events := make([]instrumentPkg.HaltEvent, 0)
+	for len(ch) > 0 {
+		events = append(events, <-ch)
+	}
+	start := int32(tapeStart.Unix())
+	want := []instrumentPkg.HaltEvent{
+		{Ticker: testTicker, Halted: true, Time: start + 10, Until: start + 310},
+		{Ticker: testTicker, Time: start + 310},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("events = %+v, want %+v", events, want)
+	}
+
+	//после возобновления окно начинается с чистого листа
+	dm.breakerOnTrade(tapeDeal(testTicker, 132, 320), logger)
+	if dm.Breaker.Halted(testTicker) {
+		t.Errorf("halted by prices from before the halt")
+	}
+}
+
+func TestBreaker_RewindResumes(t *testing.T) {
+	b := NewBreaker(breakerConfig())
+	b.onTrade(testTicker, 100, tapeStart.Add(100*time.Second))
+	b.onTrade(testTicker, 110, tapeStart.Add(110*time.Second))
+
+	//перемотка ленты до остановки снимает ее
+	resumed := b.resume(tapeStart)
+	if len(resumed) != 1 || b.Halted(testTicker) {
+		t.Errorf("resume() after rewind = %+v, halted %v", resumed, b.Halted(testTicker))
+	}
+}
+
+func TestBreaker_Expire(t *testing.T) {
+	b := NewBreaker(breakerConfig())
+	b.onTrade(testTicker, 100, tapeStart)
+	b.onTrade(testTicker, 110, tapeStart.Add(10*time.Second))
+	halt := b.Halts[testTicker]
+
+	//лента на паузе: по часам прошло 100 секунд из 300
+	b.HaltedAt[testTicker] = time.Now().Add(-100 * time.Second)
+	err := b.CheckOrder(limitOrder(dealPkg.TypeBuy, 110, 1))
+	if !errors.Is(err, instrumentPkg.ErrNotTrading) || !strings.Contains(err.Error(), "resumes in 3m20s") {
+		t.Errorf("CheckOrder() while halted error = %v", err)
+	}
+	if resumed := b.expire(time.Now()); len(resumed) != 0 {
+		t.Fatalf("expire() before cooldown = %+v", resumed)
+	}
+
+	//без новых сделок ленты остановка снимается по часам
+	resumed := b.expire(time.Now().Add(200 * time.Second))
+	want := []instrumentPkg.HaltEvent{{Ticker: testTicker, Time: halt.Until}}
+	if !reflect.DeepEqual(resumed, want) || b.Halted(testTicker) {
+		t.Errorf("expire() after cooldown = %+v, halted %v, want %+v", resumed, b.Halted(testTicker), want)
+	}
+	if len(b.HaltedAt) != 0 {
+		t.Errorf("HaltedAt = %v, want empty", b.HaltedAt)
+	}
+}
+
+func TestBreaker_CheckOrder(t *testing.T) {
+	config := breakerConfig()
+	config.Exchange.Instruments = append(config.Exchange.Instruments, struct {
+		Ticker      string
+		Description string
+		TickSize    float32
+		LotSize     int32
+		Currency    string
+		PriceMin    float32
+		PriceMax    float32
+		Band        float32
+	}{Ticker: "SPFB.Si", Band: 2})
+
+	tests := []struct {
+		name    string
+		ticker  string
+		last    float32
+		price   float32
+		stop    float32
+		wantErr error
+	}{
+		{name: "До первой сделки границ нет",
+			ticker: testTicker, price: 1000,
+		},
+		{name: "Цена внутри общих границ",
+			ticker: testTicker, last: 100, price: 109,
+		},
+		{name: "Цена за общими границами",
+			ticker: testTicker, last: 100, price: 111,
+			wantErr: dealPkg.ErrInvalidOrder,
+		},
+		{name: "Стоп-цена за общими границами",
+			ticker: testTicker, last: 100, price: 100, stop: 89,
+			wantErr: dealPkg.ErrInvalidOrder,
+		},
+		{name: "Свои границы инструмента уже общих",
+			ticker: "SPFB.Si", last: 100, price: 103,
+			wantErr: dealPkg.ErrInvalidOrder,
+		},
+		{name: "Цена внутри своих границ инструмента",
+			ticker: "SPFB.Si", last: 100, price: 101.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBreaker(config)
+			if tt.last > 0 {
+				b.onTrade(tt.ticker, tt.last, tapeStart)
+			}
+			order := &dealPkg.Order{Ticker: tt.ticker, Price: tt.price, StopPrice: tt.stop, Volume: 1}
+			if err := b.CheckOrder(order); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBreaker_Publish(t *testing.T) {
+	b := NewBreaker(breakerConfig())
+	ch, _ := b.Subscribe()
+	slow, _ := b.Subscribe()
+	for i := 0; i < haltsBuffer; i++ {
+		slow <- instrumentPkg.HaltEvent{}
+	}
+
+	//переполненный канал пропускает событие и не задерживает остальных подписчиков
+	event := instrumentPkg.HaltEvent{Ticker: testTicker, Halted: true}
+	b.publish(event, testLogger())
+	if got := <-ch; got != event {
+		t.Errorf("event = %+v, want %+v", got, event)
+	}
+	if len(slow) != haltsBuffer {
+		t.Errorf("slow subscriber queue = %v, want %v", len(slow), haltsBuffer)
+	}
+}
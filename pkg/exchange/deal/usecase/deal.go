@@ -10,21 +10,30 @@ import (
 	"github.com/KeynihAV/exchange/pkg/exchange/deal"
 	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
 	dealRepoPkg "github.com/KeynihAV/exchange/pkg/exchange/deal/repo"
+	instrumentUsecasePkg "github.com/KeynihAV/exchange/pkg/exchange/instrument/usecase"
+	metricsPkg "github.com/KeynihAV/exchange/pkg/exchange/metrics"
 	"github.com/KeynihAV/exchange/pkg/logging"
 	"go.uber.org/zap"
 )
 
 type ExchangeRepo interface {
-	AddOrder(order *dealPkg.Order) (int64, error)
-	DeleteOrder(orderID int64) error
-	GetOrdersForClose(ticker string, price float32) ([]*dealPkg.Order, error)
-	MakeDeal(order *dealPkg.Order, volumeToClose int32) (*deal.Deal, error)
-	MarkDealShipped(dealID int64) error
+	AddOrder(order *dealPkg.Order, idempotencyKey string) (int64, error)
+	GetOrderIDByKey(brokerID int32, idempotencyKey string) (int64, error)
+	ReplaceOrder(order *dealPkg.Order) error
+	GetOpenOrders() ([]*dealPkg.Order, error)
+	MakeDeal(order *dealPkg.Order, volumeToClose int32, price float32) (*deal.Deal, error)
+	MakeDeals(first, second *dealPkg.Order, volumeToClose int32, price float32) ([]*deal.Deal, error)
+	CloseOrder(order *dealPkg.Order, event string) (*deal.Deal, error)
+	TriggerOrder(order *dealPkg.Order) (*deal.Deal, error)
+	MarkDealShipped(brokerID int32, dealID int64) error
+	GetUnshippedDeals(brokerID int32) ([]*dealPkg.Deal, error)
 }
 
+const defaultSessionEnd = "23:50"
+
 type Consumers struct {
-	Channels map[chan dealPkg.OHLCV]struct{}
-	Mux      *sync.RWMutex
+	Subscriptions map[*StatsSubscription]struct{}
+	Mux           *sync.RWMutex
 }
 
 type ResultsConsumers struct {
@@ -32,11 +41,22 @@ type ResultsConsumers struct {
 	Mux      *sync.RWMutex
 }
 
+type TradesConsumers struct {
+	Channels map[chan dealPkg.TradePrint]struct{}
+	Mux      *sync.RWMutex
+}
+
 type DealsManager struct {
+	Config           *configPkg.Config
 	ER               ExchangeRepo
 	DealsFlowCh      chan *dealPkg.Deal
 	StatsConsumers   *Consumers
 	ResultsConsumers *ResultsConsumers
+	TradesConsumers  *TradesConsumers
+	OrderBooks       *OrderBooks
+	Session          *Session
+	Instruments      *instrumentUsecasePkg.InstrumentsManager
+	Breaker          *Breaker
 }
 
 func NewDealsManager(db *sql.DB, config *configPkg.Config) (*DealsManager, error) {
@@ -44,99 +64,584 @@ func NewDealsManager(db *sql.DB, config *configPkg.Config) (*DealsManager, error
 	if err != nil {
 		return nil, err
 	}
-	return &DealsManager{
-		ER: exchangeDB,
+	session, err := NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+	instruments, err := instrumentUsecasePkg.NewInstrumentsManager(db, config)
+	if err != nil {
+		return nil, err
+	}
+	dm := &DealsManager{
+		Config: config,
+		ER:     exchangeDB,
 		StatsConsumers: &Consumers{
-			Mux:      &sync.RWMutex{},
-			Channels: map[chan dealPkg.OHLCV]struct{}{},
+			Mux:           &sync.RWMutex{},
+			Subscriptions: make(map[*StatsSubscription]struct{}),
 		},
 		ResultsConsumers: &ResultsConsumers{
 			Mux:      &sync.RWMutex{},
 			Channels: make(map[int64]chan dealPkg.Deal),
 		},
-	}, nil
+		TradesConsumers: &TradesConsumers{
+			Mux:      &sync.RWMutex{},
+			Channels: make(map[chan dealPkg.TradePrint]struct{}),
+		},
+		OrderBooks:  NewOrderBooks(),
+		Session:     session,
+		Instruments: instruments,
+		Breaker:     NewBreaker(config),
+	}
+
+	err = dm.loadOrderBooks()
+	if err != nil {
+		return nil, err
+	}
+
+	return dm, nil
 }
 
-func (dm *DealsManager) CreateOrder(order *dealPkg.Order) (int64, error) {
-	order.Time = int32(time.Now().Unix())
-	return dm.ER.AddOrder(order)
+func (dm *DealsManager) loadOrderBooks() error {
+	orders, err := dm.ER.GetOpenOrders()
+	if err != nil {
+		return err
+	}
+
+	dm.OrderBooks.Mux.Lock()
+	defer dm.OrderBooks.Mux.Unlock()
+	for _, order := range orders {
+		dm.OrderBooks.Add(order)
+	}
+	return nil
 }
 
+// CreateOrder - непустой idempotencyKey защищает от повторного создания заявки при переотправке брокером
+func (dm *DealsManager) CreateOrder(order *dealPkg.Order, idempotencyKey string, logger *logging.Logger) (int64, error) {
+	if order.Kind == "" {
+		order.Kind = dealPkg.KindLimit
+	}
+	if order.TimeInForce == "" {
+		order.TimeInForce = dealPkg.TimeInForceGTC
+	}
+	err := order.Validate()
+	if err != nil {
+		return 0, err
+	}
+	err = dm.checkInstrument(order)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	order.Time = int32(now.Unix())
+	order.CompletedVolume = 0
+
+	switch order.TimeInForce {
+	case dealPkg.TimeInForceGTC:
+		order.ExpiresAt = 0
+	case dealPkg.TimeInForceDay:
+		order.ExpiresAt, err = dayOrderExpiration(now, dm.Config.Exchange.SessionEnd)
+		if err != nil {
+			return 0, err
+		}
+	case dealPkg.TimeInForceGTD:
+		if order.ExpiresAt <= order.Time {
+			return 0, fmt.Errorf("%w: expiration time %v is in the past", dealPkg.ErrInvalidOrder, order.ExpiresAt)
+		}
+	}
+
+	dm.OrderBooks.Mux.Lock()
+	defer dm.OrderBooks.Mux.Unlock()
+
+	if idempotencyKey != "" {
+		existingID, err := dm.ER.GetOrderIDByKey(order.BrokerID, idempotencyKey)
+		if err != nil || existingID != 0 {
+			return existingID, err
+		}
+	}
+
+	phase := dm.Session.Current().Phase
+	err = checkCreate(phase, order.Kind)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := dm.ER.AddOrder(order, idempotencyKey)
+	if err != nil {
+		return 0, err
+	}
+	order.ID = id
+
+	//стоп-заявка ждет срабатывания по ленте, вне непрерывных торгов заявка ждет аукциона
+	if dealPkg.IsStopKind(order.Kind) || phase != dealPkg.PhaseContinuous {
+		dm.OrderBooks.Add(order)
+		return id, nil
+	}
+
+	if order.Kind != dealPkg.KindFOK || dm.availableVolume(order) >= order.Volume {
+		dm.matchWithBook(order, logger)
+	}
+	dm.placeRemainder(order, logger)
+
+	return id, nil
+}
+
+// checkInstrument - заявка по инструменту из справочника, с его шагом цены и лотом,
+// в ценовых границах и без остановки торгов; пустой справочник отклоняет все заявки,
+// пропускает их без проверок только явно заданный exchange.allowUnlisted
+func (dm *DealsManager) checkInstrument(order *dealPkg.Order) error {
+	if !dm.Instruments.Empty() || !dm.Config.Exchange.AllowUnlisted {
+		instrument, err := dm.Instruments.Get(order.Ticker)
+		if err != nil {
+			return fmt.Errorf("%w: %v", dealPkg.ErrInvalidOrder, err)
+		}
+		err = instrument.CheckOrder(order)
+		if err != nil {
+			return err
+		}
+	}
+	return dm.Breaker.CheckOrder(order)
+}
+
+// placeRemainder ставит в стакан остаток лимитной заявки, остаток остальных отменяется
+func (dm *DealsManager) placeRemainder(order *dealPkg.Order, logger *logging.Logger) {
+	if order.RemainingVolume() == 0 {
+		return
+	}
+	if order.Kind == dealPkg.KindLimit {
+		dm.OrderBooks.Add(order)
+	} else {
+		dm.closeOrder(order, dealPkg.EventCancel, logger)
+	}
+}
+
+// availableVolume - объем встречных заявок, с которыми может исполниться order
+func (dm *DealsManager) availableVolume(order *dealPkg.Order) int32 {
+	book, ok := dm.OrderBooks.Books[order.Ticker]
+	if !ok {
+		return 0
+	}
+
+	var volume int32
+	for _, restingOrder := range *book.side(oppositeType(order.Type)) {
+		if volume >= order.Volume || !crossesPrice(order, restingOrder.Price) {
+			break
+		}
+		volume += restingOrder.RemainingVolume()
+	}
+	return volume
+}
+
+// matchWithBook исполняет новую заявку против встречных заявок любых брокеров по цене стоящей заявки,
+// на каждое исполнение получается по сделке на каждую сторону
+func (dm *DealsManager) matchWithBook(order *dealPkg.Order, logger *logging.Logger) {
+	book, ok := dm.OrderBooks.Books[order.Ticker]
+	if !ok {
+		return
+	}
+
+	orders := book.side(oppositeType(order.Type))
+	for order.RemainingVolume() > 0 && len(*orders) > 0 {
+		restingOrder := (*orders)[0]
+		if !crossesPrice(order, restingOrder.Price) {
+			break
+		}
+		volumeToClose := order.RemainingVolume()
+		if restingOrder.RemainingVolume() < volumeToClose {
+			volumeToClose = restingOrder.RemainingVolume()
+		}
+		price := restingOrder.Price
+
+		deals, err := dm.makeDeals(restingOrder, order, volumeToClose, price)
+		if err != nil {
+			logger.Zap.Error("not close orders",
+				zap.String("logger", "CreateOrder"),
+				zap.String("err", err.Error()),
+			)
+			break
+		}
+		dm.publishTrade(dealPkg.TradePrint{
+			Ticker: order.Ticker,
+			Price:  price,
+			Volume: volumeToClose,
+			Type:   order.Type,
+			Time:   deals[len(deals)-1].Time,
+		})
+	}
+}
+
+// CancelOrder снимает остаток заявки, брокер получает событие отмены в Results
 func (dm *DealsManager) CancelOrder(dealID int64) error {
-	return dm.ER.DeleteOrder(dealID)
+	dm.OrderBooks.Mux.Lock()
+	defer dm.OrderBooks.Mux.Unlock()
+
+	order, ok := dm.OrderBooks.Orders[dealID]
+	if !ok {
+		return fmt.Errorf("%w: %v", dealPkg.ErrOrderNotFound, dealID)
+	}
+	err := checkChange(dm.Session.Current().Phase)
+	if err != nil {
+		return err
+	}
+
+	cancelEvent, err := dm.ER.CloseOrder(order, dealPkg.EventCancel)
+	if err != nil {
+		return err
+	}
+	dm.OrderBooks.Remove(dealID)
+	dm.sendToBroker(cancelEvent)
+
+	return nil
+}
+
+// ReplaceOrder меняет цену и/или объем стоящей заявки, нулевые значения не меняются; volume - полный объем
+// вместе с исполненным, а не остаток, чтобы брокер, еще не получивший часть сделок, не разошелся с биржей
+// в размере заявки. Заявка теряет место в очереди при смене цены или увеличении объема,
+// возвращается заявка сразу после изменения
+func (dm *DealsManager) ReplaceOrder(orderID int64, price float32, volume int32, logger *logging.Logger) (dealPkg.Order, error) {
+	if price < 0 || volume < 0 {
+		return dealPkg.Order{}, fmt.Errorf("%w: price and volume must not be negative", dealPkg.ErrInvalidOrder)
+	}
+
+	dm.OrderBooks.Mux.Lock()
+	defer dm.OrderBooks.Mux.Unlock()
+
+	order, ok := dm.OrderBooks.Orders[orderID]
+	if !ok {
+		return dealPkg.Order{}, fmt.Errorf("%w: %v", dealPkg.ErrOrderNotFound, orderID)
+	}
+	phase := dm.Session.Current().Phase
+	err := checkChange(phase)
+	if err != nil {
+		return dealPkg.Order{}, err
+	}
+
+	replaced := *order
+	if price > 0 {
+		replaced.Price = price
+	}
+	if volume > 0 {
+		if volume <= order.CompletedVolume {
+			return dealPkg.Order{}, fmt.Errorf("%w: order %v is already filled for %v of new volume %v",
+				dealPkg.ErrInvalidOrder, orderID, order.CompletedVolume, volume)
+		}
+		replaced.Volume = volume
+	}
+	if replaced.Price != order.Price || replaced.Volume > order.Volume {
+		replaced.Time = int32(time.Now().Unix())
+		replaced.Seq = 0
+	}
+	if replaced.Kind == dealPkg.KindStop && replaced.Price != order.Price {
+		return dealPkg.Order{}, fmt.Errorf("%w: price of %v order cannot be changed", dealPkg.ErrInvalidOrder, order.Kind)
+	}
+	err = dm.checkInstrument(&replaced)
+	if err != nil {
+		return dealPkg.Order{}, err
+	}
+
+	err = dm.ER.ReplaceOrder(&replaced)
+	if err != nil {
+		return dealPkg.Order{}, err
+	}
+
+	dm.OrderBooks.Remove(orderID)
+	priceChanged := replaced.Price != order.Price
+	*order = replaced
+	if dealPkg.IsStopKind(order.Kind) || !priceChanged || phase != dealPkg.PhaseContinuous {
+		dm.OrderBooks.Add(order)
+		return replaced, nil
+	}
+
+	//по новой цене заявка может сразу исполниться
+	dm.matchWithBook(order, logger)
+	dm.placeRemainder(order, logger)
+
+	return replaced, nil
 }
 
 func (dm *DealsManager) ProcessingTradingOperations(IntervalSeconds int, logger *logging.Logger) {
 	tiker := time.NewTicker(time.Duration(IntervalSeconds) * time.Second)
 	stats := make(map[string]*dealPkg.OHLCV, 0)
+	lastPrints := make(map[string]dealPkg.TradePrint)
 	var ohclvID int64
 
 	for {
 		select {
-		case <-tiker.C:
-			for _, ohlcv := range stats {
-				for ch := range dm.StatsConsumers.Channels {
-					ch <- *ohlcv
-				}
-				fmt.Println(ohlcv)
-			}
+		case now := <-tiker.C:
+			dm.publishStats(stats, int32(now.Unix()))
 			stats = make(map[string]*dealPkg.OHLCV, 0)
 		case deal := <-dm.DealsFlowCh:
 			calculateStats(stats, deal, ohclvID)
+			dm.publishTrade(tapePrint(deal, lastPrints, int32(time.Now().Unix())))
+			dm.breakerOnTrade(deal, logger)
+			dm.matchWithTape(deal, logger)
+		}
+	}
+}
 
-			ordersForClose, err := dm.ER.GetOrdersForClose(deal.Ticker, deal.Price)
+// tapePrint - сделка ленты для подписчиков, в потоке нет стороны, поэтому она определяется по тику:
+// рост цены - инициатор покупатель, падение - продавец, та же цена - как у предыдущей сделки.
+// Время - now, а не время ленты: при воспроизведении истории лента в прошлом, а сделки стакана - по часам
+func tapePrint(deal *dealPkg.Deal, lastPrints map[string]dealPkg.TradePrint, now int32) dealPkg.TradePrint {
+	trade := dealPkg.TradePrint{
+		Ticker: deal.Ticker,
+		Price:  deal.Price,
+		Volume: deal.Volume,
+		Time:   now,
+	}
+	last, ok := lastPrints[deal.Ticker]
+	switch {
+	case !ok:
+	case deal.Price > last.Price:
+		trade.Type = dealPkg.TypeBuy
+	case deal.Price < last.Price:
+		trade.Type = dealPkg.TypeSell
+	default:
+		trade.Type = last.Type
+	}
+	lastPrints[deal.Ticker] = trade
+	return trade
+}
+
+// publishTrade отправляет сделку подписчикам ленты, переполненный канал пропускает сделку,
+// чтобы медленный подписчик не останавливал торги, пропуски видны в метрике
+func (dm *DealsManager) publishTrade(trade dealPkg.TradePrint) {
+	dm.TradesConsumers.Mux.RLock()
+	defer dm.TradesConsumers.Mux.RUnlock()
+
+	for ch := range dm.TradesConsumers.Channels {
+		select {
+		case ch <- trade:
+		default:
+			metricsPkg.TradeDropped(trade.Ticker)
+		}
+	}
+}
+
+// matchWithTape исполняет заявки из стакана против сделки из ленты:
+// покупки с ценой не ниже цены сделки, продажи - не выше, в пределах объема сделки по каждой стороне,
+// вне непрерывных торгов и при остановке торгов инструментом лента заявки не исполняет
+func (dm *DealsManager) matchWithTape(deal *dealPkg.Deal, logger *logging.Logger) {
+	dm.OrderBooks.Mux.Lock()
+	defer dm.OrderBooks.Mux.Unlock()
+
+	if dm.Session.Current().Phase != dealPkg.PhaseContinuous || dm.Breaker.Halted(deal.Ticker) {
+		return
+	}
+
+	book, ok := dm.OrderBooks.Books[deal.Ticker]
+	if !ok {
+		return
+	}
+
+	tapeVolume := map[string]int32{dealPkg.TypeBuy: deal.Volume, dealPkg.TypeSell: deal.Volume}
+	dm.triggerStops(book, deal, tapeVolume, logger)
+
+	for _, orderType := range []string{dealPkg.TypeBuy, dealPkg.TypeSell} {
+		orders := book.side(orderType)
+		allVolume := tapeVolume[orderType]
+		//полностью исполненная заявка уходит из стакана, поэтому всегда берем лучшую
+		for allVolume > 0 && len(*orders) > 0 {
+			orderForClose := (*orders)[0]
+			if !crossesPrice(orderForClose, deal.Price) {
+				break
+			}
+			volumeToClose := orderForClose.RemainingVolume()
+			if allVolume < volumeToClose {
+				//закрыть частично, остаток ждет следующих сделок
+				volumeToClose = allVolume
+			}
+			_, err := dm.makeDeal(orderForClose, volumeToClose, orderForClose.Price)
 			if err != nil {
-				logger.Zap.Error("get orders for close",
+				logger.Zap.Error("not close deal",
 					zap.String("logger", "ProcessingTradingOperations"),
 					zap.String("err", err.Error()),
 				)
+				break
 			}
-			if len(ordersForClose) == 0 {
-				continue
+			allVolume -= volumeToClose
+		}
+	}
+}
+
+// triggerStops превращает сработавшие стоп-заявки в рыночные или лимитные и исполняет их,
+// рыночный остаток, не нашедший встречных заявок в стакане, исполняется по цене ленты
+func (dm *DealsManager) triggerStops(book *OrderBook, deal *dealPkg.Deal, tapeVolume map[string]int32, logger *logging.Logger) {
+	triggered := make([]*dealPkg.Order, 0)
+	for _, order := range book.Stops {
+		if stopTriggered(order, deal.Price) {
+			triggered = append(triggered, order)
+		}
+	}
+
+	for _, order := range triggered {
+		stopKind, stopTime, stopSeq := order.Kind, order.Time, order.Seq
+		dm.OrderBooks.Remove(order.ID)
+		order.Kind = dealPkg.TriggeredKind(order.Kind)
+		order.Time, order.Seq = int32(time.Now().Unix()), 0
+		triggerEvent, err := dm.ER.TriggerOrder(order)
+		if err != nil {
+			logger.Zap.Error("not trigger stop order",
+				zap.String("logger", "ProcessingTradingOperations"),
+				zap.String("err", err.Error()),
+			)
+			order.Kind, order.Time, order.Seq = stopKind, stopTime, stopSeq
+			dm.OrderBooks.Add(order)
+			continue
+		}
+		dm.sendToBroker(triggerEvent)
+
+		dm.matchWithBook(order, logger)
+		volumeToClose := order.RemainingVolume()
+		if order.Kind == dealPkg.KindMarket && volumeToClose > 0 && tapeVolume[order.Type] > 0 {
+			if tapeVolume[order.Type] < volumeToClose {
+				volumeToClose = tapeVolume[order.Type]
 			}
-			allVolume := deal.Volume
-			for _, orderForClose := range ordersForClose {
-				var volumeToClose int32
-				if allVolume >= orderForClose.Volume {
-					allVolume -= orderForClose.Volume
-					volumeToClose = orderForClose.Volume
-				} else {
-					//закрыть частично и попробовать закрыть следующим объемом
-					volumeToClose = allVolume
-					allVolume = 0
-				}
-				err := dm.makeDeal(orderForClose, volumeToClose)
-				if err != nil {
-					logger.Zap.Error("not close deal",
-						zap.String("logger", "ProcessingTradingOperations"),
-						zap.String("err", err.Error()),
-					)
-				}
-				if allVolume == 0 {
-					break
-				}
+			_, err = dm.makeDeal(order, volumeToClose, deal.Price)
+			if err != nil {
+				logger.Zap.Error("not close triggered order",
+					zap.String("logger", "ProcessingTradingOperations"),
+					zap.String("err", err.Error()),
+				)
+			} else {
+				tapeVolume[order.Type] -= volumeToClose
 			}
 		}
+		dm.placeRemainder(order, logger)
 	}
 }
 
-func (dm *DealsManager) makeDeal(order *dealPkg.Order, volume int32) error {
-	order.Time = int32(time.Now().Unix())
+// makeDeal фиксирует сделку по заявке против ленты, вызывать под OrderBooks.Mux
+func (dm *DealsManager) makeDeal(order *dealPkg.Order, volume int32, price float32) (*dealPkg.Deal, error) {
 	order.CompletedVolume += volume
-	deal, err := dm.ER.MakeDeal(order, volume)
+	deal, err := dm.ER.MakeDeal(order, volume, price)
 	if err != nil {
-		return err
+		order.CompletedVolume -= volume
+		return nil, err
+	}
+	dm.filled(order)
+	dm.sendToBroker(deal)
+
+	return deal, nil
+}
+
+// makeDeals фиксирует исполнение двух встречных заявок одной транзакцией, брокеры получают сделки после нее,
+// при ошибке не исполняется ни одна сторона; вызывать под OrderBooks.Mux
+func (dm *DealsManager) makeDeals(first, second *dealPkg.Order, volume int32, price float32) ([]*dealPkg.Deal, error) {
+	first.CompletedVolume += volume
+	second.CompletedVolume += volume
+	deals, err := dm.ER.MakeDeals(first, second, volume, price)
+	if err != nil {
+		first.CompletedVolume -= volume
+		second.CompletedVolume -= volume
+		return nil, err
+	}
+	dm.filled(first)
+	dm.filled(second)
+	for _, deal := range deals {
+		dm.sendToBroker(deal)
+	}
+
+	return deals, nil
+}
+
+// filled убирает из стакана исполненную заявку или обновляет уровень частично исполненной
+func (dm *DealsManager) filled(order *dealPkg.Order) {
+	if order.RemainingVolume() == 0 {
+		dm.OrderBooks.Remove(order.ID)
+	} else {
+		dm.OrderBooks.Filled(order)
 	}
-	chToBroker, ok := dm.ResultsConsumers.Channels[int64(order.BrokerID)]
+}
+
+// closeOrder снимает остаток заявки и сообщает об этом брокеру, вызывать под OrderBooks.Mux
+func (dm *DealsManager) closeOrder(order *dealPkg.Order, event string, logger *logging.Logger) {
+	deal, err := dm.ER.CloseOrder(order, event)
+	if err != nil {
+		logger.Zap.Error("not close order",
+			zap.String("logger", "closeOrder"),
+			zap.String("event", event),
+			zap.String("err", err.Error()),
+		)
+		return
+	}
+	dm.OrderBooks.Remove(order.ID)
+	dm.sendToBroker(deal)
+}
+
+// sendToBroker отправляет сделку подключенному брокеру, неподключенный получит ее из БД при подключении
+// отправка никогда не ждет брокера, т.к. вызывается под OrderBooks.Mux: при полной очереди брокер отключается
+// и после переподключения получает неподтвержденные сделки из БД
+func (dm *DealsManager) sendToBroker(deal *dealPkg.Deal) {
+	brokerID := int64(deal.BrokerID)
+	dm.ResultsConsumers.Mux.RLock()
+	chToBroker, ok := dm.ResultsConsumers.Channels[brokerID]
 	if ok {
-		chToBroker <- *deal
+		select {
+		case chToBroker <- *deal:
+			ok = false
+		default:
+		}
+	}
+	dm.ResultsConsumers.Mux.RUnlock()
+	if !ok {
+		return
 	}
 
-	return nil
+	dm.ResultsConsumers.Mux.Lock()
+	defer dm.ResultsConsumers.Mux.Unlock()
+	if dm.ResultsConsumers.Channels[brokerID] == chToBroker {
+		delete(dm.ResultsConsumers.Channels, brokerID)
+		close(chToBroker)
+		metricsPkg.ResultsDisconnected(brokerID)
+	}
+}
+
+// SweepExpiredOrders раз в секунду снимает заявки, у которых истек срок (GTD) или закончилась сессия (DAY)
+func (dm *DealsManager) SweepExpiredOrders(logger *logging.Logger) {
+	tiker := time.NewTicker(time.Second)
+	for now := range tiker.C {
+		dm.expireOrders(int32(now.Unix()), logger)
+	}
+}
+
+func (dm *DealsManager) expireOrders(now int32, logger *logging.Logger) {
+	dm.OrderBooks.Mux.Lock()
+	defer dm.OrderBooks.Mux.Unlock()
+
+	expired := make([]*dealPkg.Order, 0)
+	for _, order := range dm.OrderBooks.Orders {
+		if order.ExpiresAt > 0 && order.ExpiresAt <= now {
+			expired = append(expired, order)
+		}
+	}
+	for _, order := range expired {
+		dm.closeOrder(order, dealPkg.EventExpire, logger)
+	}
+}
+
+// dayOrderExpiration - ближайшее после now окончание торговой сессии, sessionEnd в формате 15:04
+func dayOrderExpiration(now time.Time, sessionEnd string) (int32, error) {
+	if sessionEnd == "" {
+		sessionEnd = defaultSessionEnd
+	}
+	end, err := time.Parse("15:04", sessionEnd)
+	if err != nil {
+		return 0, fmt.Errorf("parse session end %v: %v", sessionEnd, err)
+	}
+
+	expiration := time.Date(now.Year(), now.Month(), now.Day(), end.Hour(), end.Minute(), 0, 0, now.Location())
+	if !expiration.After(now) {
+		expiration = expiration.AddDate(0, 0, 1)
+	}
+	return int32(expiration.Unix()), nil
+}
+
+func (dm *DealsManager) MarkDealShipped(brokerID int32, dealID int64) error {
+	return dm.ER.MarkDealShipped(brokerID, dealID)
 }
 
-func (dm *DealsManager) MarkDealShipped(dealID int64) error {
-	return dm.ER.MarkDealShipped(dealID)
+func (dm *DealsManager) UnshippedDeals(brokerID int32) ([]*dealPkg.Deal, error) {
+	return dm.ER.GetUnshippedDeals(brokerID)
 }
 
 func calculateStats(stats map[string]*dealPkg.OHLCV, deal *dealPkg.Deal, ohclvID int64) {
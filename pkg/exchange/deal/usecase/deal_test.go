@@ -0,0 +1,945 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+	instrumentUsecasePkg "github.com/KeynihAV/exchange/pkg/exchange/instrument/usecase"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+const testTicker = "SPFB.RTS"
+
+// fakeRepo - ExchangeRepo в памяти, FailFills - сколько следующих исполнений вернут ошибку,
+// DealTime - время всех сделок
+type fakeRepo struct {
+	Orders    map[int64]*dealPkg.Order
+	Keys      map[string]int64
+	Deals     []*dealPkg.Deal
+	LastID    int64
+	FailFills int
+	DealTime  int32
+}
+
+func newFakeRepo() *fakeRepo {
+	return &fakeRepo{
+		Orders: make(map[int64]*dealPkg.Order),
+		Keys:   make(map[string]int64),
+	}
+}
+
+func (fr *fakeRepo) nextID() int64 {
+	fr.LastID++
+	return fr.LastID
+}
+
+func (fr *fakeRepo) AddOrder(order *dealPkg.Order, idempotencyKey string) (int64, error) {
+	id := fr.nextID()
+	saved := *order
+	saved.ID = id
+	fr.Orders[id] = &saved
+	if idempotencyKey != "" {
+		fr.Keys[fmt.Sprint(order.BrokerID, idempotencyKey)] = id
+	}
+	return id, nil
+}
+
+func (fr *fakeRepo) GetOrderIDByKey(brokerID int32, idempotencyKey string) (int64, error) {
+	return fr.Keys[fmt.Sprint(brokerID, idempotencyKey)], nil
+}
+
+func (fr *fakeRepo) ReplaceOrder(order *dealPkg.Order) error {
+	if _, ok := fr.Orders[order.ID]; !ok {
+		return fmt.Errorf("order %v not found", order.ID)
+	}
+	saved := *order
+	fr.Orders[order.ID] = &saved
+	return nil
+}
+
+func (fr *fakeRepo) GetOpenOrders() ([]*dealPkg.Order, error) {
+	result := make([]*dealPkg.Order, 0, len(fr.Orders))
+	for _, order := range fr.Orders {
+		copied := *order
+		result = append(result, &copied)
+	}
+	return result, nil
+}
+
+func (fr *fakeRepo) MakeDeal(order *dealPkg.Order, volumeToClose int32, price float32) (*dealPkg.Deal, error) {
+	if fr.FailFills > 0 {
+		fr.FailFills--
+		return nil, fmt.Errorf("make deal failed")
+	}
+	partial := order.RemainingVolume() != 0
+	if partial {
+		fr.Orders[order.ID].CompletedVolume = order.CompletedVolume
+	} else {
+		delete(fr.Orders, order.ID)
+	}
+	deal := &dealPkg.Deal{
+		ID:       fr.nextID(),
+		BrokerID: order.BrokerID,
+		ClientID: order.ClientID,
+		OrderID:  order.ID,
+		Ticker:   order.Ticker,
+		Volume:   volumeToClose,
+		Partial:  partial,
+		Time:     fr.DealTime,
+		Price:    price,
+		Type:     order.Type,
+		Event:    dealPkg.EventFill,
+	}
+	fr.Deals = append(fr.Deals, deal)
+	return deal, nil
+}
+
+func (fr *fakeRepo) MakeDeals(first, second *dealPkg.Order, volumeToClose int32, price float32) ([]*dealPkg.Deal, error) {
+	if fr.FailFills > 0 {
+		fr.FailFills--
+		return nil, fmt.Errorf("make deals failed")
+	}
+	deals := make([]*dealPkg.Deal, 0, 2)
+	for _, order := range []*dealPkg.Order{first, second} {
+		deal, err := fr.MakeDeal(order, volumeToClose, price)
+		if err != nil {
+			return nil, err
+		}
+		deals = append(deals, deal)
+	}
+	return deals, nil
+}
+
+func (fr *fakeRepo) orderEvent(order *dealPkg.Order, event string, price float32) *dealPkg.Deal {
+	deal := &dealPkg.Deal{
+		ID:       fr.nextID(),
+		BrokerID: order.BrokerID,
+		ClientID: order.ClientID,
+		OrderID:  order.ID,
+		Ticker:   order.Ticker,
+		Volume:   order.RemainingVolume(),
+		Price:    price,
+		Type:     order.Type,
+		Event:    event,
+	}
+	fr.Deals = append(fr.Deals, deal)
+	return deal
+}
+
+func (fr *fakeRepo) CloseOrder(order *dealPkg.Order, event string) (*dealPkg.Deal, error) {
+	delete(fr.Orders, order.ID)
+	return fr.orderEvent(order, event, order.Price), nil
+}
+
+func (fr *fakeRepo) TriggerOrder(order *dealPkg.Order) (*dealPkg.Deal, error) {
+	saved := *order
+	fr.Orders[order.ID] = &saved
+	return fr.orderEvent(order, dealPkg.EventTrigger, order.StopPrice), nil
+}
+
+func (fr *fakeRepo) MarkDealShipped(brokerID int32, dealID int64) error {
+	return nil
+}
+
+func (fr *fakeRepo) GetUnshippedDeals(brokerID int32) ([]*dealPkg.Deal, error) {
+	return nil, nil
+}
+
+// fills - объемы исполнений по заявкам в порядке сделок
+func (fr *fakeRepo) fills() map[int64][]int32 {
+	result := make(map[int64][]int32)
+	for _, deal := range fr.Deals {
+		if deal.Event == dealPkg.EventFill {
+			result[deal.OrderID] = append(result[deal.OrderID], deal.Volume)
+		}
+	}
+	return result
+}
+
+func (fr *fakeRepo) events(event string) []int64 {
+	result := make([]int64, 0)
+	for _, deal := range fr.Deals {
+		if deal.Event == event {
+			result = append(result, deal.OrderID)
+		}
+	}
+	return result
+}
+
+func newTestManager() (*DealsManager, *fakeRepo) {
+	config := &configPkg.Config{}
+	repo := newFakeRepo()
+	dm := &DealsManager{
+		Config: config,
+		ER:     repo,
+		StatsConsumers: &Consumers{
+			Mux:           &sync.RWMutex{},
+			Subscriptions: make(map[*StatsSubscription]struct{}),
+		},
+		ResultsConsumers: &ResultsConsumers{
+			Mux:      &sync.RWMutex{},
+			Channels: make(map[int64]chan dealPkg.Deal),
+		},
+		TradesConsumers: &TradesConsumers{
+			Mux:      &sync.RWMutex{},
+			Channels: make(map[chan dealPkg.TradePrint]struct{}),
+		},
+		OrderBooks: NewOrderBooks(),
+		Session: &Session{
+			Event:     dealPkg.SessionEvent{Phase: dealPkg.PhaseContinuous},
+			Consumers: make(map[chan dealPkg.SessionEvent]struct{}),
+			Mux:       &sync.RWMutex{},
+		},
+		Instruments: &instrumentUsecasePkg.InstrumentsManager{
+			Instruments: map[string]*instrumentPkg.Instrument{
+				testTicker: {Ticker: testTicker, TickSize: 1, LotSize: 1, Status: instrumentPkg.StatusTrading},
+			},
+			Mux: &sync.RWMutex{},
+		},
+		Breaker: NewBreaker(config),
+	}
+	return dm, repo
+}
+
+func testLogger() *logging.Logger {
+	return &logging.Logger{Zap: zap.NewNop()}
+}
+
+func limitOrder(orderType string, price float32, volume int32) *dealPkg.Order {
+	return &dealPkg.Order{
+		BrokerID: 1,
+		ClientID: 1,
+		Ticker:   testTicker,
+		Type:     orderType,
+		Kind:     dealPkg.KindLimit,
+		Price:    price,
+		Volume:   volume,
+	}
+}
+
+// place выставляет заявки по очереди и возвращает их ID
+func place(t *testing.T, dm *DealsManager, orders ...*dealPkg.Order) []int64 {
+	t.Helper()
+	ids := make([]int64, len(orders))
+	for i, order := range orders {
+		id, err := dm.CreateOrder(order, "", testLogger())
+		if err != nil {
+			t.Fatalf("create order %v: %v", i, err)
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+func bookIDs(orders []*dealPkg.Order) []int64 {
+	result := make([]int64, len(orders))
+	for i, order := range orders {
+		result[i] = order.ID
+	}
+	return result
+}
+
+func TestOrderBook_Priority(t *testing.T) {
+	tests := []struct {
+		name     string
+		orders   []*dealPkg.Order
+		wantBids []int64
+		wantAsks []int64
+	}{
+		{name: "Покупки по убыванию цены",
+			orders: []*dealPkg.Order{
+				{ID: 1, Type: dealPkg.TypeBuy, Price: 100, Time: 1},
+				{ID: 2, Type: dealPkg.TypeBuy, Price: 102, Time: 2},
+				{ID: 3, Type: dealPkg.TypeBuy, Price: 101, Time: 3},
+			},
+			wantBids: []int64{2, 3, 1},
+			wantAsks: []int64{},
+		},
+		{name: "Продажи по возрастанию цены",
+			orders: []*dealPkg.Order{
+				{ID: 1, Type: dealPkg.TypeSell, Price: 102, Time: 1},
+				{ID: 2, Type: dealPkg.TypeSell, Price: 100, Time: 2},
+				{ID: 3, Type: dealPkg.TypeSell, Price: 101, Time: 3},
+			},
+			wantBids: []int64{},
+			wantAsks: []int64{2, 3, 1},
+		},
+		{name: "Внутри цены по порядку поступления, даже в одну секунду",
+			orders: []*dealPkg.Order{
+				{ID: 3, Type: dealPkg.TypeBuy, Price: 100, Time: 2},
+				{ID: 2, Type: dealPkg.TypeBuy, Price: 100, Time: 2},
+				{ID: 1, Type: dealPkg.TypeBuy, Price: 100, Time: 2},
+			},
+			wantBids: []int64{3, 2, 1},
+			wantAsks: []int64{},
+		},
+		{name: "Стоп-заявки в стакане не участвуют",
+			orders: []*dealPkg.Order{
+				{ID: 1, Type: dealPkg.TypeBuy, Kind: dealPkg.KindStop, StopPrice: 110, Time: 1},
+				{ID: 2, Type: dealPkg.TypeSell, Price: 105, Time: 1},
+			},
+			wantBids: []int64{},
+			wantAsks: []int64{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obs := NewOrderBooks()
+			for _, order := range tt.orders {
+				order.Ticker = testTicker
+				if order.Kind == "" {
+					order.Kind = dealPkg.KindLimit
+				}
+				obs.Add(order)
+			}
+			book := obs.Books[testTicker]
+			if got := bookIDs(book.Bids); !reflect.DeepEqual(got, tt.wantBids) {
+				t.Errorf("bids = %v, want %v", got, tt.wantBids)
+			}
+			if got := bookIDs(book.Asks); !reflect.DeepEqual(got, tt.wantAsks) {
+				t.Errorf("asks = %v, want %v", got, tt.wantAsks)
+			}
+		})
+	}
+}
+
+func TestOrderBooks_DepthUpdates(t *testing.T) {
+	obs := NewOrderBooks()
+	obs.Add(&dealPkg.Order{ID: 1, Ticker: testTicker, Type: dealPkg.TypeBuy, Kind: dealPkg.KindLimit, Price: 100, Volume: 3})
+
+	all, snapshots := obs.Subscribe("")
+	rts, _ := obs.Subscribe(testTicker)
+	si, siSnapshots := obs.Subscribe("SPFB.Si")
+	wantSnapshots := []dealPkg.DepthUpdate{{Ticker: testTicker, Snapshot: true, Sequence: 1,
+		Bids: []dealPkg.PriceLevel{{Price: 100, Volume: 3}}, Asks: []dealPkg.PriceLevel{}}}
+	if !reflect.DeepEqual(snapshots, wantSnapshots) {
+		t.Fatalf("snapshots = %+v, want %+v", snapshots, wantSnapshots)
+	}
+	if len(siSnapshots) != 1 || siSnapshots[0].Sequence != 0 || len(siSnapshots[0].Bids) != 0 {
+		t.Fatalf("snapshot of empty book = %+v", siSnapshots)
+	}
+
+	obs.Mux.Lock()
+	second := &dealPkg.Order{ID: 2, Ticker: testTicker, Type: dealPkg.TypeBuy, Kind: dealPkg.KindLimit, Price: 100, Volume: 2}
+	obs.Add(second)
+	obs.Add(&dealPkg.Order{ID: 3, Ticker: testTicker, Type: dealPkg.TypeSell, Kind: dealPkg.KindStop, StopPrice: 90, Volume: 5})
+	second.CompletedVolume = 1
+	obs.Filled(second)
+	obs.Remove(1)
+	obs.Mux.Unlock()
+
+	//стоп-заявка уровни не меняет и номер изменения не тратит
+	want := []dealPkg.DepthUpdate{
+		{Ticker: testTicker, Sequence: 2, Bids: []dealPkg.PriceLevel{{Price: 100, Volume: 5}}},
+		{Ticker: testTicker, Sequence: 3, Bids: []dealPkg.PriceLevel{{Price: 100, Volume: 4}}},
+		{Ticker: testTicker, Sequence: 4, Bids: []dealPkg.PriceLevel{{Price: 100, Volume: 1}}},
+	}
+	for name, ch := range map[string]chan dealPkg.DepthUpdate{"all": all, testTicker: rts} {
+		got := make([]dealPkg.DepthUpdate, 0)
+		for len(ch) > 0 {
+			got = append(got, <-ch)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v updates = %+v, want %+v", name, got, want)
+		}
+	}
+	if len(si) != 0 {
+		t.Errorf("updates of other ticker = %v, want none", len(si))
+	}
+
+	obs.Unsubscribe(si)
+	if _, ok := <-si; ok {
+		t.Errorf("channel is not closed after Unsubscribe")
+	}
+	obs.Unsubscribe(si)
+}
+
+func TestOrderBooks_SlowDepthConsumer(t *testing.T) {
+	obs := NewOrderBooks()
+	slow := make(chan dealPkg.DepthUpdate, 1)
+	obs.DepthConsumers[slow] = ""
+	fast, _ := obs.Subscribe("")
+
+	obs.Mux.Lock()
+	obs.Add(&dealPkg.Order{ID: 1, Ticker: testTicker, Type: dealPkg.TypeSell, Kind: dealPkg.KindLimit, Price: 100, Volume: 1})
+	obs.Add(&dealPkg.Order{ID: 2, Ticker: testTicker, Type: dealPkg.TypeSell, Kind: dealPkg.KindLimit, Price: 101, Volume: 1})
+	obs.Mux.Unlock()
+
+	if _, ok := obs.DepthConsumers[slow]; ok {
+		t.Fatalf("slow consumer is not disconnected")
+	}
+	if update, ok := <-slow; !ok || update.Sequence != 1 {
+		t.Errorf("slow consumer first update = %+v, %v, want sequence 1", update, ok)
+	}
+	if _, ok := <-slow; ok {
+		t.Errorf("slow consumer channel is not closed")
+	}
+	if len(fast) != 2 {
+		t.Errorf("fast consumer updates = %v, want 2", len(fast))
+	}
+	//отключенный за переполнение подписчик отписывается без повторного закрытия канала
+	obs.Unsubscribe(slow)
+}
+
+func TestDealsManager_MatchWithTape(t *testing.T) {
+	tests := []struct {
+		name      string
+		orders    []*dealPkg.Order
+		tape      dealPkg.Deal
+		wantFills map[int64][]int32
+		wantBook  []int64
+	}{
+		{name: "Покупка исполняется по своей цене при сделке не выше",
+			orders:    []*dealPkg.Order{limitOrder(dealPkg.TypeBuy, 100, 5)},
+			tape:      dealPkg.Deal{Ticker: testTicker, Price: 99, Volume: 10},
+			wantFills: map[int64][]int32{1: {5}},
+			wantBook:  []int64{},
+		},
+		{name: "Сделка выше цены покупки ее не исполняет",
+			orders:    []*dealPkg.Order{limitOrder(dealPkg.TypeBuy, 100, 5)},
+			tape:      dealPkg.Deal{Ticker: testTicker, Price: 101, Volume: 10},
+			wantFills: map[int64][]int32{},
+			wantBook:  []int64{1},
+		},
+		{name: "Объем сделки делится по приоритету, остаток ждет",
+			orders: []*dealPkg.Order{
+				limitOrder(dealPkg.TypeSell, 101, 3),
+				limitOrder(dealPkg.TypeSell, 100, 3),
+			},
+			tape:      dealPkg.Deal{Ticker: testTicker, Price: 101, Volume: 4},
+			wantFills: map[int64][]int32{2: {3}, 1: {1}},
+			wantBook:  []int64{1},
+		},
+		{name: "Сделка по другому инструменту заявки не трогает",
+			orders:    []*dealPkg.Order{limitOrder(dealPkg.TypeBuy, 100, 5)},
+			tape:      dealPkg.Deal{Ticker: "SPFB.Si", Price: 90, Volume: 10},
+			wantFills: map[int64][]int32{},
+			wantBook:  []int64{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, repo := newTestManager()
+			place(t, dm, tt.orders...)
+
+			dm.matchWithTape(&tt.tape, testLogger())
+
+			if got := repo.fills(); !reflect.DeepEqual(got, tt.wantFills) {
+				t.Errorf("fills = %v, want %v", got, tt.wantFills)
+			}
+			got := make([]int64, 0)
+			for id := range dm.OrderBooks.Orders {
+				got = append(got, id)
+			}
+			if !reflect.DeepEqual(got, tt.wantBook) {
+				t.Errorf("book = %v, want %v", got, tt.wantBook)
+			}
+		})
+	}
+}
+
+func TestTapePrint(t *testing.T) {
+	lastPrints := make(map[string]dealPkg.TradePrint)
+	const now = 1670000000
+	tests := []struct {
+		name string
+		deal *dealPkg.Deal
+		want dealPkg.TradePrint
+	}{
+		{name: "Первая сделка без стороны",
+			deal: &dealPkg.Deal{Ticker: testTicker, Price: 100, Volume: 1, Time: 1000},
+			want: dealPkg.TradePrint{Ticker: testTicker, Price: 100, Volume: 1, Time: now},
+		},
+		{name: "Рост цены - покупка",
+			deal: &dealPkg.Deal{Ticker: testTicker, Price: 101, Volume: 2, Time: 1001},
+			want: dealPkg.TradePrint{Ticker: testTicker, Price: 101, Volume: 2, Type: dealPkg.TypeBuy, Time: now},
+		},
+		{name: "Та же цена - сторона предыдущей сделки",
+			deal: &dealPkg.Deal{Ticker: testTicker, Price: 101, Volume: 3, Time: 1001},
+			want: dealPkg.TradePrint{Ticker: testTicker, Price: 101, Volume: 3, Type: dealPkg.TypeBuy, Time: now},
+		},
+		{name: "Другой инструмент считается отдельно",
+			deal: &dealPkg.Deal{Ticker: "SPFB.Si", Price: 50, Volume: 1, Time: 900},
+			want: dealPkg.TradePrint{Ticker: "SPFB.Si", Price: 50, Volume: 1, Time: now},
+		},
+		{name: "Падение цены - продажа, время по часам, а не из ленты",
+			deal: &dealPkg.Deal{Ticker: testTicker, Price: 99, Volume: 1, Time: 950},
+			want: dealPkg.TradePrint{Ticker: testTicker, Price: 99, Volume: 1, Type: dealPkg.TypeSell, Time: now},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tapePrint(tt.deal, lastPrints, now); got != tt.want {
+				t.Errorf("tapePrint() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDealsManager_PublishTrade(t *testing.T) {
+	dm, repo := newTestManager()
+	repo.DealTime = 1670000000
+	ch := make(chan dealPkg.TradePrint, 1)
+	full := make(chan dealPkg.TradePrint)
+	dm.TradesConsumers.Channels[ch] = struct{}{}
+	dm.TradesConsumers.Channels[full] = struct{}{}
+
+	//подписчик без места в канале торги не останавливает
+	place(t, dm, limitOrder(dealPkg.TypeSell, 100, 5), limitOrder(dealPkg.TypeBuy, 101, 2))
+
+	want := dealPkg.TradePrint{Ticker: testTicker, Price: 100, Volume: 2, Type: dealPkg.TypeBuy, Time: repo.DealTime}
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Errorf("trade = %+v, want %+v", got, want)
+		}
+	default:
+		t.Fatalf("trade is not published")
+	}
+
+	//переполненный канал пропускает сделку
+	place(t, dm, limitOrder(dealPkg.TypeBuy, 100, 1), limitOrder(dealPkg.TypeBuy, 100, 1))
+	if len(ch) != 1 {
+		t.Errorf("queued trades = %v, want 1", len(ch))
+	}
+}
+
+func TestDealsManager_SendToBroker(t *testing.T) {
+	dm, _ := newTestManager()
+	ch := make(chan dealPkg.Deal, 1)
+	dm.ResultsConsumers.Channels[1] = ch
+
+	dm.sendToBroker(&dealPkg.Deal{ID: 1, BrokerID: 1})
+	if len(ch) != 1 {
+		t.Fatalf("deal not queued")
+	}
+
+	//очередь полна: отправка не ждет, брокер отключается
+	dm.sendToBroker(&dealPkg.Deal{ID: 2, BrokerID: 1})
+	if _, ok := dm.ResultsConsumers.Channels[1]; ok {
+		t.Errorf("lagging broker is still subscribed")
+	}
+	<-ch
+	if _, ok := <-ch; ok {
+		t.Errorf("channel of lagging broker is not closed")
+	}
+
+	//неподключенный брокер получит сделку из БД
+	dm.sendToBroker(&dealPkg.Deal{ID: 3, BrokerID: 2})
+}
+
+func TestDealsManager_MatchWithBook(t *testing.T) {
+	tests := []struct {
+		name      string
+		resting   []*dealPkg.Order
+		incoming  *dealPkg.Order
+		failFills int
+		wantFills map[int64][]int32
+		wantPrice float32
+		wantBook  []int64
+	}{
+		{name: "Исполнение по цене стоящей заявки",
+			resting:   []*dealPkg.Order{limitOrder(dealPkg.TypeSell, 100, 5)},
+			incoming:  limitOrder(dealPkg.TypeBuy, 102, 5),
+			wantFills: map[int64][]int32{1: {5}, 2: {5}},
+			wantPrice: 100,
+			wantBook:  []int64{},
+		},
+		{name: "Остаток новой заявки встает в стакан",
+			resting:   []*dealPkg.Order{limitOrder(dealPkg.TypeSell, 100, 3)},
+			incoming:  limitOrder(dealPkg.TypeBuy, 100, 5),
+			wantFills: map[int64][]int32{1: {3}, 2: {3}},
+			wantPrice: 100,
+			wantBook:  []int64{2},
+		},
+		{name: "Заявки разных брокеров исполняются между собой",
+			resting: []*dealPkg.Order{
+				{BrokerID: 2, ClientID: 7, Ticker: testTicker, Type: dealPkg.TypeBuy, Kind: dealPkg.KindLimit, Price: 100, Volume: 2},
+			},
+			incoming:  limitOrder(dealPkg.TypeSell, 99, 2),
+			wantFills: map[int64][]int32{1: {2}, 2: {2}},
+			wantPrice: 100,
+			wantBook:  []int64{},
+		},
+		{name: "Ошибка БД не исполняет ни одну из сторон",
+			resting:   []*dealPkg.Order{limitOrder(dealPkg.TypeSell, 100, 5)},
+			incoming:  limitOrder(dealPkg.TypeBuy, 100, 5),
+			failFills: 1,
+			wantFills: map[int64][]int32{},
+			wantBook:  []int64{1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, repo := newTestManager()
+			results := make(chan dealPkg.Deal, 10)
+			dm.ResultsConsumers.Channels[1] = results
+			place(t, dm, tt.resting...)
+			repo.FailFills = tt.failFills
+
+			place(t, dm, tt.incoming)
+
+			if got := repo.fills(); !reflect.DeepEqual(got, tt.wantFills) {
+				t.Errorf("fills = %v, want %v", got, tt.wantFills)
+			}
+			for _, deal := range repo.Deals {
+				if deal.Price != tt.wantPrice {
+					t.Errorf("deal price = %v, want %v", deal.Price, tt.wantPrice)
+				}
+			}
+			for _, order := range dm.OrderBooks.Orders {
+				if order.CompletedVolume != repo.Orders[order.ID].CompletedVolume {
+					t.Errorf("order %v completed %v in book, %v in db", order.ID, order.CompletedVolume, repo.Orders[order.ID].CompletedVolume)
+				}
+			}
+			got := make([]int64, 0)
+			for id := 1; id <= 2; id++ {
+				if _, ok := dm.OrderBooks.Orders[int64(id)]; ok {
+					got = append(got, int64(id))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantBook) {
+				t.Errorf("book = %v, want %v", got, tt.wantBook)
+			}
+			var wantSent int
+			for _, deal := range repo.Deals {
+				if deal.BrokerID == 1 {
+					wantSent++
+				}
+			}
+			if len(results) != wantSent {
+				t.Errorf("sent %v deals to broker, want %v", len(results), wantSent)
+			}
+		})
+	}
+}
+
+func TestDealsManager_CreateOrderKinds(t *testing.T) {
+	tests := []struct {
+		name        string
+		kind        string
+		price       float32
+		volume      int32
+		wantFills   []int32
+		wantCancel  bool
+		wantResting bool
+	}{
+		{name: "Рыночная исполняется по любой цене, остаток снимается",
+			kind: dealPkg.KindMarket, volume: 10,
+			wantFills: []int32{3, 4}, wantCancel: true,
+		},
+		{name: "IOC исполняется в пределах цены, остаток снимается",
+			kind: dealPkg.KindIOC, price: 100, volume: 5,
+			wantFills: []int32{3}, wantCancel: true,
+		},
+		{name: "FOK без достаточного объема снимается целиком",
+			kind: dealPkg.KindFOK, price: 101, volume: 8,
+			wantCancel: true,
+		},
+		{name: "FOK с достаточным объемом исполняется целиком",
+			kind: dealPkg.KindFOK, price: 101, volume: 7,
+			wantFills: []int32{3, 4},
+		},
+		{name: "Лимитная остатком встает в стакан",
+			kind: dealPkg.KindLimit, price: 100, volume: 5,
+			wantFills: []int32{3}, wantResting: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, repo := newTestManager()
+			place(t, dm, limitOrder(dealPkg.TypeSell, 100, 3), limitOrder(dealPkg.TypeSell, 101, 4))
+			order := limitOrder(dealPkg.TypeBuy, tt.price, tt.volume)
+			order.Kind = tt.kind
+
+			id := place(t, dm, order)[0]
+
+			if got := repo.fills()[id]; !reflect.DeepEqual(got, tt.wantFills) {
+				t.Errorf("fills = %v, want %v", got, tt.wantFills)
+			}
+			cancelled := reflect.DeepEqual(repo.events(dealPkg.EventCancel), []int64{id})
+			if cancelled != tt.wantCancel {
+				t.Errorf("cancelled = %v, want %v", cancelled, tt.wantCancel)
+			}
+			if _, ok := dm.OrderBooks.Orders[id]; ok != tt.wantResting {
+				t.Errorf("resting = %v, want %v", ok, tt.wantResting)
+			}
+		})
+	}
+}
+
+func TestDealsManager_TriggerStops(t *testing.T) {
+	tests := []struct {
+		name        string
+		stop        *dealPkg.Order
+		tape        dealPkg.Deal
+		wantTrigger bool
+		wantFills   []int32
+		wantResting bool
+	}{
+		{name: "Стоп на покупку не срабатывает ниже стоп-цены",
+			stop:        &dealPkg.Order{Type: dealPkg.TypeBuy, Kind: dealPkg.KindStop, StopPrice: 105, Volume: 2},
+			tape:        dealPkg.Deal{Ticker: testTicker, Price: 104, Volume: 10},
+			wantResting: true,
+		},
+		{name: "Стоп на покупку исполняется по стакану",
+			stop:        &dealPkg.Order{Type: dealPkg.TypeBuy, Kind: dealPkg.KindStop, StopPrice: 105, Volume: 2},
+			tape:        dealPkg.Deal{Ticker: testTicker, Price: 105, Volume: 10},
+			wantTrigger: true,
+			wantFills:   []int32{2},
+		},
+		{name: "Остаток рыночного стопа исполняется по цене ленты",
+			stop:        &dealPkg.Order{Type: dealPkg.TypeBuy, Kind: dealPkg.KindStop, StopPrice: 105, Volume: 7},
+			tape:        dealPkg.Deal{Ticker: testTicker, Price: 106, Volume: 10},
+			wantTrigger: true,
+			wantFills:   []int32{3, 4},
+		},
+		{name: "Стоп-лимит остатком встает в стакан",
+			stop:        &dealPkg.Order{Type: dealPkg.TypeBuy, Kind: dealPkg.KindStopLimit, StopPrice: 105, Price: 105, Volume: 7},
+			tape:        dealPkg.Deal{Ticker: testTicker, Price: 106, Volume: 10},
+			wantTrigger: true,
+			wantFills:   []int32{3},
+			wantResting: true,
+		},
+		{name: "Стоп на продажу срабатывает сверху вниз",
+			stop:        &dealPkg.Order{Type: dealPkg.TypeSell, Kind: dealPkg.KindStop, StopPrice: 95, Volume: 2},
+			tape:        dealPkg.Deal{Ticker: testTicker, Price: 94, Volume: 10},
+			wantTrigger: true,
+			wantFills:   []int32{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, repo := newTestManager()
+			place(t, dm, limitOrder(dealPkg.TypeSell, 105, 3), limitOrder(dealPkg.TypeBuy, 90, 5))
+			tt.stop.BrokerID, tt.stop.ClientID, tt.stop.Ticker = 1, 1, testTicker
+			id := place(t, dm, tt.stop)[0]
+
+			dm.matchWithTape(&tt.tape, testLogger())
+
+			triggered := reflect.DeepEqual(repo.events(dealPkg.EventTrigger), []int64{id})
+			if triggered != tt.wantTrigger {
+				t.Errorf("triggered = %v, want %v", triggered, tt.wantTrigger)
+			}
+			if got := repo.fills()[id]; !reflect.DeepEqual(got, tt.wantFills) {
+				t.Errorf("fills = %v, want %v", got, tt.wantFills)
+			}
+			if _, ok := dm.OrderBooks.Orders[id]; ok != tt.wantResting {
+				t.Errorf("resting = %v, want %v", ok, tt.wantResting)
+			}
+		})
+	}
+}
+
+func TestDayOrderExpiration(t *testing.T) {
+	tests := []struct {
+		name       string
+		now        time.Time
+		sessionEnd string
+		want       time.Time
+		wantErr    bool
+	}{
+		{name: "До окончания сессии - сегодня",
+			now:        time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC),
+			sessionEnd: "18:45",
+			want:       time.Date(2023, 3, 1, 18, 45, 0, 0, time.UTC),
+		},
+		{name: "После окончания сессии - завтра",
+			now:        time.Date(2023, 3, 1, 19, 0, 0, 0, time.UTC),
+			sessionEnd: "18:45",
+			want:       time.Date(2023, 3, 2, 18, 45, 0, 0, time.UTC),
+		},
+		{name: "Ровно в окончание сессии - завтра",
+			now:        time.Date(2023, 3, 1, 18, 45, 0, 0, time.UTC),
+			sessionEnd: "18:45",
+			want:       time.Date(2023, 3, 2, 18, 45, 0, 0, time.UTC),
+		},
+		{name: "По умолчанию 23:50",
+			now:  time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC),
+			want: time.Date(2023, 3, 1, 23, 50, 0, 0, time.UTC),
+		},
+		{name: "Ошибка формата",
+			now:        time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC),
+			sessionEnd: "6pm",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := dayOrderExpiration(tt.now, tt.sessionEnd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dayOrderExpiration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != int32(tt.want.Unix()) {
+				t.Errorf("dayOrderExpiration() = %v, want %v", time.Unix(int64(got), 0).UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestDealsManager_ExpireOrders(t *testing.T) {
+	dm, repo := newTestManager()
+	gtc := limitOrder(dealPkg.TypeBuy, 90, 1)
+	gtd := limitOrder(dealPkg.TypeBuy, 91, 1)
+	gtd.TimeInForce, gtd.ExpiresAt = dealPkg.TimeInForceGTD, int32(time.Now().Unix())+60
+	later := limitOrder(dealPkg.TypeBuy, 92, 1)
+	later.TimeInForce, later.ExpiresAt = dealPkg.TimeInForceGTD, int32(time.Now().Unix())+120
+	ids := place(t, dm, gtc, gtd, later)
+
+	dm.expireOrders(int32(time.Now().Unix())+60, testLogger())
+
+	if got := repo.events(dealPkg.EventExpire); !reflect.DeepEqual(got, []int64{ids[1]}) {
+		t.Errorf("expired = %v, want %v", got, []int64{ids[1]})
+	}
+	for i, id := range ids {
+		if _, ok := dm.OrderBooks.Orders[id]; ok == (i == 1) {
+			t.Errorf("order %v resting = %v", id, ok)
+		}
+	}
+
+	past := limitOrder(dealPkg.TypeBuy, 93, 1)
+	past.TimeInForce, past.ExpiresAt = dealPkg.TimeInForceGTD, 1
+	_, err := dm.CreateOrder(past, "", testLogger())
+	if err == nil {
+		t.Errorf("order with expiration in the past accepted")
+	}
+}
+
+func TestDealsManager_ReplaceOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		price     float32
+		volume    int32
+		wantQueue []int64
+		wantFills map[int64][]int32
+		wantErr   bool
+	}{
+		{name: "Уменьшение остатка сохраняет место в очереди",
+			volume:    1,
+			wantQueue: []int64{1, 2},
+			wantFills: map[int64][]int32{},
+		},
+		{name: "Увеличение остатка ставит в конец очереди",
+			volume:    5,
+			wantQueue: []int64{2, 1},
+			wantFills: map[int64][]int32{},
+		},
+		{name: "Смена цены ставит в конец очереди",
+			price:     99,
+			wantQueue: []int64{2},
+			wantFills: map[int64][]int32{},
+		},
+		{name: "По новой цене заявка сразу исполняется",
+			price:     105,
+			wantQueue: []int64{2},
+			wantFills: map[int64][]int32{1: {2}, 3: {2}},
+		},
+		{name: "Отрицательный объем отклоняется",
+			volume:    -1,
+			wantQueue: []int64{1, 2},
+			wantFills: map[int64][]int32{},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, repo := newTestManager()
+			place(t, dm, limitOrder(dealPkg.TypeBuy, 100, 2), limitOrder(dealPkg.TypeBuy, 100, 2),
+				limitOrder(dealPkg.TypeSell, 105, 3))
+
+			_, err := dm.ReplaceOrder(1, tt.price, tt.volume, testLogger())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReplaceOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			queue := make([]int64, 0)
+			for _, order := range dm.OrderBooks.Books[testTicker].Bids {
+				if order.Price == 100 {
+					queue = append(queue, order.ID)
+				}
+			}
+			if !reflect.DeepEqual(queue, tt.wantQueue) {
+				t.Errorf("queue at 100 = %v, want %v", queue, tt.wantQueue)
+			}
+			if got := repo.fills(); !reflect.DeepEqual(got, tt.wantFills) {
+				t.Errorf("fills = %v, want %v", got, tt.wantFills)
+			}
+		})
+	}
+}
+
+func TestDealsManager_ReplaceFilledOrder(t *testing.T) {
+	dm, _ := newTestManager()
+	place(t, dm, limitOrder(dealPkg.TypeBuy, 100, 5), limitOrder(dealPkg.TypeSell, 100, 2))
+
+	//объем задается вместе с исполненным, поэтому не зависит от того, сколько сделок уже дошло до брокера
+	if _, err := dm.ReplaceOrder(1, 0, 2, testLogger()); !errors.Is(err, dealPkg.ErrInvalidOrder) {
+		t.Errorf("ReplaceOrder() to filled volume error = %v, want %v", err, dealPkg.ErrInvalidOrder)
+	}
+	order, err := dm.ReplaceOrder(1, 0, 4, testLogger())
+	if err != nil {
+		t.Fatalf("ReplaceOrder() error = %v", err)
+	}
+	if order.Volume != 4 || order.CompletedVolume != 2 || order.Price != 100 {
+		t.Errorf("replaced order = %+v, want volume 4 completed 2 price 100", order)
+	}
+	if resting := dm.OrderBooks.Orders[1]; resting.RemainingVolume() != 2 {
+		t.Errorf("remaining = %v, want 2", resting.RemainingVolume())
+	}
+}
+
+func TestDealsManager_CheckInstrument(t *testing.T) {
+	tests := []struct {
+		name          string
+		instruments   map[string]*instrumentPkg.Instrument
+		allowUnlisted bool
+		ticker        string
+		wantErr       error
+	}{
+		{name: "Инструмент из справочника",
+			ticker: testTicker,
+		},
+		{name: "Инструмента нет в справочнике",
+			ticker:  "SPFB.Si",
+			wantErr: dealPkg.ErrInvalidOrder,
+		},
+		{name: "Инструмента нет в справочнике, allowUnlisted только для пустого",
+			allowUnlisted: true,
+			ticker:        "SPFB.Si",
+			wantErr:       dealPkg.ErrInvalidOrder,
+		},
+		{name: "Пустой справочник отклоняет заявки",
+			instruments: map[string]*instrumentPkg.Instrument{},
+			ticker:      "SPFB.Si",
+			wantErr:     dealPkg.ErrInvalidOrder,
+		},
+		{name: "Пустой справочник с allowUnlisted не проверяется",
+			instruments:   map[string]*instrumentPkg.Instrument{},
+			allowUnlisted: true,
+			ticker:        "SPFB.Si",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, _ := newTestManager()
+			dm.Config.Exchange.AllowUnlisted = tt.allowUnlisted
+			if tt.instruments != nil {
+				dm.Instruments.Instruments = tt.instruments
+			}
+			order := limitOrder(dealPkg.TypeBuy, 100, 1)
+			order.Ticker = tt.ticker
+
+			if _, err := dm.CreateOrder(order, "", testLogger()); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CreateOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"sort"
+	"sync"
+
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+)
+
+// OrderBook - стакан заявок по одному инструменту
+// Bids отсортированы по убыванию цены, Asks - по возрастанию, внутри цены - по очереди поступления (Seq)
+// Stops - несработавшие стоп-заявки, в стакане не участвуют
+// Sequence - номер последнего изменения ценовых уровней для подписчиков на стакан
+type OrderBook struct {
+	Bids     []*dealPkg.Order
+	Asks     []*dealPkg.Order
+	Stops    []*dealPkg.Order
+	Sequence int64
+}
+
+// depthBuffer - сколько обновлений стакана может ждать отправки подписчику, медленный подписчик отключается
+const depthBuffer = 10000
+
+// OrderBooks - стаканы всех инструментов, DepthConsumers - подписчики на изменения уровней с фильтром по тикеру,
+// LastSeq - последнее выданное место в очереди: время заявки в секундах не различает заявки одной секунды
+type OrderBooks struct {
+	Books          map[string]*OrderBook
+	Orders         map[int64]*dealPkg.Order
+	DepthConsumers map[chan dealPkg.DepthUpdate]string
+	LastSeq        int64
+	Mux            *sync.Mutex
+}
+
+func NewOrderBooks() *OrderBooks {
+	return &OrderBooks{
+		Books:          make(map[string]*OrderBook),
+		Orders:         make(map[int64]*dealPkg.Order),
+		DepthConsumers: make(map[chan dealPkg.DepthUpdate]string),
+		Mux:            &sync.Mutex{},
+	}
+}
+
+// Add кладет заявку в стакан, заявка без места в очереди встает в конец, вызывать под Mux
+func (obs *OrderBooks) Add(order *dealPkg.Order) {
+	if order.Seq == 0 {
+		obs.LastSeq++
+		order.Seq = obs.LastSeq
+	}
+	book, ok := obs.Books[order.Ticker]
+	if !ok {
+		book = &OrderBook{}
+		obs.Books[order.Ticker] = book
+	}
+	book.add(order)
+	obs.Orders[order.ID] = order
+	obs.levelChanged(book, order)
+}
+
+// Remove убирает заявку из стакана, вызывать под Mux
+func (obs *OrderBooks) Remove(orderID int64) *dealPkg.Order {
+	order, ok := obs.Orders[orderID]
+	if !ok {
+		return nil
+	}
+	delete(obs.Orders, orderID)
+	book, ok := obs.Books[order.Ticker]
+	if ok {
+		book.remove(order)
+		obs.levelChanged(book, order)
+	}
+	return order
+}
+
+// Filled сообщает подписчикам об уменьшении остатка стоящей в стакане заявки, вызывать под Mux
+func (obs *OrderBooks) Filled(order *dealPkg.Order) {
+	if _, ok := obs.Orders[order.ID]; !ok {
+		return
+	}
+	if book, ok := obs.Books[order.Ticker]; ok {
+		obs.levelChanged(book, order)
+	}
+}
+
+// Subscribe регистрирует подписчика на стакан ticker (пустой - все инструменты) и возвращает снимки стаканов,
+// снимки и регистрация под одной блокировкой, поэтому между ними изменения не теряются
+func (obs *OrderBooks) Subscribe(ticker string) (chan dealPkg.DepthUpdate, []dealPkg.DepthUpdate) {
+	obs.Mux.Lock()
+	defer obs.Mux.Unlock()
+
+	snapshots := make([]dealPkg.DepthUpdate, 0)
+	if ticker != "" {
+		book, ok := obs.Books[ticker]
+		if !ok {
+			book = &OrderBook{}
+		}
+		snapshots = append(snapshots, book.snapshot(ticker))
+	} else {
+		for bookTicker, book := range obs.Books {
+			snapshots = append(snapshots, book.snapshot(bookTicker))
+		}
+	}
+
+	ch := make(chan dealPkg.DepthUpdate, depthBuffer)
+	obs.DepthConsumers[ch] = ticker
+	return ch, snapshots
+}
+
+// Unsubscribe убирает подписчика, если он еще не был отключен за переполнение
+func (obs *OrderBooks) Unsubscribe(ch chan dealPkg.DepthUpdate) {
+	obs.Mux.Lock()
+	defer obs.Mux.Unlock()
+
+	if _, ok := obs.DepthConsumers[ch]; ok {
+		delete(obs.DepthConsumers, ch)
+		close(ch)
+	}
+}
+
+// levelChanged отправляет подписчикам новый объем уровня, на котором стоит заявка
+func (obs *OrderBooks) levelChanged(book *OrderBook, order *dealPkg.Order) {
+	if dealPkg.IsStopKind(order.Kind) {
+		return
+	}
+	book.Sequence++
+	if len(obs.DepthConsumers) == 0 {
+		return
+	}
+
+	update := dealPkg.DepthUpdate{Ticker: order.Ticker, Sequence: book.Sequence}
+	level := []dealPkg.PriceLevel{{Price: order.Price, Volume: book.levelVolume(order.Type, order.Price)}}
+	if order.Type == dealPkg.TypeBuy {
+		update.Bids = level
+	} else {
+		update.Asks = level
+	}
+
+	for ch, ticker := range obs.DepthConsumers {
+		if ticker != "" && ticker != order.Ticker {
+			continue
+		}
+		select {
+		case ch <- update:
+		default:
+			//пропуск обновления испортит стакан подписчика, пусть переподпишется и получит снимок
+			delete(obs.DepthConsumers, ch)
+			close(ch)
+		}
+	}
+}
+
+// snapshot - ценовые уровни стакана от лучших цен
+func (ob *OrderBook) snapshot(ticker string) dealPkg.DepthUpdate {
+	return dealPkg.DepthUpdate{
+		Ticker:   ticker,
+		Snapshot: true,
+		Bids:     levels(ob.Bids),
+		Asks:     levels(ob.Asks),
+		Sequence: ob.Sequence,
+	}
+}
+
+func (ob *OrderBook) levelVolume(orderType string, price float32) int32 {
+	var volume int32
+	for _, order := range *ob.side(orderType) {
+		if order.Price == price {
+			volume += order.RemainingVolume()
+		}
+	}
+	return volume
+}
+
+// levels сворачивает отсортированные по приоритету заявки в ценовые уровни
+func levels(orders []*dealPkg.Order) []dealPkg.PriceLevel {
+	result := make([]dealPkg.PriceLevel, 0)
+	for _, order := range orders {
+		if len(result) > 0 && result[len(result)-1].Price == order.Price {
+			result[len(result)-1].Volume += order.RemainingVolume()
+			continue
+		}
+		result = append(result, dealPkg.PriceLevel{Price: order.Price, Volume: order.RemainingVolume()})
+	}
+	return result
+}
+
+func (ob *OrderBook) side(orderType string) *[]*dealPkg.Order {
+	if orderType == dealPkg.TypeBuy {
+		return &ob.Bids
+	}
+	return &ob.Asks
+}
+
+func (ob *OrderBook) add(order *dealPkg.Order) {
+	if dealPkg.IsStopKind(order.Kind) {
+		ob.Stops = append(ob.Stops, order)
+		return
+	}
+	orders := ob.side(order.Type)
+	i := sort.Search(len(*orders), func(i int) bool {
+		return higherPriority(order, (*orders)[i])
+	})
+	*orders = append(*orders, nil)
+	copy((*orders)[i+1:], (*orders)[i:])
+	(*orders)[i] = order
+}
+
+func (ob *OrderBook) remove(order *dealPkg.Order) {
+	orders := ob.side(order.Type)
+	if dealPkg.IsStopKind(order.Kind) {
+		orders = &ob.Stops
+	}
+	for i, o := range *orders {
+		if o.ID == order.ID {
+			*orders = append((*orders)[:i], (*orders)[i+1:]...)
+			return
+		}
+	}
+}
+
+// higherPriority - заявка a стоит в очереди раньше заявки b той же стороны
+func higherPriority(a, b *dealPkg.Order) bool {
+	if a.Price != b.Price {
+		if a.Type == dealPkg.TypeBuy {
+			return a.Price > b.Price
+		}
+		return a.Price < b.Price
+	}
+	return a.Seq < b.Seq
+}
+
+func oppositeType(orderType string) string {
+	if orderType == dealPkg.TypeSell {
+		return dealPkg.TypeBuy
+	}
+	return dealPkg.TypeSell
+}
+
+// crossesPrice - заявка исполнима по цене price: покупка не ниже, продажа не выше, рыночная - по любой
+func crossesPrice(order *dealPkg.Order, price float32) bool {
+	if order.Kind == dealPkg.KindMarket {
+		return true
+	}
+	if order.Type == dealPkg.TypeBuy {
+		return order.Price >= price
+	}
+	return order.Price <= price
+}
+
+// stopTriggered - цена сделки достигла стоп-цены: для покупки снизу вверх, для продажи сверху вниз
+func stopTriggered(order *dealPkg.Order, price float32) bool {
+	if order.Type == dealPkg.TypeBuy {
+		return price >= order.StopPrice
+	}
+	return price <= order.StopPrice
+}
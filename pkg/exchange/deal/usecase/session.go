@@ -0,0 +1,357 @@
+package usecase
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// sessionBuffer - сколько событий сессии может ждать отправки подписчику, лишние пропускаются
+const sessionBuffer = 100
+
+// phaseStart - начало фазы торгового дня
+type phaseStart struct {
+	Phase string
+	At    time.Time
+}
+
+// Calendar - расписание торгового дня по торговым дням, время фаз в зоне Location
+type Calendar struct {
+	Location *time.Location
+	Starts   []phaseStart // от начала суток, только часы и минуты
+	Weekdays map[time.Weekday]bool
+	Holidays map[string]bool
+}
+
+// NewCalendar - календарь из конфига, без PreOpen - nil, торги идут круглосуточно
+func NewCalendar(config *configPkg.Config) (*Calendar, error) {
+	cfg := config.Exchange.Session
+	if cfg.PreOpen == "" {
+		return nil, nil
+	}
+
+	c := &Calendar{
+		Location: time.Local,
+		Weekdays: make(map[time.Weekday]bool),
+		Holidays: make(map[string]bool),
+	}
+	if cfg.Timezone != "" {
+		location, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("session timezone %v: %v", cfg.Timezone, err)
+		}
+		c.Location = location
+	}
+
+	sessionEnd := config.Exchange.SessionEnd
+	if sessionEnd == "" {
+		sessionEnd = defaultSessionEnd
+	}
+	continuous := cfg.Continuous
+	if continuous == "" {
+		return nil, fmt.Errorf("session continuous start is not set")
+	}
+	openingAuction := cfg.OpeningAuction
+	if openingAuction == "" {
+		openingAuction = continuous
+	}
+	closingAuction := cfg.ClosingAuction
+	if closingAuction == "" {
+		closingAuction = sessionEnd
+	}
+
+	phases := []struct {
+		Phase string
+		Start string
+	}{
+		{dealPkg.PhasePreOpen, cfg.PreOpen},
+		{dealPkg.PhaseOpeningAuction, openingAuction},
+		{dealPkg.PhaseContinuous, continuous},
+		{dealPkg.PhaseClosingAuction, closingAuction},
+		{dealPkg.PhaseClosed, sessionEnd},
+	}
+	for i, phase := range phases {
+		start, err := time.Parse("15:04", phase.Start)
+		if err != nil {
+			return nil, fmt.Errorf("parse %v start %v: %v", phase.Phase, phase.Start, err)
+		}
+		if i > 0 && start.Before(c.Starts[i-1].At) {
+			return nil, fmt.Errorf("%v starts before %v", phase.Phase, c.Starts[i-1].Phase)
+		}
+		c.Starts = append(c.Starts, phaseStart{Phase: phase.Phase, At: start})
+	}
+	if !c.Starts[0].At.Before(c.Starts[len(c.Starts)-1].At) {
+		return nil, fmt.Errorf("session ends before pre-open")
+	}
+
+	for _, day := range cfg.Weekdays {
+		c.Weekdays[time.Weekday(day)] = true
+	}
+	if len(c.Weekdays) == 0 {
+		for day := time.Monday; day <= time.Friday; day++ {
+			c.Weekdays[day] = true
+		}
+	}
+	for _, holiday := range cfg.Holidays {
+		_, err := time.Parse("2006-01-02", holiday)
+		if err != nil {
+			return nil, fmt.Errorf("parse holiday %v: %v", holiday, err)
+		}
+		c.Holidays[holiday] = true
+	}
+	return c, nil
+}
+
+// boundaries - начала фаз в торговый день day, фазы нулевой длины пропускаются
+func (c *Calendar) boundaries(day time.Time) []phaseStart {
+	if !c.Weekdays[day.Weekday()] || c.Holidays[day.Format("2006-01-02")] {
+		return nil
+	}
+	result := make([]phaseStart, 0, len(c.Starts))
+	for i, start := range c.Starts {
+		at := time.Date(day.Year(), day.Month(), day.Day(), start.At.Hour(), start.At.Minute(), 0, 0, c.Location)
+		if i+1 < len(c.Starts) && c.Starts[i+1].At.Equal(start.At) {
+			continue
+		}
+		result = append(result, phaseStart{Phase: start.Phase, At: at})
+	}
+	return result
+}
+
+// Phase - фаза сессии в момент now с временем ее начала и ближайшая ее смена в пределах года,
+// до начала торгового дня - закрытие с окончанием прошлого торгового дня
+func (c *Calendar) Phase(now time.Time) dealPkg.SessionEvent {
+	now = now.In(c.Location)
+	event := dealPkg.SessionEvent{Phase: dealPkg.PhaseClosed}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, c.Location)
+	for _, start := range c.boundaries(today) {
+		if !start.At.After(now) {
+			event.Phase = start.Phase
+			event.Time = int32(start.At.Unix())
+		}
+	}
+	for day := 1; event.Time == 0 && day <= 366; day++ {
+		boundaries := c.boundaries(today.AddDate(0, 0, -day))
+		if len(boundaries) > 0 {
+			event.Time = int32(boundaries[len(boundaries)-1].At.Unix())
+		}
+	}
+	for day := 0; day <= 366; day++ {
+		for _, start := range c.boundaries(today.AddDate(0, 0, day)) {
+			if start.At.After(now) && start.Phase != event.Phase {
+				event.NextPhase = start.Phase
+				event.NextTime = int32(start.At.Unix())
+				return event
+			}
+		}
+	}
+	return event
+}
+
+// Session - текущая фаза торговой сессии и подписчики на ее смену, без Calendar - всегда непрерывные торги
+type Session struct {
+	Calendar  *Calendar
+	Event     dealPkg.SessionEvent
+	Consumers map[chan dealPkg.SessionEvent]struct{}
+	Mux       *sync.RWMutex
+}
+
+func NewSession(config *configPkg.Config) (*Session, error) {
+	calendar, err := NewCalendar(config)
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{
+		Calendar:  calendar,
+		Consumers: make(map[chan dealPkg.SessionEvent]struct{}),
+		Mux:       &sync.RWMutex{},
+	}
+	session.Event = session.phaseAt(time.Now())
+	return session, nil
+}
+
+func (s *Session) phaseAt(now time.Time) dealPkg.SessionEvent {
+	if s.Calendar == nil {
+		return dealPkg.SessionEvent{Phase: dealPkg.PhaseContinuous, Time: int32(now.Unix())}
+	}
+	return s.Calendar.Phase(now)
+}
+
+func (s *Session) Current() dealPkg.SessionEvent {
+	s.Mux.RLock()
+	defer s.Mux.RUnlock()
+	return s.Event
+}
+
+// Subscribe регистрирует подписчика и возвращает текущую фазу под одной блокировкой
+func (s *Session) Subscribe() (chan dealPkg.SessionEvent, dealPkg.SessionEvent) {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+
+	ch := make(chan dealPkg.SessionEvent, sessionBuffer)
+	s.Consumers[ch] = struct{}{}
+	return ch, s.Event
+}
+
+func (s *Session) Unsubscribe(ch chan dealPkg.SessionEvent) {
+	s.Mux.Lock()
+	defer s.Mux.Unlock()
+	delete(s.Consumers, ch)
+}
+
+// collectsOrders - в фазе заявки принимаются без исполнения и ждут аукциона
+func collectsOrders(phase string) bool {
+	return phase == dealPkg.PhasePreOpen || phase == dealPkg.PhaseOpeningAuction || phase == dealPkg.PhaseClosingAuction
+}
+
+// checkCreate - можно ли выставить заявку kind в фазе phase: при закрытой бирже - никакую,
+// без непрерывных торгов - только лимитные и стоп-заявки
+func checkCreate(phase, kind string) error {
+	switch {
+	case phase == dealPkg.PhaseClosed:
+		return fmt.Errorf("%w: market is closed", dealPkg.ErrSessionPhase)
+	case phase != dealPkg.PhaseContinuous && (kind == dealPkg.KindMarket || kind == dealPkg.KindIOC || kind == dealPkg.KindFOK):
+		return fmt.Errorf("%w: %v orders are not accepted in %v", dealPkg.ErrSessionPhase, kind, phase)
+	}
+	return nil
+}
+
+// checkChange - на аукционах заявки нельзя снять или изменить, иначе цена аукциона непредсказуема
+func checkChange(phase string) error {
+	if phase == dealPkg.PhaseOpeningAuction || phase == dealPkg.PhaseClosingAuction {
+		return fmt.Errorf("%w: orders cannot be cancelled or replaced in %v", dealPkg.ErrSessionPhase, phase)
+	}
+	return nil
+}
+
+// RunSession раз в секунду сверяет фазу с календарем
+func (dm *DealsManager) RunSession(logger *logging.Logger) {
+	tiker := time.NewTicker(time.Second)
+	for now := range tiker.C {
+		dm.switchPhase(now, logger)
+	}
+}
+
+// switchPhase переводит сессию в фазу момента now, по окончании сбора заявок проводит аукцион,
+// подписчики узнают о смене фазы после аукциона
+func (dm *DealsManager) switchPhase(now time.Time, logger *logging.Logger) {
+	event := dm.Session.phaseAt(now)
+
+	dm.OrderBooks.Mux.Lock()
+	dm.Session.Mux.Lock()
+	prev := dm.Session.Event
+	if prev.Phase == event.Phase {
+		//следующая фаза могла сдвинуться, например, после праздника
+		dm.Session.Event.NextPhase, dm.Session.Event.NextTime = event.NextPhase, event.NextTime
+		dm.Session.Mux.Unlock()
+		dm.OrderBooks.Mux.Unlock()
+		return
+	}
+	dm.Session.Event = event
+	dm.Session.Mux.Unlock()
+
+	if collectsOrders(prev.Phase) && !collectsOrders(event.Phase) {
+		for ticker, book := range dm.OrderBooks.Books {
+			dm.uncross(ticker, book, logger)
+		}
+	}
+	dm.OrderBooks.Mux.Unlock()
+
+	logger.Zap.Info("session phase",
+		zap.String("logger", "session"),
+		zap.String("from", prev.Phase),
+		zap.String("to", event.Phase),
+	)
+
+	dm.Session.Mux.RLock()
+	defer dm.Session.Mux.RUnlock()
+	for ch := range dm.Session.Consumers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// uncross исполняет пересекающиеся заявки стакана по единой цене аукциона, вызывать под OrderBooks.Mux
+func (dm *DealsManager) uncross(ticker string, book *OrderBook, logger *logging.Logger) {
+	price, volume := clearingPrice(book)
+	if volume == 0 {
+		return
+	}
+
+	for len(book.Bids) > 0 && len(book.Asks) > 0 {
+		bid, ask := book.Bids[0], book.Asks[0]
+		if bid.Price < price || ask.Price > price {
+			break
+		}
+		volumeToClose := bid.RemainingVolume()
+		if ask.RemainingVolume() < volumeToClose {
+			volumeToClose = ask.RemainingVolume()
+		}
+
+		deals, err := dm.makeDeals(bid, ask, volumeToClose, price)
+		if err != nil {
+			logger.Zap.Error("not close auction orders",
+				zap.String("logger", "session"),
+				zap.String("err", err.Error()),
+			)
+			return
+		}
+		dm.publishTrade(dealPkg.TradePrint{
+			Ticker: ticker,
+			Price:  price,
+			Volume: volumeToClose,
+			Time:   deals[len(deals)-1].Time,
+		})
+	}
+}
+
+// clearingPrice - цена аукциона из цен заявок стакана: максимальный исполнимый объем,
+// при равенстве - минимальный неисполненный остаток, дальше - ниже цена при перевесе продавцов и выше при перевесе покупателей
+func clearingPrice(book *OrderBook) (float32, int32) {
+	var price float32
+	var volume, imbalance int32
+	for _, candidate := range append(levels(book.Bids), levels(book.Asks)...) {
+		var demand, supply int32
+		for _, bid := range book.Bids {
+			if bid.Price >= candidate.Price {
+				demand += bid.RemainingVolume()
+			}
+		}
+		for _, ask := range book.Asks {
+			if ask.Price <= candidate.Price {
+				supply += ask.RemainingVolume()
+			}
+		}
+
+		executable, surplus := demand, demand-supply
+		if supply < executable {
+			executable = supply
+		}
+		if executable == 0 {
+			continue
+		}
+		better := executable > volume ||
+			executable == volume && abs(surplus) < abs(imbalance) ||
+			executable == volume && abs(surplus) == abs(imbalance) &&
+				(surplus > 0 && candidate.Price > price || surplus <= 0 && candidate.Price < price)
+		if volume == 0 || better {
+			price, volume, imbalance = candidate.Price, executable, surplus
+		}
+	}
+	return price, volume
+}
+
+func abs(value int32) int32 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,320 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+)
+
+func sessionConfig(openingAuction, closingAuction string) *configPkg.Config {
+	config := &configPkg.Config{}
+	config.Exchange.SessionEnd = "18:50"
+	config.Exchange.Session.Timezone = "Europe/Moscow"
+	config.Exchange.Session.PreOpen = "09:50"
+	config.Exchange.Session.OpeningAuction = openingAuction
+	config.Exchange.Session.Continuous = "10:00"
+	config.Exchange.Session.ClosingAuction = closingAuction
+	config.Exchange.Session.Holidays = []string{"2022-12-30"}
+	return config
+}
+
+func TestCalendar_Phase(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	at := func(day, hour, minute int) int32 {
+		return int32(time.Date(2022, 12, day, hour, minute, 0, 0, moscow).Unix())
+	}
+	full := sessionConfig("09:55", "18:40")
+	//1 декабря 2022 - четверг, 30 декабря - праздник
+	tests := []struct {
+		name   string
+		config *configPkg.Config
+		now    int32
+		want   dealPkg.SessionEvent
+	}{
+		{name: "До начала дня - закрытие с окончания прошлого дня",
+			config: full,
+			now:    at(1, 9, 49),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhaseClosed, Time: int32(time.Date(2022, 11, 30, 18, 50, 0, 0, moscow).Unix()),
+				NextPhase: dealPkg.PhasePreOpen, NextTime: at(1, 9, 50)},
+		},
+		{name: "Начало фазы включительно",
+			config: full,
+			now:    at(1, 9, 50),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhasePreOpen, Time: at(1, 9, 50),
+				NextPhase: dealPkg.PhaseOpeningAuction, NextTime: at(1, 9, 55)},
+		},
+		{name: "Открывающий аукцион",
+			config: full,
+			now:    at(1, 9, 59),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhaseOpeningAuction, Time: at(1, 9, 55),
+				NextPhase: dealPkg.PhaseContinuous, NextTime: at(1, 10, 0)},
+		},
+		{name: "Непрерывные торги",
+			config: full,
+			now:    at(1, 12, 0),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhaseContinuous, Time: at(1, 10, 0),
+				NextPhase: dealPkg.PhaseClosingAuction, NextTime: at(1, 18, 40)},
+		},
+		{name: "Закрывающий аукцион",
+			config: full,
+			now:    at(1, 18, 49),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhaseClosingAuction, Time: at(1, 18, 40),
+				NextPhase: dealPkg.PhaseClosed, NextTime: at(1, 18, 50)},
+		},
+		{name: "После окончания дня",
+			config: full,
+			now:    at(1, 18, 50),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhaseClosed, Time: at(1, 18, 50),
+				NextPhase: dealPkg.PhasePreOpen, NextTime: at(2, 9, 50)},
+		},
+		{name: "Выходной - закрытие с пятницы до понедельника",
+			config: full,
+			now:    at(3, 12, 0),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhaseClosed, Time: at(2, 18, 50),
+				NextPhase: dealPkg.PhasePreOpen, NextTime: at(5, 9, 50)},
+		},
+		{name: "Праздник пропускается",
+			config: full,
+			now:    at(31, 12, 0),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhaseClosed, Time: at(29, 18, 50),
+				NextPhase: dealPkg.PhasePreOpen, NextTime: int32(time.Date(2023, 1, 2, 9, 50, 0, 0, moscow).Unix())},
+		},
+		{name: "Без аукционов после сбора заявок сразу непрерывные торги",
+			config: sessionConfig("", ""),
+			now:    at(1, 9, 55),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhasePreOpen, Time: at(1, 9, 50),
+				NextPhase: dealPkg.PhaseContinuous, NextTime: at(1, 10, 0)},
+		},
+		{name: "Без закрывающего аукциона торги до конца дня",
+			config: sessionConfig("", ""),
+			now:    at(1, 18, 45),
+			want: dealPkg.SessionEvent{Phase: dealPkg.PhaseContinuous, Time: at(1, 10, 0),
+				NextPhase: dealPkg.PhaseClosed, NextTime: at(1, 18, 50)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar, err := NewCalendar(tt.config)
+			if err != nil {
+				t.Fatalf("NewCalendar() error = %v", err)
+			}
+			got := calendar.Phase(time.Unix(int64(tt.now), 0))
+			if got != tt.want {
+				t.Errorf("Phase() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCalendar(t *testing.T) {
+	if calendar, err := NewCalendar(&configPkg.Config{}); calendar != nil || err != nil {
+		t.Errorf("NewCalendar() without pre-open = %v, %v, want nil calendar", calendar, err)
+	}
+
+	tests := []struct {
+		name      string
+		configure func(config *configPkg.Config)
+	}{
+		{name: "Нет начала непрерывных торгов",
+			configure: func(config *configPkg.Config) { config.Exchange.Session.Continuous = "" },
+		},
+		{name: "Фазы не по порядку",
+			configure: func(config *configPkg.Config) { config.Exchange.Session.OpeningAuction = "10:30" },
+		},
+		{name: "Неверное время",
+			configure: func(config *configPkg.Config) { config.Exchange.Session.PreOpen = "9.50" },
+		},
+		{name: "Неверный праздник",
+			configure: func(config *configPkg.Config) { config.Exchange.Session.Holidays = []string{"30.12.2022"} },
+		},
+		{name: "Неизвестная зона",
+			configure: func(config *configPkg.Config) { config.Exchange.Session.Timezone = "Mars/Olympus" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := sessionConfig("09:55", "18:40")
+			tt.configure(config)
+			if _, err := NewCalendar(config); err == nil {
+				t.Errorf("NewCalendar() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestClearingPrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		bids       []*dealPkg.Order
+		asks       []*dealPkg.Order
+		wantPrice  float32
+		wantVolume int32
+	}{
+		{name: "Максимальный исполнимый объем",
+			bids:      []*dealPkg.Order{limitOrder(dealPkg.TypeBuy, 102, 3), limitOrder(dealPkg.TypeBuy, 101, 2)},
+			asks:      []*dealPkg.Order{limitOrder(dealPkg.TypeSell, 100, 2), limitOrder(dealPkg.TypeSell, 101, 4)},
+			wantPrice: 101, wantVolume: 5,
+		},
+		{name: "При равном объеме - минимальный остаток",
+			bids:      []*dealPkg.Order{limitOrder(dealPkg.TypeBuy, 102, 2), limitOrder(dealPkg.TypeBuy, 101, 1)},
+			asks:      []*dealPkg.Order{limitOrder(dealPkg.TypeSell, 100, 2), limitOrder(dealPkg.TypeSell, 102, 5)},
+			wantPrice: 101, wantVolume: 2,
+		},
+		{name: "Перевес продавцов - ниже цена",
+			bids:      []*dealPkg.Order{limitOrder(dealPkg.TypeBuy, 102, 3)},
+			asks:      []*dealPkg.Order{limitOrder(dealPkg.TypeSell, 100, 2), limitOrder(dealPkg.TypeSell, 101, 2)},
+			wantPrice: 101, wantVolume: 3,
+		},
+		{name: "Перевес покупателей - выше цена",
+			bids:      []*dealPkg.Order{limitOrder(dealPkg.TypeBuy, 101, 2), limitOrder(dealPkg.TypeBuy, 100, 2)},
+			asks:      []*dealPkg.Order{limitOrder(dealPkg.TypeSell, 99, 3)},
+			wantPrice: 100, wantVolume: 3,
+		},
+		{name: "Стакан не пересекается",
+			bids: []*dealPkg.Order{limitOrder(dealPkg.TypeBuy, 99, 1)},
+			asks: []*dealPkg.Order{limitOrder(dealPkg.TypeSell, 100, 1)},
+		},
+		{name: "Пустая сторона",
+			bids: []*dealPkg.Order{limitOrder(dealPkg.TypeBuy, 99, 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obs := NewOrderBooks()
+			for i, order := range append(tt.bids, tt.asks...) {
+				order.ID = int64(i + 1)
+				obs.Add(order)
+			}
+			book, ok := obs.Books[testTicker]
+			if !ok {
+				book = &OrderBook{}
+			}
+			price, volume := clearingPrice(book)
+			if price != tt.wantPrice || volume != tt.wantVolume {
+				t.Errorf("clearingPrice() = %v, %v, want %v, %v", price, volume, tt.wantPrice, tt.wantVolume)
+			}
+		})
+	}
+}
+
+func TestCheckCreate(t *testing.T) {
+	phases := []string{dealPkg.PhaseClosed, dealPkg.PhasePreOpen, dealPkg.PhaseOpeningAuction, dealPkg.PhaseContinuous,
+		dealPkg.PhaseClosingAuction}
+	kinds := []string{dealPkg.KindLimit, dealPkg.KindStop, dealPkg.KindStopLimit, dealPkg.KindMarket, dealPkg.KindIOC,
+		dealPkg.KindFOK}
+	//разрешенные виды заявок по фазам
+	allowed := map[string]map[string]bool{
+		dealPkg.PhaseClosed:         {},
+		dealPkg.PhasePreOpen:        {dealPkg.KindLimit: true, dealPkg.KindStop: true, dealPkg.KindStopLimit: true},
+		dealPkg.PhaseOpeningAuction: {dealPkg.KindLimit: true, dealPkg.KindStop: true, dealPkg.KindStopLimit: true},
+		dealPkg.PhaseContinuous: {dealPkg.KindLimit: true, dealPkg.KindStop: true, dealPkg.KindStopLimit: true,
+			dealPkg.KindMarket: true, dealPkg.KindIOC: true, dealPkg.KindFOK: true},
+		dealPkg.PhaseClosingAuction: {dealPkg.KindLimit: true, dealPkg.KindStop: true, dealPkg.KindStopLimit: true},
+	}
+	for _, phase := range phases {
+		for _, kind := range kinds {
+			err := checkCreate(phase, kind)
+			if allowed[phase][kind] && err != nil {
+				t.Errorf("checkCreate(%v, %v) error = %v, want nil", phase, kind, err)
+			}
+			if !allowed[phase][kind] && !errors.Is(err, dealPkg.ErrSessionPhase) {
+				t.Errorf("checkCreate(%v, %v) error = %v, want %v", phase, kind, err, dealPkg.ErrSessionPhase)
+			}
+		}
+	}
+}
+
+func TestCheckChange(t *testing.T) {
+	tests := []struct {
+		phase   string
+		wantErr error
+	}{
+		{phase: dealPkg.PhaseClosed},
+		{phase: dealPkg.PhasePreOpen},
+		{phase: dealPkg.PhaseOpeningAuction, wantErr: dealPkg.ErrSessionPhase},
+		{phase: dealPkg.PhaseContinuous},
+		{phase: dealPkg.PhaseClosingAuction, wantErr: dealPkg.ErrSessionPhase},
+	}
+	for _, tt := range tests {
+		t.Run(tt.phase, func(t *testing.T) {
+			if err := checkChange(tt.phase); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkChange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDealsManager_SwitchPhase(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	tests := []struct {
+		name       string
+		failFills  int
+		wantFills  map[int64][]int32
+		wantPrints int
+		wantBids   []int64
+		wantAsks   []int64
+	}{
+		{name: "Аукцион исполняет обе стороны по единой цене",
+			wantFills:  map[int64][]int32{1: {2}, 3: {2}, 2: {1}, 4: {1}},
+			wantPrints: 2,
+			wantBids:   []int64{},
+			wantAsks:   []int64{4},
+		},
+		{name: "Ошибка сделки не исполняет ни одну сторону",
+			failFills: 1,
+			wantFills: map[int64][]int32{},
+			wantBids:  []int64{1, 2},
+			wantAsks:  []int64{3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, repo := newTestManager()
+			dm.Session.Calendar, err = NewCalendar(sessionConfig("09:55", "18:40"))
+			if err != nil {
+				t.Fatalf("NewCalendar() error = %v", err)
+			}
+			dm.Session.Event = dealPkg.SessionEvent{Phase: dealPkg.PhaseOpeningAuction}
+			trades := make(chan dealPkg.TradePrint, 10)
+			dm.TradesConsumers.Channels[trades] = struct{}{}
+			sessions, _ := dm.Session.Subscribe()
+
+			place(t, dm, limitOrder(dealPkg.TypeBuy, 102, 2), limitOrder(dealPkg.TypeBuy, 101, 1),
+				limitOrder(dealPkg.TypeSell, 100, 2), limitOrder(dealPkg.TypeSell, 101, 3))
+			repo.FailFills = tt.failFills
+			dm.switchPhase(time.Date(2022, 12, 1, 10, 0, 0, 0, moscow), testLogger())
+
+			if got := repo.fills(); !reflect.DeepEqual(got, tt.wantFills) {
+				t.Errorf("fills = %v, want %v", got, tt.wantFills)
+			}
+			for i := 0; i < tt.wantPrints; i++ {
+				if trade := <-trades; trade.Price != 101 {
+					t.Errorf("auction trade price %v, want 101", trade.Price)
+				}
+			}
+			if len(trades) != 0 {
+				t.Errorf("extra trades %v", len(trades))
+			}
+			book := dm.OrderBooks.Books[testTicker]
+			if got := bookIDs(book.Bids); !reflect.DeepEqual(got, tt.wantBids) {
+				t.Errorf("bids = %v, want %v", got, tt.wantBids)
+			}
+			if got := bookIDs(book.Asks); !reflect.DeepEqual(got, tt.wantAsks) {
+				t.Errorf("asks = %v, want %v", got, tt.wantAsks)
+			}
+			if event := <-sessions; event.Phase != dealPkg.PhaseContinuous {
+				t.Errorf("session event %v, want %v", event.Phase, dealPkg.PhaseContinuous)
+			}
+		})
+	}
+}
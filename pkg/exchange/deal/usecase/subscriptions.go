@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	metricsPkg "github.com/KeynihAV/exchange/pkg/exchange/metrics"
+)
+
+const (
+	defaultStatsQueueSize = 10000
+
+	PolicyDrop       = "drop"
+	PolicyDisconnect = "disconnect"
+)
+
+// StatsSubscription - подписка брокера на свечи: Tickers пустой - все инструменты,
+// Interval 0 - свечи интервала торгов без агрегации, Bars - еще не закрытые свечи интервала по инструментам
+type StatsSubscription struct {
+	BrokerID int64
+	Tickers  map[string]struct{}
+	Interval int32
+	Ch       chan dealPkg.OHLCV
+	Bars     map[string]*dealPkg.OHLCV
+}
+
+// SubscribeStats регистрирует подписку, закрытие Ch означает отключение за медленное чтение
+func (dm *DealsManager) SubscribeStats(brokerID int64, tickers []string, interval int32) *StatsSubscription {
+	queueSize := dm.Config.Exchange.StatsQueueSize
+	if queueSize <= 0 {
+		queueSize = defaultStatsQueueSize
+	}
+	if interval <= int32(dm.Config.Exchange.TradingInterval) {
+		interval = 0
+	}
+
+	sub := &StatsSubscription{
+		BrokerID: brokerID,
+		Tickers:  make(map[string]struct{}, len(tickers)),
+		Interval: interval,
+		Ch:       make(chan dealPkg.OHLCV, queueSize),
+		Bars:     make(map[string]*dealPkg.OHLCV),
+	}
+	for _, ticker := range tickers {
+		sub.Tickers[ticker] = struct{}{}
+	}
+
+	dm.StatsConsumers.Mux.Lock()
+	dm.StatsConsumers.Subscriptions[sub] = struct{}{}
+	dm.StatsConsumers.Mux.Unlock()
+	return sub
+}
+
+// UnsubscribeStats убирает подписку, если она еще не была отключена
+func (dm *DealsManager) UnsubscribeStats(sub *StatsSubscription) {
+	dm.StatsConsumers.Mux.Lock()
+	defer dm.StatsConsumers.Mux.Unlock()
+
+	if _, ok := dm.StatsConsumers.Subscriptions[sub]; ok {
+		delete(dm.StatsConsumers.Subscriptions, sub)
+		close(sub.Ch)
+	}
+}
+
+// publishStats раздает свечи интервала торгов подписчикам, отправка никогда не блокирует торги:
+// при полной очереди свеча пропускается или подписчик отключается по SlowConsumerPolicy
+func (dm *DealsManager) publishStats(stats map[string]*dealPkg.OHLCV, now int32) {
+	dm.StatsConsumers.Mux.Lock()
+	defer dm.StatsConsumers.Mux.Unlock()
+
+	depth := make(map[int64]int)
+	for sub := range dm.StatsConsumers.Subscriptions {
+		for _, ohlcv := range sub.closedBars(stats, now) {
+			if !dm.sendStats(sub, ohlcv) {
+				break
+			}
+		}
+		if _, ok := dm.StatsConsumers.Subscriptions[sub]; ok {
+			depth[sub.BrokerID] += len(sub.Ch)
+		}
+	}
+	for brokerID, queued := range depth {
+		metricsPkg.SetStatsQueueDepth(brokerID, queued)
+	}
+}
+
+// sendStats возвращает false, если подписчик отключен, вызывать под StatsConsumers.Mux
+func (dm *DealsManager) sendStats(sub *StatsSubscription, ohlcv dealPkg.OHLCV) bool {
+	select {
+	case sub.Ch <- ohlcv:
+		return true
+	default:
+	}
+
+	if dm.Config.Exchange.SlowConsumerPolicy == PolicyDisconnect {
+		delete(dm.StatsConsumers.Subscriptions, sub)
+		close(sub.Ch)
+		metricsPkg.StatsDisconnected(sub.BrokerID)
+		metricsPkg.SetStatsQueueDepth(sub.BrokerID, 0)
+		return false
+	}
+	metricsPkg.StatsDropped(sub.BrokerID)
+	return true
+}
+
+// closedBars - свечи, готовые к отправке подписчику: без агрегации - сразу,
+// иначе свеча интервала закрывается, когда пришла свеча следующего интервала или интервал истек
+func (sub *StatsSubscription) closedBars(stats map[string]*dealPkg.OHLCV, now int32) []dealPkg.OHLCV {
+	closed := make([]dealPkg.OHLCV, 0)
+	for ticker, ohlcv := range stats {
+		if _, ok := sub.Tickers[ticker]; len(sub.Tickers) > 0 && !ok {
+			continue
+		}
+		if sub.Interval == 0 {
+			closed = append(closed, *ohlcv)
+			continue
+		}
+
+		start := ohlcv.Time - ohlcv.Time%sub.Interval
+		bar, ok := sub.Bars[ticker]
+		if ok && bar.Time != start {
+			closed = append(closed, *bar)
+			ok = false
+		}
+		if !ok {
+			bar = &dealPkg.OHLCV{
+				ID:       ohlcv.ID,
+				Ticker:   ticker,
+				Time:     start,
+				Interval: sub.Interval,
+				Open:     ohlcv.Open,
+				High:     ohlcv.High,
+				Low:      ohlcv.Low,
+			}
+			sub.Bars[ticker] = bar
+		}
+		if ohlcv.High > bar.High {
+			bar.High = ohlcv.High
+		}
+		if ohlcv.Low < bar.Low {
+			bar.Low = ohlcv.Low
+		}
+		bar.Close = ohlcv.Close
+		bar.Volume += ohlcv.Volume
+	}
+
+	for ticker, bar := range sub.Bars {
+		if bar.Time+sub.Interval <= now {
+			closed = append(closed, *bar)
+			delete(sub.Bars, ticker)
+		}
+	}
+	return closed
+}
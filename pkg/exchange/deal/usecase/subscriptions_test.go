@@ -0,0 +1,175 @@
+package usecase
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+)
+
+// statsStep - свечи интервала торгов и время их публикации
+type statsStep struct {
+	stats map[string]*dealPkg.OHLCV
+	now   int32
+	want  []dealPkg.OHLCV
+}
+
+func TestStatsSubscription_ClosedBars(t *testing.T) {
+	tests := []struct {
+		name     string
+		tickers  []string
+		interval int32
+		steps    []statsStep
+	}{
+		{name: "Без агрегации свечи уходят сразу, чужие инструменты отфильтрованы",
+			tickers: []string{testTicker},
+			steps: []statsStep{
+				{stats: map[string]*dealPkg.OHLCV{
+					testTicker: {Ticker: testTicker, Time: 5, Open: 10, High: 11, Low: 9, Close: 10, Volume: 1},
+					"SPFB.Si":  {Ticker: "SPFB.Si", Time: 5, Open: 1, High: 1, Low: 1, Close: 1, Volume: 1}},
+					now: 10,
+					want: []dealPkg.OHLCV{
+						{Ticker: testTicker, Time: 5, Open: 10, High: 11, Low: 9, Close: 10, Volume: 1}},
+				},
+			},
+		},
+		{name: "Свеча интервала закрывается свечой следующего интервала",
+			interval: 60,
+			steps: []statsStep{
+				{stats: map[string]*dealPkg.OHLCV{
+					testTicker: {ID: 7, Ticker: testTicker, Time: 0, Open: 10, High: 12, Low: 9, Close: 11, Volume: 1}},
+					now:  5,
+					want: []dealPkg.OHLCV{},
+				},
+				{stats: map[string]*dealPkg.OHLCV{
+					testTicker: {ID: 8, Ticker: testTicker, Time: 30, Open: 11, High: 15, Low: 8, Close: 14, Volume: 2}},
+					now:  35,
+					want: []dealPkg.OHLCV{},
+				},
+				{stats: map[string]*dealPkg.OHLCV{
+					testTicker: {ID: 9, Ticker: testTicker, Time: 60, Open: 14, High: 14, Low: 13, Close: 13, Volume: 4}},
+					now: 58,
+					want: []dealPkg.OHLCV{
+						{ID: 7, Ticker: testTicker, Time: 0, Interval: 60, Open: 10, High: 15, Low: 8, Close: 14, Volume: 3}},
+				},
+			},
+		},
+		{name: "Свеча без новых сделок закрывается по истечении интервала",
+			interval: 60,
+			steps: []statsStep{
+				{stats: map[string]*dealPkg.OHLCV{
+					testTicker: {Ticker: testTicker, Time: 70, Open: 10, High: 10, Low: 10, Close: 10, Volume: 1},
+					"SPFB.Si":  {Ticker: "SPFB.Si", Time: 100, Open: 1, High: 2, Low: 1, Close: 2, Volume: 5}},
+					now:  110,
+					want: []dealPkg.OHLCV{},
+				},
+				{stats: map[string]*dealPkg.OHLCV{},
+					now:  119,
+					want: []dealPkg.OHLCV{},
+				},
+				{stats: map[string]*dealPkg.OHLCV{},
+					now: 120,
+					want: []dealPkg.OHLCV{
+						{Ticker: testTicker, Time: 60, Interval: 60, Open: 10, High: 10, Low: 10, Close: 10, Volume: 1},
+						{Ticker: "SPFB.Si", Time: 60, Interval: 60, Open: 1, High: 2, Low: 1, Close: 2, Volume: 5}},
+				},
+				{stats: map[string]*dealPkg.OHLCV{},
+					now:  200,
+					want: []dealPkg.OHLCV{},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, _ := newTestManager()
+			sub := dm.SubscribeStats(1, tt.tickers, tt.interval)
+			for i, step := range tt.steps {
+				got := sub.closedBars(step.stats, step.now)
+				sort.Slice(got, func(i, j int) bool {
+					return got[i].Ticker < got[j].Ticker
+				})
+				if !reflect.DeepEqual(got, step.want) {
+					t.Errorf("step %v closedBars() = %+v, want %+v", i, got, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDealsManager_PublishStats(t *testing.T) {
+	stats := map[string]*dealPkg.OHLCV{
+		testTicker: {Ticker: testTicker, Time: 1, Close: 10, Volume: 1},
+		"SPFB.Si":  {Ticker: "SPFB.Si", Time: 1, Close: 20, Volume: 1},
+	}
+	tests := []struct {
+		name           string
+		policy         string
+		wantSlowQueued int
+		wantConnected  bool
+	}{
+		{name: "По умолчанию лишняя свеча пропускается, подписчик остается",
+			wantSlowQueued: 1,
+			wantConnected:  true,
+		},
+		{name: "Пропуск свечей",
+			policy:         PolicyDrop,
+			wantSlowQueued: 1,
+			wantConnected:  true,
+		},
+		{name: "Отключение медленного подписчика",
+			policy:         PolicyDisconnect,
+			wantSlowQueued: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm, _ := newTestManager()
+			dm.Config.Exchange.SlowConsumerPolicy = tt.policy
+			dm.Config.Exchange.StatsQueueSize = 1
+			slow := dm.SubscribeStats(1, nil, 0)
+			dm.Config.Exchange.StatsQueueSize = 10
+			fast := dm.SubscribeStats(2, nil, 0)
+
+			//отправка не блокирует торги, даже если подписчик не читает
+			dm.publishStats(stats, 2)
+
+			if len(fast.Ch) != 2 {
+				t.Errorf("fast subscriber got %v candles, want 2", len(fast.Ch))
+			}
+			if len(slow.Ch) != tt.wantSlowQueued {
+				t.Errorf("slow subscriber queued %v candles, want %v", len(slow.Ch), tt.wantSlowQueued)
+			}
+			_, connected := dm.StatsConsumers.Subscriptions[slow]
+			if connected != tt.wantConnected {
+				t.Fatalf("slow subscriber connected = %v, want %v", connected, tt.wantConnected)
+			}
+			if !tt.wantConnected {
+				<-slow.Ch
+				if _, ok := <-slow.Ch; ok {
+					t.Errorf("disconnected subscriber channel is not closed")
+				}
+			}
+			//отписка после отключения не закрывает канал второй раз
+			dm.UnsubscribeStats(slow)
+			dm.UnsubscribeStats(fast)
+			if len(dm.StatsConsumers.Subscriptions) != 0 {
+				t.Errorf("subscriptions left %v, want 0", len(dm.StatsConsumers.Subscriptions))
+			}
+		})
+	}
+}
+
+func TestDealsManager_SubscribeStats(t *testing.T) {
+	dm, _ := newTestManager()
+	dm.Config.Exchange.TradingInterval = 60
+
+	//интервал не длиннее интервала торгов означает свечи торгов без агрегации
+	if sub := dm.SubscribeStats(1, nil, 60); sub.Interval != 0 || cap(sub.Ch) != defaultStatsQueueSize {
+		t.Errorf("SubscribeStats() interval %v queue %v, want 0 and %v", sub.Interval, cap(sub.Ch), defaultStatsQueueSize)
+	}
+	if sub := dm.SubscribeStats(1, []string{testTicker}, 300); sub.Interval != 300 || len(sub.Tickers) != 1 {
+		t.Errorf("SubscribeStats() interval %v tickers %v, want 300 and 1", sub.Interval, len(sub.Tickers))
+	}
+}
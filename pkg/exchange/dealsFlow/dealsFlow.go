@@ -0,0 +1,56 @@
+package dealsFlow
+
+import (
+	"errors"
+	"time"
+
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+)
+
+// TimeLayout - дата и время сделки в файлах ленты (колонки DATE и TIME подряд)
+const TimeLayout = "20060102150405"
+
+// SpeedMax - воспроизведение без пауз между сделками
+const SpeedMax = "max"
+
+// источники ленты, exchange.source.type в конфиге
+const (
+	SourceCSV       = "csv"
+	SourceJSONL     = "jsonl"
+	SourceSynthetic = "synthetic"
+)
+
+var (
+	ErrBadSpeed      = errors.New("bad replay speed")
+	ErrUnknownSource = errors.New("unknown market data source")
+	ErrBadSource     = errors.New("bad market data source settings")
+	// ErrReplayBusy - воспроизведение не приняло команду вовремя, например, пока открывается лента
+	ErrReplayBusy = errors.New("replay is busy")
+)
+
+// MarketDataSource - лента сделок, упорядоченная по времени, из которой Replay наполняет DealsFlowCh
+type MarketDataSource interface {
+	// Next - следующая сделка, nil - лента закончилась
+	Next() (*Tick, error)
+	Close()
+}
+
+// Tick - сделка исторической ленты с ее временем
+type Tick struct {
+	Time time.Time
+	Deal *dealPkg.Deal
+}
+
+// ReplayStatus - состояние воспроизведения ленты, Position - время последней отправленной сделки,
+// Speed 0 - без пауз
+type ReplayStatus struct {
+	Paused   bool
+	Finished bool
+	Speed    float64
+	Position int64
+	Start    int64
+	End      int64
+	Loop     bool
+	Loops    int
+	Sent     int64
+}
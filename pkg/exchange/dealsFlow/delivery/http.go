@@ -0,0 +1,65 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KeynihAV/exchange/pkg/common"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+	dealsFlowUsecasePkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow/usecase"
+)
+
+// ReplayHandler - управление воспроизведением исторической ленты
+type ReplayHandler struct {
+	Replay *dealsFlowUsecasePkg.Replay
+}
+
+// SeekRequest - перемотка ленты на Time (unix time)
+type SeekRequest struct {
+	Time int64
+}
+
+func (rh *ReplayHandler) Status(w http.ResponseWriter, r *http.Request) {
+	common.WriteStructToResponse(rh.Replay.GetStatus(), r.Context(), w)
+}
+
+func (rh *ReplayHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	err := rh.Replay.Pause(r.Context())
+	rh.respond(err, w, r)
+}
+
+func (rh *ReplayHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	err := rh.Replay.Resume(r.Context())
+	rh.respond(err, w, r)
+}
+
+func (rh *ReplayHandler) Seek(w http.ResponseWriter, r *http.Request) {
+	req := &SeekRequest{}
+	ok := common.GetStructFromRequest(req, r, w)
+	if !ok {
+		return
+	}
+	if req.Time <= 0 {
+		err := fmt.Errorf("bad seek time %v", req.Time)
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+
+	err := rh.Replay.Seek(r.Context(), time.Unix(req.Time, 0).UTC())
+	rh.respond(err, w, r)
+}
+
+// respond отвечает состоянием воспроизведения, если команда принята
+func (rh *ReplayHandler) respond(err error, w http.ResponseWriter, r *http.Request) {
+	if errors.Is(err, dealsFlowPkg.ErrReplayBusy) {
+		common.RespJSONError(w, http.StatusServiceUnavailable, err, "replay is busy, try again later", r.Context())
+		return
+	}
+	if err != nil {
+		common.RespJSONError(w, http.StatusInternalServerError, err, "replay command failed", r.Context())
+		return
+	}
+	common.WriteStructToResponse(rh.Replay.GetStatus(), r.Context(), w)
+}
@@ -0,0 +1,158 @@
+package repo
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+)
+
+// columns - заголовки колонок csv ленты, Time пустой - время вместе с датой в Date
+type columns struct {
+	Ticker string
+	Date   string
+	Time   string
+	Price  string
+	Volume string
+}
+
+// csvReader читает csv с заголовком, колонки находятся по именам из конфига
+type csvReader struct {
+	Reader *csv.Reader
+	Layout string
+	Ticker int
+	Date   int
+	Time   int // -1 - нет колонки времени
+	Price  int
+	Volume int
+	Width  int // колонок должно быть не меньше
+}
+
+// csvColumns - колонки из конфига, если не задана ни одна - формат Финама
+func csvColumns(config *configPkg.Config) (columns, error) {
+	cfg := config.Exchange.Source.Columns
+	cols := columns{
+		Ticker: cfg.Ticker,
+		Date:   cfg.Date,
+		Time:   cfg.Time,
+		Price:  cfg.Price,
+		Volume: cfg.Volume,
+	}
+	if cols == (columns{}) {
+		return columns{Ticker: "TICKER", Date: "DATE", Time: "TIME", Price: "LAST", Volume: "VOL"}, nil
+	}
+	if cols.Ticker == "" || cols.Date == "" || cols.Price == "" || cols.Volume == "" {
+		return cols, fmt.Errorf("%w: csv columns ticker, date, price and volume are required", dealsFlowPkg.ErrBadSource)
+	}
+	return cols, nil
+}
+
+// normalizeHeader - имя колонки без регистра, пробелов и угловых скобок Финама (<TICKER>)
+func normalizeHeader(name string) string {
+	name = strings.TrimPrefix(name, "\ufeff")
+	return strings.ToUpper(strings.Trim(strings.TrimSpace(name), "<>"))
+}
+
+func newCSVReader(r io.Reader, config *configPkg.Config) (*csvReader, error) {
+	cols, err := csvColumns(config)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = ','
+	if config.Exchange.Source.Comma != "" {
+		reader.Comma = []rune(config.Exchange.Source.Comma)[0]
+	}
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[normalizeHeader(name)] = i
+	}
+
+	cr := &csvReader{Reader: reader, Layout: timeLayout(config), Time: -1}
+	find := func(name string) (int, error) {
+		pos, ok := positions[normalizeHeader(name)]
+		if !ok {
+			return 0, fmt.Errorf("%w: no column %v in header", dealsFlowPkg.ErrBadSource, name)
+		}
+		if pos+1 > cr.Width {
+			cr.Width = pos + 1
+		}
+		return pos, nil
+	}
+	if cr.Ticker, err = find(cols.Ticker); err != nil {
+		return nil, err
+	}
+	if cr.Date, err = find(cols.Date); err != nil {
+		return nil, err
+	}
+	if cr.Price, err = find(cols.Price); err != nil {
+		return nil, err
+	}
+	if cr.Volume, err = find(cols.Volume); err != nil {
+		return nil, err
+	}
+	if cols.Time != "" {
+		if cr.Time, err = find(cols.Time); err != nil {
+			return nil, err
+		}
+	}
+	return cr, nil
+}
+
+func (cr *csvReader) Read() (*dealsFlowPkg.Tick, error) {
+	rec, err := cr.Reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, fmt.Errorf("%w: %v", errBadRow, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rec) < cr.Width {
+		return nil, fmt.Errorf("%w: expected %v columns, got %v", errBadRow, cr.Width, len(rec))
+	}
+
+	value := rec[cr.Date]
+	if cr.Time >= 0 {
+		value += rec[cr.Time]
+	}
+	return parseTick(rec[cr.Ticker], value, rec[cr.Price], rec[cr.Volume], cr.Layout)
+}
+
+// parseTick - сделка из текстовых полей ленты
+func parseTick(ticker, tickTime, price, volume, layout string) (*dealsFlowPkg.Tick, error) {
+	parsedTime, err := time.Parse(layout, tickTime)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parse time: %v", errBadRow, err)
+	}
+	parsedPrice, err := strconv.ParseFloat(price, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parse price: %v", errBadRow, err)
+	}
+	vol, err := strconv.ParseInt(volume, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parse volume: %v", errBadRow, err)
+	}
+
+	return &dealsFlowPkg.Tick{
+		Time: parsedTime,
+		Deal: &dealPkg.Deal{
+			Ticker: ticker,
+			Price:  float32(parsedPrice),
+			Volume: int32(vol),
+		},
+	}, nil
+}
@@ -0,0 +1,150 @@
+package repo
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+)
+
+// readAll - все сделки ридера и ошибки пропущенных строк
+func readAll(t *testing.T, reader tickReader) ([]*dealsFlowPkg.Tick, []error) {
+	t.Helper()
+	ticks := make([]*dealsFlowPkg.Tick, 0)
+	badRows := make([]error, 0)
+	for {
+		tick, err := reader.Read()
+		if err == io.EOF {
+			return ticks, badRows
+		}
+		if errors.Is(err, errBadRow) {
+			badRows = append(badRows, err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		ticks = append(ticks, tick)
+	}
+}
+
+func tick(at string, ticker string, price float32, volume int32) *dealsFlowPkg.Tick {
+	tickTime, err := time.Parse(dealsFlowPkg.TimeLayout, at)
+	if err != nil {
+		panic(err)
+	}
+	return &dealsFlowPkg.Tick{Time: tickTime, Deal: &dealPkg.Deal{Ticker: ticker, Price: price, Volume: volume}}
+}
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name        string
+		configure   func(config *configPkg.Config)
+		data        string
+		wantTicks   []*dealsFlowPkg.Tick
+		wantBadRows int
+		wantErr     error
+	}{
+		{name: "Формат Финама с угловыми скобками и BOM",
+			data: "\ufeff<TICKER>,<PER>,<DATE>,<TIME>,<LAST>,<VOL>\n" +
+				"SPFB.RTS,0,20180518,130001,116650.000000000,1\n" +
+				"SPFB.RTS,0,20180518,130002,116660.000000000,3\n",
+			wantTicks: []*dealsFlowPkg.Tick{
+				tick("20180518130001", "SPFB.RTS", 116650, 1),
+				tick("20180518130002", "SPFB.RTS", 116660, 3)},
+		},
+		{name: "Свои колонки, разделитель и время вместе с датой",
+			configure: func(config *configPkg.Config) {
+				config.Exchange.Source.Columns.Ticker = "symbol"
+				config.Exchange.Source.Columns.Date = "ts"
+				config.Exchange.Source.Columns.Price = "price"
+				config.Exchange.Source.Columns.Volume = "qty"
+				config.Exchange.Source.Comma = ";"
+				config.Exchange.Source.TimeLayout = "2006-01-02 15:04:05"
+			},
+			data: "qty;price;ts;symbol\n" +
+				"5;101.5;2022-12-01 10:00:00;SPFB.Si\n",
+			wantTicks: []*dealsFlowPkg.Tick{
+				{Time: time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC),
+					Deal: &dealPkg.Deal{Ticker: "SPFB.Si", Price: 101.5, Volume: 5}}},
+		},
+		{name: "Плохие строки пропускаются",
+			data: "TICKER,DATE,TIME,LAST,VOL\n" +
+				"SPFB.RTS,20180518,130001,abc,1\n" +
+				"SPFB.RTS,20180518,130001\n" +
+				"SPFB.RTS,2018-05-18,130001,100,1\n" +
+				"SPFB.RTS,20180518,130001,100,1.5\n" +
+				"SPFB.RTS,20180518,130003,100,2\n",
+			wantTicks:   []*dealsFlowPkg.Tick{tick("20180518130003", "SPFB.RTS", 100, 2)},
+			wantBadRows: 4,
+		},
+		{name: "Нет колонки в заголовке",
+			data:    "TICKER,DATE,TIME,VOL\n",
+			wantErr: dealsFlowPkg.ErrBadSource,
+		},
+		{name: "Заданы не все обязательные колонки",
+			configure: func(config *configPkg.Config) {
+				config.Exchange.Source.Columns.Ticker = "symbol"
+			},
+			data:    "symbol\n",
+			wantErr: dealsFlowPkg.ErrBadSource,
+		},
+		{name: "Пустой файл",
+			data:    "",
+			wantErr: io.EOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &configPkg.Config{}
+			if tt.configure != nil {
+				tt.configure(config)
+			}
+			reader, err := newCSVReader(strings.NewReader(tt.data), config)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("newCSVReader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			ticks, badRows := readAll(t, reader)
+			if !reflect.DeepEqual(ticks, tt.wantTicks) {
+				t.Errorf("ticks = %v, want %v", ticks, tt.wantTicks)
+			}
+			if len(badRows) != tt.wantBadRows {
+				t.Errorf("bad rows %v, want %v", badRows, tt.wantBadRows)
+			}
+		})
+	}
+}
+
+func TestJSONReader(t *testing.T) {
+	data := `{"ticker":"SPFB.RTS","time":"20180518130001","price":116650,"volume":1}
+
+{"ticker":"SPFB.RTS","time":"20180518130002","price":116660.5,"volume":2}
+{"ticker":"","time":"20180518130003","price":1,"volume":1}
+{"ticker":"SPFB.RTS","time":"18.05.2018","price":1,"volume":1}
+not json
+{"Ticker":"SPFB.Si","Time":"20180518130004","Price":60000,"Volume":7}
+`
+	ticks, badRows := readAll(t, newJSONReader(strings.NewReader(data), dealsFlowPkg.TimeLayout))
+
+	want := []*dealsFlowPkg.Tick{
+		tick("20180518130001", "SPFB.RTS", 116650, 1),
+		tick("20180518130002", "SPFB.RTS", 116660.5, 2),
+		tick("20180518130004", "SPFB.Si", 60000, 7),
+	}
+	if !reflect.DeepEqual(ticks, want) {
+		t.Errorf("ticks = %v, want %v", ticks, want)
+	}
+	if len(badRows) != 3 {
+		t.Errorf("bad rows %v, want 3", badRows)
+	}
+}
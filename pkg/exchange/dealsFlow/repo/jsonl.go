@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+)
+
+// maxLine - самая длинная строка jsonl ленты
+const maxLine = 1024 * 1024
+
+// jsonTick - строка jsonl ленты: {"ticker":"SPFB.RTS","time":"20180518130001","price":116650,"volume":1}
+type jsonTick struct {
+	Ticker string
+	Time   string
+	Price  float32
+	Volume int32
+}
+
+type jsonReader struct {
+	Scanner *bufio.Scanner
+	Layout  string
+}
+
+func newJSONReader(r io.Reader, layout string) *jsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLine)
+	return &jsonReader{Scanner: scanner, Layout: layout}
+}
+
+func (jr *jsonReader) Read() (*dealsFlowPkg.Tick, error) {
+	for jr.Scanner.Scan() {
+		line := jr.Scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		row := &jsonTick{}
+		err := json.Unmarshal(line, row)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errBadRow, err)
+		}
+		if row.Ticker == "" {
+			return nil, fmt.Errorf("%w: empty ticker", errBadRow)
+		}
+		tickTime, err := time.Parse(jr.Layout, row.Time)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parse time: %v", errBadRow, err)
+		}
+		return &dealsFlowPkg.Tick{
+			Time: tickTime,
+			Deal: &dealPkg.Deal{
+				Ticker: row.Ticker,
+				Price:  row.Price,
+				Volume: row.Volume,
+			},
+		}, nil
+	}
+
+	err := jr.Scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,217 @@
+package repo
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// errBadRow - строку ленты не удалось разобрать, она пропускается
+var errBadRow = errors.New("bad row")
+
+// tickReader читает сделки одного файла по порядку, в конце - io.EOF
+type tickReader interface {
+	Read() (*dealsFlowPkg.Tick, error)
+}
+
+// fileReader читает сделки одного файла, в Next - следующая непрочитанная сделка
+type fileReader struct {
+	Path    string
+	Closers []io.Closer
+	Reader  tickReader
+	Next    *dealsFlowPkg.Tick
+}
+
+// Source - сделки нескольких файлов ленты, слитые по времени, каждый файл упорядочен по времени
+type Source struct {
+	Readers []*fileReader
+	Logger  *logging.Logger
+}
+
+// CheckSource проверяет настройки источника до начала воспроизведения, возвращает файлы ленты
+func CheckSource(config *configPkg.Config) ([]string, error) {
+	switch sourceType(config) {
+	case dealsFlowPkg.SourceCSV:
+		_, err := csvColumns(config)
+		if err != nil {
+			return nil, err
+		}
+	case dealsFlowPkg.SourceJSONL:
+	case dealsFlowPkg.SourceSynthetic:
+		_, err := NewGenerator(config, time.Time{})
+		return nil, err
+	default:
+		return nil, fmt.Errorf("%w: %v", dealsFlowPkg.ErrUnknownSource, config.Exchange.Source.Type)
+	}
+	return FlowFiles(config.Exchange.DealsFlowFile, config.Exchange.DealsFlowDir)
+}
+
+// OpenSource открывает источник из конфига и пропускает сделки раньше from (нулевое - с начала),
+// files - файлы ленты из CheckSource
+func OpenSource(config *configPkg.Config, files []string, from time.Time, logger *logging.Logger) (dealsFlowPkg.MarketDataSource, error) {
+	if sourceType(config) == dealsFlowPkg.SourceSynthetic {
+		return NewGenerator(config, from)
+	}
+
+	source := &Source{Logger: logger}
+	for _, path := range files {
+		fr, err := openFile(config, path)
+		if err != nil {
+			source.Close()
+			return nil, err
+		}
+		source.Readers = append(source.Readers, fr)
+
+		for {
+			err = source.advance(fr)
+			if err != nil {
+				source.Close()
+				return nil, err
+			}
+			if fr.Next == nil || !fr.Next.Time.Before(from) {
+				break
+			}
+		}
+	}
+	return source, nil
+}
+
+func sourceType(config *configPkg.Config) string {
+	if config.Exchange.Source.Type == "" {
+		return dealsFlowPkg.SourceCSV
+	}
+	return config.Exchange.Source.Type
+}
+
+func timeLayout(config *configPkg.Config) string {
+	if config.Exchange.Source.TimeLayout == "" {
+		return dealsFlowPkg.TimeLayout
+	}
+	return config.Exchange.Source.TimeLayout
+}
+
+// FlowFiles - файлы ленты: file, если задан, иначе все файлы каталога dir по имени
+func FlowFiles(file, dir string) ([]string, error) {
+	if dir == "" {
+		if file == "" {
+			return nil, fmt.Errorf("deals flow file or directory is not set")
+		}
+		return []string{file}, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no deals flow files in %v", dir)
+	}
+	return files, nil
+}
+
+// openFile открывает файл ленты, сжатый gzip распознается по первым байтам
+func openFile(config *configPkg.Config, path string) (*fileReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fr := &fileReader{Path: path, Closers: []io.Closer{file}}
+
+	var r io.Reader = bufio.NewReader(file)
+	magic, _ := r.(*bufio.Reader).Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			fr.close()
+			return nil, fmt.Errorf("open gzip %v: %v", path, err)
+		}
+		fr.Closers = append(fr.Closers, gz)
+		r = gz
+	}
+
+	if sourceType(config) == dealsFlowPkg.SourceJSONL {
+		fr.Reader = newJSONReader(r, timeLayout(config))
+		return fr, nil
+	}
+	fr.Reader, err = newCSVReader(r, config)
+	if err != nil {
+		fr.close()
+		return nil, fmt.Errorf("read header of %v: %w", path, err)
+	}
+	return fr, nil
+}
+
+func (fr *fileReader) close() {
+	for i := len(fr.Closers) - 1; i >= 0; i-- {
+		fr.Closers[i].Close()
+	}
+}
+
+// Next - самая ранняя из следующих сделок всех файлов, nil - сделки закончились
+func (s *Source) Next() (*dealsFlowPkg.Tick, error) {
+	var earliest *fileReader
+	for _, fr := range s.Readers {
+		if fr.Next != nil && (earliest == nil || fr.Next.Time.Before(earliest.Next.Time)) {
+			earliest = fr
+		}
+	}
+	if earliest == nil {
+		return nil, nil
+	}
+
+	tick := earliest.Next
+	err := s.advance(earliest)
+	if err != nil {
+		return nil, err
+	}
+	return tick, nil
+}
+
+func (s *Source) Close() {
+	for _, fr := range s.Readers {
+		fr.close()
+	}
+	s.Readers = nil
+}
+
+// advance читает следующую сделку файла, строки с ошибками пропускаются
+func (s *Source) advance(fr *fileReader) error {
+	for {
+		tick, err := fr.Reader.Read()
+		if err == io.EOF {
+			fr.Next = nil
+			return nil
+		}
+		if errors.Is(err, errBadRow) {
+			s.Logger.Zap.Error("parsing deals flow row",
+				zap.String("logger", "dealsFlow"),
+				zap.String("file", fr.Path),
+				zap.String("err", err.Error()),
+			)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read %v: %v", fr.Path, err)
+		}
+		fr.Next = tick
+		return nil
+	}
+}
@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+func writeFile(t *testing.T, dir, name, data string, compress bool) string {
+	t.Helper()
+	content := []byte(data)
+	if compress {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		if _, err := gz.Write(content); err != nil {
+			t.Fatalf("gzip %v: %v", name, err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatalf("gzip %v: %v", name, err)
+		}
+		content = buf.Bytes()
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatalf("write %v: %v", name, err)
+	}
+	return path
+}
+
+// readSource - все сделки источника до конца ленты
+func readSource(t *testing.T, source dealsFlowPkg.MarketDataSource) []*dealsFlowPkg.Tick {
+	t.Helper()
+	defer source.Close()
+	ticks := make([]*dealsFlowPkg.Tick, 0)
+	for {
+		tick, err := source.Next()
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if tick == nil {
+			return ticks
+		}
+		ticks = append(ticks, tick)
+	}
+}
+
+func TestOpenSource(t *testing.T) {
+	const header = "<TICKER>,<PER>,<DATE>,<TIME>,<LAST>,<VOL>\n"
+	rts := header +
+		"SPFB.RTS,0,20180518,130001,100,1\n" +
+		"SPFB.RTS,0,20180518,130003,101,1\n" +
+		"SPFB.RTS,0,20180518,130005,102,1\n"
+	si := header +
+		"SPFB.Si,0,20180518,130002,60000,2\n" +
+		"SPFB.Si,0,20180518,130004,60001,2\n"
+	logger := &logging.Logger{Zap: zap.NewNop()}
+
+	tests := []struct {
+		name      string
+		source    string
+		files     map[string]string
+		compress  map[string]bool
+		from      string
+		wantTicks []*dealsFlowPkg.Tick
+	}{
+		{name: "Файлы по инструментам сливаются по времени",
+			files: map[string]string{"rts.csv": rts, "si.csv": si},
+			wantTicks: []*dealsFlowPkg.Tick{
+				tick("20180518130001", "SPFB.RTS", 100, 1),
+				tick("20180518130002", "SPFB.Si", 60000, 2),
+				tick("20180518130003", "SPFB.RTS", 101, 1),
+				tick("20180518130004", "SPFB.Si", 60001, 2),
+				tick("20180518130005", "SPFB.RTS", 102, 1)},
+		},
+		{name: "Сжатый gzip файл распознается по содержимому, а не по имени",
+			files:    map[string]string{"rts.csv": rts, "si.dat": si},
+			compress: map[string]bool{"si.dat": true},
+			from:     "20180518130003",
+			wantTicks: []*dealsFlowPkg.Tick{
+				tick("20180518130003", "SPFB.RTS", 101, 1),
+				tick("20180518130004", "SPFB.Si", 60001, 2),
+				tick("20180518130005", "SPFB.RTS", 102, 1)},
+		},
+		{name: "jsonl в gzip",
+			source: dealsFlowPkg.SourceJSONL,
+			files: map[string]string{"deals.jsonl.gz": `{"ticker":"SPFB.RTS","time":"20180518130001","price":100,"volume":1}
+{"ticker":"SPFB.RTS","time":"20180518130002","price":101,"volume":3}
+`},
+			compress: map[string]bool{"deals.jsonl.gz": true},
+			wantTicks: []*dealsFlowPkg.Tick{
+				tick("20180518130001", "SPFB.RTS", 100, 1),
+				tick("20180518130002", "SPFB.RTS", 101, 3)},
+		},
+		{name: "Перемотка за конец ленты",
+			files:     map[string]string{"rts.csv": rts},
+			from:      "20180519000000",
+			wantTicks: []*dealsFlowPkg.Tick{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, data := range tt.files {
+				writeFile(t, dir, name, data, tt.compress[name])
+			}
+			config := &configPkg.Config{}
+			config.Exchange.DealsFlowDir = dir
+			config.Exchange.Source.Type = tt.source
+
+			files, err := CheckSource(config)
+			if err != nil {
+				t.Fatalf("CheckSource() error = %v", err)
+			}
+			var from time.Time
+			if tt.from != "" {
+				from = tick(tt.from, "", 0, 0).Time
+			}
+			source, err := OpenSource(config, files, from, logger)
+			if err != nil {
+				t.Fatalf("OpenSource() error = %v", err)
+			}
+			if ticks := readSource(t, source); !reflect.DeepEqual(ticks, tt.wantTicks) {
+				t.Errorf("ticks = %v, want %v", ticks, tt.wantTicks)
+			}
+		})
+	}
+}
+
+func TestOpenSource_BrokenGzip(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "rts.csv", "\x1f\x8bnot gzip", false)
+
+	_, err := OpenSource(&configPkg.Config{}, []string{path}, time.Time{}, &logging.Logger{Zap: zap.NewNop()})
+	if err == nil {
+		t.Errorf("OpenSource() opened broken gzip")
+	}
+}
+
+func TestFlowFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "b.csv", "", false)
+	writeFile(t, dir, "a.csv", "", false)
+	if err := os.Mkdir(filepath.Join(dir, "archive"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	files, err := FlowFiles("ignored.csv", dir)
+	want := []string{filepath.Join(dir, "a.csv"), filepath.Join(dir, "b.csv")}
+	if err != nil || !reflect.DeepEqual(files, want) {
+		t.Errorf("FlowFiles() = %v, %v, want %v", files, err, want)
+	}
+	if files, err = FlowFiles("deals.csv", ""); err != nil || !reflect.DeepEqual(files, []string{"deals.csv"}) {
+		t.Errorf("FlowFiles() single file = %v, %v", files, err)
+	}
+	if _, err = FlowFiles("", ""); err == nil {
+		t.Errorf("FlowFiles() without settings returned no error")
+	}
+	if _, err = FlowFiles("", t.TempDir()); err == nil {
+		t.Errorf("FlowFiles() of empty directory returned no error")
+	}
+}
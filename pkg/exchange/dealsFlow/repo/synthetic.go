@@ -0,0 +1,117 @@
+package repo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+)
+
+// модели цены синтетической ленты
+const (
+	ModelWalk = "walk"
+	ModelGBM  = "gbm"
+)
+
+// minPrice - цена не опускается ниже шага цены
+const minPrice = 0.01
+
+type syntheticTicker struct {
+	Ticker     string
+	Price      float64
+	Drift      float64
+	Volatility float64
+	Volume     int
+}
+
+// Generator - бесконечная синтетическая лента для нагрузочных тестов:
+// каждые Step по сделке на инструмент, цена - случайное блуждание или геометрическое броуновское движение
+type Generator struct {
+	Model   string
+	Step    time.Duration
+	Tickers []*syntheticTicker
+	Rand    *rand.Rand
+	Time    time.Time
+	next    int
+}
+
+// NewGenerator - лента с from, нулевое - с текущей секунды; с одинаковым Seed цены повторяются
+func NewGenerator(config *configPkg.Config, from time.Time) (*Generator, error) {
+	cfg := config.Exchange.Source.Synthetic
+	g := &Generator{
+		Model: cfg.Model,
+		Step:  time.Duration(cfg.Step) * time.Second,
+		Time:  from,
+	}
+	if g.Model == "" {
+		g.Model = ModelWalk
+	}
+	if g.Model != ModelWalk && g.Model != ModelGBM {
+		return nil, fmt.Errorf("%w: unknown synthetic model %v", dealsFlowPkg.ErrBadSource, cfg.Model)
+	}
+	if g.Step <= 0 {
+		g.Step = time.Second
+	}
+	if g.Time.IsZero() {
+		g.Time = time.Now().UTC().Truncate(time.Second)
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	g.Rand = rand.New(rand.NewSource(seed))
+
+	if len(cfg.Tickers) == 0 {
+		return nil, fmt.Errorf("%w: no synthetic tickers", dealsFlowPkg.ErrBadSource)
+	}
+	for _, t := range cfg.Tickers {
+		if t.Ticker == "" || t.Price <= 0 || t.Volatility < 0 {
+			return nil, fmt.Errorf("%w: synthetic ticker %v needs positive price and non-negative volatility",
+				dealsFlowPkg.ErrBadSource, t.Ticker)
+		}
+		volume := t.Volume
+		if volume <= 0 {
+			volume = 1
+		}
+		g.Tickers = append(g.Tickers, &syntheticTicker{
+			Ticker:     t.Ticker,
+			Price:      t.Price,
+			Drift:      t.Drift,
+			Volatility: t.Volatility,
+			Volume:     volume,
+		})
+	}
+	return g, nil
+}
+
+func (g *Generator) Next() (*dealsFlowPkg.Tick, error) {
+	if g.next == len(g.Tickers) {
+		g.next = 0
+		g.Time = g.Time.Add(g.Step)
+	}
+	t := g.Tickers[g.next]
+	g.next++
+
+	shock := g.Rand.NormFloat64()
+	if g.Model == ModelGBM {
+		t.Price *= math.Exp(t.Drift - t.Volatility*t.Volatility/2 + t.Volatility*shock)
+	} else {
+		t.Price += t.Drift + t.Volatility*shock
+	}
+	t.Price = math.Max(math.Round(t.Price*100)/100, minPrice)
+
+	return &dealsFlowPkg.Tick{
+		Time: g.Time,
+		Deal: &dealPkg.Deal{
+			Ticker: t.Ticker,
+			Price:  float32(t.Price),
+			Volume: int32(1 + g.Rand.Intn(t.Volume)),
+		},
+	}, nil
+}
+
+func (g *Generator) Close() {}
@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+)
+
+func syntheticConfig(model string, seed int64) *configPkg.Config {
+	config := &configPkg.Config{}
+	config.Exchange.Source.Type = dealsFlowPkg.SourceSynthetic
+	config.Exchange.Source.Synthetic.Model = model
+	config.Exchange.Source.Synthetic.Step = 2
+	config.Exchange.Source.Synthetic.Seed = seed
+	synthetic := &config.Exchange.Source.Synthetic
+	synthetic.Tickers = append(synthetic.Tickers, struct {
+		Ticker     string
+		Price      float64
+		Drift      float64
+		Volatility float64
+		Volume     int
+	}{Ticker: "SPFB.RTS", Price: 100000, Volatility: 50, Volume: 10})
+	synthetic.Tickers = append(synthetic.Tickers, struct {
+		Ticker     string
+		Price      float64
+		Drift      float64
+		Volatility float64
+		Volume     int
+	}{Ticker: "SPFB.Si", Price: 0.02, Drift: -1, Volatility: 0.1})
+	return config
+}
+
+func generate(t *testing.T, config *configPkg.Config, from time.Time, n int) []*dealsFlowPkg.Tick {
+	t.Helper()
+	g, err := NewGenerator(config, from)
+	if err != nil {
+		t.Fatalf("NewGenerator() error = %v", err)
+	}
+	ticks := make([]*dealsFlowPkg.Tick, n)
+	for i := range ticks {
+		if ticks[i], err = g.Next(); err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+	}
+	return ticks
+}
+
+func TestGenerator(t *testing.T) {
+	from := time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+	for _, model := range []string{ModelWalk, ModelGBM} {
+		t.Run(model, func(t *testing.T) {
+			first := generate(t, syntheticConfig(model, 42), from, 100)
+			second := generate(t, syntheticConfig(model, 42), from, 100)
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("same seed generated different ticks")
+			}
+			if other := generate(t, syntheticConfig(model, 43), from, 100); reflect.DeepEqual(first, other) {
+				t.Errorf("different seeds generated same ticks")
+			}
+
+			for i, tick := range first {
+				//по сделке на инструмент каждый шаг
+				wantTime := from.Add(time.Duration(i/2) * 2 * time.Second)
+				if !tick.Time.Equal(wantTime) {
+					t.Fatalf("tick %v time %v, want %v", i, tick.Time, wantTime)
+				}
+				if tick.Deal.Price < minPrice {
+					t.Errorf("tick %v price %v below %v", i, tick.Deal.Price, minPrice)
+				}
+				maxVolume := int32(10)
+				if tick.Deal.Ticker == "SPFB.Si" {
+					maxVolume = 1
+				}
+				if tick.Deal.Volume < 1 || tick.Deal.Volume > maxVolume {
+					t.Errorf("tick %v volume %v, want from 1 to %v", i, tick.Deal.Volume, maxVolume)
+				}
+			}
+		})
+	}
+}
+
+func TestNewGenerator_BadConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(config *configPkg.Config)
+	}{
+		{name: "Неизвестная модель",
+			configure: func(config *configPkg.Config) { config.Exchange.Source.Synthetic.Model = "levy" },
+		},
+		{name: "Нет инструментов",
+			configure: func(config *configPkg.Config) { config.Exchange.Source.Synthetic.Tickers = nil },
+		},
+		{name: "Нулевая цена",
+			configure: func(config *configPkg.Config) { config.Exchange.Source.Synthetic.Tickers[0].Price = 0 },
+		},
+		{name: "Отрицательная волатильность",
+			configure: func(config *configPkg.Config) { config.Exchange.Source.Synthetic.Tickers[1].Volatility = -1 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := syntheticConfig(ModelWalk, 1)
+			tt.configure(config)
+			if _, err := CheckSource(config); !errors.Is(err, dealsFlowPkg.ErrBadSource) {
+				t.Errorf("CheckSource() error = %v, want %v", err, dealsFlowPkg.ErrBadSource)
+			}
+		})
+	}
+}
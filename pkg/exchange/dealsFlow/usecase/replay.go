@@ -0,0 +1,326 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+	dealsFlowRepoPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow/repo"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+const (
+	// retryPause - пауза перед повторным открытием ленты после ошибки чтения или пустого круга
+	retryPause = 5 * time.Second
+	// commandsBuffer - сколько команд ждут, пока воспроизведение занято открытием ленты
+	commandsBuffer = 16
+	// commandTimeout - сколько команда ждет места в очереди
+	commandTimeout = 5 * time.Second
+)
+
+const (
+	commandPause  = "pause"
+	commandResume = "resume"
+	commandSeek   = "seek"
+)
+
+type command struct {
+	Name string
+	Time time.Time
+}
+
+// Replay воспроизводит ленту MarketDataSource в DealsFlowCh с паузами между сделками по их времени,
+// деленными на скорость; управляется командами Pause, Resume и Seek.
+// Open открывает ленту с from, RetryPause - пауза перед повторным открытием
+type Replay struct {
+	Config     *configPkg.Config
+	Files      []string // файлы ленты, у синтетической - нет
+	Speed      float64  // 0 - без пауз
+	Start      time.Time
+	End        time.Time
+	Loop       bool
+	Out        chan *dealPkg.Deal
+	Status     dealsFlowPkg.ReplayStatus
+	Open       func(from time.Time, logger *logging.Logger) (dealsFlowPkg.MarketDataSource, error)
+	RetryPause time.Duration
+	Mux        *sync.Mutex
+	commands   chan command
+}
+
+func NewReplay(config *configPkg.Config, out chan *dealPkg.Deal) (*Replay, error) {
+	files, err := dealsFlowRepoPkg.CheckSource(config)
+	if err != nil {
+		return nil, err
+	}
+	speed, err := ParseSpeed(config.Exchange.Replay.Speed)
+	if err != nil {
+		return nil, err
+	}
+	replay := &Replay{
+		Config: config,
+		Files:  files,
+		Speed:  speed,
+		Loop:   config.Exchange.Replay.Loop,
+		Out:    out,
+		Open: func(from time.Time, logger *logging.Logger) (dealsFlowPkg.MarketDataSource, error) {
+			return dealsFlowRepoPkg.OpenSource(config, files, from, logger)
+		},
+		RetryPause: retryPause,
+		Mux:        &sync.Mutex{},
+		commands:   make(chan command, commandsBuffer),
+	}
+	replay.Start, err = parseReplayTime(config.Exchange.Replay.Start)
+	if err != nil {
+		return nil, err
+	}
+	replay.End, err = parseReplayTime(config.Exchange.Replay.End)
+	if err != nil {
+		return nil, err
+	}
+
+	replay.Status = dealsFlowPkg.ReplayStatus{
+		Speed: speed,
+		Start: unixOrZero(replay.Start),
+		End:   unixOrZero(replay.End),
+		Loop:  replay.Loop,
+	}
+	return replay, nil
+}
+
+// ParseSpeed - множитель скорости: пусто - реальное время, max - без пауз
+func ParseSpeed(speed string) (float64, error) {
+	switch speed {
+	case "":
+		return 1, nil
+	case dealsFlowPkg.SpeedMax:
+		return 0, nil
+	}
+	parsed, err := strconv.ParseFloat(speed, 64)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("%w: %v", dealsFlowPkg.ErrBadSpeed, speed)
+	}
+	return parsed, nil
+}
+
+// parseReplayTime - граница воспроизведения в формате ленты (20060102150405), пусто - без ограничения
+func parseReplayTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dealsFlowPkg.TimeLayout, value)
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// Pause, Resume и Seek ставят команду в очередь и сразу меняют Status, чтобы ответ на команду его отражал,
+// воспроизведение выполнит команду, как только освободится
+func (r *Replay) Pause(ctx context.Context) error {
+	err := r.send(ctx, command{Name: commandPause})
+	if err != nil {
+		return err
+	}
+	r.update(func(status *dealsFlowPkg.ReplayStatus) { status.Paused = true })
+	return nil
+}
+
+func (r *Replay) Resume(ctx context.Context) error {
+	err := r.send(ctx, command{Name: commandResume})
+	if err != nil {
+		return err
+	}
+	r.update(func(status *dealsFlowPkg.ReplayStatus) { status.Paused = false })
+	return nil
+}
+
+// Seek продолжает воспроизведение со сделок не раньше at, в том числе после окончания ленты
+func (r *Replay) Seek(ctx context.Context, at time.Time) error {
+	err := r.send(ctx, command{Name: commandSeek, Time: at})
+	if err != nil {
+		return err
+	}
+	r.update(func(status *dealsFlowPkg.ReplayStatus) {
+		status.Paused = false
+		status.Position = at.Unix()
+	})
+	return nil
+}
+
+// send ставит команду в очередь, ждет места не дольше commandTimeout
+func (r *Replay) send(ctx context.Context, cmd command) error {
+	select {
+	case r.commands <- cmd:
+		return nil
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	select {
+	case r.commands <- cmd:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v command: %v", dealsFlowPkg.ErrReplayBusy, cmd.Name, ctx.Err())
+	}
+}
+
+func (r *Replay) GetStatus() dealsFlowPkg.ReplayStatus {
+	r.Mux.Lock()
+	defer r.Mux.Unlock()
+	return r.Status
+}
+
+// Run воспроизводит ленту от Start до End, с Loop - по кругу
+func (r *Replay) Run(logger *logging.Logger) {
+	from := r.Start
+	for {
+		seek, sent, err := r.play(from, logger)
+		if err != nil {
+			logger.Zap.Error("replay deals flow",
+				zap.String("logger", "dealsFlow"),
+				zap.String("err", err.Error()),
+			)
+			seek = r.waitCommand(time.After(r.RetryPause))
+			if seek == nil {
+				continue
+			}
+		}
+		if seek != nil {
+			from = *seek
+			continue
+		}
+
+		from = r.Start
+		if r.Loop && sent == 0 {
+			//круг без сделок повторялся бы без пауз
+			logger.Zap.Warn("no deals to replay in loop",
+				zap.String("logger", "dealsFlow"),
+			)
+			seek = r.waitCommand(time.After(r.RetryPause))
+			if seek != nil {
+				from = *seek
+			}
+			continue
+		}
+		if r.Loop {
+			r.update(func(status *dealsFlowPkg.ReplayStatus) { status.Loops++ })
+			continue
+		}
+		r.update(func(status *dealsFlowPkg.ReplayStatus) { status.Finished = true })
+		//лента закончилась, воспроизведение можно продолжить только перемоткой
+		for seek == nil {
+			seek = r.waitCommand(nil)
+		}
+		from = *seek
+	}
+}
+
+// play отправляет сделки с from до End, возвращает время перемотки, если она была запрошена,
+// и число отправленных сделок
+func (r *Replay) play(from time.Time, logger *logging.Logger) (*time.Time, int64, error) {
+	source, err := r.Open(from, logger)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer source.Close()
+	r.update(func(status *dealsFlowPkg.ReplayStatus) { status.Finished = false })
+
+	var prev time.Time
+	var sent int64
+	for {
+		tick, err := source.Next()
+		if err != nil {
+			return nil, sent, err
+		}
+		if tick == nil || (!r.End.IsZero() && tick.Time.After(r.End)) {
+			return nil, sent, nil
+		}
+
+		if !prev.IsZero() && r.Speed > 0 && tick.Time.After(prev) {
+			delay := time.Duration(float64(tick.Time.Sub(prev)) / r.Speed)
+			seek := r.waitCommand(time.After(delay))
+			if seek != nil {
+				return seek, sent, nil
+			}
+		}
+		prev = tick.Time
+
+		//время сделки - время ленты, по нему считаются окно и остановки торгов
+		tick.Deal.Time = int32(tick.Time.Unix())
+		seek := r.sendDeal(tick.Deal)
+		if seek != nil {
+			return seek, sent, nil
+		}
+		sent++
+		r.update(func(status *dealsFlowPkg.ReplayStatus) {
+			status.Position = tick.Time.Unix()
+			status.Sent++
+		})
+	}
+}
+
+// sendDeal ждет места в канале сделок, продолжая принимать команды
+func (r *Replay) sendDeal(deal *dealPkg.Deal) *time.Time {
+	for {
+		select {
+		case r.Out <- deal:
+			return nil
+		case cmd := <-r.commands:
+			seek := r.handle(cmd)
+			if seek != nil {
+				return seek
+			}
+		}
+	}
+}
+
+// waitCommand ждет timeout (nil - без ограничения), выполняя команды; на паузе timeout не учитывается
+func (r *Replay) waitCommand(timeout <-chan time.Time) *time.Time {
+	for {
+		select {
+		case <-timeout:
+			return nil
+		case cmd := <-r.commands:
+			seek := r.handle(cmd)
+			if seek != nil {
+				return seek
+			}
+		}
+	}
+}
+
+// handle выполняет команду, на паузе ждет продолжения или перемотки
+func (r *Replay) handle(cmd command) *time.Time {
+	for {
+		switch cmd.Name {
+		case commandSeek:
+			r.update(func(status *dealsFlowPkg.ReplayStatus) {
+				status.Paused = false
+				status.Position = cmd.Time.Unix()
+			})
+			return &cmd.Time
+		case commandResume:
+			r.update(func(status *dealsFlowPkg.ReplayStatus) { status.Paused = false })
+			return nil
+		}
+
+		r.update(func(status *dealsFlowPkg.ReplayStatus) { status.Paused = true })
+		cmd = <-r.commands
+	}
+}
+
+func (r *Replay) update(change func(status *dealsFlowPkg.ReplayStatus)) {
+	r.Mux.Lock()
+	defer r.Mux.Unlock()
+	change(&r.Status)
+}
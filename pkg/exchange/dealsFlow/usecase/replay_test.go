@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+	dealsFlowPkg "github.com/KeynihAV/exchange/pkg/exchange/dealsFlow"
+	"github.com/KeynihAV/exchange/pkg/logging"
+	"go.uber.org/zap"
+)
+
+var replayStart = time.Date(2022, 12, 1, 10, 0, 0, 0, time.UTC)
+
+// fakeSource - лента из заданных сделок
+type fakeSource struct {
+	Ticks []*dealsFlowPkg.Tick
+}
+
+func (fs *fakeSource) Next() (*dealsFlowPkg.Tick, error) {
+	if len(fs.Ticks) == 0 {
+		return nil, nil
+	}
+	tick := fs.Ticks[0]
+	fs.Ticks = fs.Ticks[1:]
+	return tick, nil
+}
+
+func (fs *fakeSource) Close() {}
+
+// newTestReplay - воспроизведение ticks сделок по секунде от replayStart, каждое открытие ленты уходит в opens
+func newTestReplay(ticks int, opens chan time.Time) *Replay {
+	return &Replay{
+		Out: make(chan *dealPkg.Deal),
+		Open: func(from time.Time, logger *logging.Logger) (dealsFlowPkg.MarketDataSource, error) {
+			opens <- from
+			source := &fakeSource{}
+			for i := 0; i < ticks; i++ {
+				at := replayStart.Add(time.Duration(i) * time.Second)
+				if !at.Before(from) {
+					source.Ticks = append(source.Ticks, &dealsFlowPkg.Tick{Time: at, Deal: &dealPkg.Deal{ID: int64(i + 1)}})
+				}
+			}
+			return source, nil
+		},
+		RetryPause: time.Hour,
+		Mux:        &sync.Mutex{},
+		commands:   make(chan command, commandsBuffer),
+	}
+}
+
+func testLogger() *logging.Logger {
+	return &logging.Logger{Zap: zap.NewNop()}
+}
+
+func receive(t *testing.T, out chan *dealPkg.Deal) int64 {
+	t.Helper()
+	select {
+	case deal := <-out:
+		//сделка несет время своего тика
+		if want := int32(replayStart.Add(time.Duration(deal.ID-1) * time.Second).Unix()); deal.Time != want {
+			t.Errorf("deal %v time %v, want %v", deal.ID, deal.Time, want)
+		}
+		return deal.ID
+	case <-time.After(time.Second):
+		t.Fatalf("no deal replayed")
+	}
+	return 0
+}
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		speed   string
+		want    float64
+		wantErr error
+	}{
+		{speed: "", want: 1},
+		{speed: dealsFlowPkg.SpeedMax, want: 0},
+		{speed: "2.5", want: 2.5},
+		{speed: "0", wantErr: dealsFlowPkg.ErrBadSpeed},
+		{speed: "-1", wantErr: dealsFlowPkg.ErrBadSpeed},
+		{speed: "fast", wantErr: dealsFlowPkg.ErrBadSpeed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.speed, func(t *testing.T) {
+			got, err := ParseSpeed(tt.speed)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("ParseSpeed() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReplay_Run(t *testing.T) {
+	opens := make(chan time.Time, 10)
+	replay := newTestReplay(3, opens)
+	replay.Start = replayStart
+	go replay.Run(testLogger())
+
+	<-opens
+	for want := int64(1); want <= 3; want++ {
+		if got := receive(t, replay.Out); got != want {
+			t.Fatalf("replayed deal %v, want %v", got, want)
+		}
+	}
+
+	//после окончания ленты воспроизведение ждет перемотки
+	ctx := context.Background()
+	if err := replay.Seek(ctx, replayStart.Add(2*time.Second)); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	if from := <-opens; !from.Equal(replayStart.Add(2 * time.Second)) {
+		t.Errorf("reopened from %v, want %v", from, replayStart.Add(2*time.Second))
+	}
+	if got := receive(t, replay.Out); got != 3 {
+		t.Errorf("replayed deal after seek %v, want 3", got)
+	}
+}
+
+func TestReplay_PauseResume(t *testing.T) {
+	opens := make(chan time.Time, 10)
+	replay := newTestReplay(3, opens)
+	go replay.Run(testLogger())
+	<-opens
+
+	ctx := context.Background()
+	if err := replay.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if !replay.GetStatus().Paused {
+		t.Errorf("status is not paused")
+	}
+	select {
+	case deal := <-replay.Out:
+		t.Fatalf("deal %v replayed on pause", deal.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := replay.Resume(ctx); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if got := receive(t, replay.Out); got != 1 {
+		t.Errorf("replayed deal after resume %v, want 1", got)
+	}
+	if replay.GetStatus().Paused {
+		t.Errorf("status is paused after resume")
+	}
+}
+
+func TestReplay_CommandsWhileOpening(t *testing.T) {
+	opened := make(chan struct{})
+	replay := newTestReplay(0, nil)
+	replay.Open = func(from time.Time, logger *logging.Logger) (dealsFlowPkg.MarketDataSource, error) {
+		<-opened
+		return &fakeSource{}, nil
+	}
+	go replay.Run(testLogger())
+
+	//пока лента открывается, команды встают в очередь и не блокируют вызывающего
+	ctx := context.Background()
+	for i := 0; i < commandsBuffer; i++ {
+		if err := replay.Pause(ctx); err != nil {
+			t.Fatalf("Pause() %v error = %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := replay.Resume(ctx); !errors.Is(err, dealsFlowPkg.ErrReplayBusy) {
+		t.Errorf("Resume() with full queue error = %v, want %v", err, dealsFlowPkg.ErrReplayBusy)
+	}
+	if !replay.GetStatus().Paused {
+		t.Errorf("rejected command changed status")
+	}
+	close(opened)
+}
+
+func TestReplay_EmptyLoop(t *testing.T) {
+	opens := make(chan time.Time, 10)
+	replay := newTestReplay(0, opens)
+	replay.Loop = true
+	go replay.Run(testLogger())
+
+	//пустой круг не повторяется без паузы
+	<-opens
+	select {
+	case <-opens:
+		t.Fatalf("empty source reopened without backoff")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if loops := replay.GetStatus().Loops; loops != 0 {
+		t.Errorf("loops %v, want 0", loops)
+	}
+
+	//перемотка прерывает паузу
+	if err := replay.Seek(context.Background(), replayStart); err != nil {
+		t.Fatalf("Seek() error = %v", err)
+	}
+	select {
+	case from := <-opens:
+		if !from.Equal(replayStart) {
+			t.Errorf("reopened from %v, want %v", from, replayStart)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("seek did not reopen source")
+	}
+}
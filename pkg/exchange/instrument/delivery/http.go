@@ -0,0 +1,36 @@
+package delivery
+
+import (
+	"net/http"
+
+	"github.com/KeynihAV/exchange/pkg/common"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+	instrumentUsecasePkg "github.com/KeynihAV/exchange/pkg/exchange/instrument/usecase"
+	"github.com/gorilla/mux"
+)
+
+// InstrumentsHandler - ведение справочника инструментов через административный порт биржи
+type InstrumentsHandler struct {
+	InstrumentsManager *instrumentUsecasePkg.InstrumentsManager
+}
+
+func (ih *InstrumentsHandler) List(w http.ResponseWriter, r *http.Request) {
+	common.WriteStructToResponse(ih.InstrumentsManager.List(), r.Context(), w)
+}
+
+// Save добавляет или заменяет инструмент целиком, в том числе меняет статус торгов
+func (ih *InstrumentsHandler) Save(w http.ResponseWriter, r *http.Request) {
+	instrument := &instrumentPkg.Instrument{}
+	ok := common.GetStructFromRequest(instrument, r, w)
+	if !ok {
+		return
+	}
+	instrument.Ticker = mux.Vars(r)["ticker"]
+
+	err := ih.InstrumentsManager.Save(instrument)
+	if err != nil {
+		common.RespJSONError(w, http.StatusBadRequest, err, err.Error(), r.Context())
+		return
+	}
+	common.WriteStructToResponse(instrument, r.Context(), w)
+}
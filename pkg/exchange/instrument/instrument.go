@@ -0,0 +1,99 @@
+package instrument
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+)
+
+const (
+	StatusTrading  = "trading"
+	StatusHalted   = "halted"
+	StatusDelisted = "delisted"
+)
+
+// CodeNotTrading - код отказа в заявке по инструменту, торги которым приостановлены, отдается клиенту вместе с текстом ошибки
+const CodeNotTrading = "not_trading"
+
+var (
+	// ErrUnknownInstrument - инструмента нет в справочнике биржи
+	ErrUnknownInstrument = errors.New("unknown instrument")
+	// ErrNotTrading - торги инструментом приостановлены или прекращены
+	ErrNotTrading = errors.New("instrument is not trading")
+)
+
+// Instrument - справочные данные инструмента: цена кратна TickSize, объем заявки - LotSize,
+// PriceMin и PriceMax - статические ценовые границы, 0 - без ограничения
+type Instrument struct {
+	Ticker      string
+	Description string
+	TickSize    float32
+	LotSize     int32
+	Currency    string
+	PriceMin    float32
+	PriceMax    float32
+	Status      string
+}
+
+func (i *Instrument) Validate() error {
+	if i.Ticker == "" {
+		return fmt.Errorf("ticker must be set")
+	}
+	if i.TickSize <= 0 || i.LotSize <= 0 {
+		return fmt.Errorf("%v: tick size and lot size must be positive", i.Ticker)
+	}
+	if i.PriceMin < 0 || i.PriceMax < 0 || (i.PriceMax > 0 && i.PriceMin > i.PriceMax) {
+		return fmt.Errorf("%v: bad price bands %v-%v", i.Ticker, i.PriceMin, i.PriceMax)
+	}
+	switch i.Status {
+	case StatusTrading, StatusHalted, StatusDelisted:
+	default:
+		return fmt.Errorf("%v: unknown status %v", i.Ticker, i.Status)
+	}
+	return nil
+}
+
+// CheckOrder - заявка по инструменту: торги идут, цены кратны шагу и в статических границах, объем кратен лоту
+func (i *Instrument) CheckOrder(order *dealPkg.Order) error {
+	if i.Status != StatusTrading {
+		return fmt.Errorf("%w: %v is %v", ErrNotTrading, i.Ticker, i.Status)
+	}
+	if order.Volume%i.LotSize != 0 {
+		return fmt.Errorf("%w: volume %v is not a multiple of lot size %v", dealPkg.ErrInvalidOrder, order.Volume, i.LotSize)
+	}
+	for _, price := range []float32{order.Price, order.StopPrice} {
+		if price == 0 {
+			continue
+		}
+		if !i.onTick(price) {
+			return fmt.Errorf("%w: price %v is not a multiple of tick size %v", dealPkg.ErrInvalidOrder, price, i.TickSize)
+		}
+		if (i.PriceMin > 0 && price < i.PriceMin) || (i.PriceMax > 0 && price > i.PriceMax) {
+			return fmt.Errorf("%w: price %v is outside of %v-%v", dealPkg.ErrInvalidOrder, price, i.PriceMin, i.PriceMax)
+		}
+	}
+	return nil
+}
+
+// onTick - цена кратна шагу: ближайшее целое число шагов дает ту же цену float32 с точностью до ее младшего разряда,
+// поэтому допуск растет вместе с ценой
+func (i *Instrument) onTick(price float32) bool {
+	//шаг в float32 неточен (0.01 - это 0.0099999998), считаем от его десятичной записи
+	tick, err := strconv.ParseFloat(strconv.FormatFloat(float64(i.TickSize), 'g', -1, 32), 64)
+	if err != nil || tick <= 0 {
+		return false
+	}
+	onTick := float32(math.Round(float64(price)/tick) * tick)
+	return onTick == price || math.Nextafter32(onTick, price) == price
+}
+
+// HaltEvent - остановка торгов инструментом при резком движении цены до Until по ленте или их возобновление
+type HaltEvent struct {
+	Ticker string
+	Halted bool
+	Time   int32
+	Until  int32
+}
@@ -0,0 +1,93 @@
+package instrument
+
+import (
+	"errors"
+	"testing"
+
+	dealPkg "github.com/KeynihAV/exchange/pkg/exchange/deal"
+)
+
+func TestInstrument_onTick(t *testing.T) {
+	tests := []struct {
+		name  string
+		price float32
+		tick  float32
+		want  bool
+	}{
+		{name: "Копейки", price: 1234.56, tick: 0.01, want: true},
+		{name: "Копейки у большой цены", price: 76543.21, tick: 0.01, want: true},
+		{name: "Копейки у маленькой цены", price: 0.07, tick: 0.01, want: true},
+		{name: "Полкопейки", price: 1234.565, tick: 0.01, want: false},
+		{name: "Тысячные доли", price: 1.234, tick: 0.001, want: true},
+		{name: "Меньше тысячной доли", price: 1.2345, tick: 0.001, want: false},
+		{name: "Шаг 0.25", price: 99.75, tick: 0.25, want: true},
+		{name: "Не кратно 0.25", price: 99.8, tick: 0.25, want: false},
+		{name: "Шаг 10", price: 116650, tick: 10, want: true},
+		{name: "Не кратно 10", price: 116655, tick: 10, want: false},
+		{name: "Целый шаг у большой цены", price: 9999999, tick: 1, want: true},
+		{name: "Шаг 0.1 у большой цены", price: 87654.3, tick: 0.1, want: true},
+		{name: "Не кратно 0.1 у большой цены", price: 87654.35, tick: 0.1, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := &Instrument{TickSize: tt.tick}
+			if got := i.onTick(tt.price); got != tt.want {
+				t.Errorf("onTick(%v, %v) = %v, want %v", tt.price, tt.tick, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstrument_CheckOrder(t *testing.T) {
+	instrument := &Instrument{Ticker: "SPFB.RTS", TickSize: 0.01, LotSize: 10, PriceMin: 100, PriceMax: 200,
+		Status: StatusTrading}
+	tests := []struct {
+		name    string
+		status  string
+		order   *dealPkg.Order
+		wantErr error
+	}{
+		{name: "Заявка по правилам",
+			order: &dealPkg.Order{Price: 150.25, Volume: 20},
+		},
+		{name: "Рыночная заявка без цены",
+			order: &dealPkg.Order{Volume: 10},
+		},
+		{name: "Торги остановлены",
+			status:  StatusHalted,
+			order:   &dealPkg.Order{Price: 150, Volume: 10},
+			wantErr: ErrNotTrading,
+		},
+		{name: "Объем не кратен лоту",
+			order:   &dealPkg.Order{Price: 150, Volume: 15},
+			wantErr: dealPkg.ErrInvalidOrder,
+		},
+		{name: "Цена не кратна шагу",
+			order:   &dealPkg.Order{Price: 150.255, Volume: 10},
+			wantErr: dealPkg.ErrInvalidOrder,
+		},
+		{name: "Стоп-цена не кратна шагу",
+			order:   &dealPkg.Order{StopPrice: 150.001, Volume: 10},
+			wantErr: dealPkg.ErrInvalidOrder,
+		},
+		{name: "Цена ниже границы",
+			order:   &dealPkg.Order{Price: 99.99, Volume: 10},
+			wantErr: dealPkg.ErrInvalidOrder,
+		},
+		{name: "Стоп-цена выше границы",
+			order:   &dealPkg.Order{Price: 150, StopPrice: 200.01, Volume: 10},
+			wantErr: dealPkg.ErrInvalidOrder,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checked := *instrument
+			if tt.status != "" {
+				checked.Status = tt.status
+			}
+			if err := checked.CheckOrder(tt.order); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
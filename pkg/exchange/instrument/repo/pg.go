@@ -0,0 +1,76 @@
+package repo
+
+import (
+	"database/sql"
+
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+type InstrumentsRepo struct {
+	DB *sql.DB
+}
+
+func NewInstrumentsRepo(db *sql.DB) (*InstrumentsRepo, error) {
+	_, err := db.Exec(
+		`CREATE TABLE IF NOT EXISTS instruments(
+			ticker varchar(200) PRIMARY KEY,
+			description varchar(500) NOT NULL DEFAULT '',
+			tickSize float8 NOT NULL,
+			lotSize int NOT NULL,
+			currency varchar(10) NOT NULL DEFAULT '',
+			priceMin float8 NOT NULL DEFAULT 0,
+			priceMax float8 NOT NULL DEFAULT 0,
+			status varchar(10) NOT NULL DEFAULT 'trading');`)
+	if err != nil {
+		return nil, err
+	}
+	return &InstrumentsRepo{DB: db}, nil
+}
+
+// AddInstrument добавляет инструмент, если его еще нет, существующий не меняется
+func (ir *InstrumentsRepo) AddInstrument(instrument *instrumentPkg.Instrument) error {
+	_, err := ir.DB.Exec(`
+	INSERT INTO instruments(ticker, description, tickSize, lotSize, currency, priceMin, priceMax, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (ticker) DO NOTHING`,
+		instrument.Ticker, instrument.Description, instrument.TickSize, instrument.LotSize, instrument.Currency,
+		instrument.PriceMin, instrument.PriceMax, instrument.Status)
+	return err
+}
+
+// SaveInstrument добавляет или полностью заменяет инструмент
+func (ir *InstrumentsRepo) SaveInstrument(instrument *instrumentPkg.Instrument) error {
+	_, err := ir.DB.Exec(`
+	INSERT INTO instruments(ticker, description, tickSize, lotSize, currency, priceMin, priceMax, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (ticker) DO UPDATE SET description = $2, tickSize = $3, lotSize = $4, currency = $5,
+		priceMin = $6, priceMax = $7, status = $8`,
+		instrument.Ticker, instrument.Description, instrument.TickSize, instrument.LotSize, instrument.Currency,
+		instrument.PriceMin, instrument.PriceMax, instrument.Status)
+	return err
+}
+
+func (ir *InstrumentsRepo) GetInstruments() ([]*instrumentPkg.Instrument, error) {
+	queryResult, err := ir.DB.Query(`
+	SELECT ticker, description, tickSize, lotSize, currency, priceMin, priceMax, status
+	FROM instruments
+	ORDER BY ticker`)
+	if err != nil {
+		return nil, err
+	}
+	defer queryResult.Close()
+
+	result := make([]*instrumentPkg.Instrument, 0)
+	for queryResult.Next() {
+		instrument := &instrumentPkg.Instrument{}
+		err = queryResult.Scan(&instrument.Ticker, &instrument.Description, &instrument.TickSize, &instrument.LotSize,
+			&instrument.Currency, &instrument.PriceMin, &instrument.PriceMax, &instrument.Status)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, instrument)
+	}
+
+	return result, nil
+}
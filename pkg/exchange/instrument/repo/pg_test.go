@@ -0,0 +1,157 @@
+package repo
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+	sqlmock "gopkg.in/DATA-DOG/go-sqlmock.v2"
+)
+
+func TestInstrumentsRepo_AddInstrument(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	instrument := &instrumentPkg.Instrument{Ticker: "ticker1", Description: "desc", TickSize: 0.5, LotSize: 10,
+		Currency: "RUB", PriceMin: 1, PriceMax: 100, Status: "trading"}
+	tests := []struct {
+		name    string
+		ir      *InstrumentsRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка insert",
+			ir:      &InstrumentsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO instruments.*DO NOTHING`).WillReturnError(fmt.Errorf("insert error"))
+			},
+		},
+		{name: "Успешный insert",
+			ir:      &InstrumentsRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO instruments.*DO NOTHING`).
+					WithArgs("ticker1", "desc", float32(0.5), int32(10), "RUB", float32(1), float32(100), "trading").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			err := tt.ir.AddInstrument(instrument)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InstrumentsRepo.AddInstrument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstrumentsRepo_SaveInstrument(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	instrument := &instrumentPkg.Instrument{Ticker: "ticker1", TickSize: 1, LotSize: 1, Status: "halted"}
+	tests := []struct {
+		name    string
+		ir      *InstrumentsRepo
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка upsert",
+			ir:      &InstrumentsRepo{DB: db},
+			wantErr: true,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO instruments.*DO UPDATE`).WillReturnError(fmt.Errorf("upsert error"))
+			},
+		},
+		{name: "Успешный upsert",
+			ir:      &InstrumentsRepo{DB: db},
+			wantErr: false,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectExec(`INSERT INTO instruments.*DO UPDATE`).
+					WithArgs("ticker1", "", float32(1), int32(1), "", float32(0), float32(0), "halted").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			err := tt.ir.SaveInstrument(instrument)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InstrumentsRepo.SaveInstrument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInstrumentsRepo_GetInstruments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("cant create mock: %s", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name    string
+		ir      *InstrumentsRepo
+		want    []*instrumentPkg.Instrument
+		wantErr bool
+		mockF   func(sqlmock.Sqlmock)
+	}{
+		{name: "Ошибка select",
+			ir:      &InstrumentsRepo{DB: db},
+			wantErr: true,
+			want:    nil,
+			mockF: func(s sqlmock.Sqlmock) {
+				s.ExpectQuery(`SELECT`).WillReturnError(fmt.Errorf("select error"))
+			},
+		},
+		{name: "Ошибка scan",
+			ir:      &InstrumentsRepo{DB: db},
+			wantErr: true,
+			want:    nil,
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"ticker", "description"}).AddRow("ticker1", "desc")
+				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
+			},
+		},
+		{name: "Успешный select",
+			ir:      &InstrumentsRepo{DB: db},
+			wantErr: false,
+			want: []*instrumentPkg.Instrument{
+				{Ticker: "ticker1", Description: "desc", TickSize: 0.5, LotSize: 10, Currency: "RUB", PriceMin: 1,
+					PriceMax: 100, Status: "trading"},
+				{Ticker: "ticker2", TickSize: 1, LotSize: 1, Status: "delisted"}},
+			mockF: func(s sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"ticker", "description", "tickSize", "lotSize", "currency", "priceMin",
+					"priceMax", "status"}).
+					AddRow("ticker1", "desc", 0.5, 10, "RUB", 1, 100, "trading").
+					AddRow("ticker2", "", 1, 1, "", 0, 0, "delisted")
+				s.ExpectQuery(`SELECT`).WillReturnRows(rows)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockF(mock)
+			got, err := tt.ir.GetInstruments()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InstrumentsRepo.GetInstruments() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InstrumentsRepo.GetInstruments() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
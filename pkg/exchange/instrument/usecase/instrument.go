@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+
+	configPkg "github.com/KeynihAV/exchange/pkg/config"
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+	instrumentRepoPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument/repo"
+)
+
+type InstrumentsRepo interface {
+	AddInstrument(instrument *instrumentPkg.Instrument) error
+	SaveInstrument(instrument *instrumentPkg.Instrument) error
+	GetInstruments() ([]*instrumentPkg.Instrument, error)
+}
+
+// InstrumentsManager - справочник инструментов биржи, хранится в БД и целиком в памяти
+type InstrumentsManager struct {
+	IR          InstrumentsRepo
+	Instruments map[string]*instrumentPkg.Instrument
+	Mux         *sync.RWMutex
+}
+
+// NewInstrumentsManager добавляет в справочник инструменты из конфига, которых в нем нет, и загружает его
+func NewInstrumentsManager(db *sql.DB, config *configPkg.Config) (*InstrumentsManager, error) {
+	ir, err := instrumentRepoPkg.NewInstrumentsRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	im := &InstrumentsManager{
+		IR:          ir,
+		Instruments: make(map[string]*instrumentPkg.Instrument),
+		Mux:         &sync.RWMutex{},
+	}
+
+	for _, cfg := range config.Exchange.Instruments {
+		instrument := &instrumentPkg.Instrument{
+			Ticker:      cfg.Ticker,
+			Description: cfg.Description,
+			TickSize:    cfg.TickSize,
+			LotSize:     cfg.LotSize,
+			Currency:    cfg.Currency,
+			PriceMin:    cfg.PriceMin,
+			PriceMax:    cfg.PriceMax,
+			Status:      instrumentPkg.StatusTrading,
+		}
+		err = instrument.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("config instrument: %v", err)
+		}
+		err = ir.AddInstrument(instrument)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	instruments, err := ir.GetInstruments()
+	if err != nil {
+		return nil, err
+	}
+	for _, instrument := range instruments {
+		im.Instruments[instrument.Ticker] = instrument
+	}
+	//инструменты из конфига уже в БД, если их нет в загруженном справочнике - он загрузился не целиком
+	for _, cfg := range config.Exchange.Instruments {
+		if _, ok := im.Instruments[cfg.Ticker]; !ok {
+			return nil, fmt.Errorf("load instruments: %w: %v", instrumentPkg.ErrUnknownInstrument, cfg.Ticker)
+		}
+	}
+	return im, nil
+}
+
+// Get - копия инструмента, менять справочник можно только через Save
+func (im *InstrumentsManager) Get(ticker string) (*instrumentPkg.Instrument, error) {
+	im.Mux.RLock()
+	defer im.Mux.RUnlock()
+
+	instrument, ok := im.Instruments[ticker]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", instrumentPkg.ErrUnknownInstrument, ticker)
+	}
+	result := *instrument
+	return &result, nil
+}
+
+// Empty - справочник еще не заполнен, например, на бирже, запущенной до его появления
+func (im *InstrumentsManager) Empty() bool {
+	im.Mux.RLock()
+	defer im.Mux.RUnlock()
+	return len(im.Instruments) == 0
+}
+
+// List - все инструменты по тикеру
+func (im *InstrumentsManager) List() []*instrumentPkg.Instrument {
+	im.Mux.RLock()
+	defer im.Mux.RUnlock()
+
+	result := make([]*instrumentPkg.Instrument, 0, len(im.Instruments))
+	for _, instrument := range im.Instruments {
+		copied := *instrument
+		result = append(result, &copied)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Ticker < result[j].Ticker
+	})
+	return result
+}
+
+// Save добавляет или заменяет инструмент, пустой статус - торгуется
+func (im *InstrumentsManager) Save(instrument *instrumentPkg.Instrument) error {
+	if instrument.Status == "" {
+		instrument.Status = instrumentPkg.StatusTrading
+	}
+	err := instrument.Validate()
+	if err != nil {
+		return err
+	}
+
+	im.Mux.Lock()
+	defer im.Mux.Unlock()
+
+	err = im.IR.SaveInstrument(instrument)
+	if err != nil {
+		return err
+	}
+	saved := *instrument
+	im.Instruments[instrument.Ticker] = &saved
+	return nil
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	instrumentPkg "github.com/KeynihAV/exchange/pkg/exchange/instrument"
+)
+
+var errSave = errors.New("save failed")
+
+type fakeRepo struct {
+	Saved    []*instrumentPkg.Instrument
+	FailSave bool
+}
+
+func (fr *fakeRepo) AddInstrument(instrument *instrumentPkg.Instrument) error {
+	return nil
+}
+
+func (fr *fakeRepo) SaveInstrument(instrument *instrumentPkg.Instrument) error {
+	if fr.FailSave {
+		return errSave
+	}
+	saved := *instrument
+	fr.Saved = append(fr.Saved, &saved)
+	return nil
+}
+
+func (fr *fakeRepo) GetInstruments() ([]*instrumentPkg.Instrument, error) {
+	return fr.Saved, nil
+}
+
+func newTestManager() (*InstrumentsManager, *fakeRepo) {
+	repo := &fakeRepo{}
+	return &InstrumentsManager{
+		IR:          repo,
+		Instruments: make(map[string]*instrumentPkg.Instrument),
+		Mux:         &sync.RWMutex{},
+	}, repo
+}
+
+func TestInstrumentsManager_Save(t *testing.T) {
+	tests := []struct {
+		name       string
+		instrument instrumentPkg.Instrument
+		failSave   bool
+		wantErr    error
+		wantStatus string
+	}{
+		{name: "Без статуса инструмент торгуется",
+			instrument: instrumentPkg.Instrument{Ticker: "SPFB.RTS", TickSize: 10, LotSize: 1},
+			wantStatus: instrumentPkg.StatusTrading,
+		},
+		{name: "Статус сохраняется",
+			instrument: instrumentPkg.Instrument{Ticker: "SPFB.RTS", TickSize: 10, LotSize: 1,
+				Status: instrumentPkg.StatusHalted},
+			wantStatus: instrumentPkg.StatusHalted,
+		},
+		{name: "Ошибка БД не меняет справочник",
+			instrument: instrumentPkg.Instrument{Ticker: "SPFB.RTS", TickSize: 10, LotSize: 1},
+			failSave:   true,
+			wantErr:    errSave,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im, repo := newTestManager()
+			repo.FailSave = tt.failSave
+			instrument := tt.instrument
+
+			if err := im.Save(&instrument); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Save() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if !im.Empty() {
+					t.Errorf("registry changed after failed save")
+				}
+				return
+			}
+			got, err := im.Get(instrument.Ticker)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got.Status != tt.wantStatus || len(repo.Saved) != 1 {
+				t.Errorf("status = %v, saved %v, want %v, 1", got.Status, len(repo.Saved), tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestInstrumentsManager_SaveInvalid(t *testing.T) {
+	im, repo := newTestManager()
+	for _, instrument := range []*instrumentPkg.Instrument{
+		{TickSize: 1, LotSize: 1},
+		{Ticker: "SPFB.RTS", LotSize: 1},
+		{Ticker: "SPFB.RTS", TickSize: 1, LotSize: 1, PriceMin: 200, PriceMax: 100},
+		{Ticker: "SPFB.RTS", TickSize: 1, LotSize: 1, Status: "closed"},
+	} {
+		if err := im.Save(instrument); err == nil {
+			t.Errorf("Save(%+v) accepted invalid instrument", instrument)
+		}
+	}
+	if len(repo.Saved) != 0 || !im.Empty() {
+		t.Errorf("invalid instruments saved: %v", repo.Saved)
+	}
+}
+
+func TestInstrumentsManager_Get(t *testing.T) {
+	im, _ := newTestManager()
+	if !im.Empty() {
+		t.Fatalf("new registry is not empty")
+	}
+	for _, ticker := range []string{"SPFB.Si", "SPFB.RTS"} {
+		if err := im.Save(&instrumentPkg.Instrument{Ticker: ticker, TickSize: 1, LotSize: 1}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	if _, err := im.Get("SPFB.BR"); !errors.Is(err, instrumentPkg.ErrUnknownInstrument) {
+		t.Errorf("Get() unknown error = %v, want %v", err, instrumentPkg.ErrUnknownInstrument)
+	}
+
+	//справочник меняется только через Save
+	got, _ := im.Get("SPFB.RTS")
+	got.Status = instrumentPkg.StatusDelisted
+	for _, instrument := range im.List() {
+		instrument.LotSize = 100
+	}
+	want := []*instrumentPkg.Instrument{
+		{Ticker: "SPFB.RTS", TickSize: 1, LotSize: 1, Status: instrumentPkg.StatusTrading},
+		{Ticker: "SPFB.Si", TickSize: 1, LotSize: 1, Status: instrumentPkg.StatusTrading},
+	}
+	if list := im.List(); !reflect.DeepEqual(list, want) {
+		t.Errorf("List() = %+v, want %+v", list, want)
+	}
+	if im.Empty() {
+		t.Errorf("filled registry is empty")
+	}
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	statsQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "exchange_stats_queue_depth",
+			Help: "OHLCV waiting to be sent to broker subscriptions",
+		},
+		[]string{"broker"},
+	)
+	statsDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_stats_dropped_total",
+			Help: "OHLCV dropped because broker subscription queue was full",
+		},
+		[]string{"broker"},
+	)
+	statsDisconnects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_stats_disconnects_total",
+			Help: "Broker subscriptions disconnected for being too slow",
+		},
+		[]string{"broker"},
+	)
+	resultsDisconnects = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_results_disconnects_total",
+			Help: "Broker Results streams disconnected because their queue was full",
+		},
+		[]string{"broker"},
+	)
+	tradesDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_trades_dropped_total",
+			Help: "Trade prints dropped because a tape subscriber queue was full",
+		},
+		[]string{"ticker"},
+	)
+	haltsDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "exchange_halt_events_dropped_total",
+			Help: "Trading halts and resumes dropped because a subscriber queue was full",
+		},
+		[]string{"ticker"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(statsQueueDepth, statsDropped, statsDisconnects, resultsDisconnects, tradesDropped,
+		haltsDropped)
+}
+
+func SetStatsQueueDepth(brokerID int64, depth int) {
+	statsQueueDepth.WithLabelValues(strconv.FormatInt(brokerID, 10)).Set(float64(depth))
+}
+
+func StatsDropped(brokerID int64) {
+	statsDropped.WithLabelValues(strconv.FormatInt(brokerID, 10)).Inc()
+}
+
+func StatsDisconnected(brokerID int64) {
+	statsDisconnects.WithLabelValues(strconv.FormatInt(brokerID, 10)).Inc()
+}
+
+func ResultsDisconnected(brokerID int64) {
+	resultsDisconnects.WithLabelValues(strconv.FormatInt(brokerID, 10)).Inc()
+}
+
+func TradeDropped(ticker string) {
+	tradesDropped.WithLabelValues(ticker).Inc()
+}
+
+func HaltEventDropped(ticker string) {
+	haltsDropped.WithLabelValues(ticker).Inc()
+}